@@ -0,0 +1,428 @@
+// Package citygml holds the CityGML building model shared by the converters,
+// mergers and elevate: the output structures they write, the input structures
+// they read back, and the namespace and coordinate helpers around them.
+package citygml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Namespaces that do not change with the CityGML version
+const (
+	GMLNamespace   = "http://www.opengis.net/gml"
+	XLinkNamespace = "http://www.w3.org/1999/xlink"
+	XSINamespace   = "http://www.w3.org/2001/XMLSchema-instance"
+	XALNamespace   = "urn:oasis:names:tc:ciq:xsdschema:xAL:2.0"
+)
+
+// Namespaces holds the module namespace URLs for one CityGML version
+type Namespaces struct {
+	Version string
+	Core    string
+	Bldg    string
+	App     string
+	Gen     string
+	Grp     string
+}
+
+// NamespacesFor returns the namespace set for a supported CityGML version.
+// Only 1.0 and 2.0 are supported; both share the same geometry model.
+func NamespacesFor(version string) (Namespaces, error) {
+	if version != "1.0" && version != "2.0" {
+		return Namespaces{}, fmt.Errorf("unsupported CityGML version %q (supported: 1.0, 2.0)", version)
+	}
+
+	base := "http://www.opengis.net/citygml/"
+	return Namespaces{
+		Version: version,
+		Core:    base + version,
+		Bldg:    base + "building/" + version,
+		App:     base + "appearance/" + version,
+		Gen:     base + "generics/" + version,
+		Grp:     base + "cityobjectgroup/" + version,
+	}, nil
+}
+
+// SchemaLocation pairs a namespace with its XSD under schemas.opengis.net
+func SchemaLocation(namespace, module, version, xsd string) string {
+	return fmt.Sprintf("%s http://schemas.opengis.net/citygml/%s%s/%s", namespace, module, version, xsd)
+}
+
+// SRSName formats an EPSG code as an http URI, or as a URN when form is "urn"
+func SRSName(form, code string) string {
+	if form == "urn" {
+		return "urn:ogc:def:crs:EPSG::" + code
+	}
+	return "http://www.opengis.net/def/crs/EPSG/0/" + code
+}
+
+// CityModel is the output document. Elements carry their prefixes, which the
+// root declares; Namespaces adds prefixes used by passed-through members.
+type CityModel struct {
+	XMLName        xml.Name   `xml:"core:CityModel"`
+	GML            string     `xml:"xmlns:gml,attr"`
+	Core           string     `xml:"xmlns:core,attr"`
+	Bldg           string     `xml:"xmlns:bldg,attr"`
+	App            string     `xml:"xmlns:app,attr"`
+	Gen            string     `xml:"xmlns:gen,attr"`
+	Grp            string     `xml:"xmlns:grp,attr"`
+	XAL            string     `xml:"xmlns:xAL,attr,omitempty"`
+	XLink          string     `xml:"xmlns:xlink,attr"`
+	XSI            string     `xml:"xmlns:xsi,attr"`
+	SchemaLocation string     `xml:"xsi:schemaLocation,attr"`
+	Namespaces     []xml.Attr `xml:",any,attr"`
+	Name           string     `xml:"gml:name,omitempty"`
+
+	BoundedBy        BoundedBy          `xml:"gml:boundedBy"`
+	CityObjectMember []CityObjectMember `xml:"core:cityObjectMember"`
+	AppearanceMember []AppearanceMember `xml:"app:appearanceMember,omitempty"`
+}
+
+type BoundedBy struct {
+	Envelope Envelope `xml:"gml:Envelope"`
+}
+
+type Envelope struct {
+	SrsName      string `xml:"srsName,attr"`
+	SrsDimension string `xml:"srsDimension,attr,omitempty"`
+	LowerCorner  string `xml:"gml:lowerCorner"`
+	UpperCorner  string `xml:"gml:upperCorner"`
+}
+
+// CityObjectMember holds either a Building or, for other city objects, the
+// original member content copied verbatim
+type CityObjectMember struct {
+	Building *Building `xml:"bldg:Building,omitempty"`
+	Raw      string    `xml:",innerxml"`
+}
+
+// Building covers the LOD1 and LOD2 properties the tools write, in schema
+// order. StringAttributes and MeasureAttributes are used by the converters;
+// GenericAttributes keeps attributes copied from input in document order.
+type Building struct {
+	ID                 string                 `xml:"gml:id,attr"`
+	Description        string                 `xml:"gml:description,omitempty"`
+	Name               string                 `xml:"gml:name,omitempty"`
+	CreationDate       string                 `xml:"core:creationDate,omitempty"`
+	RelativeToTerrain  string                 `xml:"core:relativeToTerrain,omitempty"`
+	MeasureAttributes  []MeasureAttribute     `xml:"gen:measureAttribute,omitempty"`
+	StringAttributes   []StringAttribute      `xml:"gen:stringAttribute,omitempty"`
+	GenericAttributes  []GenericAttribute     `xml:",omitempty"`
+	Class              *Code                  `xml:"bldg:class,omitempty"`
+	Function           *Code                  `xml:"bldg:function,omitempty"`
+	Usage              *Code                  `xml:"bldg:usage,omitempty"`
+	YearOfConstruction string                 `xml:"bldg:yearOfConstruction,omitempty"`
+	RoofType           *Code                  `xml:"bldg:roofType,omitempty"`
+	MeasuredHeight     *Measure               `xml:"bldg:measuredHeight,omitempty"`
+	StoreysAboveGround string                 `xml:"bldg:storeysAboveGround,omitempty"`
+	StoreysBelowGround string                 `xml:"bldg:storeysBelowGround,omitempty"`
+	Lod1Solid          *SolidProperty         `xml:"bldg:lod1Solid,omitempty"`
+	Lod1MultiSurface   *MultiSurfaceProperty  `xml:"bldg:lod1MultiSurface,omitempty"`
+	Lod2Solid          *SolidProperty         `xml:"bldg:lod2Solid,omitempty"`
+	Lod2MultiSurface   *MultiSurfaceProperty  `xml:"bldg:lod2MultiSurface,omitempty"`
+	BoundedBy          []BoundarySurface      `xml:"bldg:boundedBy,omitempty"`
+	Parts              []BuildingPartProperty `xml:"bldg:consistsOfBuildingPart,omitempty"`
+}
+
+type BuildingPartProperty struct {
+	BuildingPart BuildingPart `xml:"bldg:BuildingPart"`
+}
+
+type BuildingPart struct {
+	ID        string            `xml:"gml:id,attr"`
+	Name      string            `xml:"gml:name,omitempty"`
+	BoundedBy []BoundarySurface `xml:"bldg:boundedBy,omitempty"`
+}
+
+type StringAttribute struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"gen:value"`
+}
+
+type MeasureAttribute struct {
+	Name  string  `xml:"name,attr"`
+	Value Measure `xml:"gen:value"`
+}
+
+// GenericAttribute is a gen:stringAttribute or gen:measureAttribute, named by XMLName
+type GenericAttribute struct {
+	XMLName xml.Name
+	Name    string  `xml:"name,attr"`
+	Value   Measure `xml:"gen:value"`
+}
+
+// Code is a gml:CodeType value such as bldg:function
+type Code struct {
+	Value     string `xml:",chardata"`
+	CodeSpace string `xml:"codeSpace,attr,omitempty"`
+}
+
+// Measure is a value with a unit of measure, such as bldg:measuredHeight
+type Measure struct {
+	Value string `xml:",chardata"`
+	UOM   string `xml:"uom,attr,omitempty"`
+}
+
+type SolidProperty struct {
+	Solid Solid `xml:"gml:Solid"`
+}
+
+type Solid struct {
+	ID       string   `xml:"gml:id,attr,omitempty"`
+	Exterior Exterior `xml:"gml:exterior"`
+}
+
+type Exterior struct {
+	CompositeSurface CompositeSurface `xml:"gml:CompositeSurface"`
+}
+
+type CompositeSurface struct {
+	SurfaceMember []SurfaceMember `xml:"gml:surfaceMember"`
+}
+
+type MultiSurfaceProperty struct {
+	MultiSurface MultiSurface `xml:"gml:MultiSurface"`
+}
+
+type MultiSurface struct {
+	ID            string          `xml:"gml:id,attr,omitempty"`
+	SurfaceMember []SurfaceMember `xml:"gml:surfaceMember"`
+}
+
+// SurfaceMember holds a Polygon, or points at one defined elsewhere with Href
+type SurfaceMember struct {
+	Href    string   `xml:"xlink:href,attr,omitempty"`
+	Polygon *Polygon `xml:"gml:Polygon,omitempty"`
+}
+
+type Polygon struct {
+	ID       string          `xml:"gml:id,attr,omitempty"`
+	Exterior PolygonExterior `xml:"gml:exterior"`
+
+	Material string `xml:"-"` // OBJ material of the source face, not written
+}
+
+type PolygonExterior struct {
+	LinearRing LinearRing `xml:"gml:LinearRing"`
+}
+
+// LinearRing is written as one posList, or as a gml:pos per vertex
+type LinearRing struct {
+	ID      string   `xml:"gml:id,attr,omitempty"`
+	PosList string   `xml:"gml:posList,omitempty"`
+	Pos     []string `xml:"gml:pos,omitempty"`
+}
+
+// BoundarySurface attaches semantics to polygons; only one surface is set
+type BoundarySurface struct {
+	RoofSurface         *SemanticSurface `xml:"bldg:RoofSurface,omitempty"`
+	WallSurface         *SemanticSurface `xml:"bldg:WallSurface,omitempty"`
+	GroundSurface       *SemanticSurface `xml:"bldg:GroundSurface,omitempty"`
+	ClosureSurface      *SemanticSurface `xml:"bldg:ClosureSurface,omitempty"`
+	OuterCeilingSurface *SemanticSurface `xml:"bldg:OuterCeilingSurface,omitempty"`
+	OuterFloorSurface   *SemanticSurface `xml:"bldg:OuterFloorSurface,omitempty"`
+}
+
+// Surface returns the set surface and its element name, such as "RoofSurface"
+func (b *BoundarySurface) Surface() (string, *SemanticSurface) {
+	switch {
+	case b.RoofSurface != nil:
+		return "RoofSurface", b.RoofSurface
+	case b.WallSurface != nil:
+		return "WallSurface", b.WallSurface
+	case b.GroundSurface != nil:
+		return "GroundSurface", b.GroundSurface
+	case b.ClosureSurface != nil:
+		return "ClosureSurface", b.ClosureSurface
+	case b.OuterCeilingSurface != nil:
+		return "OuterCeilingSurface", b.OuterCeilingSurface
+	case b.OuterFloorSurface != nil:
+		return "OuterFloorSurface", b.OuterFloorSurface
+	}
+	return "", nil
+}
+
+type SemanticSurface struct {
+	ID                string                `xml:"gml:id,attr,omitempty"`
+	Description       string                `xml:"gml:description,omitempty"`
+	Name              string                `xml:"gml:name,omitempty"`
+	MeasureAttributes []MeasureAttribute    `xml:"gen:measureAttribute,omitempty"`
+	Lod2MultiSurface  *MultiSurfaceProperty `xml:"bldg:lod2MultiSurface,omitempty"`
+}
+
+type AppearanceMember struct {
+	Appearance Appearance `xml:"app:Appearance"`
+}
+
+type Appearance struct {
+	Theme             string              `xml:"app:theme,omitempty"`
+	SurfaceDataMember []SurfaceDataMember `xml:"app:surfaceDataMember"`
+}
+
+type SurfaceDataMember struct {
+	X3DMaterial X3DMaterial `xml:"app:X3DMaterial"`
+}
+
+type X3DMaterial struct {
+	ID           string   `xml:"gml:id,attr,omitempty"`
+	Name         string   `xml:"gml:name,omitempty"`
+	DiffuseColor string   `xml:"app:diffuseColor"`
+	Target       []string `xml:"app:target"`
+}
+
+// EachPolygon calls visit with every polygon of the building geometry and
+// boundary surfaces, including those of building parts. surfaceType is the
+// boundary surface element, such as "RoofSurface", or "" outside boundedBy.
+func (b *Building) EachPolygon(visit func(surfaceType string, polygon *Polygon)) {
+	visitMembers := func(surfaceType string, members []SurfaceMember) {
+		for i := range members {
+			if members[i].Polygon != nil {
+				visit(surfaceType, members[i].Polygon)
+			}
+		}
+	}
+	if b.Lod1Solid != nil {
+		visitMembers("", b.Lod1Solid.Solid.Exterior.CompositeSurface.SurfaceMember)
+	}
+	if b.Lod1MultiSurface != nil {
+		visitMembers("", b.Lod1MultiSurface.MultiSurface.SurfaceMember)
+	}
+	if b.Lod2Solid != nil {
+		visitMembers("", b.Lod2Solid.Solid.Exterior.CompositeSurface.SurfaceMember)
+	}
+	if b.Lod2MultiSurface != nil {
+		visitMembers("", b.Lod2MultiSurface.MultiSurface.SurfaceMember)
+	}
+
+	surfaces := b.BoundedBy
+	for _, part := range b.Parts {
+		surfaces = append(surfaces, part.BuildingPart.BoundedBy...)
+	}
+	for i := range surfaces {
+		surfaceType, surface := surfaces[i].Surface()
+		if surface != nil && surface.Lod2MultiSurface != nil {
+			visitMembers(surfaceType, surface.Lod2MultiSurface.MultiSurface.SurfaceMember)
+		}
+	}
+}
+
+// DropPolygons removes the polygons for which drop returns true, visiting
+// them like EachPolygon, along with surface members that reference a removed
+// polygon by xlink:href. Boundary surfaces left without polygons are removed
+// too. It returns the number of polygons removed.
+func (b *Building) DropPolygons(drop func(surfaceType string, polygon *Polygon) bool) int {
+	dropped := make(map[string]bool)
+	removed := 0
+	filter := func(surfaceType string, members []SurfaceMember) []SurfaceMember {
+		kept := members[:0]
+		for _, member := range members {
+			if member.Polygon != nil && drop(surfaceType, member.Polygon) {
+				dropped["#"+member.Polygon.ID] = true
+				removed++
+				continue
+			}
+			kept = append(kept, member)
+		}
+		return kept
+	}
+	filterSurfaces := func(surfaces []BoundarySurface) []BoundarySurface {
+		kept := surfaces[:0]
+		for _, boundary := range surfaces {
+			surfaceType, surface := boundary.Surface()
+			if surface != nil && surface.Lod2MultiSurface != nil {
+				multiSurface := &surface.Lod2MultiSurface.MultiSurface
+				multiSurface.SurfaceMember = filter(surfaceType, multiSurface.SurfaceMember)
+				if len(multiSurface.SurfaceMember) == 0 {
+					continue
+				}
+			}
+			kept = append(kept, boundary)
+		}
+		return kept
+	}
+	unreferenced := func(members []SurfaceMember) []SurfaceMember {
+		kept := members[:0]
+		for _, member := range members {
+			if member.Href == "" || !dropped[member.Href] {
+				kept = append(kept, member)
+			}
+		}
+		return kept
+	}
+
+	var lists []*[]SurfaceMember
+	if b.Lod1Solid != nil {
+		lists = append(lists, &b.Lod1Solid.Solid.Exterior.CompositeSurface.SurfaceMember)
+	}
+	if b.Lod1MultiSurface != nil {
+		lists = append(lists, &b.Lod1MultiSurface.MultiSurface.SurfaceMember)
+	}
+	if b.Lod2Solid != nil {
+		lists = append(lists, &b.Lod2Solid.Solid.Exterior.CompositeSurface.SurfaceMember)
+	}
+	if b.Lod2MultiSurface != nil {
+		lists = append(lists, &b.Lod2MultiSurface.MultiSurface.SurfaceMember)
+	}
+	for _, list := range lists {
+		*list = filter("", *list)
+	}
+	b.BoundedBy = filterSurfaces(b.BoundedBy)
+	for i := range b.Parts {
+		part := &b.Parts[i].BuildingPart
+		part.BoundedBy = filterSurfaces(part.BoundedBy)
+	}
+
+	if len(dropped) > 0 {
+		for _, list := range lists {
+			*list = unreferenced(*list)
+		}
+	}
+	return removed
+}
+
+// Coordinates returns the ring as one posList, joining gml:pos elements
+func (r *LinearRing) Coordinates() string {
+	if r.PosList != "" || len(r.Pos) == 0 {
+		return r.PosList
+	}
+	return strings.Join(r.Pos, " ")
+}
+
+// ParseCoordinates reads the x y z triple at the start of a pos or corner
+func ParseCoordinates(coordStr string) (float64, float64, float64, error) {
+	fields := strings.Fields(coordStr)
+	if len(fields) < 3 {
+		return 0, 0, 0, fmt.Errorf("invalid coordinates %q", coordStr)
+	}
+	var xyz [3]float64
+	for i := range xyz {
+		value, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return 0, 0, 0, fmt.Errorf("invalid coordinates %q", coordStr)
+		}
+		xyz[i] = value
+	}
+	return xyz[0], xyz[1], xyz[2], nil
+}
+
+// ParsePosList reads the x y z triples of a posList
+func ParsePosList(posList string) ([][3]float64, error) {
+	fields := strings.Fields(posList)
+	if len(fields)%3 != 0 {
+		return nil, fmt.Errorf("posList has %d values, not a multiple of 3", len(fields))
+	}
+	points := make([][3]float64, len(fields)/3)
+	for i := range points {
+		for j := 0; j < 3; j++ {
+			value, err := strconv.ParseFloat(fields[i*3+j], 64)
+			if err != nil {
+				return nil, err
+			}
+			points[i][j] = value
+		}
+	}
+	return points, nil
+}
@@ -0,0 +1,325 @@
+package citygml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// readFixture parses a testdata file with the input model
+func readFixture(t *testing.T, name string) InputCityModel {
+	t.Helper()
+	content, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var model InputCityModel
+	if err := xml.Unmarshal(content, &model); err != nil {
+		t.Fatalf("parsing %s: %v", name, err)
+	}
+	return model
+}
+
+// surfaceRings lists the surface type and coordinates of every ring of a building
+func surfaceRings(building *InputBuilding) [][2]string {
+	var rings [][2]string
+	building.EachRing(func(surfaceType string, ring *InputLinearRing) {
+		rings = append(rings, [2]string{surfaceType, ring.Coordinates()})
+	})
+	return rings
+}
+
+// The fixtures are obj2lod2gml output for a unit cube: lod2_cube.gml with
+// -emit-volume -emit-surface-area -emit-bounds, and lod2_cube_parts.gml with
+// -parts roof -flatten-namespaces
+func TestInputParsesLOD2ConverterOutput(t *testing.T) {
+	for _, name := range []string{"lod2_cube.gml", "lod2_cube_parts.gml"} {
+		t.Run(name, func(t *testing.T) {
+			model := readFixture(t, name)
+			if model.BoundedBy == nil || model.BoundedBy.Envelope == nil {
+				t.Fatal("envelope not parsed")
+			}
+			if got := model.BoundedBy.Envelope.UpperCorner; got != "1.000000 1.000000 1.000000" {
+				t.Errorf("upperCorner = %q", got)
+			}
+			if len(model.CityObjectMember) != 1 || model.CityObjectMember[0].Building == nil {
+				t.Fatalf("want one building, got %d members", len(model.CityObjectMember))
+			}
+
+			building := model.CityObjectMember[0].Building
+			if building.ID != "cube" {
+				t.Errorf("id = %q, want cube", building.ID)
+			}
+			if building.MeasuredHeight == nil || building.MeasuredHeight.Value != "1.00" || building.MeasuredHeight.UOM != "m" {
+				t.Errorf("measuredHeight = %+v", building.MeasuredHeight)
+			}
+			if building.RoofType == nil || building.RoofType.Value != "1030" {
+				t.Errorf("roofType = %+v", building.RoofType)
+			}
+
+			counts := make(map[string]int)
+			for _, ring := range surfaceRings(building) {
+				counts[ring[0]]++
+				points, err := ParsePosList(ring[1])
+				if err != nil {
+					t.Fatalf("%s ring: %v", ring[0], err)
+				}
+				if len(points) != 5 || points[0] != points[4] {
+					t.Errorf("%s ring has %d positions, want a closed ring of 5", ring[0], len(points))
+				}
+			}
+			want := map[string]int{"RoofSurface": 1, "WallSurface": 4, "GroundSurface": 1}
+			if !reflect.DeepEqual(counts, want) {
+				t.Errorf("rings per surface = %v, want %v", counts, want)
+			}
+		})
+	}
+
+	model := readFixture(t, "lod2_cube_parts.gml")
+	building := model.CityObjectMember[0].Building
+	if len(building.Parts) != 1 || building.Parts[0].BuildingPart == nil || building.Parts[0].BuildingPart.ID != "cube_roof" {
+		t.Fatalf("roof BuildingPart not parsed: %+v", building.Parts)
+	}
+}
+
+func TestGenericAttributesKeepsOrderAndPrefix(t *testing.T) {
+	building := readFixture(t, "lod2_cube.gml").CityObjectMember[0].Building
+	attributes := GenericAttributes(building.GenericAttributes)
+
+	var names []string
+	for _, attribute := range attributes {
+		names = append(names, attribute.XMLName.Local+" "+attribute.Name)
+	}
+	want := []string{
+		"gen:measureAttribute GrossPlannedArea",
+		"gen:measureAttribute BoundingSphereCenterX",
+		"gen:measureAttribute BoundingSphereCenterY",
+		"gen:measureAttribute BoundingSphereCenterZ",
+		"gen:measureAttribute BoundingSphereRadius",
+		"gen:measureAttribute Volume",
+		"gen:stringAttribute ConstructionMethod",
+		"gen:stringAttribute IsLandmarked",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("attributes = %q, want %q", names, want)
+	}
+	if attributes[5].Value != (Measure{Value: "1.00", UOM: "m3"}) {
+		t.Errorf("Volume = %+v", attributes[5].Value)
+	}
+}
+
+// Writing a parsed building with the output model and parsing it again keeps
+// A building without semantic values writes none of their elements, rather
+// than empty ones strict CityGML readers reject
+func TestBlankSemanticsOmitted(t *testing.T) {
+	const member = `<bldg:Building xmlns:bldg="http://www.opengis.net/citygml/building/2.0" xmlns:gml="http://www.opengis.net/gml" gml:id="b">` +
+		`<bldg:lod1Solid><gml:Solid gml:id="s"><gml:exterior><gml:CompositeSurface><gml:surfaceMember><gml:Polygon gml:id="p"><gml:exterior><gml:LinearRing>` +
+		`<gml:posList>0 0 0 1 0 0 1 1 0 0 0 0</gml:posList></gml:LinearRing></gml:exterior></gml:Polygon></gml:surfaceMember></gml:CompositeSurface></gml:exterior></gml:Solid></bldg:lod1Solid></bldg:Building>`
+	var input InputBuilding
+	if err := xml.Unmarshal([]byte(member), &input); err != nil {
+		t.Fatal(err)
+	}
+	building := input.Output()
+	data, err := xml.Marshal(building)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty := regexp.MustCompile(`<([\w:]+)[^>]*>\s*</([\w:]+)>|/>`).Find(data); empty != nil {
+		t.Errorf("empty element %s in:\n%s", empty, data)
+	}
+	if semantic := regexp.MustCompile(`<(gml:name|gml:description|bldg:class|bldg:function|bldg:usage|bldg:yearOfConstruction|bldg:roofType|bldg:measuredHeight|bldg:storeys\w+)\b`).Find(data); semantic != nil {
+		t.Errorf("blank %s written:\n%s", semantic, data)
+	}
+}
+
+// its properties and every ring with its surface type
+func TestOutputRoundTrip(t *testing.T) {
+	for _, name := range []string{"lod2_cube.gml", "lod2_cube_parts.gml"} {
+		t.Run(name, func(t *testing.T) {
+			input := readFixture(t, name).CityObjectMember[0].Building
+			building := input.Output()
+
+			model := CityModel{
+				GML:              GMLNamespace,
+				Core:             "http://www.opengis.net/citygml/2.0",
+				Bldg:             "http://www.opengis.net/citygml/building/2.0",
+				App:              "http://www.opengis.net/citygml/appearance/2.0",
+				Gen:              "http://www.opengis.net/citygml/generics/2.0",
+				Grp:              "http://www.opengis.net/citygml/cityobjectgroup/2.0",
+				XLink:            XLinkNamespace,
+				XSI:              XSINamespace,
+				CityObjectMember: []CityObjectMember{{Building: &building}},
+			}
+			data, err := xml.Marshal(model)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var parsed InputCityModel
+			if err := xml.Unmarshal(data, &parsed); err != nil {
+				t.Fatal(err)
+			}
+			if len(parsed.CityObjectMember) != 1 || parsed.CityObjectMember[0].Building == nil {
+				t.Fatalf("building lost in round trip:\n%s", data)
+			}
+			output := parsed.CityObjectMember[0].Building
+
+			if !reflect.DeepEqual(surfaceRings(output), surfaceRings(input)) {
+				t.Errorf("rings changed in round trip:\n got %v\nwant %v", surfaceRings(output), surfaceRings(input))
+			}
+			if output.ID != input.ID || output.Name != input.Name || output.Description != input.Description ||
+				!reflect.DeepEqual(output.MeasuredHeight, input.MeasuredHeight) || !reflect.DeepEqual(output.Class, input.Class) {
+				t.Errorf("building properties changed in round trip:\n got %+v\nwant %+v", output, input)
+			}
+			if !reflect.DeepEqual(GenericAttributes(output.GenericAttributes), GenericAttributes(input.GenericAttributes)) {
+				t.Errorf("generic attributes changed in round trip")
+			}
+			if len(output.Parts) != len(input.Parts) {
+				t.Errorf("%d building parts after round trip, want %d", len(output.Parts), len(input.Parts))
+			}
+		})
+	}
+}
+
+func TestParsePosList(t *testing.T) {
+	points, err := ParsePosList(" 1 2 3\n4.5 -6 7e1 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][3]float64{{1, 2, 3}, {4.5, -6, 70}}; !reflect.DeepEqual(points, want) {
+		t.Errorf("points = %v, want %v", points, want)
+	}
+	for _, bad := range []string{"1 2", "1 2 x"} {
+		if _, err := ParsePosList(bad); err == nil {
+			t.Errorf("ParsePosList(%q) did not fail", bad)
+		}
+	}
+}
+
+func TestParseCoordinates(t *testing.T) {
+	x, y, z, err := ParseCoordinates("10 20 30 40")
+	if err != nil || x != 10 || y != 20 || z != 30 {
+		t.Errorf("ParseCoordinates = %v %v %v, %v", x, y, z, err)
+	}
+	for _, bad := range []string{"", "1 2", "1 two 3"} {
+		if _, _, _, err := ParseCoordinates(bad); err == nil {
+			t.Errorf("ParseCoordinates(%q) did not fail", bad)
+		}
+	}
+}
+
+// DropPolygons removes the matching polygons, the boundary surfaces they
+// leave empty, including those of building parts, and hrefs to them
+func TestDropPolygons(t *testing.T) {
+	input := readFixture(t, "lod2_cube_parts.gml").CityObjectMember[0].Building
+	building := input.Output()
+	var roofID string
+	building.EachPolygon(func(surfaceType string, polygon *Polygon) {
+		if surfaceType == "RoofSurface" {
+			roofID = polygon.ID
+		}
+	})
+	building.Lod2Solid = &SolidProperty{}
+	building.Lod2Solid.Solid.Exterior.CompositeSurface.SurfaceMember = []SurfaceMember{{Href: "#" + roofID}, {Href: "#other"}}
+
+	removed := building.DropPolygons(func(surfaceType string, polygon *Polygon) bool {
+		return surfaceType == "RoofSurface"
+	})
+	if removed != 1 {
+		t.Errorf("removed %d polygons, want the one roof", removed)
+	}
+	counts := make(map[string]int)
+	building.EachPolygon(func(surfaceType string, polygon *Polygon) {
+		counts[surfaceType]++
+	})
+	if want := map[string]int{"WallSurface": 4, "GroundSurface": 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("polygons left = %v, want %v", counts, want)
+	}
+	if len(building.Parts) != 1 || len(building.Parts[0].BuildingPart.BoundedBy) != 0 {
+		t.Errorf("emptied roof surface kept in the building part: %+v", building.Parts)
+	}
+	if members := building.Lod2Solid.Solid.Exterior.CompositeSurface.SurfaceMember; len(members) != 1 || members[0].Href != "#other" {
+		t.Errorf("solid members = %+v, want only the href to other", members)
+	}
+}
+
+// RewriteCoordinates replaces only the text of coordinate elements, leaves
+// mixed content alone and copies every other byte unchanged
+func TestRewriteCoordinates(t *testing.T) {
+	const input = `<?xml version="1.0" encoding="UTF-8"?>
+<core:CityModel xmlns:core="c" xmlns:gml="g" xmlns:x="u"><!-- kept -->
+<gml:Envelope><gml:lowerCorner>0 0 0</gml:lowerCorner><gml:upperCorner>1 1 1</gml:upperCorner></gml:Envelope>
+<x:unknown a='1'>text &amp; more</x:unknown>
+<gml:posList srsDimension="3">1 2 3
+ 4 5 6</gml:posList><gml:pos>7 8 9</gml:pos>
+<gml:pos>1 <x:b/>2 3</gml:pos>
+</core:CityModel>`
+	const want = `<?xml version="1.0" encoding="UTF-8"?>
+<core:CityModel xmlns:core="c" xmlns:gml="g" xmlns:x="u"><!-- kept -->
+<gml:Envelope><gml:lowerCorner>lowerCorner[0 0 0]</gml:lowerCorner><gml:upperCorner>upperCorner[1 1 1]</gml:upperCorner></gml:Envelope>
+<x:unknown a='1'>text &amp; more</x:unknown>
+<gml:posList srsDimension="3">posList[1 2 3
+ 4 5 6]</gml:posList><gml:pos>pos[7 8 9]</gml:pos>
+<gml:pos>1 <x:b/>2 3</gml:pos>
+</core:CityModel>`
+	output, err := RewriteCoordinates([]byte(input), func(name, text string) string {
+		return name + "[" + text + "]"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != want {
+		t.Errorf("got\n%s\nwant\n%s", output, want)
+	}
+}
+
+// elementNames lists every element of a document by namespace URI and local
+// name, in document order
+func elementNames(t *testing.T, data []byte) []xml.Name {
+	t.Helper()
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var names []xml.Name
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return names
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			names = append(names, start.Name)
+		}
+	}
+}
+
+// Flattened output has no prefixed elements, yet every element keeps its
+// namespace, and the model still parses
+func TestFlattenNamespaces(t *testing.T) {
+	content, err := os.ReadFile("testdata/lod2_cube.gml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	flat, err := FlattenNamespaces(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefixed := regexp.MustCompile(`</?[A-Za-z]+:`).Find(flat); prefixed != nil {
+		t.Errorf("flattened output still has prefixed element %q", prefixed)
+	}
+	if got, want := elementNames(t, flat), elementNames(t, content); !reflect.DeepEqual(got, want) {
+		t.Errorf("flattening changed the elements:\n%v\nwant\n%v", got, want)
+	}
+
+	var model InputCityModel
+	if err := xml.Unmarshal(flat, &model); err != nil {
+		t.Fatal(err)
+	}
+	original := readFixture(t, "lod2_cube.gml")
+	if got, want := surfaceRings(model.CityObjectMember[0].Building), surfaceRings(original.CityObjectMember[0].Building); !reflect.DeepEqual(got, want) {
+		t.Errorf("flattened rings = %v, want %v", got, want)
+	}
+}
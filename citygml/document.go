@@ -0,0 +1,256 @@
+package citygml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// outputPrefixes are the namespace prefixes CityModel always declares
+var outputPrefixes = map[string]bool{
+	"gml": true, "core": true, "bldg": true, "app": true,
+	"gen": true, "grp": true, "xlink": true, "xsi": true,
+}
+
+// RawCityObjectMembers returns the verbatim content of each cityObjectMember
+// in document order, along with the xmlns declarations on the root element
+// that the passed-through content may rely on
+func RawCityObjectMembers(content []byte) ([]string, []xml.Attr, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	var members []string
+	var namespaces []xml.Attr
+	depth := 0
+	start := -1
+
+	for {
+		offset := int(decoder.InputOffset())
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				for _, attr := range t.Attr {
+					if attr.Name.Space == "xmlns" {
+						namespaces = append(namespaces, xml.Attr{Name: xml.Name{Local: "xmlns:" + attr.Name.Local}, Value: attr.Value})
+					}
+				}
+			}
+			if depth == 2 && t.Name.Local == "cityObjectMember" {
+				start = int(decoder.InputOffset())
+			}
+		case xml.EndElement:
+			if depth == 2 && start >= 0 && t.Name.Local == "cityObjectMember" {
+				members = append(members, string(content[start:offset]))
+				start = -1
+			}
+			depth--
+		}
+	}
+
+	return members, namespaces, nil
+}
+
+// AddNamespaces appends the declarations whose prefix the output does not
+// declare yet; the first file to declare a prefix decides its namespace
+func AddNamespaces(existing, declared []xml.Attr) []xml.Attr {
+	for _, attr := range declared {
+		prefix := strings.TrimPrefix(attr.Name.Local, "xmlns:")
+		if outputPrefixes[prefix] {
+			continue
+		}
+		found := false
+		for _, e := range existing {
+			if e.Name.Local == attr.Name.Local {
+				found = true
+				break
+			}
+		}
+		if !found {
+			existing = append(existing, attr)
+		}
+	}
+	return existing
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+)
+
+// FlattenNamespaces rewrites prefixed elements (core:, bldg:, gml:, ...) as
+// unprefixed elements that switch the default namespace via xmlns="..." wherever
+// it changes. Prefixed attributes keep their prefixes, so the root declarations stay.
+func FlattenNamespaces(data []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var out bytes.Buffer
+
+	scopes := []map[string]string{{}}
+	defaults := []string{""}
+
+	lookup := func(prefix string) (string, bool) {
+		for i := len(scopes) - 1; i >= 0; i-- {
+			if uri, ok := scopes[i][prefix]; ok {
+				return uri, true
+			}
+		}
+		return "", false
+	}
+
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			scope := make(map[string]string)
+			currentDefault := defaults[len(defaults)-1]
+			newDefault := currentDefault
+			explicitDefault := false
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" {
+					scope[attr.Name.Local] = attr.Value
+				} else if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+					newDefault = attr.Value
+					explicitDefault = true
+				}
+			}
+			scopes = append(scopes, scope)
+
+			name := t.Name.Local
+			if t.Name.Space != "" {
+				if uri, ok := lookup(t.Name.Space); ok {
+					newDefault = uri
+				} else {
+					// Leave undeclared prefixes alone rather than guessing a namespace
+					name = t.Name.Space + ":" + t.Name.Local
+				}
+			}
+			defaults = append(defaults, newDefault)
+
+			out.WriteString("<" + name)
+			if newDefault != currentDefault && !explicitDefault {
+				out.WriteString(` xmlns="` + attrEscaper.Replace(newDefault) + `"`)
+			}
+			for _, attr := range t.Attr {
+				attrName := attr.Name.Local
+				if attr.Name.Space != "" {
+					attrName = attr.Name.Space + ":" + attr.Name.Local
+				}
+				out.WriteString(" " + attrName + `="` + attrEscaper.Replace(attr.Value) + `"`)
+			}
+			out.WriteString(">")
+
+		case xml.EndElement:
+			name := t.Name.Local
+			if t.Name.Space != "" {
+				if _, ok := lookup(t.Name.Space); !ok {
+					name = t.Name.Space + ":" + t.Name.Local
+				}
+			}
+			out.WriteString("</" + name + ">")
+			scopes = scopes[:len(scopes)-1]
+			defaults = defaults[:len(defaults)-1]
+
+		case xml.CharData:
+			out.WriteString(textEscaper.Replace(string(t)))
+
+		case xml.Comment:
+			out.WriteString("<!--" + string(t) + "-->")
+
+		case xml.ProcInst:
+			out.WriteString("<?" + t.Target + " " + string(t.Inst) + "?>")
+
+		case xml.Directive:
+			out.WriteString("<!" + string(t) + ">")
+		}
+	}
+
+	return out.Bytes(), nil
+}
+
+// coordinateElements are the GML elements whose text holds x y z coordinates
+var coordinateElements = map[string]bool{
+	"posList":     true,
+	"pos":         true,
+	"lowerCorner": true,
+	"upperCorner": true,
+}
+
+// RewriteCoordinates streams the document through the XML tokenizer and
+// replaces the text of each posList, pos and envelope corner with what rewrite
+// returns for the element name and text. Every other byte, including
+// elements the caller does not model, is copied from the input unchanged.
+func RewriteCoordinates(content []byte, rewrite func(name, text string) string) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	var out bytes.Buffer
+	var copied int64
+
+	current := "" // coordinate element being read, if any
+	depth := 0    // elements nested inside it
+	plain := true // only character data seen inside it
+	var textStart int64
+	var text strings.Builder
+
+	for {
+		tokenStart := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if current != "" {
+				depth++
+				plain = false
+			} else if coordinateElements[t.Name.Local] {
+				current = t.Name.Local
+				textStart = decoder.InputOffset()
+				text.Reset()
+				plain = true
+			}
+		case xml.CharData:
+			if current != "" {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if current == "" {
+				continue
+			}
+			if depth > 0 {
+				depth--
+				continue
+			}
+			// Leave mixed content alone rather than guess where the numbers are
+			if plain {
+				out.Write(content[copied:textStart])
+				out.WriteString(rewrite(current, text.String()))
+				copied = tokenStart
+			}
+			current = ""
+		default:
+			if current != "" {
+				plain = false
+			}
+		}
+	}
+
+	out.Write(content[copied:])
+	return out.Bytes(), nil
+}
@@ -0,0 +1,236 @@
+package citygml
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// The input model names elements without prefixes, so files parse whichever
+// prefixes or default namespaces they were written with. Optional children are
+// pointers, so a reader can tell a missing element from an empty one.
+
+type InputCityModel struct {
+	BoundedBy        *InputBoundedBy         `xml:"boundedBy"`
+	CityObjectMember []InputCityObjectMember `xml:"cityObjectMember"`
+}
+
+type InputBoundedBy struct {
+	Envelope *InputEnvelope `xml:"Envelope"`
+}
+
+type InputEnvelope struct {
+	SrsName      string `xml:"srsName,attr"`
+	SrsDimension string `xml:"srsDimension,attr"`
+	LowerCorner  string `xml:"lowerCorner"`
+	UpperCorner  string `xml:"upperCorner"`
+}
+
+// InputCityObjectMember has a nil Building for other city objects
+type InputCityObjectMember struct {
+	Building *InputBuilding `xml:"Building"`
+}
+
+// InputBuilding reads what Building writes. Children it does not model,
+// including gen:stringAttribute and gen:measureAttribute, are captured in
+// GenericAttributes in document order.
+type InputBuilding struct {
+	ID                 string                      `xml:"id,attr"`
+	Description        string                      `xml:"description"`
+	Name               string                      `xml:"name"`
+	CreationDate       string                      `xml:"creationDate"`
+	RelativeToTerrain  string                      `xml:"relativeToTerrain"`
+	Class              *Code                       `xml:"class"`
+	Function           *Code                       `xml:"function"`
+	Usage              *Code                       `xml:"usage"`
+	YearOfConstruction string                      `xml:"yearOfConstruction"`
+	RoofType           *Code                       `xml:"roofType"`
+	MeasuredHeight     *Measure                    `xml:"measuredHeight"`
+	StoreysAboveGround string                      `xml:"storeysAboveGround"`
+	StoreysBelowGround string                      `xml:"storeysBelowGround"`
+	Lod1Solid          *InputSolidProperty         `xml:"lod1Solid"`
+	Lod1MultiSurface   *InputMultiSurfaceProperty  `xml:"lod1MultiSurface"`
+	Lod2Solid          *InputSolidProperty         `xml:"lod2Solid"`
+	Lod2MultiSurface   *InputMultiSurfaceProperty  `xml:"lod2MultiSurface"`
+	BoundedBy          []InputBoundarySurface      `xml:"boundedBy"`
+	Parts              []InputBuildingPartProperty `xml:"consistsOfBuildingPart"`
+	GenericAttributes  []InputGenericAttribute     `xml:",any"`
+}
+
+type InputBuildingPartProperty struct {
+	BuildingPart *InputBuildingPart `xml:"BuildingPart"`
+}
+
+type InputBuildingPart struct {
+	ID        string                 `xml:"id,attr"`
+	Name      string                 `xml:"name"`
+	BoundedBy []InputBoundarySurface `xml:"boundedBy"`
+}
+
+type InputGenericAttribute struct {
+	XMLName xml.Name
+	Name    string  `xml:"name,attr"`
+	Value   Measure `xml:"value"`
+}
+
+type InputSolidProperty struct {
+	Solid *InputSolid `xml:"Solid"`
+}
+
+type InputSolid struct {
+	ID       string         `xml:"id,attr"`
+	Exterior *InputExterior `xml:"exterior"`
+}
+
+type InputExterior struct {
+	CompositeSurface *InputCompositeSurface `xml:"CompositeSurface"`
+}
+
+type InputCompositeSurface struct {
+	SurfaceMember []InputSurfaceMember `xml:"surfaceMember"`
+}
+
+type InputMultiSurfaceProperty struct {
+	MultiSurface *InputMultiSurface `xml:"MultiSurface"`
+}
+
+type InputMultiSurface struct {
+	ID            string               `xml:"id,attr"`
+	SurfaceMember []InputSurfaceMember `xml:"surfaceMember"`
+}
+
+type InputSurfaceMember struct {
+	Href    string        `xml:"href,attr"`
+	Polygon *InputPolygon `xml:"Polygon"`
+}
+
+type InputPolygon struct {
+	ID       string                `xml:"id,attr"`
+	Exterior *InputPolygonExterior `xml:"exterior"`
+}
+
+type InputPolygonExterior struct {
+	LinearRing *InputLinearRing `xml:"LinearRing"`
+}
+
+type InputLinearRing struct {
+	ID      string   `xml:"id,attr"`
+	PosList string   `xml:"posList"`
+	Pos     []string `xml:"pos"`
+}
+
+type InputBoundarySurface struct {
+	RoofSurface         *InputSemanticSurface `xml:"RoofSurface"`
+	WallSurface         *InputSemanticSurface `xml:"WallSurface"`
+	GroundSurface       *InputSemanticSurface `xml:"GroundSurface"`
+	ClosureSurface      *InputSemanticSurface `xml:"ClosureSurface"`
+	OuterCeilingSurface *InputSemanticSurface `xml:"OuterCeilingSurface"`
+	OuterFloorSurface   *InputSemanticSurface `xml:"OuterFloorSurface"`
+}
+
+type InputSemanticSurface struct {
+	ID                string                     `xml:"id,attr"`
+	Description       string                     `xml:"description"`
+	Name              string                     `xml:"name"`
+	MeasureAttributes []InputGenericAttribute    `xml:"measureAttribute"`
+	Lod2MultiSurface  *InputMultiSurfaceProperty `xml:"lod2MultiSurface"`
+}
+
+// Surface returns the set surface and its element name, such as "RoofSurface"
+func (b *InputBoundarySurface) Surface() (string, *InputSemanticSurface) {
+	switch {
+	case b.RoofSurface != nil:
+		return "RoofSurface", b.RoofSurface
+	case b.WallSurface != nil:
+		return "WallSurface", b.WallSurface
+	case b.GroundSurface != nil:
+		return "GroundSurface", b.GroundSurface
+	case b.ClosureSurface != nil:
+		return "ClosureSurface", b.ClosureSurface
+	case b.OuterCeilingSurface != nil:
+		return "OuterCeilingSurface", b.OuterCeilingSurface
+	case b.OuterFloorSurface != nil:
+		return "OuterFloorSurface", b.OuterFloorSurface
+	}
+	return "", nil
+}
+
+// Coordinates returns the ring as one posList, joining gml:pos elements
+func (r *InputLinearRing) Coordinates() string {
+	if r.PosList != "" || len(r.Pos) == 0 {
+		return r.PosList
+	}
+	return strings.Join(r.Pos, " ")
+}
+
+// Ring returns the linear ring of a surface member, or nil when the member is
+// an xlink or is missing part of the Polygon/exterior/LinearRing chain
+func (m *InputSurfaceMember) Ring() *InputLinearRing {
+	if m.Polygon == nil || m.Polygon.Exterior == nil {
+		return nil
+	}
+	return m.Polygon.Exterior.LinearRing
+}
+
+// Members returns the surface members of a solid, or nil when part of the
+// Solid/exterior/CompositeSurface chain is missing
+func (p *InputSolidProperty) Members() []InputSurfaceMember {
+	if p == nil || p.Solid == nil || p.Solid.Exterior == nil || p.Solid.Exterior.CompositeSurface == nil {
+		return nil
+	}
+	return p.Solid.Exterior.CompositeSurface.SurfaceMember
+}
+
+// Members returns the surface members of a multi surface, or nil when it is empty
+func (p *InputMultiSurfaceProperty) Members() []InputSurfaceMember {
+	if p == nil || p.MultiSurface == nil {
+		return nil
+	}
+	return p.MultiSurface.SurfaceMember
+}
+
+// EachRing calls visit with every linear ring of the building geometry and
+// boundary surfaces, including those of building parts. surfaceType is the
+// boundary surface element, such as "RoofSurface", or "" outside boundedBy.
+func (b *InputBuilding) EachRing(visit func(surfaceType string, ring *InputLinearRing)) {
+	visitMembers := func(surfaceType string, members []InputSurfaceMember) {
+		for i := range members {
+			if ring := members[i].Ring(); ring != nil {
+				visit(surfaceType, ring)
+			}
+		}
+	}
+	visitMembers("", b.Lod1Solid.Members())
+	visitMembers("", b.Lod1MultiSurface.Members())
+	visitMembers("", b.Lod2Solid.Members())
+	visitMembers("", b.Lod2MultiSurface.Members())
+
+	surfaces := b.BoundedBy
+	for _, part := range b.Parts {
+		if part.BuildingPart != nil {
+			surfaces = append(surfaces, part.BuildingPart.BoundedBy...)
+		}
+	}
+	for i := range surfaces {
+		surfaceType, surface := surfaces[i].Surface()
+		if surface != nil {
+			visitMembers(surfaceType, surface.Lod2MultiSurface.Members())
+		}
+	}
+}
+
+// GenericAttributes keeps the string and measure attributes of the captured
+// building children, preserving their order and restoring the gen: prefix
+func GenericAttributes(attributes []InputGenericAttribute) []GenericAttribute {
+	var result []GenericAttribute
+	for _, attribute := range attributes {
+		if attribute.XMLName.Local != "stringAttribute" && attribute.XMLName.Local != "measureAttribute" {
+			continue
+		}
+		result = append(result, GenericAttribute{
+			XMLName: xml.Name{Local: "gen:" + attribute.XMLName.Local},
+			Name:    attribute.Name,
+			Value:   attribute.Value,
+		})
+	}
+	return result
+}
@@ -0,0 +1,117 @@
+package citygml
+
+// Output converts a parsed building into the output model, keeping every
+// property the input model reads. Surface members that are xlinks stay xlinks;
+// polygons without a LinearRing are dropped.
+func (b *InputBuilding) Output() Building {
+	building := Building{
+		ID:                 b.ID,
+		Description:        b.Description,
+		Name:               b.Name,
+		CreationDate:       b.CreationDate,
+		RelativeToTerrain:  b.RelativeToTerrain,
+		GenericAttributes:  GenericAttributes(b.GenericAttributes),
+		Class:              b.Class,
+		Function:           b.Function,
+		Usage:              b.Usage,
+		YearOfConstruction: b.YearOfConstruction,
+		RoofType:           b.RoofType,
+		MeasuredHeight:     b.MeasuredHeight,
+		StoreysAboveGround: b.StoreysAboveGround,
+		StoreysBelowGround: b.StoreysBelowGround,
+		Lod1Solid:          outputSolid(b.Lod1Solid),
+		Lod1MultiSurface:   outputMultiSurface(b.Lod1MultiSurface),
+		Lod2Solid:          outputSolid(b.Lod2Solid),
+		Lod2MultiSurface:   outputMultiSurface(b.Lod2MultiSurface),
+		BoundedBy:          outputBoundarySurfaces(b.BoundedBy),
+	}
+	for _, part := range b.Parts {
+		if part.BuildingPart == nil {
+			continue
+		}
+		building.Parts = append(building.Parts, BuildingPartProperty{BuildingPart: BuildingPart{
+			ID:        part.BuildingPart.ID,
+			Name:      part.BuildingPart.Name,
+			BoundedBy: outputBoundarySurfaces(part.BuildingPart.BoundedBy),
+		}})
+	}
+	return building
+}
+
+func outputSolid(p *InputSolidProperty) *SolidProperty {
+	if p == nil || p.Solid == nil {
+		return nil
+	}
+	solid := &SolidProperty{Solid: Solid{ID: p.Solid.ID}}
+	solid.Solid.Exterior.CompositeSurface.SurfaceMember = outputMembers(p.Members())
+	return solid
+}
+
+func outputMultiSurface(p *InputMultiSurfaceProperty) *MultiSurfaceProperty {
+	if p == nil || p.MultiSurface == nil {
+		return nil
+	}
+	return &MultiSurfaceProperty{MultiSurface: MultiSurface{
+		ID:            p.MultiSurface.ID,
+		SurfaceMember: outputMembers(p.MultiSurface.SurfaceMember),
+	}}
+}
+
+func outputMembers(members []InputSurfaceMember) []SurfaceMember {
+	var result []SurfaceMember
+	for i := range members {
+		ring := members[i].Ring()
+		if ring == nil {
+			if members[i].Polygon == nil && members[i].Href != "" {
+				result = append(result, SurfaceMember{Href: members[i].Href})
+			}
+			continue
+		}
+		result = append(result, SurfaceMember{Polygon: &Polygon{
+			ID: members[i].Polygon.ID,
+			Exterior: PolygonExterior{LinearRing: LinearRing{
+				ID:      ring.ID,
+				PosList: ring.PosList,
+				Pos:     ring.Pos,
+			}},
+		}})
+	}
+	return result
+}
+
+func outputBoundarySurfaces(surfaces []InputBoundarySurface) []BoundarySurface {
+	var result []BoundarySurface
+	for i := range surfaces {
+		surfaceType, surface := surfaces[i].Surface()
+		if surface == nil {
+			continue
+		}
+		semantic := &SemanticSurface{
+			ID:               surface.ID,
+			Description:      surface.Description,
+			Name:             surface.Name,
+			Lod2MultiSurface: outputMultiSurface(surface.Lod2MultiSurface),
+		}
+		for _, attribute := range surface.MeasureAttributes {
+			semantic.MeasureAttributes = append(semantic.MeasureAttributes, MeasureAttribute{Name: attribute.Name, Value: attribute.Value})
+		}
+
+		var property BoundarySurface
+		switch surfaceType {
+		case "RoofSurface":
+			property.RoofSurface = semantic
+		case "WallSurface":
+			property.WallSurface = semantic
+		case "GroundSurface":
+			property.GroundSurface = semantic
+		case "ClosureSurface":
+			property.ClosureSurface = semantic
+		case "OuterCeilingSurface":
+			property.OuterCeilingSurface = semantic
+		case "OuterFloorSurface":
+			property.OuterFloorSurface = semantic
+		}
+		result = append(result, property)
+	}
+	return result
+}
@@ -0,0 +1,228 @@
+<?xml version="1.0" encoding="UTF-8"?>
+<!-- OBJ to CityGML LOD2 Converter Output -->
+<!-- copyrights 2025 © Fairuz Akmal Pradana | fakmalpradana@gmail.com  -->
+<core:CityModel xmlns:gml="http://www.opengis.net/gml" xmlns:core="http://www.opengis.net/citygml/2.0" xmlns:bldg="http://www.opengis.net/citygml/building/2.0" xmlns:app="http://www.opengis.net/citygml/appearance/2.0" xmlns:gen="http://www.opengis.net/citygml/generics/2.0" xmlns:grp="http://www.opengis.net/citygml/cityobjectgroup/2.0" xmlns:xAL="urn:oasis:names:tc:ciq:xsdschema:xAL:2.0" xmlns:xlink="http://www.w3.org/1999/xlink" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.opengis.net/citygml/2.0 http://schemas.opengis.net/citygml/2.0/cityGMLBase.xsd http://www.opengis.net/citygml/appearance/2.0 http://schemas.opengis.net/citygml/appearance/2.0/appearance.xsd http://www.opengis.net/citygml/building/2.0 http://schemas.opengis.net/citygml/building/2.0/building.xsd http://www.opengis.net/citygml/generics/2.0 http://schemas.opengis.net/citygml/generics/2.0/generics.xsd">
+  <gml:name>AC14-cube</gml:name>
+  <gml:boundedBy>
+    <gml:Envelope srsName="http://www.opengis.net/def/crs/EPSG/0/32748" srsDimension="3">
+      <gml:lowerCorner>0.000000 0.000000 0.000000</gml:lowerCorner>
+      <gml:upperCorner>1.000000 1.000000 1.000000</gml:upperCorner>
+    </gml:Envelope>
+  </gml:boundedBy>
+  <core:cityObjectMember>
+    <bldg:Building gml:id="cube">
+      <gml:description>cube, created by converter</gml:description>
+      <gml:name>AC14-cube</gml:name>
+      <core:creationDate>2026-10-17</core:creationDate>
+      <core:relativeToTerrain>entirelyAboveTerrain</core:relativeToTerrain>
+      <gen:measureAttribute name="GrossPlannedArea">
+        <gen:value uom="m2">120.00</gen:value>
+      </gen:measureAttribute>
+      <gen:measureAttribute name="BoundingSphereCenterX">
+        <gen:value uom="m">0.500000</gen:value>
+      </gen:measureAttribute>
+      <gen:measureAttribute name="BoundingSphereCenterY">
+        <gen:value uom="m">0.500000</gen:value>
+      </gen:measureAttribute>
+      <gen:measureAttribute name="BoundingSphereCenterZ">
+        <gen:value uom="m">0.500000</gen:value>
+      </gen:measureAttribute>
+      <gen:measureAttribute name="BoundingSphereRadius">
+        <gen:value uom="m">0.866025</gen:value>
+      </gen:measureAttribute>
+      <gen:measureAttribute name="Volume">
+        <gen:value uom="m3">1.00</gen:value>
+      </gen:measureAttribute>
+      <gen:stringAttribute name="ConstructionMethod">
+        <gen:value>New Building</gen:value>
+      </gen:stringAttribute>
+      <gen:stringAttribute name="IsLandmarked">
+        <gen:value>NO</gen:value>
+      </gen:stringAttribute>
+      <bldg:class codeSpace="http://www.sig3d.org/codelists/citygml/2.0/building/2.0/_AbstractBuilding_class.xml">1000</bldg:class>
+      <bldg:function codeSpace="http://www.sig3d.org/codelists/citygml/2.0/building/2.0/_AbstractBuilding_function.xml">1000</bldg:function>
+      <bldg:usage codeSpace="http://www.sig3d.org/codelists/citygml/2.0/building/2.0/_AbstractBuilding_usage.xml">1000</bldg:usage>
+      <bldg:yearOfConstruction>2026</bldg:yearOfConstruction>
+      <bldg:roofType codeSpace="http://www.sig3d.org/codelists/citygml/2.0/building/2.0/_AbstractBuilding_roofType.xml">1030</bldg:roofType>
+      <bldg:measuredHeight uom="m">1.00</bldg:measuredHeight>
+      <bldg:storeysAboveGround>2</bldg:storeysAboveGround>
+      <bldg:storeysBelowGround>0</bldg:storeysBelowGround>
+      <bldg:boundedBy>
+        <bldg:WallSurface gml:id="GML_d281adfc-4901-0f52-540b--8992768035996270699">
+          <gml:name>Outer Wall 1</gml:name>
+          <gen:measureAttribute name="Area">
+            <gen:value uom="m2">1.00</gen:value>
+          </gen:measureAttribute>
+          <bldg:lod2MultiSurface>
+            <gml:MultiSurface>
+              <gml:surfaceMember>
+                <gml:Polygon gml:id="PolyID7350_878_759628_120742">
+                  <gml:exterior>
+                    <gml:LinearRing gml:id="PolyID7350_878_759628_120742_0">
+                      <gml:pos>0.000000 0.000000 0.000000</gml:pos>
+                      <gml:pos>1.000000 0.000000 0.000000</gml:pos>
+                      <gml:pos>1.000000 0.000000 1.000000</gml:pos>
+                      <gml:pos>0.000000 0.000000 1.000000</gml:pos>
+                      <gml:pos>0.000000 0.000000 0.000000</gml:pos>
+                    </gml:LinearRing>
+                  </gml:exterior>
+                </gml:Polygon>
+              </gml:surfaceMember>
+            </gml:MultiSurface>
+          </bldg:lod2MultiSurface>
+        </bldg:WallSurface>
+      </bldg:boundedBy>
+      <bldg:boundedBy>
+        <bldg:WallSurface gml:id="GML_d281adfc-4901-0f52-540b--8992768035996270698">
+          <gml:name>Outer Wall 2</gml:name>
+          <gen:measureAttribute name="Area">
+            <gen:value uom="m2">1.00</gen:value>
+          </gen:measureAttribute>
+          <bldg:lod2MultiSurface>
+            <gml:MultiSurface>
+              <gml:surfaceMember>
+                <gml:Polygon gml:id="PolyID7350_878_759628_120742_2">
+                  <gml:exterior>
+                    <gml:LinearRing gml:id="PolyID7350_878_759628_120742_2_0">
+                      <gml:pos>1.000000 0.000000 0.000000</gml:pos>
+                      <gml:pos>1.000000 1.000000 0.000000</gml:pos>
+                      <gml:pos>1.000000 1.000000 1.000000</gml:pos>
+                      <gml:pos>1.000000 0.000000 1.000000</gml:pos>
+                      <gml:pos>1.000000 0.000000 0.000000</gml:pos>
+                    </gml:LinearRing>
+                  </gml:exterior>
+                </gml:Polygon>
+              </gml:surfaceMember>
+            </gml:MultiSurface>
+          </bldg:lod2MultiSurface>
+        </bldg:WallSurface>
+      </bldg:boundedBy>
+      <bldg:boundedBy>
+        <bldg:WallSurface gml:id="GML_d281adfc-4901-0f52-540b--8992768035996270697">
+          <gml:name>Outer Wall 3</gml:name>
+          <gen:measureAttribute name="Area">
+            <gen:value uom="m2">1.00</gen:value>
+          </gen:measureAttribute>
+          <bldg:lod2MultiSurface>
+            <gml:MultiSurface>
+              <gml:surfaceMember>
+                <gml:Polygon gml:id="PolyID7350_878_759628_120742_3">
+                  <gml:exterior>
+                    <gml:LinearRing gml:id="PolyID7350_878_759628_120742_3_0">
+                      <gml:pos>1.000000 1.000000 0.000000</gml:pos>
+                      <gml:pos>0.000000 1.000000 0.000000</gml:pos>
+                      <gml:pos>0.000000 1.000000 1.000000</gml:pos>
+                      <gml:pos>1.000000 1.000000 1.000000</gml:pos>
+                      <gml:pos>1.000000 1.000000 0.000000</gml:pos>
+                    </gml:LinearRing>
+                  </gml:exterior>
+                </gml:Polygon>
+              </gml:surfaceMember>
+            </gml:MultiSurface>
+          </bldg:lod2MultiSurface>
+        </bldg:WallSurface>
+      </bldg:boundedBy>
+      <bldg:boundedBy>
+        <bldg:WallSurface gml:id="GML_d281adfc-4901-0f52-540b--8992768035996270696">
+          <gml:name>Outer Wall 4</gml:name>
+          <gen:measureAttribute name="Area">
+            <gen:value uom="m2">1.00</gen:value>
+          </gen:measureAttribute>
+          <bldg:lod2MultiSurface>
+            <gml:MultiSurface>
+              <gml:surfaceMember>
+                <gml:Polygon gml:id="PolyID7350_878_759628_120742_4">
+                  <gml:exterior>
+                    <gml:LinearRing gml:id="PolyID7350_878_759628_120742_4_0">
+                      <gml:pos>0.000000 1.000000 0.000000</gml:pos>
+                      <gml:pos>0.000000 0.000000 0.000000</gml:pos>
+                      <gml:pos>0.000000 0.000000 1.000000</gml:pos>
+                      <gml:pos>0.000000 1.000000 1.000000</gml:pos>
+                      <gml:pos>0.000000 1.000000 0.000000</gml:pos>
+                    </gml:LinearRing>
+                  </gml:exterior>
+                </gml:Polygon>
+              </gml:surfaceMember>
+            </gml:MultiSurface>
+          </bldg:lod2MultiSurface>
+        </bldg:WallSurface>
+      </bldg:boundedBy>
+      <bldg:boundedBy>
+        <bldg:RoofSurface gml:id="GML_d281adfc-4901-0f52-540b-2720099172848762">
+          <gml:name>Roof 1</gml:name>
+          <gen:measureAttribute name="Area">
+            <gen:value uom="m2">1.00</gen:value>
+          </gen:measureAttribute>
+          <gen:measureAttribute name="Slope">
+            <gen:value uom="deg">0.00</gen:value>
+          </gen:measureAttribute>
+          <bldg:lod2MultiSurface>
+            <gml:MultiSurface>
+              <gml:surfaceMember>
+                <gml:Polygon gml:id="PolyID7353_166_774155_320806">
+                  <gml:exterior>
+                    <gml:LinearRing gml:id="PolyID7353_166_774155_320806_0">
+                      <gml:pos>0.000000 0.000000 1.000000</gml:pos>
+                      <gml:pos>1.000000 0.000000 1.000000</gml:pos>
+                      <gml:pos>1.000000 1.000000 1.000000</gml:pos>
+                      <gml:pos>0.000000 1.000000 1.000000</gml:pos>
+                      <gml:pos>0.000000 0.000000 1.000000</gml:pos>
+                    </gml:LinearRing>
+                  </gml:exterior>
+                </gml:Polygon>
+              </gml:surfaceMember>
+            </gml:MultiSurface>
+          </bldg:lod2MultiSurface>
+        </bldg:RoofSurface>
+      </bldg:boundedBy>
+      <bldg:boundedBy>
+        <bldg:GroundSurface gml:id="GML_d281adfc-4901-0f52-540b-9107244622800959251">
+          <gml:description>Bodenplatte</gml:description>
+          <gml:name>Base Surface</gml:name>
+          <gen:measureAttribute name="Area">
+            <gen:value uom="m2">1.00</gen:value>
+          </gen:measureAttribute>
+          <bldg:lod2MultiSurface>
+            <gml:MultiSurface>
+              <gml:surfaceMember>
+                <gml:Polygon gml:id="PolyID7356_612_880782_415367">
+                  <gml:exterior>
+                    <gml:LinearRing gml:id="PolyID7356_612_880782_415367_0">
+                      <gml:pos>0.000000 0.000000 0.000000</gml:pos>
+                      <gml:pos>0.000000 1.000000 0.000000</gml:pos>
+                      <gml:pos>1.000000 1.000000 0.000000</gml:pos>
+                      <gml:pos>1.000000 0.000000 0.000000</gml:pos>
+                      <gml:pos>0.000000 0.000000 0.000000</gml:pos>
+                    </gml:LinearRing>
+                  </gml:exterior>
+                </gml:Polygon>
+              </gml:surfaceMember>
+            </gml:MultiSurface>
+          </bldg:lod2MultiSurface>
+        </bldg:GroundSurface>
+      </bldg:boundedBy>
+    </bldg:Building>
+  </core:cityObjectMember>
+  <app:appearanceMember>
+    <app:Appearance>
+      <app:theme>rgbTexture</app:theme>
+      <app:surfaceDataMember>
+        <app:X3DMaterial gml:id="cube_mat_grey">
+          <gml:name>grey</gml:name>
+          <app:diffuseColor>0.5 0.5 0.5</app:diffuseColor>
+          <app:target>#PolyID7350_878_759628_120742</app:target>
+          <app:target>#PolyID7350_878_759628_120742_2</app:target>
+          <app:target>#PolyID7350_878_759628_120742_3</app:target>
+          <app:target>#PolyID7350_878_759628_120742_4</app:target>
+        </app:X3DMaterial>
+      </app:surfaceDataMember>
+      <app:surfaceDataMember>
+        <app:X3DMaterial gml:id="cube_mat_red">
+          <gml:name>red</gml:name>
+          <app:diffuseColor>1 0 0</app:diffuseColor>
+          <app:target>#PolyID7353_166_774155_320806</app:target>
+        </app:X3DMaterial>
+      </app:surfaceDataMember>
+    </app:Appearance>
+  </app:appearanceMember>
+</core:CityModel>
\ No newline at end of file
@@ -0,0 +1,204 @@
+<?xml version="1.0" encoding="UTF-8"?>
+<!-- OBJ to CityGML LOD2 Converter Output -->
+<!-- copyrights 2025 © Fairuz Akmal Pradana | fakmalpradana@gmail.com  -->
+<CityModel xmlns="http://www.opengis.net/citygml/2.0" xmlns:gml="http://www.opengis.net/gml" xmlns:core="http://www.opengis.net/citygml/2.0" xmlns:bldg="http://www.opengis.net/citygml/building/2.0" xmlns:app="http://www.opengis.net/citygml/appearance/2.0" xmlns:gen="http://www.opengis.net/citygml/generics/2.0" xmlns:grp="http://www.opengis.net/citygml/cityobjectgroup/2.0" xmlns:xAL="urn:oasis:names:tc:ciq:xsdschema:xAL:2.0" xmlns:xlink="http://www.w3.org/1999/xlink" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.opengis.net/citygml/2.0 http://schemas.opengis.net/citygml/2.0/cityGMLBase.xsd http://www.opengis.net/citygml/appearance/2.0 http://schemas.opengis.net/citygml/appearance/2.0/appearance.xsd http://www.opengis.net/citygml/building/2.0 http://schemas.opengis.net/citygml/building/2.0/building.xsd http://www.opengis.net/citygml/generics/2.0 http://schemas.opengis.net/citygml/generics/2.0/generics.xsd">
+  <name xmlns="http://www.opengis.net/gml">AC14-cube</name>
+  <boundedBy xmlns="http://www.opengis.net/gml">
+    <Envelope srsName="http://www.opengis.net/def/crs/EPSG/0/32748" srsDimension="3">
+      <lowerCorner>0.000000 0.000000 0.000000</lowerCorner>
+      <upperCorner>1.000000 1.000000 1.000000</upperCorner>
+    </Envelope>
+  </boundedBy>
+  <cityObjectMember>
+    <Building xmlns="http://www.opengis.net/citygml/building/2.0" gml:id="cube">
+      <description xmlns="http://www.opengis.net/gml">cube, created by converter</description>
+      <name xmlns="http://www.opengis.net/gml">AC14-cube</name>
+      <creationDate xmlns="http://www.opengis.net/citygml/2.0">2026-10-17</creationDate>
+      <relativeToTerrain xmlns="http://www.opengis.net/citygml/2.0">entirelyAboveTerrain</relativeToTerrain>
+      <measureAttribute xmlns="http://www.opengis.net/citygml/generics/2.0" name="GrossPlannedArea">
+        <value uom="m2">120.00</value>
+      </measureAttribute>
+      <stringAttribute xmlns="http://www.opengis.net/citygml/generics/2.0" name="ConstructionMethod">
+        <value>New Building</value>
+      </stringAttribute>
+      <stringAttribute xmlns="http://www.opengis.net/citygml/generics/2.0" name="IsLandmarked">
+        <value>NO</value>
+      </stringAttribute>
+      <class codeSpace="http://www.sig3d.org/codelists/citygml/2.0/building/2.0/_AbstractBuilding_class.xml">1000</class>
+      <function codeSpace="http://www.sig3d.org/codelists/citygml/2.0/building/2.0/_AbstractBuilding_function.xml">1000</function>
+      <usage codeSpace="http://www.sig3d.org/codelists/citygml/2.0/building/2.0/_AbstractBuilding_usage.xml">1000</usage>
+      <yearOfConstruction>2026</yearOfConstruction>
+      <roofType codeSpace="http://www.sig3d.org/codelists/citygml/2.0/building/2.0/_AbstractBuilding_roofType.xml">1030</roofType>
+      <measuredHeight uom="m">1.00</measuredHeight>
+      <storeysAboveGround>2</storeysAboveGround>
+      <storeysBelowGround>0</storeysBelowGround>
+      <boundedBy>
+        <WallSurface gml:id="GML_d281adfc-4901-0f52-540b--8992768035996270699">
+          <name xmlns="http://www.opengis.net/gml">Outer Wall 1</name>
+          <lod2MultiSurface>
+            <MultiSurface xmlns="http://www.opengis.net/gml">
+              <surfaceMember>
+                <Polygon gml:id="PolyID7350_878_759628_120742">
+                  <exterior>
+                    <LinearRing gml:id="PolyID7350_878_759628_120742_0">
+                      <pos>0.000000 0.000000 0.000000</pos>
+                      <pos>1.000000 0.000000 0.000000</pos>
+                      <pos>1.000000 0.000000 1.000000</pos>
+                      <pos>0.000000 0.000000 1.000000</pos>
+                      <pos>0.000000 0.000000 0.000000</pos>
+                    </LinearRing>
+                  </exterior>
+                </Polygon>
+              </surfaceMember>
+            </MultiSurface>
+          </lod2MultiSurface>
+        </WallSurface>
+      </boundedBy>
+      <boundedBy>
+        <WallSurface gml:id="GML_d281adfc-4901-0f52-540b--8992768035996270698">
+          <name xmlns="http://www.opengis.net/gml">Outer Wall 2</name>
+          <lod2MultiSurface>
+            <MultiSurface xmlns="http://www.opengis.net/gml">
+              <surfaceMember>
+                <Polygon gml:id="PolyID7350_878_759628_120742_2">
+                  <exterior>
+                    <LinearRing gml:id="PolyID7350_878_759628_120742_2_0">
+                      <pos>1.000000 0.000000 0.000000</pos>
+                      <pos>1.000000 1.000000 0.000000</pos>
+                      <pos>1.000000 1.000000 1.000000</pos>
+                      <pos>1.000000 0.000000 1.000000</pos>
+                      <pos>1.000000 0.000000 0.000000</pos>
+                    </LinearRing>
+                  </exterior>
+                </Polygon>
+              </surfaceMember>
+            </MultiSurface>
+          </lod2MultiSurface>
+        </WallSurface>
+      </boundedBy>
+      <boundedBy>
+        <WallSurface gml:id="GML_d281adfc-4901-0f52-540b--8992768035996270697">
+          <name xmlns="http://www.opengis.net/gml">Outer Wall 3</name>
+          <lod2MultiSurface>
+            <MultiSurface xmlns="http://www.opengis.net/gml">
+              <surfaceMember>
+                <Polygon gml:id="PolyID7350_878_759628_120742_3">
+                  <exterior>
+                    <LinearRing gml:id="PolyID7350_878_759628_120742_3_0">
+                      <pos>1.000000 1.000000 0.000000</pos>
+                      <pos>0.000000 1.000000 0.000000</pos>
+                      <pos>0.000000 1.000000 1.000000</pos>
+                      <pos>1.000000 1.000000 1.000000</pos>
+                      <pos>1.000000 1.000000 0.000000</pos>
+                    </LinearRing>
+                  </exterior>
+                </Polygon>
+              </surfaceMember>
+            </MultiSurface>
+          </lod2MultiSurface>
+        </WallSurface>
+      </boundedBy>
+      <boundedBy>
+        <WallSurface gml:id="GML_d281adfc-4901-0f52-540b--8992768035996270696">
+          <name xmlns="http://www.opengis.net/gml">Outer Wall 4</name>
+          <lod2MultiSurface>
+            <MultiSurface xmlns="http://www.opengis.net/gml">
+              <surfaceMember>
+                <Polygon gml:id="PolyID7350_878_759628_120742_4">
+                  <exterior>
+                    <LinearRing gml:id="PolyID7350_878_759628_120742_4_0">
+                      <pos>0.000000 1.000000 0.000000</pos>
+                      <pos>0.000000 0.000000 0.000000</pos>
+                      <pos>0.000000 0.000000 1.000000</pos>
+                      <pos>0.000000 1.000000 1.000000</pos>
+                      <pos>0.000000 1.000000 0.000000</pos>
+                    </LinearRing>
+                  </exterior>
+                </Polygon>
+              </surfaceMember>
+            </MultiSurface>
+          </lod2MultiSurface>
+        </WallSurface>
+      </boundedBy>
+      <boundedBy>
+        <GroundSurface gml:id="GML_d281adfc-4901-0f52-540b-9107244622800959251">
+          <description xmlns="http://www.opengis.net/gml">Bodenplatte</description>
+          <name xmlns="http://www.opengis.net/gml">Base Surface</name>
+          <lod2MultiSurface>
+            <MultiSurface xmlns="http://www.opengis.net/gml">
+              <surfaceMember>
+                <Polygon gml:id="PolyID7356_612_880782_415367">
+                  <exterior>
+                    <LinearRing gml:id="PolyID7356_612_880782_415367_0">
+                      <pos>0.000000 0.000000 0.000000</pos>
+                      <pos>0.000000 1.000000 0.000000</pos>
+                      <pos>1.000000 1.000000 0.000000</pos>
+                      <pos>1.000000 0.000000 0.000000</pos>
+                      <pos>0.000000 0.000000 0.000000</pos>
+                    </LinearRing>
+                  </exterior>
+                </Polygon>
+              </surfaceMember>
+            </MultiSurface>
+          </lod2MultiSurface>
+        </GroundSurface>
+      </boundedBy>
+      <consistsOfBuildingPart>
+        <BuildingPart gml:id="cube_roof">
+          <name xmlns="http://www.opengis.net/gml">Roof</name>
+          <boundedBy>
+            <RoofSurface gml:id="GML_d281adfc-4901-0f52-540b-2720099172848762">
+              <name xmlns="http://www.opengis.net/gml">Roof 1</name>
+              <lod2MultiSurface>
+                <MultiSurface xmlns="http://www.opengis.net/gml">
+                  <surfaceMember>
+                    <Polygon gml:id="PolyID7353_166_774155_320806">
+                      <exterior>
+                        <LinearRing gml:id="PolyID7353_166_774155_320806_0">
+                          <pos>0.000000 0.000000 1.000000</pos>
+                          <pos>1.000000 0.000000 1.000000</pos>
+                          <pos>1.000000 1.000000 1.000000</pos>
+                          <pos>0.000000 1.000000 1.000000</pos>
+                          <pos>0.000000 0.000000 1.000000</pos>
+                        </LinearRing>
+                      </exterior>
+                    </Polygon>
+                  </surfaceMember>
+                </MultiSurface>
+              </lod2MultiSurface>
+            </RoofSurface>
+          </boundedBy>
+        </BuildingPart>
+      </consistsOfBuildingPart>
+    </Building>
+  </cityObjectMember>
+  <appearanceMember xmlns="http://www.opengis.net/citygml/appearance/2.0">
+    <Appearance>
+      <theme>rgbTexture</theme>
+      <surfaceDataMember>
+        <X3DMaterial gml:id="cube_default_Roof">
+          <name xmlns="http://www.opengis.net/gml">Roof</name>
+          <diffuseColor>0.8 0.1 0.1</diffuseColor>
+          <target>#PolyID7353_166_774155_320806</target>
+        </X3DMaterial>
+      </surfaceDataMember>
+      <surfaceDataMember>
+        <X3DMaterial gml:id="cube_default_Wall">
+          <name xmlns="http://www.opengis.net/gml">Wall</name>
+          <diffuseColor>0.7 0.7 0.7</diffuseColor>
+          <target>#PolyID7350_878_759628_120742</target>
+          <target>#PolyID7350_878_759628_120742_2</target>
+          <target>#PolyID7350_878_759628_120742_3</target>
+          <target>#PolyID7350_878_759628_120742_4</target>
+        </X3DMaterial>
+      </surfaceDataMember>
+      <surfaceDataMember>
+        <X3DMaterial gml:id="cube_default_Ground">
+          <name xmlns="http://www.opengis.net/gml">Ground</name>
+          <diffuseColor>0.5 0.35 0.2</diffuseColor>
+          <target>#PolyID7356_612_880782_415367</target>
+        </X3DMaterial>
+      </surfaceDataMember>
+    </Appearance>
+  </appearanceMember>
+</CityModel>
\ No newline at end of file
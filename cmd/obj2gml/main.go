@@ -0,0 +1,13 @@
+// Command obj2gml runs every tool of the repository as a subcommand of one
+// binary: obj2gml convert, obj2gml separate, obj2gml merge and so on.
+package main
+
+import (
+	"os"
+
+	"github.com/fakmalpradana/OBJ2GML/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Main("obj2gml", os.Args[1:]))
+}
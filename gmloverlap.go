@@ -0,0 +1,15 @@
+//go:build ignore
+
+// gmloverlap reports CityGML buildings whose footprints overlap.
+// It is also the overlap subcommand of cmd/obj2gml.
+package main
+
+import (
+	"os"
+
+	"github.com/fakmalpradana/OBJ2GML/internal/overlap"
+)
+
+func main() {
+	os.Exit(overlap.Main("gmloverlap", os.Args[1:]))
+}
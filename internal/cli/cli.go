@@ -0,0 +1,194 @@
+// Package cli runs every tool of the repository as a subcommand of one
+// binary, cmd/obj2gml. Each subcommand calls the tool's Main directly.
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fakmalpradana/OBJ2GML/internal/convert"
+	"github.com/fakmalpradana/OBJ2GML/internal/elevate"
+	"github.com/fakmalpradana/OBJ2GML/internal/lod2"
+	"github.com/fakmalpradana/OBJ2GML/internal/merge"
+	"github.com/fakmalpradana/OBJ2GML/internal/mergelod2"
+	"github.com/fakmalpradana/OBJ2GML/internal/normals"
+	"github.com/fakmalpradana/OBJ2GML/internal/overlap"
+	"github.com/fakmalpradana/OBJ2GML/internal/separate"
+	"github.com/fakmalpradana/OBJ2GML/internal/stats"
+	"github.com/fakmalpradana/OBJ2GML/internal/translate"
+)
+
+// subcommand is one tool. epsg and workers tell which of the shared flags the
+// tool takes, as its own -epsg and -workers flags.
+type subcommand struct {
+	run         func(name string, args []string) int
+	description string
+	epsg        bool
+	workers     bool
+}
+
+var subcommands = map[string]subcommand{
+	"convert":      {convert.Main, "Convert OBJ files to LOD1 CityGML", true, false},
+	"convert-lod2": {lod2.Main, "Convert OBJ files to LOD2 CityGML with semantic surfaces", true, false},
+	"separate":     {separate.Main, "Split an OBJ into per-footprint OBJ files using GeoJSON", true, false},
+	"merge":        {merge.Main, "Merge LOD1 CityGML files into one", true, false},
+	"merge-lod2":   {mergelod2.Main, "Merge LOD2 CityGML files into one", true, false},
+	"elevate":      {elevate.Main, "Shift CityGML heights by GeoJSON elevation, a DEM or a global offset", false, false},
+	"translate":    {translate.Main, "Translate OBJ or GML coordinates by an offset", false, true},
+	"stats":        {stats.Main, "Print OBJ mesh statistics", false, false},
+	"normals":      {normals.Main, "Recompute OBJ vertex normals", false, false},
+	"overlap":      {overlap.Main, "Report CityGML buildings whose footprints overlap", false, false},
+}
+
+// logLevels maps -log-level to the line prefixes it keeps; info keeps every line
+var logLevels = map[string][]string{
+	"error": {"Error"},
+	"warn":  {"Error", "Warning"},
+	"info":  nil,
+}
+
+// Main runs the subcommand named in args, the command line after the program
+// name, and returns its exit status. Shared flags go before the subcommand.
+func Main(program string, args []string) int {
+	flags := flag.NewFlagSet(program, flag.ContinueOnError)
+	epsg := flags.String("epsg", "", "EPSG code for subcommands that write a CRS: "+supporting(func(c subcommand) bool { return c.epsg }))
+	workers := flags.Int("workers", 0, "Number of concurrent workers for subcommands that run in parallel: "+supporting(func(c subcommand) bool { return c.workers }))
+	logLevel := flags.String("log-level", "info", "Console output to keep: error, warn (errors and warnings) or info (everything)")
+	flags.Usage = func() { printUsage(flags) }
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	rest := flags.Args()
+	if len(rest) == 0 {
+		flags.SetOutput(os.Stdout)
+		printUsage(flags)
+		return 1
+	}
+	name, args := rest[0], rest[1:]
+	if name == "help" {
+		if len(args) == 0 {
+			flags.SetOutput(os.Stdout)
+			printUsage(flags)
+			return 0
+		}
+		name, args = args[0], []string{"-h"}
+	}
+
+	command, ok := subcommands[name]
+	if !ok {
+		fmt.Printf("Unknown subcommand %q\n\n", name)
+		flags.SetOutput(os.Stdout)
+		printUsage(flags)
+		return 2
+	}
+	prefixes, ok := logLevels[*logLevel]
+	if !ok {
+		fmt.Printf("Error: unknown -log-level %q (use error, warn or info)\n", *logLevel)
+		return 2
+	}
+
+	// Shared flags become the tool's own flags, ahead of the ones given after
+	// the subcommand so those still win
+	var shared []string
+	if *epsg != "" {
+		if !command.epsg {
+			fmt.Printf("Error: %s does not take -epsg\n", name)
+			return 2
+		}
+		shared = append(shared, "-epsg="+*epsg)
+	}
+	if *workers != 0 {
+		if !command.workers {
+			fmt.Printf("Error: %s does not take -workers\n", name)
+			return 2
+		}
+		shared = append(shared, fmt.Sprintf("-workers=%d", *workers))
+	}
+
+	if prefixes != nil {
+		restore, err := filterStdout(prefixes)
+		if err != nil {
+			fmt.Printf("Error: filtering output for -log-level: %v\n", err)
+			return 1
+		}
+		defer restore()
+	}
+	return command.run(program+" "+name, append(shared, args...))
+}
+
+// supporting lists the subcommands that take a shared flag
+func supporting(takes func(subcommand) bool) string {
+	var names []string
+	for name, command := range subcommands {
+		if takes(command) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func printUsage(flags *flag.FlagSet) {
+	out := flags.Output()
+	fmt.Fprintf(out, "Usage: %s [shared options] <subcommand> [options]\n", flags.Name())
+	fmt.Fprintf(out, "Run %s help <subcommand>, or a subcommand with -h, to see its options.\n", flags.Name())
+	fmt.Fprintln(out, "Subcommands:")
+
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-13s %s\n", name, subcommands[name].description)
+	}
+	fmt.Fprintln(out, "Shared options:")
+	flags.PrintDefaults()
+}
+
+// filterStdout sends os.Stdout through a pipe that keeps only the lines
+// starting with one of prefixes. The returned function flushes the pipe and
+// restores os.Stdout.
+func filterStdout(prefixes []string) (func(), error) {
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	os.Stdout = w
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(line, prefix) {
+					io.WriteString(stdout, line)
+					break
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return func() {
+		w.Close()
+		<-done
+		r.Close()
+		os.Stdout = stdout
+	}, nil
+}
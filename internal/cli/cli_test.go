@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// capture runs Main with args and returns its status and everything it wrote
+// to stdout and stderr
+func capture(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+
+	status := Main("obj2gml", args)
+	os.Stdout, os.Stderr = stdout, stderr
+	w.Close()
+	return status, <-output
+}
+
+func TestSubcommandHelp(t *testing.T) {
+	for name := range subcommands {
+		t.Run(name, func(t *testing.T) {
+			status, output := capture(t, name, "-h")
+			if status != 0 {
+				t.Errorf("%s -h exited with %d", name, status)
+			}
+			if !strings.Contains(output, "Usage") || !strings.Contains(output, "  -") {
+				t.Errorf("%s -h printed no usage:\n%s", name, output)
+			}
+
+			if status, helpOutput := capture(t, "help", name); status != 0 || helpOutput != output {
+				t.Errorf("help %s = %d, %q; want the -h usage", name, status, helpOutput)
+			}
+		})
+	}
+}
+
+func TestUsage(t *testing.T) {
+	status, output := capture(t, "help")
+	if status != 0 {
+		t.Errorf("help exited with %d", status)
+	}
+	for name := range subcommands {
+		if !strings.Contains(output, "  "+name+" ") {
+			t.Errorf("usage does not list %s:\n%s", name, output)
+		}
+	}
+
+	if status, output := capture(t, "frobnicate"); status != 2 || !strings.Contains(output, `Unknown subcommand "frobnicate"`) {
+		t.Errorf("unknown subcommand = %d, %q", status, output)
+	}
+}
+
+func TestSharedFlagsOnlyForSubcommandsThatTakeThem(t *testing.T) {
+	for _, args := range [][]string{
+		{"-workers", "2", "convert"},
+		{"-epsg", "4326", "translate"},
+	} {
+		status, output := capture(t, args...)
+		if status != 2 || !strings.Contains(output, "does not take") {
+			t.Errorf("%q = %d, %q; want a rejection", args, status, output)
+		}
+	}
+	if status, output := capture(t, "-log-level", "debug", "stats"); status != 2 || !strings.Contains(output, "unknown -log-level") {
+		t.Errorf("-log-level debug = %d, %q", status, output)
+	}
+}
+
+const cubeOBJ = `v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+v 0 0 1
+v 1 0 1
+v 1 1 1
+v 0 1 1
+f 1 2 6 5
+f 2 3 7 6
+f 3 4 8 7
+f 4 1 5 8
+f 5 6 7 8
+f 1 4 3 2
+`
+
+// TestConvertEndToEnd converts a cube through the combined binary's entry
+// point, with the shared -epsg forwarded and info lines filtered out
+func TestConvertEndToEnd(t *testing.T) {
+	input := t.TempDir()
+	if err := os.WriteFile(filepath.Join(input, "cube.obj"), []byte(cubeOBJ), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "gml")
+
+	status, log := capture(t, "-epsg", "4326", "-log-level", "warn", "convert", "-input", input, "-output", output)
+	if status != 0 {
+		t.Fatalf("convert exited with %d:\n%s", status, log)
+	}
+	if strings.Contains(log, "Successfully converted") {
+		t.Errorf("-log-level warn kept an info line:\n%s", log)
+	}
+
+	gml, err := os.ReadFile(filepath.Join(output, "cube.gml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(gml), `srsName="http://www.opengis.net/def/crs/EPSG/0/4326"`) {
+		t.Errorf("shared -epsg not used:\n%s", gml)
+	}
+	if n := strings.Count(string(gml), "<gml:Polygon "); n != 6 {
+		t.Errorf("cube.gml has %d polygons, want 6", n)
+	}
+
+	// Without a -log-level the tool's progress is printed
+	status, log = capture(t, "convert", "-input", input, "-output", output)
+	if status != 0 || !strings.Contains(log, "Successfully converted 1 from 1 OBJ files") {
+		t.Errorf("convert = %d:\n%s", status, log)
+	}
+}
+
+func TestFilterStdout(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	restore, err := filterStdout(logLevels["warn"])
+	if err != nil {
+		os.Stdout = stdout
+		t.Fatal(err)
+	}
+	os.Stdout.WriteString("Processing a.obj\nWarning: a.obj has no faces\nError: b.obj not found\nDone")
+	restore()
+	os.Stdout = stdout
+	w.Close()
+
+	data, _ := io.ReadAll(r)
+	if want := "Warning: a.obj has no faces\nError: b.obj not found\n"; string(data) != want {
+		t.Errorf("filtered output = %q, want %q", data, want)
+	}
+}
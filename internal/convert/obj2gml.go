@@ -0,0 +1,1135 @@
+package convert
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fakmalpradana/OBJ2GML/citygml"
+	"github.com/fakmalpradana/OBJ2GML/internal/fileio"
+	"github.com/fakmalpradana/OBJ2GML/internal/objconv"
+)
+
+// XML namespaces and schema declarations
+const (
+	xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<!-- OBJ to CityGML Converter Output - Complete Model Preservation -->
+<!-- copyrights 2025 © Fairuz Akmal Pradana | fakmalpradana@gmail.com  -->
+`
+)
+
+// OBJFace holds the 1-based vertex indices of a face, as the OBJ gives them
+type OBJFace []int
+
+// Ring returns the corners of the face as 0-based indices
+func (f OBJFace) Ring() []int {
+	ring := make([]int, len(f))
+	for i, idx := range f {
+		ring[i] = idx - 1
+	}
+	return ring
+}
+
+// WithRing returns the face with the 0-based ring as its corners
+func (f OBJFace) WithRing(ring []int) OBJFace {
+	face := make(OBJFace, len(ring))
+	for i, idx := range ring {
+		face[i] = idx + 1
+	}
+	return face
+}
+
+// ConvertOptions holds the per-run settings shared by every converted file
+type ConvertOptions struct {
+	EPSGCode        string
+	SRSForm         string // url or urn srsName
+	EPSGAttribute   bool   // add an EPSG string attribute to each building
+	Namespaces      citygml.Namespaces
+	Geometry        string  // solid, multisurface or auto
+	Simplify        float64 // coplanar merge tolerance in degrees, 0 disables
+	DropNonManifold bool
+	CapBottom       bool                      // close an open bottom boundary with ground polygons
+	Strict          bool                      // reject files with NaN/Inf coordinates or bad face indices instead of skipping them
+	MaxFaces        int                       // stop parsing once the face count exceeds this, 0 disables
+	MaxVertices     int                       // stop parsing once the vertex count exceeds this, 0 disables
+	MaxRingVertices int                       // split faces with more corners than this, 0 disables
+	ClipMode        string                    // clip or drop faces crossing ClipBelow; empty keeps all geometry
+	ClipBelow       float64                   // datum Z below which geometry is removed when ClipMode is set
+	Units           string                    // uom written for lengths; m or ft
+	Semantics       bool                      // add roof/wall/ground surfaces linking to the LOD1 polygons
+	ConvertUnits    bool                      // scale meter heights into Units
+	PreserveCoords  bool                      // write the OBJ coordinate strings unchanged
+	Canonical       bool                      // sort faces by centroid before converting
+	Meta            []citygml.StringAttribute // -meta key=value pairs added to every building
+	Envelope        []float64                 // fixed minx miny minz maxx maxy maxz for gml:Envelope, nil uses the geometry
+
+	FlattenNamespaces bool
+}
+
+// formatCorner writes an envelope corner exactly as given, without rounding
+func formatCorner(values []float64) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strings.Join(parts, " ")
+}
+
+// checkpoint records the inputs converted so far, one per line, so an
+// interrupted batch run can resume where it stopped
+type checkpoint struct {
+	file *os.File
+	done map[string]bool
+}
+
+// openCheckpoint reads the inputs a previous run completed, if any, and opens
+// the file for appending
+func openCheckpoint(path string) (*checkpoint, error) {
+	done := make(map[string]bool)
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			done[line] = true
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &checkpoint{file: file, done: done}, nil
+}
+
+// record marks an input as completed and syncs, so the entry survives a crash
+// right after it
+func (c *checkpoint) record(name string) error {
+	if _, err := fmt.Fprintln(c.file, name); err != nil {
+		return err
+	}
+	c.done[name] = true
+	return c.file.Sync()
+}
+
+// Main runs obj2gml with args, the command line after the program name, and
+// returns its exit status: 2 for missing or invalid arguments, 1 when the
+// output cannot be set up or, with -fail-on-error or -fail-fast, a file
+// fails, and 0 otherwise
+func Main(name string, args []string) int {
+	// Parse command-line arguments
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	inputDir := flags.String("input", "", "Directory containing OBJ files")
+	combine := flags.Bool("combine", false, "Convert every OBJ in memory into one merged CityModel written to -output, which is then a file")
+	tarArchive := flags.String("tar", "", "Tar archive whose .obj entries are converted instead of -input, without extracting it")
+	outputDir := flags.String("output", "", "Directory for output CityGML files, or the output file with -combine")
+	epsgCode := flags.String("epsg", "32748", "EPSG code for the coordinate reference system")
+	cityGMLVersion := flags.String("citygml-version", "2.0", "CityGML version for namespaces and schemaLocation: 1.0 or 2.0")
+	srsForm := flags.String("srs-form", "url", "srsName style: url (http://www.opengis.net/def/crs/EPSG/0/<code>) or urn (urn:ogc:def:crs:EPSG::<code>)")
+	epsgAttribute := flags.Bool("epsg-attribute", false, "Add the EPSG code as an \"EPSG\" gen:stringAttribute on each building")
+	epsgMap := flags.String("epsg-map", "", "CSV of filename-or-prefix,epsg rows giving per-file EPSG codes; unmapped files use -epsg")
+	onlyIDs := flags.String("only-ids", "", "Convert only these building ids (file names without .obj): id1,id2,... or @file with one id per line")
+	failOnError := flags.Bool("fail-on-error", false, "Exit with status 1 when any file fails")
+	failFast := flags.Bool("fail-fast", false, "Stop at the first failed file and exit with status 1")
+	checkpointPath := flags.String("checkpoint", "", "File recording converted inputs; rerunning with it skips inputs already converted")
+	var meta objconv.MetaFlag
+	flags.Var(&meta, "meta", "Add a key=value gen:stringAttribute to every building (repeatable)")
+	flattenNS := flags.Bool("flatten-namespaces", false, "Emit unprefixed elements with default namespaces for legacy consumers")
+	simplify := flags.Float64("simplify", 0, "Merge adjacent coplanar faces whose normals differ by at most this many degrees (0 disables)")
+	maxFaces := flags.Int("max-faces", 0, "Reject OBJ files with more than this many faces (0 disables)")
+	semantics := flags.Bool("semantics", false, "Classify faces and add Roof/Wall/GroundSurfaces that reference the LOD1 polygons by xlink")
+	units := flags.String("units", "m", "Length unit for uom attributes: m or ft")
+	convertUnits := flags.Bool("convert-units", false, "Scale heights, assumed in meters, into -units; X and Y stay in the units of the CRS")
+	preserveCoords := flags.Bool("preserve-coords", false, "Write coordinates exactly as given in the OBJ instead of reformatting them")
+	canonical := flags.Bool("canonical", false, "Sort faces by centroid so the output does not depend on the face order of the OBJ")
+	maxVertices := flags.Int("max-vertices", 0, "Reject OBJ files with more than this many vertices (0 disables)")
+	clipBelowFlag := flags.String("clip-below", "", "Remove geometry below this Z datum, e.g. basements; see -clip-mode for faces crossing it")
+	clipMode := flags.String("clip-mode", "clip", "How -clip-below treats faces crossing the datum: clip cuts them at it, drop removes them")
+	maxRingVertices := flags.Int("max-ring-vertices", 0, "Split polygons with more corners than this into smaller polygons or triangles (0 disables, at least 3)")
+	strict := flags.Bool("strict", false, "Fail on NaN/Inf vertex coordinates or malformed face vertices instead of skipping the affected faces")
+	capBottom := flags.Bool("cap-bottom", false, "Close an open bottom boundary at the lowest Z with ground polygons")
+	dropNonManifold := flags.Bool("drop-nonmanifold", false, "Exclude faces that use an edge shared by more than two faces")
+	envelope := flags.String("envelope", "", "Fixed envelope \"minx miny minz maxx maxy maxz\" written instead of the geometry bounds, e.g. a tile extent")
+	geometry := flags.String("geometry", "solid", "LOD1 geometry type: solid, multisurface or auto (solid only when watertight)")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if (*inputDir == "" && *tarArchive == "") || *outputDir == "" {
+		fmt.Println("Usage: obj2citygml -input <input_directory> | -tar <archive.tar> -output <output_directory> [-epsg <epsg_code>]")
+		return 2
+	}
+
+	if *geometry != "solid" && *geometry != "multisurface" && *geometry != "auto" {
+		fmt.Printf("Invalid -geometry value %q: must be solid, multisurface or auto\n", *geometry)
+		return 2
+	}
+
+	if *srsForm != "url" && *srsForm != "urn" {
+		fmt.Printf("Error: unsupported -srs-form %q (use url or urn)\n", *srsForm)
+		return 2
+	}
+
+	if *maxRingVertices != 0 && *maxRingVertices < 3 {
+		fmt.Println("Error: -max-ring-vertices must be 0 or at least 3")
+		return 2
+	}
+
+	if *clipMode != "clip" && *clipMode != "drop" {
+		fmt.Printf("Error: unsupported -clip-mode %q (use clip or drop)\n", *clipMode)
+		return 2
+	}
+	var clipDatum float64
+	if *clipBelowFlag != "" {
+		datum, err := strconv.ParseFloat(*clipBelowFlag, 64)
+		if err != nil || math.IsNaN(datum) || math.IsInf(datum, 0) {
+			fmt.Printf("Error: invalid -clip-below %q\n", *clipBelowFlag)
+			return 2
+		}
+		clipDatum = datum
+	} else {
+		*clipMode = ""
+	}
+
+	namespaces, err := citygml.NamespacesFor(*cityGMLVersion)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 2
+	}
+
+	if _, ok := objconv.UnitScales[*units]; !ok {
+		fmt.Printf("Error: unsupported -units %q (use m or ft)\n", *units)
+		return 2
+	}
+
+	var envelopeBounds []float64
+	if *envelope != "" {
+		if envelopeBounds, err = objconv.ParseEnvelope(*envelope); err != nil {
+			fmt.Printf("Error: invalid -envelope: %v\n", err)
+			return 2
+		}
+	}
+
+	opts := ConvertOptions{
+		EPSGCode:        *epsgCode,
+		SRSForm:         *srsForm,
+		EPSGAttribute:   *epsgAttribute,
+		Namespaces:      namespaces,
+		Geometry:        *geometry,
+		Simplify:        *simplify,
+		DropNonManifold: *dropNonManifold,
+		CapBottom:       *capBottom,
+		Strict:          *strict,
+		MaxFaces:        *maxFaces,
+		MaxVertices:     *maxVertices,
+		MaxRingVertices: *maxRingVertices,
+		ClipMode:        *clipMode,
+		ClipBelow:       clipDatum,
+		Units:           *units,
+		Semantics:       *semantics,
+		ConvertUnits:    *convertUnits,
+		PreserveCoords:  *preserveCoords,
+		Canonical:       *canonical,
+		Envelope:        envelopeBounds,
+
+		Meta:              meta,
+		FlattenNamespaces: *flattenNS,
+	}
+
+	if *combine && *checkpointPath != "" {
+		fmt.Println("Error: -checkpoint cannot be used with -combine, which writes one file at the end")
+		return 2
+	}
+
+	// Create output directory if it doesn't exist; with -combine -output is the file
+	outputParent := *outputDir
+	if *combine {
+		outputParent = filepath.Dir(*outputDir)
+	}
+	if err := os.MkdirAll(outputParent, 0755); err != nil {
+		fmt.Printf("Error creating output directory: %v\n", err)
+		return 1
+	}
+
+	var ids map[string]bool
+	if *onlyIDs != "" {
+		ids, err = objconv.ParseIDList(*onlyIDs)
+		if err != nil {
+			fmt.Printf("Error: invalid -only-ids: %v\n", err)
+			return 2
+		}
+	}
+
+	var epsgMappings []objconv.EPSGMapping
+	if *epsgMap != "" {
+		epsgMappings, err = objconv.ReadEPSGMap(*epsgMap)
+		if err != nil {
+			fmt.Printf("Error: invalid -epsg-map: %v\n", err)
+			return 2
+		}
+	}
+
+	var cp *checkpoint
+	if *checkpointPath != "" {
+		cp, err = openCheckpoint(*checkpointPath)
+		if err != nil {
+			fmt.Printf("Error opening checkpoint: %v\n", err)
+			return 1
+		}
+		defer cp.file.Close()
+		fmt.Printf("Checkpoint %s lists %d converted inputs\n", *checkpointPath, len(cp.done))
+	}
+
+	processed, resumed := 0, 0
+	successCount := 0
+	errorFiles := []string{}
+
+	// With -combine every building is added to one model written at the end
+	var combined citygml.CityModel
+	combinedBounds := []float64{}
+	convert := func(r io.Reader, name, outputFile, buildingID string, fileOpts ConvertOptions) error {
+		if !*combine {
+			return convertOBJReader(r, name, outputFile, buildingID, fileOpts)
+		}
+		model, err := buildCityModel(r, name, buildingID, fileOpts)
+		if err != nil {
+			return err
+		}
+		// One CityModel has one CRS, so a file -epsg-map puts in another one fails
+		if len(combined.CityObjectMember) == 0 {
+			combined = model
+			combined.CityObjectMember = nil
+		} else if model.BoundedBy.Envelope.SrsName != combined.BoundedBy.Envelope.SrsName {
+			return fmt.Errorf("its CRS %s differs from %s of the combined file; -combine needs one EPSG code for every file",
+				model.BoundedBy.Envelope.SrsName, combined.BoundedBy.Envelope.SrsName)
+		}
+		combined.CityObjectMember = append(combined.CityObjectMember, model.CityObjectMember...)
+
+		envelope := model.BoundedBy.Envelope
+		if bounds, err := objconv.ParseEnvelope(envelope.LowerCorner + " " + envelope.UpperCorner); err == nil {
+			if len(combinedBounds) == 0 {
+				combinedBounds = bounds
+			}
+			for i := 0; i < 3; i++ {
+				combinedBounds[i] = math.Min(combinedBounds[i], bounds[i])
+				combinedBounds[i+3] = math.Max(combinedBounds[i+3], bounds[i+3])
+			}
+		}
+		return nil
+	}
+
+	// convertOne converts one OBJ source with run and reports whether to go on
+	convertOne := func(name string, run func(outputFile, buildingID string, fileOpts ConvertOptions) error) bool {
+		baseFileName := filepath.Base(name)
+		if cp != nil && cp.done[baseFileName] {
+			resumed++
+			return true
+		}
+		processed++
+		fileNameWithoutExt := strings.TrimSuffix(baseFileName, filepath.Ext(baseFileName))
+		outputFile := filepath.Join(*outputDir, fileNameWithoutExt+".gml")
+
+		fileOpts := opts
+		fileOpts.EPSGCode = objconv.EPSGForFile(epsgMappings, baseFileName, opts.EPSGCode)
+
+		if err := run(outputFile, fileNameWithoutExt, fileOpts); err != nil {
+			fmt.Printf("Error processing %s: %v\n", baseFileName, err)
+			errorFiles = append(errorFiles, baseFileName)
+			if *failFast {
+				fmt.Println("Stopping at the first failure (-fail-fast)")
+				return false
+			}
+		} else {
+			successCount++
+			if cp != nil {
+				if err := cp.record(baseFileName); err != nil {
+					fmt.Printf("Error updating checkpoint: %v\n", err)
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	if *tarArchive != "" {
+		// Stream the .obj entries of the archive without extracting them
+		found, skipped := 0, 0
+		entries := make(map[string]string)
+		err = objconv.EachTarOBJ(*tarArchive, func(name string, r io.Reader) bool {
+			found++
+			if ids != nil && !ids[strings.TrimSuffix(path.Base(name), path.Ext(name))] {
+				skipped++
+				return true
+			}
+			// Outputs and checkpoints are keyed by the base name, so an entry
+			// of the same name in another directory would overwrite the first
+			if first, duplicate := entries[path.Base(name)]; duplicate {
+				fmt.Printf("Error processing %s: %s already has the name %s, rename one of them\n", name, first, path.Base(name))
+				errorFiles = append(errorFiles, name)
+				if *failFast {
+					fmt.Println("Stopping at the first failure (-fail-fast)")
+					return false
+				}
+				return true
+			}
+			entries[path.Base(name)] = name
+			return convertOne(name, func(outputFile, buildingID string, fileOpts ConvertOptions) error {
+				return convert(r, name, outputFile, buildingID, fileOpts)
+			})
+		})
+		if err != nil {
+			fmt.Printf("Error reading tar archive %s: %v\n", *tarArchive, err)
+			errorFiles = append(errorFiles, filepath.Base(*tarArchive))
+		}
+		fmt.Printf("Found %d OBJ entries in %s\n", found, filepath.Base(*tarArchive))
+		if ids != nil {
+			fmt.Printf("Skipped %d of %d OBJ entries not listed in -only-ids\n", skipped, found)
+		}
+	} else {
+		// Find all OBJ files in the input directory
+		objFiles, err := filepath.Glob(filepath.Join(*inputDir, "*.obj"))
+		if err != nil {
+			fmt.Printf("Error finding OBJ files: %v\n", err)
+			return 0
+		}
+
+		if ids != nil {
+			found := len(objFiles)
+			objFiles = objconv.FilterByID(objFiles, ids)
+			fmt.Printf("Skipped %d of %d OBJ files not listed in -only-ids\n", found-len(objFiles), found)
+		}
+
+		fmt.Printf("Found %d OBJ files to process\n", len(objFiles))
+
+		// Process each OBJ file
+		for _, objFile := range objFiles {
+			objFile := objFile
+			if !convertOne(objFile, func(outputFile, buildingID string, fileOpts ConvertOptions) error {
+				file, err := os.Open(objFile)
+				if err != nil {
+					return fmt.Errorf("failed to parse OBJ file: %v", err)
+				}
+				defer file.Close()
+				return convert(file, objFile, outputFile, buildingID, fileOpts)
+			}) {
+				break
+			}
+		}
+	}
+
+	if *combine && len(combined.CityObjectMember) > 0 {
+		if len(combinedBounds) == 6 && opts.Envelope == nil {
+			b := combinedBounds
+			combined.BoundedBy.Envelope.LowerCorner, combined.BoundedBy.Envelope.UpperCorner = objconv.EnvelopeCorners(b[0], b[1], b[2], b[3], b[4], b[5])
+		}
+		if err := writeCityModel(combined, *outputDir, opts); err != nil {
+			fmt.Printf("Error writing combined output: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Combined %d buildings into %s\n", len(combined.CityObjectMember), *outputDir)
+	}
+
+	// Print summary
+	if resumed > 0 {
+		fmt.Printf("Skipped %d OBJ files already converted according to the checkpoint\n", resumed)
+	}
+	fmt.Printf("Successfully converted %d from %d OBJ files\n", successCount, processed)
+	if len(errorFiles) > 0 {
+		fmt.Printf("Failed to convert %d files: %v\n", len(errorFiles), errorFiles)
+		if *failOnError || *failFast {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Calculate normal vector for a triangle
+func calculateNormal(v1, v2, v3 objconv.Vertex) objconv.Vector3D {
+	// Calculate vectors from v1 to v2 and v1 to v3
+	ux := v2.X - v1.X
+	uy := v2.Y - v1.Y
+	uz := v2.Z - v1.Z
+
+	vx := v3.X - v1.X
+	vy := v3.Y - v1.Y
+	vz := v3.Z - v1.Z
+
+	// Cross product
+	nx := uy*vz - uz*vy
+	ny := uz*vx - ux*vz
+	nz := ux*vy - uy*vx
+
+	// Normalize
+	length := math.Sqrt(nx*nx + ny*ny + nz*nz)
+	if length > 0 {
+		nx /= length
+		ny /= length
+		nz /= length
+	}
+
+	return objconv.Vector3D{X: nx, Y: ny, Z: nz}
+}
+
+// classifySurface labels a face Roof, Wall or Ground from its normal, using the
+// same thresholds as obj2lod2gml.go
+func classifySurface(vertices []objconv.Vertex, face OBJFace) string {
+	if len(face) < 3 {
+		return "Wall"
+	}
+	for _, idx := range face[:3] {
+		if idx < 1 || idx > len(vertices) {
+			return "Wall"
+		}
+	}
+
+	normal := calculateNormal(vertices[face[0]-1], vertices[face[1]-1], vertices[face[2]-1])
+	if normal.Z > 0.7 {
+		return "Roof"
+	} else if normal.Z < -0.7 {
+		return "Ground"
+	}
+	return "Wall"
+}
+
+// createBoundarySurfaces builds one semantic surface per class whose members
+// link to the polygons already written in the LOD1 geometry
+func createBoundarySurfaces(buildingID string, polygons map[string][]string) []citygml.BoundarySurface {
+	var result []citygml.BoundarySurface
+	for _, surfaceType := range []string{"Roof", "Wall", "Ground"} {
+		if len(polygons[surfaceType]) == 0 {
+			continue
+		}
+
+		surface := &citygml.SemanticSurface{
+			ID:               fmt.Sprintf("%s-%s", buildingID, strings.ToLower(surfaceType)),
+			Lod2MultiSurface: &citygml.MultiSurfaceProperty{},
+		}
+		for _, polygonID := range polygons[surfaceType] {
+			surface.Lod2MultiSurface.MultiSurface.SurfaceMember = append(surface.Lod2MultiSurface.MultiSurface.SurfaceMember,
+				citygml.SurfaceMember{Href: "#" + polygonID})
+		}
+
+		switch surfaceType {
+		case "Roof":
+			result = append(result, citygml.BoundarySurface{RoofSurface: surface})
+		case "Wall":
+			result = append(result, citygml.BoundarySurface{WallSurface: surface})
+		case "Ground":
+			result = append(result, citygml.BoundarySurface{GroundSurface: surface})
+		}
+	}
+	return result
+}
+
+// Ensure consistent winding order for face
+func ensureConsistentWindingOrder(vertices []objconv.Vertex, face OBJFace) OBJFace {
+	if len(face) < 3 {
+		return face
+	}
+
+	// Get vertices for the face
+	v1 := vertices[face[0]-1]
+	v2 := vertices[face[1]-1]
+	v3 := vertices[face[2]-1]
+
+	// Calculate normal
+	normal := calculateNormal(v1, v2, v3)
+
+	// If normal is pointing inward (negative Z), reverse the winding order
+	// This is a simplification - in a real application, you'd need a more sophisticated check
+	if normal.Z < 0 {
+		// Reverse the face indices
+		for i, j := 0, len(face)-1; i < j; i, j = i+1, j-1 {
+			face[i], face[j] = face[j], face[i]
+		}
+	}
+
+	return face
+}
+
+// isWatertight reports whether every edge of the mesh is shared by exactly two faces
+func isWatertight(faces []OBJFace) bool {
+	if len(faces) == 0 {
+		return false
+	}
+
+	for _, count := range edgeFaceCounts(faces) {
+		if count != 2 {
+			return false
+		}
+	}
+	return true
+}
+
+// edgeFaceCounts maps every undirected edge to the number of faces using it
+func edgeFaceCounts(faces []OBJFace) map[[2]int]int {
+	counts := make(map[[2]int]int)
+	for _, face := range faces {
+		for i := range face {
+			a, b := face[i], face[(i+1)%len(face)]
+			if a > b {
+				a, b = b, a
+			}
+			counts[[2]int{a, b}]++
+		}
+	}
+	return counts
+}
+
+// capBottomFaces builds the polygons that close the open boundary loops lying
+// at the lowest Z of the mesh. Boundary edges are those used by a single face;
+// each cap walks them in reverse so it winds the same way as its neighbours.
+func capBottomFaces(vertices []objconv.Vertex, faces []OBJFace) ([]OBJFace, error) {
+	counts := edgeFaceCounts(faces)
+
+	minZ, maxZ := math.MaxFloat64, -math.MaxFloat64
+	for _, face := range faces {
+		for _, idx := range face {
+			if idx < 1 || idx > len(vertices) || !objconv.IsFiniteVertex(vertices[idx-1]) {
+				continue
+			}
+			minZ = math.Min(minZ, vertices[idx-1].Z)
+			maxZ = math.Max(maxZ, vertices[idx-1].Z)
+		}
+	}
+	if minZ > maxZ {
+		return nil, nil
+	}
+	// The bottom region allows for small height noise in the footprint
+	tolerance := math.Max((maxZ-minZ)*0.01, 1e-6)
+	atBottom := func(idx int) bool {
+		return idx >= 1 && idx <= len(vertices) && vertices[idx-1].Z <= minZ+tolerance
+	}
+
+	// Map each bottom boundary vertex to the next one along the reversed edges
+	next := make(map[int]int)
+	var starts []int
+	for _, face := range faces {
+		for i := range face {
+			a, b := face[i], face[(i+1)%len(face)]
+			if counts[objconv.UndirectedEdge(a, b)] != 1 || !atBottom(a) || !atBottom(b) {
+				continue
+			}
+			if _, ok := next[b]; ok {
+				return nil, fmt.Errorf("bottom boundary branches at vertex %d", b)
+			}
+			next[b] = a
+			starts = append(starts, b)
+		}
+	}
+
+	var caps []OBJFace
+	visited := make(map[int]bool)
+	for _, start := range starts {
+		if visited[start] {
+			continue
+		}
+		var loop OBJFace
+		for v := start; !visited[v]; {
+			visited[v] = true
+			loop = append(loop, v)
+			n, ok := next[v]
+			if !ok {
+				return nil, fmt.Errorf("bottom boundary is not closed at vertex %d", v)
+			}
+			v = n
+		}
+		if next[loop[len(loop)-1]] != start {
+			return nil, fmt.Errorf("bottom boundary is not a simple loop at vertex %d", start)
+		}
+		if len(loop) >= 3 {
+			caps = append(caps, loop)
+		}
+	}
+	return caps, nil
+}
+
+// scaleVertices converts vertex heights from meters into the given unit. X
+// and Y are left alone: they are in the units of the projected CRS, which
+// the srsName keeps declaring.
+func scaleVertices(vertices []objconv.Vertex, units string) {
+	factor := 1 / objconv.UnitScales[units]
+	for i := range vertices {
+		vertices[i].Z *= factor
+		vertices[i].Raw = [3]string{}
+	}
+}
+
+// nonManifoldEdges returns the edges shared by more than two faces, sorted by vertex index
+func nonManifoldEdges(faces []OBJFace) [][2]int {
+	var edges [][2]int
+	for edge, count := range edgeFaceCounts(faces) {
+		if count > 2 {
+			edges = append(edges, edge)
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] != edges[j][0] {
+			return edges[i][0] < edges[j][0]
+		}
+		return edges[i][1] < edges[j][1]
+	})
+	return edges
+}
+
+// dropNonManifoldFaces removes every face that uses one of the given edges
+func dropNonManifoldFaces(faces []OBJFace, edges [][2]int) []OBJFace {
+	bad := make(map[[2]int]bool)
+	for _, edge := range edges {
+		bad[edge] = true
+	}
+
+	var kept []OBJFace
+	for _, face := range faces {
+		keep := true
+		for i := range face {
+			a, b := face[i], face[(i+1)%len(face)]
+			if a > b {
+				a, b = b, a
+			}
+			if bad[[2]int{a, b}] {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			kept = append(kept, face)
+		}
+	}
+	return kept
+}
+
+// formatEdges lists up to limit edges as 1-based OBJ vertex pairs
+func formatEdges(edges [][2]int, limit int) string {
+	var parts []string
+	for i, edge := range edges {
+		if i == limit {
+			parts = append(parts, fmt.Sprintf("... (%d more)", len(edges)-limit))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%d-%d", edge[0], edge[1]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// simplifyFaces merges adjacent coplanar faces into single polygons
+func simplifyFaces(vertices []objconv.Vertex, faces []OBJFace, toleranceDeg float64) []OBJFace {
+	var rings [][]int
+	var groups []string
+	var result []OBJFace
+	// parseOBJ has already rejected faces with bad indices
+	for _, face := range faces {
+		rings = append(rings, face.Ring())
+		groups = append(groups, "")
+	}
+
+	merged, _ := objconv.MergeCoplanarRings(vertices, rings, groups, toleranceDeg)
+	for _, ring := range merged {
+		face := make(OBJFace, len(ring))
+		for i, idx := range ring {
+			face[i] = idx + 1
+		}
+		result = append(result, face)
+	}
+	return result
+}
+
+// convertOBJReader converts OBJ data read from r, such as a tar entry, into
+// its own CityGML file; name identifies the source in messages
+func convertOBJReader(r io.Reader, name, outputPath, buildingID string, opts ConvertOptions) error {
+	cityModel, err := buildCityModel(r, name, buildingID, opts)
+	if err != nil {
+		return err
+	}
+	return writeCityModel(cityModel, outputPath, opts)
+}
+
+// buildCityModel parses OBJ data from r into a CityModel holding one building
+func buildCityModel(r io.Reader, name, buildingID string, opts ConvertOptions) (citygml.CityModel, error) {
+	// The id ends up in gml:id and xlink targets, so it must be valid XML text
+	if safe := objconv.XMLText(buildingID); safe != buildingID {
+		fmt.Printf("Warning: removed characters not allowed in XML from building id %q\n", buildingID)
+		buildingID = safe
+	}
+
+	// Read and parse OBJ file
+	vertices, faces, err := parseOBJ(r, name, opts)
+	if err != nil {
+		return citygml.CityModel{}, fmt.Errorf("failed to parse OBJ file: %v", err)
+	}
+
+	if opts.ConvertUnits {
+		scaleVertices(vertices, opts.Units)
+	}
+
+	if opts.Canonical {
+		faces = objconv.CanonicalFaces(vertices, faces)
+	}
+
+	if opts.ClipMode != "" {
+		var dropped, clipped int
+		vertices, faces, dropped, clipped = objconv.ClipBelow(vertices, faces, opts.ClipBelow, opts.ClipMode)
+		if dropped+clipped > 0 {
+			fmt.Printf("Clipped %s below z=%g: dropped %d faces, cut %d faces at the datum\n", buildingID, opts.ClipBelow, dropped, clipped)
+		}
+		if len(faces) == 0 {
+			return citygml.CityModel{}, fmt.Errorf("no faces left above -clip-below %g", opts.ClipBelow)
+		}
+	}
+
+	if opts.Simplify > 0 {
+		before := len(faces)
+		faces = simplifyFaces(vertices, faces, opts.Simplify)
+		fmt.Printf("Simplified %s: %d faces -> %d faces\n", buildingID, before, len(faces))
+	}
+
+	// Report edges shared by more than two faces, these make the solid invalid
+	if edges := nonManifoldEdges(faces); len(edges) > 0 {
+		fmt.Printf("Warning: %s has %d non-manifold edges: %s\n", buildingID, len(edges), formatEdges(edges, 5))
+		if opts.DropNonManifold {
+			before := len(faces)
+			faces = dropNonManifoldFaces(faces, edges)
+			fmt.Printf("Dropped %d faces with non-manifold edges from %s\n", before-len(faces), buildingID)
+		}
+	}
+
+	if opts.CapBottom {
+		var caps []OBJFace
+		caps, err = capBottomFaces(vertices, faces)
+		if err != nil {
+			fmt.Printf("Warning: could not cap %s: %v\n", buildingID, err)
+		} else if len(caps) > 0 {
+			faces = append(faces, caps...)
+			fmt.Printf("Capped open bottom of %s with %d polygons\n", buildingID, len(caps))
+		}
+	}
+
+	if opts.MaxRingVertices > 0 {
+		var split int
+		faces, split = objconv.SplitLargeFaces(vertices, faces, opts.MaxRingVertices)
+		if split > 0 {
+			fmt.Printf("Split %d faces of %s with more than %d vertices\n", split, buildingID, opts.MaxRingVertices)
+		}
+	}
+
+	// Calculate bounding box
+	minX, minY, minZ, maxX, maxY, maxZ := objconv.VertexBounds(vertices)
+	lowerCorner, upperCorner := objconv.EnvelopeCorners(minX, minY, minZ, maxX, maxY, maxZ)
+
+	// Calculate height
+	height := maxZ - minZ
+
+	// Create CityGML structure
+	ns := opts.Namespaces
+	cityModel := citygml.CityModel{
+		GML:   citygml.GMLNamespace,
+		Core:  ns.Core,
+		Bldg:  ns.Bldg,
+		App:   ns.App,
+		Gen:   ns.Gen,
+		Grp:   ns.Grp,
+		XLink: citygml.XLinkNamespace,
+		XSI:   citygml.XSINamespace,
+		SchemaLocation: strings.Join([]string{
+			citygml.SchemaLocation(ns.Core, "", ns.Version, "cityGMLBase.xsd"),
+			citygml.SchemaLocation(ns.Bldg, "building/", ns.Version, "building.xsd"),
+		}, " "),
+		BoundedBy: citygml.BoundedBy{
+			Envelope: citygml.Envelope{
+				SrsName:      citygml.SRSName(opts.SRSForm, opts.EPSGCode),
+				SrsDimension: "3",
+				LowerCorner:  lowerCorner,
+				UpperCorner:  upperCorner,
+			},
+		},
+	}
+	if opts.Envelope != nil {
+		cityModel.BoundedBy.Envelope.LowerCorner = formatCorner(opts.Envelope[:3])
+		cityModel.BoundedBy.Envelope.UpperCorner = formatCorner(opts.Envelope[3:])
+	}
+
+	// Create building
+	building := citygml.Building{
+		ID:                 buildingID,
+		YearOfConstruction: strconv.Itoa(time.Now().Year()),
+		RoofType:           &citygml.Code{Value: "1000"}, // Default roof type
+		MeasuredHeight: &citygml.Measure{
+			Value: fmt.Sprintf("%.2f", height),
+			UOM:   opts.Units,
+		},
+	}
+
+	// Let consumers skip open or non-manifold solids without re-checking them
+	watertight := isWatertight(faces)
+	watertightValue := "NO"
+	if watertight {
+		watertightValue = "YES"
+	}
+	building.StringAttributes = append(building.StringAttributes, citygml.StringAttribute{Name: "Watertight", Value: watertightValue})
+	if opts.EPSGAttribute {
+		building.StringAttributes = append(building.StringAttributes, citygml.StringAttribute{Name: "EPSG", Value: opts.EPSGCode})
+	}
+
+	for _, attribute := range opts.Meta {
+		attribute = citygml.StringAttribute{Name: objconv.XMLText(attribute.Name), Value: objconv.XMLText(attribute.Value)}
+		building.StringAttributes = objconv.SetStringAttribute(building.StringAttributes, attribute)
+	}
+
+	// Add ALL faces to the building without any filtering or classification
+	surfaceMembers := []citygml.SurfaceMember{}
+	semanticPolygons := make(map[string][]string)
+	for i, face := range faces {
+		polygonID := fmt.Sprintf("%s-polygon-%d", buildingID, i)
+
+		// Classify before the winding fix, which points every face upward
+		if opts.Semantics {
+			surfaceType := classifySurface(vertices, face)
+			semanticPolygons[surfaceType] = append(semanticPolygons[surfaceType], polygonID)
+		}
+
+		// Ensure consistent winding order for this face
+		face = ensureConsistentWindingOrder(vertices, face)
+
+		// Create posList from face vertices
+		var posListBuilder strings.Builder
+		for _, vIdx := range face {
+			if vIdx > 0 && vIdx <= len(vertices) {
+				v := vertices[vIdx-1]
+				posListBuilder.WriteString(v.Position() + " ")
+			}
+		}
+
+		// Add first vertex again to close the polygon
+		if len(face) > 0 {
+			vIdx := face[0]
+			if vIdx > 0 && vIdx <= len(vertices) {
+				v := vertices[vIdx-1]
+				posListBuilder.WriteString(v.Position())
+			}
+		}
+
+		surfaceMember := citygml.SurfaceMember{
+			Polygon: &citygml.Polygon{
+				ID: polygonID,
+				Exterior: citygml.PolygonExterior{
+					LinearRing: citygml.LinearRing{
+						PosList: posListBuilder.String(),
+					},
+				},
+			},
+		}
+
+		// Add to general building geometry - include ALL faces
+		surfaceMembers = append(surfaceMembers, surfaceMember)
+	}
+
+	// Only closed meshes may be asserted as a solid; open ones go into a MultiSurface
+	useSolid := opts.Geometry == "solid" || (opts.Geometry == "auto" && watertight)
+	if useSolid {
+		building.Lod1Solid = &citygml.SolidProperty{
+			Solid: citygml.Solid{
+				ID: fmt.Sprintf("%s-solid", buildingID),
+				Exterior: citygml.Exterior{
+					CompositeSurface: citygml.CompositeSurface{SurfaceMember: surfaceMembers},
+				},
+			},
+		}
+	} else {
+		building.Lod1MultiSurface = &citygml.MultiSurfaceProperty{
+			MultiSurface: citygml.MultiSurface{SurfaceMember: surfaceMembers},
+		}
+	}
+
+	if opts.Semantics {
+		building.BoundedBy = createBoundarySurfaces(buildingID, semanticPolygons)
+	}
+
+	// Add building to city model
+	cityObjectMember := citygml.CityObjectMember{
+		Building: &building,
+	}
+	cityModel.CityObjectMember = append(cityModel.CityObjectMember, cityObjectMember)
+
+	return cityModel, nil
+}
+
+// writeCityModel marshals a city model and writes it with the XML header
+func writeCityModel(cityModel citygml.CityModel, outputPath string, opts ConvertOptions) error {
+	output, err := xml.MarshalIndent(cityModel, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to generate XML: %v", err)
+	}
+
+	if opts.FlattenNamespaces {
+		if output, err = citygml.FlattenNamespaces(output); err != nil {
+			return fmt.Errorf("failed to flatten namespaces: %v", err)
+		}
+	}
+
+	// Add XML header
+	xmlData := []byte(xmlHeader + string(output))
+
+	// Write to file
+	if err := fileio.WriteFile(outputPath, xmlData); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+
+	return nil
+}
+
+// Parse OBJ data from r; name is only used in warnings
+func parseOBJ(r io.Reader, name string, opts ConvertOptions) ([]objconv.Vertex, []OBJFace, error) {
+	var vertices []objconv.Vertex
+	var faces []OBJFace
+	skipped := make(map[string]int)
+	badVertices := make(map[int]bool)
+	lineNum := 0
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		fields := strings.Fields(line)
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "v":
+			// Parse vertex
+			if len(fields) < 3 {
+				continue
+			}
+
+			x, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				continue
+			}
+
+			y, err := strconv.ParseFloat(fields[2], 64)
+			if err != nil {
+				continue
+			}
+
+			// "v x y" is a 2D vertex; it gets z=0 instead of being dropped,
+			// which would shift every later face index
+			zField := "0"
+			if len(fields) > 3 {
+				zField = fields[3]
+			}
+			z, err := strconv.ParseFloat(zField, 64)
+			if err != nil {
+				continue
+			}
+
+			vertex := objconv.Vertex{X: x, Y: y, Z: z}
+			if opts.PreserveCoords {
+				vertex.Raw = [3]string{fields[1], fields[2], zField}
+			}
+			if !objconv.IsFiniteVertex(vertex) {
+				if opts.Strict {
+					return nil, nil, fmt.Errorf("line %d: non-finite vertex coordinate %q", lineNum, line)
+				}
+				// Keep the slot so later face indices still line up
+				badVertices[len(vertices)] = true
+			}
+			vertices = append(vertices, vertex)
+			if opts.MaxVertices > 0 && len(vertices) > opts.MaxVertices {
+				return nil, nil, fmt.Errorf("line %d: more than %d vertices (-max-vertices)", lineNum, opts.MaxVertices)
+			}
+
+		case "f":
+			// Parse face
+			if len(fields) < 4 {
+				continue
+			}
+
+			var face OBJFace
+			malformed, problem := "", ""
+			for i := 1; i < len(fields); i++ {
+				// Handle different face formats (v, v/vt, v/vt/vn)
+				vertexStr := strings.Split(fields[i], "/")[0]
+				idx, err := strconv.Atoi(vertexStr)
+				if err == nil && idx < 0 {
+					idx += len(vertices) + 1 // negative indices count back from the last vertex
+				}
+				if err != nil || idx < 1 {
+					malformed, problem = fields[i], "malformed"
+					break
+				}
+				// A face may only use vertices defined above it
+				if idx > len(vertices) {
+					malformed, problem = fields[i], fmt.Sprintf("out-of-range (%d vertices defined)", len(vertices))
+					break
+				}
+				face = append(face, idx)
+			}
+			if malformed != "" {
+				if opts.Strict {
+					return nil, nil, fmt.Errorf("line %d: %s face vertex %q", lineNum, problem, malformed)
+				}
+				fmt.Printf("Warning: %s line %d: %s face vertex %q, skipping face\n", filepath.Base(name), lineNum, problem, malformed)
+				continue
+			}
+
+			if len(face) >= 3 {
+				faces = append(faces, face)
+				if opts.MaxFaces > 0 && len(faces) > opts.MaxFaces {
+					return nil, nil, fmt.Errorf("line %d: more than %d faces (-max-faces)", lineNum, opts.MaxFaces)
+				}
+			}
+
+		case "l", "p", "curv", "surf":
+			skipped[fields[0]]++
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	warnNonPolygonal(filepath.Base(name), skipped)
+
+	if len(badVertices) > 0 {
+		before := len(faces)
+		faces = objconv.DropNonFiniteFaces(faces, badVertices)
+		fmt.Printf("Warning: %s has %d vertices with NaN/Inf coordinates; skipped %d faces using them\n",
+			filepath.Base(name), len(badVertices), before-len(faces))
+	}
+
+	return vertices, faces, nil
+}
+
+// nonPolygonalTypes lists the OBJ element types that carry no polygon geometry
+var nonPolygonalTypes = []string{"l", "p", "curv", "surf"}
+
+// warnNonPolygonal reports how many non-polygonal elements were dropped while parsing
+func warnNonPolygonal(source string, counts map[string]int) {
+	parts := []string{}
+	for _, t := range nonPolygonalTypes {
+		if counts[t] > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", t, counts[t]))
+		}
+	}
+	if len(parts) > 0 {
+		fmt.Printf("Warning: %s contains non-polygonal elements that were ignored: %s\n", source, strings.Join(parts, ", "))
+	}
+}
+
+// Escapers for rewriting character data and attribute values
@@ -0,0 +1,783 @@
+package convert
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const cubeOBJ = `v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+v 0 0 1
+v 1 0 1
+v 1 1 1
+v 0 1 1
+f 1 2 6 5
+f 2 3 7 6
+f 3 4 8 7
+f 4 1 5 8
+f 5 6 7 8
+f 1 4 3 2
+`
+
+// runMain runs obj2gml with args and returns its exit status and everything
+// it wrote to stdout and stderr
+func runMain(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+
+	status := Main("obj2gml", args)
+	os.Stdout, os.Stderr = stdout, stderr
+	w.Close()
+	return status, <-output
+}
+
+// writeFiles writes name/content pairs into dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// A file -epsg-map puts in another CRS cannot join the combined CityModel
+func TestCombineRejectsMixedEPSG(t *testing.T) {
+	input := t.TempDir()
+	writeFiles(t, input, map[string]string{"a.obj": cubeOBJ, "b.obj": cubeOBJ})
+	epsgMap := filepath.Join(t.TempDir(), "epsg.csv")
+	writeFiles(t, filepath.Dir(epsgMap), map[string]string{"epsg.csv": "filename,epsg\nb,4326\n"})
+	output := filepath.Join(t.TempDir(), "combined.gml")
+
+	status, log := runMain(t, "-input", input, "-output", output, "-combine", "-epsg-map", epsgMap, "-fail-on-error")
+	if status != 1 {
+		t.Errorf("exit status = %d, want 1 for the failed file", status)
+	}
+	if !strings.Contains(log, "Error processing b.obj: its CRS http://www.opengis.net/def/crs/EPSG/0/4326 differs") {
+		t.Errorf("b.obj not reported:\n%s", log)
+	}
+
+	gml, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(gml), "<bldg:Building "); n != 1 {
+		t.Errorf("combined file has %d buildings, want only a", n)
+	}
+	if strings.Contains(string(gml), "EPSG/0/4326") {
+		t.Error("combined file mentions the CRS of the rejected file")
+	}
+
+	// Files that agree on the CRS combine as before
+	status, log = runMain(t, "-input", input, "-output", output, "-combine", "-fail-on-error")
+	if status != 0 || !strings.Contains(log, "Combined 2 buildings into "+output) {
+		t.Errorf("combine without -epsg-map = %d:\n%s", status, log)
+	}
+}
+
+// An unknown -geometry is a usage error, and nothing is converted
+func TestInvalidGeometryIsUsageError(t *testing.T) {
+	input := t.TempDir()
+	writeFiles(t, input, map[string]string{"a.obj": cubeOBJ})
+	output := filepath.Join(t.TempDir(), "out")
+
+	status, log := runMain(t, "-input", input, "-output", output, "-geometry", "brep")
+	if status != 2 || !strings.Contains(log, `Invalid -geometry value "brep"`) {
+		t.Errorf("-geometry brep = %d, want 2:\n%s", status, log)
+	}
+	if _, err := os.Stat(filepath.Join(output, "a.gml")); err == nil {
+		t.Error("a.obj was converted despite the invalid -geometry")
+	}
+}
+
+// Bad arguments exit with 2 and setup failures with 1, before anything is
+// converted
+func TestInvalidArgumentsExitStatus(t *testing.T) {
+	input := t.TempDir()
+	writeFiles(t, input, map[string]string{"a.obj": cubeOBJ})
+	notADir := filepath.Join(t.TempDir(), "file")
+	writeFiles(t, filepath.Dir(notADir), map[string]string{"file": ""})
+	output := filepath.Join(t.TempDir(), "out")
+	run := func(extra ...string) []string {
+		return append([]string{"-input", input, "-output", output}, extra...)
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		status int
+	}{
+		{"no input", []string{"-output", output}, 2},
+		{"no output", []string{"-input", input}, 2},
+		{"srs-form", run("-srs-form", "x"), 2},
+		{"max-ring-vertices", run("-max-ring-vertices", "2"), 2},
+		{"clip-mode", run("-clip-below", "0", "-clip-mode", "cut"), 2},
+		{"clip-below", run("-clip-below", "low"), 2},
+		{"citygml-version", run("-citygml-version", "3.0"), 2},
+		{"units", run("-units", "yd"), 2},
+		{"envelope", run("-envelope", "1 2"), 2},
+		{"only-ids", run("-only-ids", ","), 2},
+		{"epsg-map", run("-epsg-map", filepath.Join(input, "missing.csv")), 2},
+		{"checkpoint with combine", run("-combine", "-checkpoint", filepath.Join(t.TempDir(), "cp")), 2},
+		{"unopenable checkpoint", run("-checkpoint", filepath.Join(notADir, "cp")), 1},
+		{"output under a file", []string{"-input", input, "-output", filepath.Join(notADir, "out")}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, log := runMain(t, tt.args...); status != tt.status {
+				t.Errorf("exit status = %d, want %d:\n%s", status, tt.status, log)
+			}
+			if _, err := os.Stat(filepath.Join(output, "a.gml")); err == nil {
+				t.Error("a.obj was converted")
+			}
+		})
+	}
+}
+
+func TestOutputUsageMentionsCombine(t *testing.T) {
+	status, usage := runMain(t, "-h")
+	if status != 0 {
+		t.Fatalf("-h exited with %d", status)
+	}
+	if !strings.Contains(usage, "Directory for output CityGML files, or the output file with -combine") {
+		t.Errorf("-output usage does not mention -combine:\n%s", usage)
+	}
+}
+
+// -convert-units converts heights only; X and Y stay in the meters of the CRS
+func TestConvertUnitsScalesHeightsOnly(t *testing.T) {
+	input := t.TempDir()
+	writeFiles(t, input, map[string]string{"cube.obj": strings.ReplaceAll(cubeOBJ, " 1\n", " 10\n")})
+	output := t.TempDir()
+
+	if status, log := runMain(t, "-input", input, "-output", output, "-units", "ft", "-convert-units"); status != 0 {
+		t.Fatalf("obj2gml exited with %d:\n%s", status, log)
+	}
+	gml, err := os.ReadFile(filepath.Join(output, "cube.gml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<bldg:measuredHeight uom="ft">32.81</bldg:measuredHeight>`,
+		"<gml:upperCorner>1.000000 1.000000 32.808399</gml:upperCorner>",
+		"1.000000 1.000000 32.808399",
+	} {
+		if !strings.Contains(string(gml), want) {
+			t.Errorf("output lacks %q:\n%s", want, gml)
+		}
+	}
+}
+
+// writeTar writes name/content pairs, in order, into a tar archive at file
+func writeTar(t *testing.T, file string, entries [][2]string) {
+	t.Helper()
+	out, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	tw := tar.NewWriter(out)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry[0], Mode: 0644, Size: int64(len(entry[1])), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(entry[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Every .obj entry of a -tar archive becomes a GML named after it; an entry
+// whose name another directory already used is reported, not overwritten
+func TestTarEntries(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "tiles.tar")
+	writeTar(t, archive, [][2]string{
+		{"a/x.obj", cubeOBJ},
+		{"y.obj", cubeOBJ},
+		{"readme.txt", "not an OBJ"},
+		{"b/x.obj", strings.ReplaceAll(cubeOBJ, " 1\n", " 5\n")},
+	})
+	output := t.TempDir()
+
+	status, log := runMain(t, "-tar", archive, "-output", output, "-fail-on-error")
+	if status != 1 {
+		t.Errorf("exit status = %d, want 1 for the duplicate", status)
+	}
+	for _, want := range []string{
+		"Found 3 OBJ entries in tiles.tar",
+		"Error processing b/x.obj: a/x.obj already has the name x.obj",
+		"Successfully converted 2 from 2 OBJ files",
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("missing %q in:\n%s", want, log)
+		}
+	}
+	entries, err := os.ReadDir(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Name() != "x.gml" || entries[1].Name() != "y.gml" {
+		t.Errorf("outputs = %v, want x.gml and y.gml", entries)
+	}
+	gml, err := os.ReadFile(filepath.Join(output, "x.gml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(gml), "<gml:upperCorner>1.000000 1.000000 1.000000</gml:upperCorner>") {
+		t.Errorf("x.gml is not the a/x.obj cube:\n%s", gml)
+	}
+}
+
+// benchBoxes is the OBJ text of n×n 6 m boxes 10 m apart, the benchmark
+// fixture; n = 25 gives 5000 vertices and 3750 faces
+func benchBoxes(n int) string {
+	var obj strings.Builder
+	v := 0
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			x, y := float64(i*10), float64(j*10)
+			for _, z := range []float64{0, 6} {
+				fmt.Fprintf(&obj, "v %g %g %g\nv %g %g %g\nv %g %g %g\nv %g %g %g\n", x, y, z, x+6, y, z, x+6, y+6, z, x, y+6, z)
+			}
+			for _, face := range [][4]int{{1, 4, 3, 2}, {5, 6, 7, 8}, {1, 2, 6, 5}, {2, 3, 7, 6}, {3, 4, 8, 7}, {4, 1, 5, 8}} {
+				fmt.Fprintf(&obj, "f %d %d %d %d\n", v+face[0], v+face[1], v+face[2], v+face[3])
+			}
+			v += 8
+		}
+	}
+	return obj.String()
+}
+
+func BenchmarkParseOBJ(b *testing.B) {
+	obj := benchBoxes(25)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := parseOBJ(strings.NewReader(obj), "b.obj", ConvertOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// -clip-below removes the part of a mesh under the datum and reports it
+func TestClipBelowRemovesGeometryUnderDatum(t *testing.T) {
+	input := t.TempDir()
+	// The cube reaches from z=-1 to 1
+	writeFiles(t, input, map[string]string{"cube.obj": strings.ReplaceAll(cubeOBJ, " 0\n", " -1\n")})
+	output := t.TempDir()
+
+	status, log := runMain(t, "-input", input, "-output", output, "-clip-below", "0")
+	if status != 0 {
+		t.Fatalf("obj2gml exited with %d:\n%s", status, log)
+	}
+	if !strings.Contains(log, "Clipped cube below z=0: dropped 1 faces, cut 4 faces at the datum") {
+		t.Errorf("clipping not reported:\n%s", log)
+	}
+	gml, err := os.ReadFile(filepath.Join(output, "cube.gml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(gml), "<gml:lowerCorner>0.000000 0.000000 0.000000</gml:lowerCorner>") || strings.Contains(string(gml), "-1.000000") {
+		t.Errorf("output still reaches below z=0:\n%s", gml)
+	}
+}
+
+// Faces using undefined vertices are skipped with a warning, or rejected with
+// -strict, instead of reaching the writer
+func TestFaceIndexOutOfRange(t *testing.T) {
+	obj := "v 0 0 5\nv 10 0 5\nv 10 10 5\nf 1 2 3\nf 1 2 9\nf 1 2 -9\nf 1 2 x\nf -3 -2 -1\n"
+
+	_, faces, err := parseOBJ(strings.NewReader(obj), "b.obj", ConvertOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(faces) != "[[1 2 3] [1 2 3]]" {
+		t.Errorf("faces = %v, want the valid face and its relative copy", faces)
+	}
+
+	if _, _, err := parseOBJ(strings.NewReader(obj), "b.obj", ConvertOptions{Strict: true}); err == nil || err.Error() != `line 5: out-of-range (3 vertices defined) face vertex "9"` {
+		t.Errorf("strict parse error = %v", err)
+	}
+}
+
+// Polylines in an OBJ are reported with their count
+func TestNonPolygonalElementsWarning(t *testing.T) {
+	input := t.TempDir()
+	writeFiles(t, input, map[string]string{"cube.obj": cubeOBJ + "l 1 2\nl 2 3 4\n"})
+
+	status, log := runMain(t, "-input", input, "-output", t.TempDir())
+	if status != 0 {
+		t.Fatalf("obj2gml exited with %d:\n%s", status, log)
+	}
+	if !strings.Contains(log, "Warning: cube.obj contains non-polygonal elements that were ignored: l=2") {
+		t.Errorf("no warning:\n%s", log)
+	}
+}
+
+// -citygml-version 1.0 switches the namespaces and schemaLocation, while the
+// geometry stays that of 2.0
+func TestCityGMLVersion(t *testing.T) {
+	input := t.TempDir()
+	writeFiles(t, input, map[string]string{"cube.obj": cubeOBJ})
+
+	outputs := make(map[string]string)
+	for _, version := range []string{"1.0", "2.0"} {
+		output := t.TempDir()
+		if status, log := runMain(t, "-input", input, "-output", output, "-citygml-version", version); status != 0 {
+			t.Fatalf("obj2gml -citygml-version %s exited with %d:\n%s", version, status, log)
+		}
+		gml, err := os.ReadFile(filepath.Join(output, "cube.gml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputs[version] = string(gml)
+	}
+
+	v1 := outputs["1.0"]
+	for _, want := range []string{
+		`xmlns:core="http://www.opengis.net/citygml/1.0"`,
+		`xmlns:bldg="http://www.opengis.net/citygml/building/1.0"`,
+		"http://www.opengis.net/citygml/1.0 http://schemas.opengis.net/citygml/1.0/cityGMLBase.xsd",
+	} {
+		if !strings.Contains(v1, want) {
+			t.Errorf("1.0 output lacks %s", want)
+		}
+	}
+	if strings.Contains(v1, "/2.0") {
+		t.Errorf("1.0 output still mentions 2.0:\n%s", v1)
+	}
+	if strings.ReplaceAll(v1, "/1.0", "/2.0") != outputs["2.0"] {
+		t.Errorf("1.0 and 2.0 output differ beyond the version")
+	}
+}
+
+// An edge shared by three faces is reported, and -drop-nonmanifold removes
+// the faces using it
+func TestNonManifoldEdges(t *testing.T) {
+	input := t.TempDir()
+	// A fin on the cube's front edge 1-2 makes it the third face there
+	writeFiles(t, input, map[string]string{"cube.obj": cubeOBJ + "v 0 -1 0\nf 1 2 9\n"})
+
+	output := t.TempDir()
+	status, log := runMain(t, "-input", input, "-output", output)
+	if status != 0 {
+		t.Fatalf("obj2gml exited with %d:\n%s", status, log)
+	}
+	if !strings.Contains(log, "Warning: cube has 1 non-manifold edges: 1-2") {
+		t.Errorf("edge not reported:\n%s", log)
+	}
+	gml, err := os.ReadFile(filepath.Join(output, "cube.gml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(gml), "<gml:Polygon"); got != 7 {
+		t.Errorf("wrote %d polygons, want all 7 faces", got)
+	}
+
+	status, log = runMain(t, "-input", input, "-output", output, "-drop-nonmanifold")
+	if status != 0 || !strings.Contains(log, "Dropped 3 faces with non-manifold edges from cube") {
+		t.Fatalf("obj2gml -drop-nonmanifold = %d:\n%s", status, log)
+	}
+	if gml, err = os.ReadFile(filepath.Join(output, "cube.gml")); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(gml), "<gml:Polygon"); got != 4 {
+		t.Errorf("wrote %d polygons, want the 4 faces off the edge", got)
+	}
+}
+
+// A NaN vertex never reaches the envelope or a posList: the faces using it are
+// skipped, or with -strict the file fails
+func TestNonFiniteVertices(t *testing.T) {
+	input := t.TempDir()
+	writeFiles(t, input, map[string]string{"cube.obj": cubeOBJ + "v nan 1 2\nf 5 6 9\n"})
+
+	output := t.TempDir()
+	status, log := runMain(t, "-input", input, "-output", output)
+	if status != 0 {
+		t.Fatalf("obj2gml exited with %d:\n%s", status, log)
+	}
+	if !strings.Contains(log, "Warning: cube.obj has 1 vertices with NaN/Inf coordinates; skipped 1 faces using them") {
+		t.Errorf("vertex not reported:\n%s", log)
+	}
+	gml, err := os.ReadFile(filepath.Join(output, "cube.gml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if regexp.MustCompile(`(?i)\bnan\b`).Match(gml) || !strings.Contains(string(gml), "<gml:upperCorner>1.000000 1.000000 1.000000</gml:upperCorner>") {
+		t.Errorf("NaN reached the output:\n%s", gml)
+	}
+
+	status, log = runMain(t, "-input", input, "-output", t.TempDir(), "-strict", "-fail-on-error")
+	if status != 1 || !strings.Contains(log, "line 15: non-finite vertex coordinate") {
+		t.Errorf("obj2gml -strict = %d:\n%s", status, log)
+	}
+}
+
+// -max-faces and -max-vertices stop the parse with an error as soon as the
+// count is exceeded
+func TestParseLimits(t *testing.T) {
+	obj := "v 0 0 0\nv 1 0 0\nv 1 1 0\n" + strings.Repeat("f 1 2 3\n", 200)
+
+	_, _, err := parseOBJ(strings.NewReader(obj), "b.obj", ConvertOptions{MaxFaces: 100})
+	if err == nil || err.Error() != "line 104: more than 100 faces (-max-faces)" {
+		t.Errorf("parse with -max-faces 100 = %v", err)
+	}
+	_, _, err = parseOBJ(strings.NewReader(obj), "b.obj", ConvertOptions{MaxVertices: 2})
+	if err == nil || err.Error() != "line 3: more than 2 vertices (-max-vertices)" {
+		t.Errorf("parse with -max-vertices 2 = %v", err)
+	}
+	if _, faces, err := parseOBJ(strings.NewReader(obj), "b.obj", ConvertOptions{MaxFaces: 200, MaxVertices: 3}); err != nil || len(faces) != 200 {
+		t.Errorf("parse at the limits = %d faces, %v", len(faces), err)
+	}
+}
+
+// -semantics keeps the LOD1 solid and adds boundary surfaces that reference
+// each of its polygons once by xlink
+func TestSemanticsReferenceSolidPolygons(t *testing.T) {
+	input := t.TempDir()
+	writeFiles(t, input, map[string]string{"cube.obj": cubeOBJ})
+
+	output := t.TempDir()
+	status, log := runMain(t, "-input", input, "-output", output, "-semantics")
+	if status != 0 {
+		t.Fatalf("obj2gml exited with %d:\n%s", status, log)
+	}
+	gml, err := os.ReadFile(filepath.Join(output, "cube.gml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(gml), `<bldg:lod1Solid>`) || strings.Count(string(gml), `<gml:Polygon gml:id="cube-polygon-`) != 6 {
+		t.Fatalf("no LOD1 solid of 6 polygons:\n%s", gml)
+	}
+
+	referenced := make(map[string]string)
+	surface := regexp.MustCompile(`(?s)<bldg:(\w+Surface) gml:id="[^"]*">(.*?)</bldg:\w+Surface>`)
+	for _, match := range surface.FindAllStringSubmatch(string(gml), -1) {
+		for _, href := range regexp.MustCompile(`xlink:href="#([^"]+)"`).FindAllStringSubmatch(match[2], -1) {
+			if previous, ok := referenced[href[1]]; ok {
+				t.Errorf("%s is referenced by %s and %s", href[1], previous, match[1])
+			}
+			referenced[href[1]] = match[1]
+		}
+	}
+	want := map[string]string{
+		"cube-polygon-0": "WallSurface", "cube-polygon-1": "WallSurface",
+		"cube-polygon-2": "WallSurface", "cube-polygon-3": "WallSurface",
+		"cube-polygon-4": "RoofSurface", "cube-polygon-5": "GroundSurface",
+	}
+	if fmt.Sprint(referenced) != fmt.Sprint(want) {
+		t.Errorf("references = %v, want %v", referenced, want)
+	}
+}
+
+// -cap-bottom closes a box without its bottom face with one ground polygon,
+// which makes the solid watertight
+func TestCapBottomClosesOpenBox(t *testing.T) {
+	input := t.TempDir()
+	writeFiles(t, input, map[string]string{"cube.obj": strings.Replace(cubeOBJ, "f 1 4 3 2\n", "", 1)})
+	watertight := regexp.MustCompile(`<gen:stringAttribute name="Watertight">\s*<gen:value>(\w+)</gen:value>`)
+
+	for _, capBottom := range []bool{false, true} {
+		output := t.TempDir()
+		status, log := runMain(t, "-input", input, "-output", output, fmt.Sprintf("-cap-bottom=%v", capBottom))
+		if status != 0 {
+			t.Fatalf("obj2gml exited with %d:\n%s", status, log)
+		}
+		gml, err := os.ReadFile(filepath.Join(output, "cube.gml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		polygons, want := strings.Count(string(gml), "<gml:Polygon"), map[bool]int{false: 5, true: 6}[capBottom]
+		match := watertight.FindSubmatch(gml)
+		if polygons != want || match == nil || string(match[1]) != map[bool]string{false: "NO", true: "YES"}[capBottom] {
+			t.Errorf("-cap-bottom=%v: %d polygons, watertight %q:\n%s", capBottom, polygons, match, gml)
+		}
+		if capBottom && !strings.Contains(log, "Capped open bottom of cube with 1 polygons") {
+			t.Errorf("cap not reported:\n%s", log)
+		}
+	}
+}
+
+// Each -meta key=value becomes a string attribute of the building, and a
+// value may itself contain = and XML special characters
+func TestMetaStringAttributes(t *testing.T) {
+	input := t.TempDir()
+	writeFiles(t, input, map[string]string{"cube.obj": cubeOBJ})
+
+	output := t.TempDir()
+	status, log := runMain(t, "-input", input, "-output", output, "-meta", "SourceDataset=Jakarta <2025>", "-meta", "Operator=a=b")
+	if status != 0 {
+		t.Fatalf("obj2gml exited with %d:\n%s", status, log)
+	}
+	gml, err := os.ReadFile(filepath.Join(output, "cube.gml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<gen:stringAttribute name="SourceDataset">\s*<gen:value>Jakarta &lt;2025&gt;</gen:value>`,
+		`<gen:stringAttribute name="Operator">\s*<gen:value>a=b</gen:value>`,
+	} {
+		if !regexp.MustCompile(want).Match(gml) {
+			t.Errorf("missing %s in:\n%s", want, gml)
+		}
+	}
+
+	if status, log := runMain(t, "-input", input, "-output", output, "-meta", "novalue"); status != 2 {
+		t.Errorf("-meta without = exited with %d:\n%s", status, log)
+	}
+}
+
+// -envelope replaces the geometry bounds in the envelope and leaves the
+// geometry alone; a malformed one is a usage error
+func TestEnvelopeOverride(t *testing.T) {
+	input := t.TempDir()
+	writeFiles(t, input, map[string]string{"cube.obj": cubeOBJ})
+
+	output := t.TempDir()
+	status, log := runMain(t, "-input", input, "-output", output, "-envelope", "-500 -500 0 500 500 100.5")
+	if status != 0 {
+		t.Fatalf("obj2gml exited with %d:\n%s", status, log)
+	}
+	gml, err := os.ReadFile(filepath.Join(output, "cube.gml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(gml), "<gml:lowerCorner>-500 -500 0</gml:lowerCorner>") || !strings.Contains(string(gml), "<gml:upperCorner>500 500 100.5</gml:upperCorner>") {
+		t.Errorf("envelope not overridden:\n%s", gml)
+	}
+	if !strings.Contains(string(gml), "<gml:posList>0.000000 0.000000 1.000000 1.000000 0.000000 1.000000") {
+		t.Errorf("geometry changed:\n%s", gml)
+	}
+
+	for _, envelope := range []string{"1 2 3", "0 0 0 1 1 x", "5 0 0 1 1 1"} {
+		if status, log := runMain(t, "-input", input, "-output", t.TempDir(), "-envelope", envelope); status != 2 {
+			t.Errorf("-envelope %q exited with %d:\n%s", envelope, status, log)
+		}
+	}
+}
+
+// -only-ids converts only the listed buildings, given inline or in a file,
+// and reports how many it skipped
+func TestOnlyIDs(t *testing.T) {
+	input := t.TempDir()
+	writeFiles(t, input, map[string]string{"a.obj": cubeOBJ, "b.obj": cubeOBJ, "c.obj": cubeOBJ})
+	idFile := filepath.Join(t.TempDir(), "ids.txt")
+	if err := os.WriteFile(idFile, []byte("b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, filter := range []string{"b", "@" + idFile} {
+		output := t.TempDir()
+		status, log := runMain(t, "-input", input, "-output", output, "-only-ids", filter)
+		if status != 0 || !strings.Contains(log, "Skipped 2 of 3 OBJ files not listed in -only-ids") {
+			t.Fatalf("-only-ids %s exited with %d:\n%s", filter, status, log)
+		}
+		entries, err := os.ReadDir(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].Name() != "b.gml" {
+			t.Errorf("-only-ids %s wrote %v, want b.gml", filter, entries)
+		}
+	}
+}
+
+// -preserve-coords writes the OBJ's coordinate strings into the posList byte
+// for byte instead of reformatting them
+func TestPreserveCoords(t *testing.T) {
+	input := t.TempDir()
+	obj := strings.Replace(cubeOBJ, "v 0 0 0\n", "v 0.000 0 0\n", 1)
+	obj = strings.Replace(obj, "v 1 1 1\n", "v 1.0 1e0 1.00\n", 1)
+	writeFiles(t, input, map[string]string{"cube.obj": obj})
+
+	for preserve, want := range map[bool][]string{
+		false: {"0.000000 0.000000 0.000000 1.000000 0.000000 0.000000", "1.000000 1.000000 1.000000"},
+		true:  {"0.000 0 0 1 0 0", "1.0 1e0 1.00"},
+	} {
+		output := t.TempDir()
+		status, log := runMain(t, "-input", input, "-output", output, fmt.Sprintf("-preserve-coords=%v", preserve))
+		if status != 0 {
+			t.Fatalf("obj2gml exited with %d:\n%s", status, log)
+		}
+		gml, err := os.ReadFile(filepath.Join(output, "cube.gml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, coordinates := range want {
+			if !strings.Contains(string(gml), coordinates) {
+				t.Errorf("-preserve-coords=%v: no %q in:\n%s", preserve, coordinates, gml)
+			}
+		}
+	}
+}
+
+// A closed cube is Watertight YES; without its top it is NO
+func TestWatertightAttribute(t *testing.T) {
+	input := t.TempDir()
+	writeFiles(t, input, map[string]string{"closed.obj": cubeOBJ, "open.obj": strings.Replace(cubeOBJ, "f 5 6 7 8\n", "", 1)})
+
+	output := t.TempDir()
+	if status, log := runMain(t, "-input", input, "-output", output); status != 0 {
+		t.Fatalf("obj2gml exited with %d:\n%s", status, log)
+	}
+	watertight := regexp.MustCompile(`<gen:stringAttribute name="Watertight">\s*<gen:value>(\w+)</gen:value>`)
+	for name, want := range map[string]string{"closed": "YES", "open": "NO"} {
+		gml, err := os.ReadFile(filepath.Join(output, name+".gml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if match := watertight.FindSubmatch(gml); match == nil || string(match[1]) != want {
+			t.Errorf("%s: Watertight %q, want %s", name, match, want)
+		}
+	}
+}
+
+// -epsg-map gives each file the code of its name or longest prefix, and the
+// rest -epsg
+func TestEPSGMap(t *testing.T) {
+	input := t.TempDir()
+	writeFiles(t, input, map[string]string{"zone48_a.obj": cubeOBJ, "zone49_b.obj": cubeOBJ, "other.obj": cubeOBJ})
+	epsgMap := filepath.Join(t.TempDir(), "epsg.csv")
+	writeFiles(t, filepath.Dir(epsgMap), map[string]string{"epsg.csv": "filename,epsg\nzone4,32700\nzone48,32748\nzone49_b.obj,32749\n"})
+
+	output := t.TempDir()
+	if status, log := runMain(t, "-input", input, "-output", output, "-epsg-map", epsgMap, "-epsg", "4326"); status != 0 {
+		t.Fatalf("obj2gml exited with %d:\n%s", status, log)
+	}
+	srsName := regexp.MustCompile(`srsName="([^"]*)"`)
+	for name, want := range map[string]string{"zone48_a": "32748", "zone49_b": "32749", "other": "4326"} {
+		gml, err := os.ReadFile(filepath.Join(output, name+".gml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if match := srsName.FindSubmatch(gml); match == nil || string(match[1]) != "http://www.opengis.net/def/crs/EPSG/0/"+want {
+			t.Errorf("%s: srsName %q, want EPSG %s", name, match, want)
+		}
+	}
+}
+
+// With -canonical the same cube with its faces in another order converts to
+// an identical file
+func TestCanonicalIgnoresFaceOrder(t *testing.T) {
+	reordered := strings.Replace(cubeOBJ, "f 1 2 6 5\nf 2 3 7 6\nf 3 4 8 7\nf 4 1 5 8\nf 5 6 7 8\nf 1 4 3 2\n",
+		"f 5 6 7 8\nf 3 4 8 7\nf 1 4 3 2\nf 2 3 7 6\nf 4 1 5 8\nf 1 2 6 5\n", 1)
+	if reordered == cubeOBJ {
+		t.Fatal("faces not reordered")
+	}
+
+	var outputs [][]byte
+	for _, obj := range []string{cubeOBJ, reordered} {
+		input, output := t.TempDir(), t.TempDir()
+		writeFiles(t, input, map[string]string{"cube.obj": obj})
+		if status, log := runMain(t, "-input", input, "-output", output, "-canonical"); status != 0 {
+			t.Fatalf("obj2gml exited with %d:\n%s", status, log)
+		}
+		gml, err := os.ReadFile(filepath.Join(output, "cube.gml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, gml)
+	}
+	if string(outputs[0]) != string(outputs[1]) {
+		t.Errorf("canonical outputs differ:\n%s\n---\n%s", outputs[0], outputs[1])
+	}
+}
+
+// -srs-form picks the srsName style and -epsg-attribute records the same code
+// on the building
+func TestSRSFormAndEPSGAttribute(t *testing.T) {
+	input := t.TempDir()
+	writeFiles(t, input, map[string]string{"cube.obj": cubeOBJ})
+	srsName := regexp.MustCompile(`srsName="([^"]*)"`)
+	epsg := regexp.MustCompile(`<gen:stringAttribute name="EPSG">\s*<gen:value>([^<]*)</gen:value>`)
+
+	for form, want := range map[string]string{
+		"url": "http://www.opengis.net/def/crs/EPSG/0/32749",
+		"urn": "urn:ogc:def:crs:EPSG::32749",
+	} {
+		output := t.TempDir()
+		status, log := runMain(t, "-input", input, "-output", output, "-epsg", "32749", "-srs-form", form, "-epsg-attribute")
+		if status != 0 {
+			t.Fatalf("obj2gml exited with %d:\n%s", status, log)
+		}
+		gml, err := os.ReadFile(filepath.Join(output, "cube.gml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if match := srsName.FindSubmatch(gml); match == nil || string(match[1]) != want {
+			t.Errorf("-srs-form %s: srsName %q, want %s", form, match, want)
+		}
+		if match := epsg.FindSubmatch(gml); match == nil || string(match[1]) != "32749" {
+			t.Errorf("-srs-form %s: EPSG attribute %q, want 32749", form, match)
+		}
+	}
+}
+
+// A run stopped after the first of two files leaves it in the checkpoint, and
+// rerunning with the checkpoint converts only the other one
+func TestCheckpointResume(t *testing.T) {
+	input, output := t.TempDir(), t.TempDir()
+	checkpointPath := filepath.Join(t.TempDir(), "done.txt")
+	writeFiles(t, input, map[string]string{"a.obj": cubeOBJ})
+	// b.obj cannot be read while it is a directory
+	if err := os.Mkdir(filepath.Join(input, "b.obj"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	status, log := runMain(t, "-input", input, "-output", output, "-checkpoint", checkpointPath, "-fail-fast")
+	if status != 1 {
+		t.Fatalf("interrupted run exited with %d, want 1:\n%s", status, log)
+	}
+	if data, err := os.ReadFile(checkpointPath); err != nil || string(data) != "a.obj\n" {
+		t.Fatalf("checkpoint = %q, %v, want a.obj only", data, err)
+	}
+
+	// Removing a.gml shows whether the resumed run converts a.obj again
+	if err := os.Remove(filepath.Join(output, "a.gml")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(input, "b.obj")); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, input, map[string]string{"b.obj": cubeOBJ})
+	status, log = runMain(t, "-input", input, "-output", output, "-checkpoint", checkpointPath, "-fail-fast")
+	if status != 0 {
+		t.Fatalf("resumed run exited with %d:\n%s", status, log)
+	}
+	if !strings.Contains(log, "Skipped 1 OBJ files already converted according to the checkpoint") ||
+		!strings.Contains(log, "Successfully converted 1 from 1 OBJ files") {
+		t.Errorf("resumed run did not convert only b.obj:\n%s", log)
+	}
+	if _, err := os.Stat(filepath.Join(output, "a.gml")); !os.IsNotExist(err) {
+		t.Errorf("a.obj converted again: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(output, "b.gml")); err != nil {
+		t.Errorf("b.obj not converted: %v", err)
+	}
+	if data, err := os.ReadFile(checkpointPath); err != nil || string(data) != "a.obj\nb.obj\n" {
+		t.Errorf("checkpoint = %q, %v, want both inputs", data, err)
+	}
+}
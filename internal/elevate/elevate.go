@@ -0,0 +1,1069 @@
+package elevate
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fakmalpradana/OBJ2GML/citygml"
+	"github.com/fakmalpradana/OBJ2GML/internal/fileio"
+)
+
+// GeoJSON structures
+type GeoJSON struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+type Feature struct {
+	Type       string                 `json:"type"`
+	Properties map[string]interface{} `json:"properties"`
+	Geometry   Geometry               `json:"geometry"`
+}
+
+type Geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// Point-in-polygon structures, same layout as objseparator.go
+type Point struct {
+	X float64
+	Y float64
+	Z float64
+}
+
+type MultiPolygon struct {
+	outer  []Point
+	hole   []Point
+	island []*MultiPolygon
+}
+
+// Footprint pairs a GeoJSON polygon with its elevation for spatial matching
+type Footprint struct {
+	ID        string
+	Elevation float64
+	Polygon   MultiPolygon
+}
+
+// propertyString reads a GeoJSON property as a string, formatting numeric ids
+// without a trailing .0
+func propertyString(properties map[string]interface{}, key string) (string, bool) {
+	switch v := properties[key].(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+// propertyFloat reads a GeoJSON property as a number, accepting numeric strings
+func propertyFloat(properties map[string]interface{}, key string) (float64, bool) {
+	switch v := properties[key].(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// pointEpsilon is the tolerance used by the point-in-polygon boundary tests
+var pointEpsilon = 1e-9
+
+// readFootprint converts a Polygon or MultiPolygon geometry into a MultiPolygon
+// where every polygon after the first becomes an island
+func readFootprint(geometry Geometry) MultiPolygon {
+	var polygons []interface{}
+	switch geometry.Type {
+	case "Polygon":
+		polygons = []interface{}{geometry.Coordinates}
+	case "MultiPolygon":
+		polygons, _ = geometry.Coordinates.([]interface{})
+	}
+
+	var footprint MultiPolygon
+	for idxPolygon, polygon := range polygons {
+		polygonParts, ok := polygon.([]interface{})
+		if !ok {
+			continue
+		}
+
+		target := &footprint
+		if idxPolygon > 0 {
+			target = &MultiPolygon{}
+			footprint.island = append(footprint.island, target)
+		}
+
+		for idxPart, part := range polygonParts {
+			coords, ok := part.([]interface{})
+			if !ok || len(coords) < 3 {
+				continue
+			}
+
+			var ring []Point
+			for _, c := range coords {
+				xy, ok := c.([]interface{})
+				if !ok || len(xy) < 2 {
+					continue
+				}
+				x, okX := xy[0].(float64)
+				y, okY := xy[1].(float64)
+				if okX && okY {
+					ring = append(ring, Point{x, y, 0})
+				}
+			}
+
+			if idxPart == 0 {
+				target.outer = ring
+			} else {
+				target.hole = ring
+			}
+		}
+	}
+	return footprint
+}
+
+// buildingCentroid averages the XY of every ring vertex of the buildings in the file
+func buildingCentroid(content []byte) (Point, bool) {
+	var model citygml.InputCityModel
+	if err := xml.Unmarshal(content, &model); err != nil {
+		return Point{}, false
+	}
+
+	var sumX, sumY float64
+	count := 0
+	for _, member := range model.CityObjectMember {
+		if member.Building == nil {
+			continue
+		}
+		member.Building.EachRing(func(surfaceType string, ring *citygml.InputLinearRing) {
+			points, err := citygml.ParsePosList(ring.Coordinates())
+			if err != nil {
+				return
+			}
+			for _, p := range points {
+				sumX += p[0]
+				sumY += p[1]
+				count++
+			}
+		})
+	}
+	if count == 0 {
+		return Point{}, false
+	}
+	return Point{sumX / float64(count), sumY / float64(count), 0}, true
+}
+
+// findContainingFootprint returns the elevation of the first footprint containing the point
+func findContainingFootprint(point Point, footprints []Footprint) (float64, string, bool) {
+	for _, footprint := range footprints {
+		if IsPointInPolygon(point, footprint.Polygon) {
+			return footprint.Elevation, footprint.ID, true
+		}
+	}
+	return 0, "", false
+}
+
+// DEM is the first band of a GeoTIFF elevation raster. Values are row-major;
+// transform maps a pixel center (col, row) to model coordinates as
+// x = t[0]*col + t[1]*row + t[2], y = t[3]*col + t[4]*row + t[5].
+type DEM struct {
+	Width, Height int
+	Values        []float64
+	NoData        float64
+	HasNoData     bool
+	EPSG          int // 0 when the GeoTIFF does not name a CRS
+	transform     [6]float64
+}
+
+// TIFF tags read by readDEM
+const (
+	tiffImageWidth      = 256
+	tiffImageLength     = 257
+	tiffBitsPerSample   = 258
+	tiffCompression     = 259
+	tiffStripOffsets    = 273
+	tiffSamplesPerPixel = 277
+	tiffRowsPerStrip    = 278
+	tiffStripByteCounts = 279
+	tiffPlanarConfig    = 284
+	tiffPredictor       = 317
+	tiffTileWidth       = 322
+	tiffTileLength      = 323
+	tiffTileOffsets     = 324
+	tiffTileByteCounts  = 325
+	tiffSampleFormat    = 339
+	geoPixelScale       = 33550
+	geoTiepoint         = 33922
+	geoTransformation   = 34264
+	geoKeyDirectory     = 34735
+	gdalNoData          = 42113
+)
+
+// maxDEMPixels caps the raster size readDEM allocates, 2 GiB of samples
+const maxDEMPixels = 1 << 28
+
+// tiffTag holds the values of one IFD entry, as numbers or as ASCII text
+type tiffTag struct {
+	values []float64
+	text   string
+}
+
+// readDEM reads a single-band GeoTIFF with uncompressed or Deflate strips or
+// tiles, georeferenced by a tiepoint and pixel scale or a transformation matrix
+func readDEM(path string) (*DEM, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 8 {
+		return nil, fmt.Errorf("not a TIFF file")
+	}
+
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("not a TIFF file")
+	}
+	switch order.Uint16(data[2:]) {
+	case 42:
+	case 43:
+		return nil, fmt.Errorf("BigTIFF is not supported")
+	default:
+		return nil, fmt.Errorf("not a TIFF file")
+	}
+
+	tags, err := readTIFFTags(data, order, order.Uint32(data[4:]))
+	if err != nil {
+		return nil, err
+	}
+	tagInt := func(tag uint16, fallback int) int {
+		if t, ok := tags[tag]; ok && len(t.values) > 0 {
+			return int(t.values[0])
+		}
+		return fallback
+	}
+
+	dem := &DEM{Width: tagInt(tiffImageWidth, 0), Height: tagInt(tiffImageLength, 0)}
+	if dem.Width <= 0 || dem.Height <= 0 {
+		return nil, fmt.Errorf("missing image size")
+	}
+	if dem.Width > maxDEMPixels/dem.Height {
+		return nil, fmt.Errorf("image of %dx%d pixels is over the %d pixels supported", dem.Width, dem.Height, maxDEMPixels)
+	}
+	bits := tagInt(tiffBitsPerSample, 1)
+	format := tagInt(tiffSampleFormat, 1)
+	samples := tagInt(tiffSamplesPerPixel, 1)
+	compression := tagInt(tiffCompression, 1)
+	predictor := tagInt(tiffPredictor, 1)
+	if samples < 1 {
+		return nil, fmt.Errorf("invalid %d samples per pixel", samples)
+	}
+	if bits != 8 && bits != 16 && bits != 32 && bits != 64 {
+		return nil, fmt.Errorf("unsupported %d bits per sample", bits)
+	}
+	if format == 3 && bits != 32 && bits != 64 {
+		return nil, fmt.Errorf("unsupported %d-bit floating point samples", bits)
+	}
+	if compression != 1 && compression != 8 && compression != 32946 {
+		return nil, fmt.Errorf("unsupported compression %d (use none or Deflate)", compression)
+	}
+	if predictor != 1 && !(predictor == 2 && format != 3) {
+		return nil, fmt.Errorf("unsupported predictor %d", predictor)
+	}
+	if samples > 1 && tagInt(tiffPlanarConfig, 1) != 1 {
+		// Planar bands store band 1 first, so read it as a single-sample image
+		samples = 1
+	}
+
+	// Strips are tiles as wide as the image
+	chunkWidth, chunkHeight := dem.Width, tagInt(tiffRowsPerStrip, dem.Height)
+	offsets, counts := tags[tiffStripOffsets], tags[tiffStripByteCounts]
+	if _, tiled := tags[tiffTileOffsets]; tiled {
+		chunkWidth, chunkHeight = tagInt(tiffTileWidth, 0), tagInt(tiffTileLength, 0)
+		offsets, counts = tags[tiffTileOffsets], tags[tiffTileByteCounts]
+	}
+	if chunkWidth <= 0 || chunkHeight <= 0 || len(offsets.values) == 0 || len(offsets.values) != len(counts.values) {
+		return nil, fmt.Errorf("missing strip or tile layout")
+	}
+	if chunkWidth > maxDEMPixels/chunkHeight {
+		return nil, fmt.Errorf("strips or tiles of %dx%d pixels are over the %d pixels supported", chunkWidth, chunkHeight, maxDEMPixels)
+	}
+	chunkHeight = min(chunkHeight, dem.Height)
+
+	bytesPerSample := bits / 8
+	chunksAcross := (dem.Width + chunkWidth - 1) / chunkWidth
+	chunksDown := (dem.Height + chunkHeight - 1) / chunkHeight
+	if len(offsets.values) < chunksAcross*chunksDown {
+		return nil, fmt.Errorf("expected %d strips or tiles, found %d", chunksAcross*chunksDown, len(offsets.values))
+	}
+	dem.Values = make([]float64, dem.Width*dem.Height)
+	for i := 0; i < chunksAcross*chunksDown; i++ {
+		start, size := int(offsets.values[i]), int(counts.values[i])
+		if start < 0 || size < 0 || start+size > len(data) {
+			return nil, fmt.Errorf("chunk %d lies outside the file", i)
+		}
+		chunk := data[start : start+size]
+		rowBytes := chunkWidth * samples * bytesPerSample
+		if compression != 1 {
+			reader, err := zlib.NewReader(bytes.NewReader(chunk))
+			if err != nil {
+				return nil, fmt.Errorf("chunk %d: %v", i, err)
+			}
+			// Inflate no more than the chunk can hold
+			chunk, err = io.ReadAll(io.LimitReader(reader, int64(rowBytes)*int64(chunkHeight)))
+			if err != nil {
+				return nil, fmt.Errorf("chunk %d: %v", i, err)
+			}
+		}
+		if predictor == 2 {
+			undoHorizontalPredictor(chunk, rowBytes, samples, bytesPerSample, order)
+		}
+
+		col0, row0 := (i%chunksAcross)*chunkWidth, (i/chunksAcross)*chunkHeight
+		for r := 0; r < chunkHeight && row0+r < dem.Height; r++ {
+			for c := 0; c < chunkWidth && col0+c < dem.Width; c++ {
+				at := r*rowBytes + c*samples*bytesPerSample
+				if at+bytesPerSample > len(chunk) {
+					return nil, fmt.Errorf("chunk %d is truncated", i)
+				}
+				dem.Values[(row0+r)*dem.Width+col0+c] = tiffSample(chunk[at:], bits, format, order)
+			}
+		}
+	}
+
+	if t, ok := tags[gdalNoData]; ok {
+		if v, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimRight(t.text, "\x00")), 64); err == nil {
+			dem.NoData, dem.HasNoData = v, true
+		}
+	}
+
+	pixelIsPoint := false
+	if keys, ok := tags[geoKeyDirectory]; ok {
+		for i := 4; i+3 < len(keys.values); i += 4 {
+			key, location, value := int(keys.values[i]), int(keys.values[i+1]), int(keys.values[i+3])
+			if location != 0 {
+				continue
+			}
+			switch key {
+			case 1025: // GTRasterTypeGeoKey
+				pixelIsPoint = value == 2
+			case 2048: // GeographicTypeGeoKey
+				if dem.EPSG == 0 && value != 32767 {
+					dem.EPSG = value
+				}
+			case 3072: // ProjectedCSTypeGeoKey
+				if value != 32767 {
+					dem.EPSG = value
+				}
+			}
+		}
+	}
+
+	if m, ok := tags[geoTransformation]; ok && len(m.values) >= 8 {
+		v := m.values
+		dem.transform = [6]float64{v[0], v[1], v[3], v[4], v[5], v[7]}
+	} else {
+		scale, tie := tags[geoPixelScale].values, tags[geoTiepoint].values
+		if len(scale) < 2 || len(tie) < 6 {
+			return nil, fmt.Errorf("missing georeferencing (pixel scale and tiepoint)")
+		}
+		dem.transform = [6]float64{scale[0], 0, tie[3] - tie[0]*scale[0], 0, -scale[1], tie[4] + tie[1]*scale[1]}
+	}
+	if !pixelIsPoint {
+		// The georeferencing names the pixel corner; move it to the center
+		t := &dem.transform
+		t[2] += (t[0] + t[1]) / 2
+		t[5] += (t[3] + t[4]) / 2
+	}
+	return dem, nil
+}
+
+// readTIFFTags reads the entries of the IFD at offset
+func readTIFFTags(data []byte, order binary.ByteOrder, offset uint32) (map[uint16]tiffTag, error) {
+	if uint64(offset)+2 > uint64(len(data)) {
+		return nil, fmt.Errorf("IFD lies outside the file")
+	}
+	typeSizes := map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+	tags := make(map[uint16]tiffTag)
+	n := int(order.Uint16(data[offset:]))
+	for i := 0; i < n; i++ {
+		entry := int(offset) + 2 + i*12
+		if entry+12 > len(data) {
+			return nil, fmt.Errorf("IFD lies outside the file")
+		}
+		id := order.Uint16(data[entry:])
+		typ := order.Uint16(data[entry+2:])
+		count := uint64(order.Uint32(data[entry+4:]))
+		size, ok := typeSizes[typ]
+		if !ok {
+			continue
+		}
+		// Check the count against the file before sizing anything by it
+		if count*uint64(size) > uint64(len(data)) {
+			return nil, fmt.Errorf("tag %d has %d values, more than the file holds", id, count)
+		}
+		length := int(count) * size
+		start := uint64(entry + 8)
+		if length > 4 {
+			start = uint64(order.Uint32(data[entry+8:]))
+		}
+		if start+uint64(length) > uint64(len(data)) {
+			return nil, fmt.Errorf("tag %d lies outside the file", id)
+		}
+		raw := data[start : start+uint64(length)]
+
+		var tag tiffTag
+		if typ == 2 {
+			tag.text = string(raw)
+		}
+		for j := 0; j < int(count); j++ {
+			b := raw[j*size:]
+			var v float64
+			switch typ {
+			case 1, 2:
+				v = float64(b[0])
+			case 6:
+				v = float64(int8(b[0]))
+			case 3:
+				v = float64(order.Uint16(b))
+			case 8:
+				v = float64(int16(order.Uint16(b)))
+			case 4:
+				v = float64(order.Uint32(b))
+			case 9:
+				v = float64(int32(order.Uint32(b)))
+			case 5:
+				v = float64(order.Uint32(b)) / float64(order.Uint32(b[4:]))
+			case 10:
+				v = float64(int32(order.Uint32(b))) / float64(int32(order.Uint32(b[4:])))
+			case 11:
+				v = float64(math.Float32frombits(order.Uint32(b)))
+			case 12:
+				v = math.Float64frombits(order.Uint64(b))
+			}
+			tag.values = append(tag.values, v)
+		}
+		tags[id] = tag
+	}
+	return tags, nil
+}
+
+// undoHorizontalPredictor reverses TIFF predictor 2, which stores each integer
+// sample as the difference from the same sample of the pixel to its left
+func undoHorizontalPredictor(chunk []byte, rowBytes, samples, bytesPerSample int, order binary.ByteOrder) {
+	stride := samples * bytesPerSample
+	for row := 0; row+rowBytes <= len(chunk); row += rowBytes {
+		for at := row + stride; at+bytesPerSample <= row+rowBytes; at += bytesPerSample {
+			prev := at - stride
+			switch bytesPerSample {
+			case 1:
+				chunk[at] += chunk[prev]
+			case 2:
+				order.PutUint16(chunk[at:], order.Uint16(chunk[at:])+order.Uint16(chunk[prev:]))
+			case 4:
+				order.PutUint32(chunk[at:], order.Uint32(chunk[at:])+order.Uint32(chunk[prev:]))
+			case 8:
+				order.PutUint64(chunk[at:], order.Uint64(chunk[at:])+order.Uint64(chunk[prev:]))
+			}
+		}
+	}
+}
+
+// tiffSample decodes one sample; format is 1 for unsigned, 2 for signed and
+// 3 for floating point
+func tiffSample(b []byte, bits, format int, order binary.ByteOrder) float64 {
+	switch bits {
+	case 8:
+		if format == 2 {
+			return float64(int8(b[0]))
+		}
+		return float64(b[0])
+	case 16:
+		if format == 2 {
+			return float64(int16(order.Uint16(b)))
+		}
+		return float64(order.Uint16(b))
+	case 32:
+		switch format {
+		case 2:
+			return float64(int32(order.Uint32(b)))
+		case 3:
+			return float64(math.Float32frombits(order.Uint32(b)))
+		}
+		return float64(order.Uint32(b))
+	default:
+		switch format {
+		case 2:
+			return float64(int64(order.Uint64(b)))
+		case 3:
+			return math.Float64frombits(order.Uint64(b))
+		}
+		return float64(order.Uint64(b))
+	}
+}
+
+// Sample interpolates the elevation at a model position bilinearly between
+// the four surrounding pixel centers. Near the edge, or next to a nodata
+// pixel, the nearest pixel is used instead. ok is false outside the raster
+// or on nodata.
+func (d *DEM) Sample(x, y float64) (float64, bool) {
+	t := d.transform
+	det := t[0]*t[4] - t[1]*t[3]
+	if det == 0 {
+		return 0, false
+	}
+	dx, dy := x-t[2], y-t[5]
+	col := (t[4]*dx - t[1]*dy) / det
+	row := (t[0]*dy - t[3]*dx) / det
+	if col < -0.5 || row < -0.5 || col > float64(d.Width)-0.5 || row > float64(d.Height)-0.5 {
+		return 0, false
+	}
+
+	c0, r0 := int(math.Floor(col)), int(math.Floor(row))
+	fc, fr := col-float64(c0), row-float64(r0)
+	if c0 >= 0 && r0 >= 0 && c0+1 < d.Width && r0+1 < d.Height {
+		v00, ok00 := d.value(c0, r0)
+		v10, ok10 := d.value(c0+1, r0)
+		v01, ok01 := d.value(c0, r0+1)
+		v11, ok11 := d.value(c0+1, r0+1)
+		if ok00 && ok10 && ok01 && ok11 {
+			top := v00 + (v10-v00)*fc
+			bottom := v01 + (v11-v01)*fc
+			return top + (bottom-top)*fr, true
+		}
+	}
+	return d.value(int(math.Round(col)), int(math.Round(row)))
+}
+
+// value returns one pixel, or false when it is outside the raster or nodata
+func (d *DEM) value(col, row int) (float64, bool) {
+	col = max(0, min(col, d.Width-1))
+	row = max(0, min(row, d.Height-1))
+	v := d.Values[row*d.Width+col]
+	if (d.HasNoData && v == d.NoData) || math.IsNaN(v) {
+		return 0, false
+	}
+	return v, true
+}
+
+// srsEPSGPattern finds the EPSG code at the end of a srsName, in URL or URN form
+var srsEPSGPattern = regexp.MustCompile(`srsName="[^"]*EPSG[^"]*?(\d+)"`)
+
+// gmlEPSG returns the EPSG code of the first srsName in the document, or 0
+func gmlEPSG(content []byte) int {
+	match := srsEPSGPattern.FindSubmatch(content)
+	if match == nil {
+		return 0
+	}
+	code, _ := strconv.Atoi(string(match[1]))
+	return code
+}
+
+// reprojectPoint converts a point between the CRSs reprojection supports:
+// WGS 84 (EPSG:4326) and its UTM zones (EPSG:326xx north, 327xx south)
+func reprojectPoint(x, y float64, from, to int) (float64, float64, error) {
+	if from == to {
+		return x, y, nil
+	}
+	lon, lat := x, y
+	if from != 4326 {
+		zone, south, ok := utmZone(from)
+		if !ok {
+			return 0, 0, fmt.Errorf("cannot reproject from EPSG:%d", from)
+		}
+		lon, lat = utmToGeographic(x, y, zone, south)
+	}
+	if to == 4326 {
+		return lon, lat, nil
+	}
+	zone, south, ok := utmZone(to)
+	if !ok {
+		return 0, 0, fmt.Errorf("cannot reproject to EPSG:%d", to)
+	}
+	x, y = geographicToUTM(lon, lat, zone, south)
+	return x, y, nil
+}
+
+// utmZone returns the zone of a WGS 84 UTM EPSG code
+func utmZone(epsg int) (int, bool, bool) {
+	switch {
+	case epsg > 32600 && epsg <= 32660:
+		return epsg - 32600, false, true
+	case epsg > 32700 && epsg <= 32760:
+		return epsg - 32700, true, true
+	}
+	return 0, false, false
+}
+
+// WGS 84 ellipsoid and UTM scale factor
+const (
+	wgs84A    = 6378137.0
+	wgs84F    = 1 / 298.257223563
+	utmScale  = 0.9996
+	utmFalseE = 500000.0
+	utmFalseN = 10000000.0 // southern hemisphere only
+)
+
+// geographicToUTM projects a longitude and latitude in degrees into a UTM zone
+func geographicToUTM(lon, lat float64, zone int, south bool) (float64, float64) {
+	e2 := wgs84F * (2 - wgs84F)
+	ep2 := e2 / (1 - e2)
+	phi := lat * math.Pi / 180
+	lambda0 := float64(zone*6-183) * math.Pi / 180
+
+	sinPhi, cosPhi, tanPhi := math.Sin(phi), math.Cos(phi), math.Tan(phi)
+	n := wgs84A / math.Sqrt(1-e2*sinPhi*sinPhi)
+	t := tanPhi * tanPhi
+	c := ep2 * cosPhi * cosPhi
+	a := cosPhi * (lon*math.Pi/180 - lambda0)
+	m := wgs84A * ((1-e2/4-3*e2*e2/64-5*e2*e2*e2/256)*phi -
+		(3*e2/8+3*e2*e2/32+45*e2*e2*e2/1024)*math.Sin(2*phi) +
+		(15*e2*e2/256+45*e2*e2*e2/1024)*math.Sin(4*phi) -
+		(35*e2*e2*e2/3072)*math.Sin(6*phi))
+
+	x := utmFalseE + utmScale*n*(a+(1-t+c)*math.Pow(a, 3)/6+(5-18*t+t*t+72*c-58*ep2)*math.Pow(a, 5)/120)
+	y := utmScale * (m + n*tanPhi*(a*a/2+(5-t+9*c+4*c*c)*math.Pow(a, 4)/24+(61-58*t+t*t+600*c-330*ep2)*math.Pow(a, 6)/720))
+	if south {
+		y += utmFalseN
+	}
+	return x, y
+}
+
+// utmToGeographic returns the longitude and latitude in degrees of a UTM position
+func utmToGeographic(x, y float64, zone int, south bool) (float64, float64) {
+	e2 := wgs84F * (2 - wgs84F)
+	ep2 := e2 / (1 - e2)
+	e1 := (1 - math.Sqrt(1-e2)) / (1 + math.Sqrt(1-e2))
+	if south {
+		y -= utmFalseN
+	}
+
+	mu := y / utmScale / (wgs84A * (1 - e2/4 - 3*e2*e2/64 - 5*e2*e2*e2/256))
+	phi1 := mu + (3*e1/2-27*math.Pow(e1, 3)/32)*math.Sin(2*mu) +
+		(21*e1*e1/16-55*math.Pow(e1, 4)/32)*math.Sin(4*mu) +
+		(151*math.Pow(e1, 3)/96)*math.Sin(6*mu) +
+		(1097*math.Pow(e1, 4)/512)*math.Sin(8*mu)
+
+	sinPhi1, cosPhi1, tanPhi1 := math.Sin(phi1), math.Cos(phi1), math.Tan(phi1)
+	n1 := wgs84A / math.Sqrt(1-e2*sinPhi1*sinPhi1)
+	t1 := tanPhi1 * tanPhi1
+	c1 := ep2 * cosPhi1 * cosPhi1
+	r1 := wgs84A * (1 - e2) / math.Pow(1-e2*sinPhi1*sinPhi1, 1.5)
+	d := (x - utmFalseE) / (n1 * utmScale)
+
+	phi := phi1 - (n1*tanPhi1/r1)*(d*d/2-(5+3*t1+10*c1-4*c1*c1-9*ep2)*math.Pow(d, 4)/24+
+		(61+90*t1+298*c1+45*t1*t1-252*ep2-3*c1*c1)*math.Pow(d, 6)/720)
+	lambda := (d - (1+2*t1+c1)*math.Pow(d, 3)/6 + (5-2*c1+28*t1-3*c1*c1+8*ep2+24*t1*t1)*math.Pow(d, 5)/120) / cosPhi1
+
+	return float64(zone*6-183) + lambda*180/math.Pi, phi * 180 / math.Pi
+}
+
+func IsPointInPolygon(point Point, polygon MultiPolygon) bool {
+	// A part contains the point when its outer ring does and its hole does not.
+	// Points on either boundary count as inside.
+	inOuter, onOuter := pointInRing(point, polygon.outer)
+	if onOuter {
+		return true
+	}
+	if inOuter {
+		inHole, onHole := pointInRing(point, polygon.hole)
+		if onHole || !inHole {
+			return true
+		}
+	}
+
+	// Islands are separate parts, each of which may carry its own hole
+	for _, island := range polygon.island {
+		if IsPointInPolygon(point, *island) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pointInRing runs the even-odd ray casting test against a single ring and
+// separately reports whether the point lies on the ring within pointEpsilon
+func pointInRing(point Point, ring []Point) (bool, bool) {
+	eps := pointEpsilon
+	n := len(ring)
+	if n < 3 {
+		return false, false // Skip invalid polygon parts
+	}
+
+	inside := false
+	j := n - 1 // Previous vertex index
+	for i := 0; i < n; i++ {
+		if pointOnSegment(point, ring[j], ring[i], eps) {
+			return true, true
+		}
+
+		yi, yj := ring[i].Y, ring[j].Y
+		if (yi > point.Y) != (yj > point.Y) { // Check y-bounds
+			xi, xj := ring[i].X, ring[j].X
+			xIntersect := (xj-xi)*(point.Y-yi)/(yj-yi) + xi
+			if point.X < xIntersect {
+				inside = !inside
+			}
+		}
+		j = i
+	}
+	return inside, false
+}
+
+// pointOnSegment reports whether point is within eps of the segment a-b
+func pointOnSegment(point, a, b Point, eps float64) bool {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	lengthSq := dx*dx + dy*dy
+	t := 0.0
+	if lengthSq > 0 {
+		t = ((point.X-a.X)*dx + (point.Y-a.Y)*dy) / lengthSq
+		t = math.Max(0, math.Min(1, t))
+	}
+	px, py := a.X+t*dx-point.X, a.Y+t*dy-point.Y
+	return px*px+py*py <= eps*eps
+}
+
+// ZAdjustment shifts Z values by Offset and keeps the result within [Min, Max],
+// counting how many values had to be clamped
+type ZAdjustment struct {
+	Offset   float64
+	Min, Max float64
+	Clamped  int
+}
+
+// Apply returns the adjusted and clamped Z value
+func (a *ZAdjustment) Apply(z float64) float64 {
+	adjusted := z + a.Offset
+	if adjusted < a.Min {
+		a.Clamped++
+		return a.Min
+	}
+	if adjusted > a.Max {
+		a.Clamped++
+		return a.Max
+	}
+	return adjusted
+}
+
+// adjustCoordinateElements shifts the Z values of every coordinate element
+func adjustCoordinateElements(content []byte, adjustment *ZAdjustment) ([]byte, error) {
+	return citygml.RewriteCoordinates(content, func(name, text string) string {
+		if name == "lowerCorner" || name == "upperCorner" {
+			return adjustBoundingBox(text, adjustment)
+		}
+		return adjustCoordinates(text, adjustment)
+	})
+}
+
+// Function to parse and adjust coordinates
+func adjustCoordinates(coordStr string, adjustment *ZAdjustment) string {
+	coords := strings.Fields(coordStr)
+	adjustedCoords := make([]string, 0, len(coords))
+
+	// Process coordinates in groups of 3 (x, y, z)
+	for i := 0; i < len(coords); i += 3 {
+		if i+2 < len(coords) {
+			x := coords[i]
+			y := coords[i+1]
+
+			// Parse z coordinate and adjust it
+			z, err := strconv.ParseFloat(coords[i+2], 64)
+			if err != nil {
+				// If parsing fails, keep original
+				adjustedCoords = append(adjustedCoords, x, y, coords[i+2])
+				continue
+			}
+
+			// Apply elevation offset and clamp
+			adjustedZ := adjustment.Apply(z)
+
+			// Add adjusted coordinates to result
+			adjustedCoords = append(adjustedCoords, x, y, fmt.Sprintf("%f", adjustedZ))
+		} else {
+			// Handle incomplete coordinate sets (shouldn't happen in valid GML)
+			for j := i; j < len(coords); j++ {
+				adjustedCoords = append(adjustedCoords, coords[j])
+			}
+		}
+	}
+
+	return strings.Join(adjustedCoords, " ")
+}
+
+// Function to adjust bounding box coordinates
+func adjustBoundingBox(bbox string, adjustment *ZAdjustment) string {
+	coords := strings.Fields(bbox)
+	if len(coords) < 3 {
+		return bbox // Not enough coordinates
+	}
+
+	// Parse z coordinate (assuming format is "x y z")
+	z, err := strconv.ParseFloat(coords[2], 64)
+	if err != nil {
+		return bbox // Can't parse z
+	}
+
+	// Adjust z coordinate
+	adjustedZ := adjustment.Apply(z)
+
+	// Return adjusted bounding box
+	return fmt.Sprintf("%s %s %f", coords[0], coords[1], adjustedZ)
+}
+
+// sampleDEM reads the DEM at the building centroid, reprojected into the DEM's
+// CRS when the GML names a different one
+func sampleDEM(dem *DEM, content []byte, id string) (float64, bool) {
+	centroid, ok := buildingCentroid(content)
+	if !ok {
+		return 0, false
+	}
+	x, y := centroid.X, centroid.Y
+	if from := gmlEPSG(content); from != 0 && dem.EPSG != 0 && from != dem.EPSG {
+		var err error
+		x, y, err = reprojectPoint(x, y, from, dem.EPSG)
+		if err != nil {
+			fmt.Printf("Warning: %s: %v\n", id, err)
+			return 0, false
+		}
+	}
+	elevation, ok := dem.Sample(x, y)
+	if !ok {
+		fmt.Printf("Warning: %s lies outside the DEM or on nodata\n", id)
+	}
+	return elevation, ok
+}
+
+// Main runs elevate with args, the command line after the program name, and
+// returns its exit status
+func Main(name string, args []string) int {
+	// Parse command-line arguments
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	gmlDir := flags.String("gml", "", "Directory containing GML files")
+	geojsonFile := flags.String("geojson", "", "GeoJSON file with elevation data")
+	outputDir := flags.String("output", "", "Output directory for adjusted GML files")
+	globalOffset := flags.Float64("global-offset", 0, "Constant Z offset added to every building on top of the per-feature elevation")
+	spatialMatch := flags.Bool("spatial-match", false, "When no feature id matches, use the footprint containing the building centroid")
+	elevProp := flags.String("elev-prop", "ELEV_mean", "GeoJSON property holding the elevation")
+	idProp := flags.String("id-prop", "id", "GeoJSON property holding the building id, matched against the GML file name")
+	clampMin := flags.Float64("clamp-min", math.Inf(-1), "Lowest Z allowed after adjustment; lower values are raised to it")
+	clampMax := flags.Float64("clamp-max", math.Inf(1), "Highest Z allowed after adjustment; higher values are lowered to it")
+	failOnError := flags.Bool("fail-on-error", false, "Exit with status 1 when any file cannot be read, parsed or written")
+	failFast := flags.Bool("fail-fast", false, "Stop at the first file that cannot be read, parsed or written and exit with status 1")
+	onlyGlobal := flags.Bool("only-global", false, "Ignore the GeoJSON and shift every building by -global-offset only")
+	demFile := flags.String("dem", "", "GeoTIFF DEM sampled bilinearly at each building centroid; with -geojson it is used for buildings the GeoJSON has no elevation for")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if *gmlDir == "" || (*geojsonFile == "" && *demFile == "" && !*onlyGlobal) || *outputDir == "" {
+		fmt.Println("Usage: gml-elevation-adjuster -gml <gml_directory> -geojson <geojson_file> -output <output_directory> [-global-offset <meters>]")
+		fmt.Println("       gml-elevation-adjuster -gml <gml_directory> -dem <geotiff> -output <output_directory> [-global-offset <meters>]")
+		fmt.Println("       gml-elevation-adjuster -gml <gml_directory> -output <output_directory> -only-global -global-offset <meters>")
+		return 0
+	}
+
+	if *clampMin > *clampMax {
+		fmt.Printf("Error: -clamp-min %f is above -clamp-max %f\n", *clampMin, *clampMax)
+		return 0
+	}
+
+	// Create output directory if it doesn't exist
+	if err := os.MkdirAll(*outputDir, 0755); err != nil {
+		fmt.Printf("Error creating output directory: %v\n", err)
+		return 0
+	}
+
+	// Create a map of ID to elevation
+	elevationMap := make(map[string]float64)
+	var footprints []Footprint
+	var dem *DEM
+	if *onlyGlobal {
+		fmt.Printf("Applying global offset %f to all buildings\n", *globalOffset)
+	} else if *demFile != "" {
+		var err error
+		dem, err = readDEM(*demFile)
+		if err != nil {
+			fmt.Printf("Error reading DEM %s: %v\n", *demFile, err)
+			return 0
+		}
+		fmt.Printf("Loaded %dx%d DEM (EPSG:%d)\n", dem.Width, dem.Height, dem.EPSG)
+	}
+	if !*onlyGlobal && *geojsonFile != "" {
+		// Read and parse GeoJSON file
+		geojsonData, err := ioutil.ReadFile(*geojsonFile)
+		if err != nil {
+			fmt.Printf("Error reading GeoJSON file: %v\n", err)
+			return 0
+		}
+
+		var geojson GeoJSON
+		if err := json.Unmarshal(geojsonData, &geojson); err != nil {
+			fmt.Printf("Error parsing GeoJSON: %v\n", err)
+			return 0
+		}
+
+		missing := 0
+		for _, feature := range geojson.Features {
+			featureID, hasID := propertyString(feature.Properties, *idProp)
+			featureElevation, hasElevation := propertyFloat(feature.Properties, *elevProp)
+			if !hasID || !hasElevation {
+				missing++
+				continue
+			}
+			elevationMap[featureID] = featureElevation
+			if *spatialMatch {
+				footprints = append(footprints, Footprint{
+					ID:        featureID,
+					Elevation: featureElevation,
+					Polygon:   readFootprint(feature.Geometry),
+				})
+			}
+		}
+
+		fmt.Printf("Loaded %d features with elevation data\n", len(elevationMap))
+		if missing > 0 {
+			fmt.Printf("Warning: %d features lack a %q or numeric %q property and were ignored\n", missing, *idProp, *elevProp)
+		}
+	}
+
+	// Process GML files
+	gmlFiles, err := filepath.Glob(filepath.Join(*gmlDir, "*.gml"))
+	if err != nil {
+		fmt.Printf("Error finding GML files: %v\n", err)
+		return 0
+	}
+
+	// Add XML files as well (some CityGML files might have .xml extension)
+	xmlFiles, err := filepath.Glob(filepath.Join(*gmlDir, "*.xml"))
+	if err == nil {
+		gmlFiles = append(gmlFiles, xmlFiles...)
+	}
+
+	fmt.Printf("Found %d GML files to process\n", len(gmlFiles))
+
+	processedCount := 0
+	skippedCount := 0
+	errorCount := 0 // skipped because of an error, not missing elevation data
+
+	for _, gmlFile := range gmlFiles {
+		// Extract ID from filename (assuming filename is ID.gml or ID.xml)
+		baseFilename := filepath.Base(gmlFile)
+		id := strings.TrimSuffix(baseFilename, filepath.Ext(baseFilename))
+
+		// Read GML file
+		fileContent, err := ioutil.ReadFile(gmlFile)
+		if err != nil {
+			fmt.Printf("Error reading file %s: %v\n", baseFilename, err)
+			skippedCount++
+			errorCount++
+			if *failFast {
+				fmt.Println("Stopping at the first failure (-fail-fast)")
+				break
+			}
+			continue
+		}
+
+		// Find elevation for this ID, then add the global datum shift
+		elevation := *globalOffset
+		if !*onlyGlobal {
+			featureElevation, found := elevationMap[id]
+			if !found && *spatialMatch {
+				// Fall back to the footprint containing the building centroid
+				if centroid, ok := buildingCentroid(fileContent); ok {
+					var footprintID string
+					featureElevation, footprintID, found = findContainingFootprint(centroid, footprints)
+					if found {
+						fmt.Printf("Matched %s to footprint %s by location\n", id, footprintID)
+					}
+				}
+			}
+			if !found && dem != nil {
+				featureElevation, found = sampleDEM(dem, fileContent, id)
+			}
+			if !found {
+				fmt.Printf("Warning: No elevation data found for ID %s, skipping file\n", id)
+				skippedCount++
+				continue
+			}
+			elevation += featureElevation
+		}
+
+		// Shift every coordinate element in place so LOD2 surfaces, appearances
+		// and attributes the tool does not model are kept byte for byte
+		adjustment := &ZAdjustment{Offset: elevation, Min: *clampMin, Max: *clampMax}
+		xmlData, err := adjustCoordinateElements(fileContent, adjustment)
+		if err != nil {
+			fmt.Printf("Error parsing GML file %s: %v\n", baseFilename, err)
+			skippedCount++
+			errorCount++
+			if *failFast {
+				fmt.Println("Stopping at the first failure (-fail-fast)")
+				break
+			}
+			continue
+		}
+		if adjustment.Clamped > 0 {
+			fmt.Printf("Clamped %d Z values in %s\n", adjustment.Clamped, baseFilename)
+		}
+
+		// Write to output file, keeping the input extension
+		outputFile := filepath.Join(*outputDir, baseFilename)
+		if err := fileio.WriteFile(outputFile, xmlData); err != nil {
+			fmt.Printf("Error writing output file for %s: %v\n", baseFilename, err)
+			skippedCount++
+			errorCount++
+			if *failFast {
+				fmt.Println("Stopping at the first failure (-fail-fast)")
+				break
+			}
+			continue
+		}
+
+		processedCount++
+
+		// Print progress every 100 files
+		if processedCount%100 == 0 {
+			fmt.Printf("Processed %d files...\n", processedCount)
+		}
+	}
+
+	// Print summary
+	fmt.Printf("\nProcessing complete!\n")
+	fmt.Printf("Successfully adjusted %d GML files\n", processedCount)
+	fmt.Printf("Skipped %d GML files\n", skippedCount)
+	if errorCount > 0 {
+		fmt.Printf("Failed on %d GML files\n", errorCount)
+		if *failOnError || *failFast {
+			return 1
+		}
+	}
+	return 0
+}
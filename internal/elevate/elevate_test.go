@@ -0,0 +1,409 @@
+package elevate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// tiffEntry is one IFD entry of a test GeoTIFF; count overrides the number of
+// values written in the entry when it is not 0
+type tiffEntry struct {
+	id, typ uint16
+	values  []float64
+	count   uint32
+}
+
+// buildGeoTIFF lays out a little-endian TIFF: the header, the pixel data at
+// offset 8, the IFD and then the values that do not fit in their entry
+func buildGeoTIFF(pixels []byte, entries []tiffEntry) []byte {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].id < entries[j].id })
+	order := binary.LittleEndian
+	typeSizes := map[uint16]int{3: 2, 4: 4, 12: 8}
+
+	ifd := 8 + len(pixels)
+	extra := ifd + 2 + 12*len(entries) + 4
+	var head, tail bytes.Buffer
+	head.WriteString("II")
+	binary.Write(&head, order, uint16(42))
+	binary.Write(&head, order, uint32(ifd))
+	head.Write(pixels)
+	binary.Write(&head, order, uint16(len(entries)))
+	for _, entry := range entries {
+		var value bytes.Buffer
+		for _, v := range entry.values {
+			switch entry.typ {
+			case 3:
+				binary.Write(&value, order, uint16(v))
+			case 4:
+				binary.Write(&value, order, uint32(v))
+			case 12:
+				binary.Write(&value, order, v)
+			}
+		}
+		count := entry.count
+		if count == 0 {
+			count = uint32(len(entry.values))
+		}
+		binary.Write(&head, order, entry.id)
+		binary.Write(&head, order, entry.typ)
+		binary.Write(&head, order, count)
+		if len(entry.values)*typeSizes[entry.typ] <= 4 {
+			field := make([]byte, 4)
+			copy(field, value.Bytes())
+			head.Write(field)
+		} else {
+			binary.Write(&head, order, uint32(extra+tail.Len()))
+			tail.Write(value.Bytes())
+		}
+	}
+	binary.Write(&head, order, uint32(0))
+	return append(head.Bytes(), tail.Bytes()...)
+}
+
+// demEntries describes a 2x2 float32 raster in one strip at offset 8, with
+// 10 m pixels whose top left corner is at 1000, 2000
+func demEntries(width, height float64) []tiffEntry {
+	return []tiffEntry{
+		{id: tiffImageWidth, typ: 3, values: []float64{width}},
+		{id: tiffImageLength, typ: 3, values: []float64{height}},
+		{id: tiffBitsPerSample, typ: 3, values: []float64{32}},
+		{id: tiffSampleFormat, typ: 3, values: []float64{3}},
+		{id: tiffRowsPerStrip, typ: 3, values: []float64{height}},
+		{id: tiffStripOffsets, typ: 4, values: []float64{8}},
+		{id: tiffStripByteCounts, typ: 4, values: []float64{16}},
+		{id: geoPixelScale, typ: 12, values: []float64{10, 10, 0}},
+		{id: geoTiepoint, typ: 12, values: []float64{0, 0, 0, 1000, 2000, 0}},
+	}
+}
+
+// float32Pixels encodes samples as little-endian float32
+func float32Pixels(samples ...float32) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, samples)
+	return buf.Bytes()
+}
+
+// writeDEM writes a test GeoTIFF and returns its path
+func writeDEM(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "dem.tif")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadDEM(t *testing.T) {
+	dem, err := readDEM(writeDEM(t, buildGeoTIFF(float32Pixels(1, 2, 3, 4), demEntries(2, 2))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dem.Width != 2 || dem.Height != 2 {
+		t.Fatalf("size = %dx%d, want 2x2", dem.Width, dem.Height)
+	}
+	for _, test := range []struct {
+		x, y, want float64
+	}{
+		{1005, 1995, 1}, // top left pixel center
+		{1015, 1985, 4}, // bottom right pixel center
+		{1010, 1990, 2.5},
+	} {
+		if got, ok := dem.Sample(test.x, test.y); !ok || math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("Sample(%g, %g) = %g, %v; want %g", test.x, test.y, got, ok, test.want)
+		}
+	}
+	if _, ok := dem.Sample(900, 2000); ok {
+		t.Error("sample outside the raster succeeded")
+	}
+}
+
+// Corrupt or hostile sizes are errors, not panics or huge allocations
+func TestReadDEMRejectsBadSizes(t *testing.T) {
+	pixels := float32Pixels(1, 2, 3, 4)
+	withEntry := func(replace tiffEntry, width, height float64) []byte {
+		entries := demEntries(width, height)
+		for i := range entries {
+			if entries[i].id == replace.id {
+				entries[i] = replace
+			}
+		}
+		return buildGeoTIFF(pixels, entries)
+	}
+
+	for _, test := range []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"tag count over the file", withEntry(tiffEntry{id: geoPixelScale, typ: 12, values: []float64{10, 10, 0}, count: 1 << 30}, 2, 2), "more than the file holds"},
+		{"tag count overflowing", withEntry(tiffEntry{id: geoTiepoint, typ: 12, values: []float64{0, 0, 0, 1000, 2000, 0}, count: math.MaxUint32}, 2, 2), "more than the file holds"},
+		{"tag values past the end", withEntry(tiffEntry{id: geoTiepoint, typ: 12, values: []float64{0, 0, 0, 1000, 2000, 0}, count: 8}, 2, 2), "tag 33922 lies outside the file"},
+		{"strip past the end", withEntry(tiffEntry{id: tiffStripByteCounts, typ: 4, values: []float64{1 << 20}}, 2, 2), "chunk 0 lies outside the file"},
+		{"image too large", buildGeoTIFF(pixels, demEntries(65535, 65535)), "pixels supported"},
+		{"IFD past the end", []byte("II*\x00\xff\xff\xff\x7f"), "IFD lies outside the file"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := readDEM(writeDEM(t, test.data))
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("readDEM error = %v, want one containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+// runMain runs the elevation adjuster with args and returns its exit status
+// and everything it wrote to stdout
+func runMain(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+
+	status := Main("elevate", args)
+	os.Stdout = stdout
+	w.Close()
+	return status, <-output
+}
+
+// buildingGML is a LOD1 building over the square 100..110, 200..210 from z=0
+// to 10, with an envelope
+const buildingGML = `<?xml version="1.0" encoding="UTF-8"?>
+<core:CityModel xmlns:core="http://www.opengis.net/citygml/2.0" xmlns:bldg="http://www.opengis.net/citygml/building/2.0" xmlns:gml="http://www.opengis.net/gml">
+<gml:boundedBy><gml:Envelope><gml:lowerCorner>100 200 0</gml:lowerCorner><gml:upperCorner>110 210 10</gml:upperCorner></gml:Envelope></gml:boundedBy>
+<core:cityObjectMember><bldg:Building gml:id="b"><bldg:lod1Solid><gml:Solid><gml:exterior><gml:CompositeSurface>
+<gml:surfaceMember><gml:Polygon><gml:exterior><gml:LinearRing><gml:posList>100 200 10 110 200 10 110 210 10 100 210 10 100 200 10</gml:posList></gml:LinearRing></gml:exterior></gml:Polygon></gml:surfaceMember>
+<gml:surfaceMember><gml:Polygon><gml:exterior><gml:LinearRing><gml:posList>100 200 0 100 210 0 110 210 0 110 200 0 100 200 0</gml:posList></gml:LinearRing></gml:exterior></gml:Polygon></gml:surfaceMember>
+</gml:CompositeSurface></gml:exterior></gml:Solid></bldg:lod1Solid></bldg:Building></core:cityObjectMember>
+</core:CityModel>
+`
+
+// footprintGeoJSON is a FeatureCollection with one square footprint over
+// buildingGML, with the given id and ELEV_mean
+func footprintGeoJSON(id string, elevation float64) string {
+	return fmt.Sprintf(`{"type":"FeatureCollection","features":[{"type":"Feature","properties":{"id":%q,"ELEV_mean":%g},`+
+		`"geometry":{"type":"Polygon","coordinates":[[[99,199],[111,199],[111,211],[99,211],[99,199]]]}}]}`, id, elevation)
+}
+
+// writeInput writes files into a new directory and returns it
+func writeInput(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+var heightPattern = regexp.MustCompile(`<gml:(?:posList|lowerCorner|upperCorner)>([^<]*)<`)
+
+// heights lists the distinct Z values of every coordinate element in a file
+func heights(t *testing.T, path string) []string {
+	t.Helper()
+	gml, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	var zs []string
+	for _, match := range heightPattern.FindAllSubmatch(gml, -1) {
+		fields := strings.Fields(string(match[1]))
+		for i := 2; i < len(fields); i += 3 {
+			if !seen[fields[i]] {
+				seen[fields[i]] = true
+				zs = append(zs, fields[i])
+			}
+		}
+	}
+	sort.Strings(zs)
+	return zs
+}
+
+// -global-offset is added on top of the feature's elevation, and with
+// -only-global it is the whole shift
+func TestGlobalOffset(t *testing.T) {
+	gmlDir := writeInput(t, map[string]string{"b.gml": buildingGML})
+	geojson := filepath.Join(writeInput(t, map[string]string{"f.geojson": footprintGeoJSON("b", 50)}), "f.geojson")
+
+	for _, test := range []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"feature", []string{"-geojson", geojson}, []string{"50.000000", "60.000000"}},
+		{"feature and global", []string{"-geojson", geojson, "-global-offset", "-2.5"}, []string{"47.500000", "57.500000"}},
+		{"only global", []string{"-geojson", geojson, "-only-global", "-global-offset", "-2.5"}, []string{"-2.500000", "7.500000"}},
+	} {
+		output := t.TempDir()
+		status, log := runMain(t, append([]string{"-gml", gmlDir, "-output", output}, test.args...)...)
+		if status != 0 || !strings.Contains(log, "Successfully adjusted 1 GML files") {
+			t.Fatalf("%s: elevate = %d:\n%s", test.name, status, log)
+		}
+		if got := heights(t, filepath.Join(output, "b.gml")); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: heights = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// A .xml CityGML file is elevated and written back as .xml
+func TestElevatesXMLFiles(t *testing.T) {
+	gmlDir := writeInput(t, map[string]string{"b.xml": buildingGML})
+	output := t.TempDir()
+
+	status, log := runMain(t, "-gml", gmlDir, "-output", output, "-only-global", "-global-offset", "5")
+	if status != 0 || !strings.Contains(log, "Successfully adjusted 1 GML files") {
+		t.Fatalf("elevate = %d:\n%s", status, log)
+	}
+	if got, want := heights(t, filepath.Join(output, "b.xml")), []string{"15.000000", "5.000000"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("heights = %v, want %v", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(output, "b.gml")); err == nil {
+		t.Error("b.xml was written as b.gml")
+	}
+}
+
+// With -spatial-match a building whose file name matches no feature id takes
+// the elevation of the footprint containing its centroid
+func TestSpatialMatch(t *testing.T) {
+	gmlDir := writeInput(t, map[string]string{"unknown.gml": buildingGML})
+	geojson := filepath.Join(writeInput(t, map[string]string{"f.geojson": footprintGeoJSON("b", 50)}), "f.geojson")
+
+	output := t.TempDir()
+	status, log := runMain(t, "-gml", gmlDir, "-geojson", geojson, "-output", output)
+	if status != 0 || !strings.Contains(log, "No elevation data found for ID unknown") {
+		t.Fatalf("elevate without -spatial-match = %d:\n%s", status, log)
+	}
+
+	status, log = runMain(t, "-gml", gmlDir, "-geojson", geojson, "-output", output, "-spatial-match")
+	if status != 0 || !strings.Contains(log, "Matched unknown to footprint b by location") {
+		t.Fatalf("elevate -spatial-match = %d:\n%s", status, log)
+	}
+	if got, want := heights(t, filepath.Join(output, "unknown.gml")), []string{"50.000000", "60.000000"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("heights = %v, want %v", got, want)
+	}
+}
+
+// A LOD2 building keeps its semantic surfaces, appearance and generic
+// attributes, and the surfaces' rings are shifted like the envelope
+func TestElevateKeepsLOD2Content(t *testing.T) {
+	cube, err := os.ReadFile("../../citygml/testdata/lod2_cube.gml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gmlDir := writeInput(t, map[string]string{"cube.gml": string(cube)})
+	output := t.TempDir()
+
+	status, log := runMain(t, "-gml", gmlDir, "-output", output, "-only-global", "-global-offset", "100")
+	if status != 0 {
+		t.Fatalf("elevate = %d:\n%s", status, log)
+	}
+	elevated, err := os.ReadFile(filepath.Join(output, "cube.gml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, element := range []string{"<bldg:RoofSurface", "<bldg:WallSurface", "<bldg:GroundSurface", "<app:Appearance", "<app:target", "<gen:stringAttribute", "<gen:measureAttribute"} {
+		if got, want := strings.Count(string(elevated), element), strings.Count(string(cube), element); got != want || want == 0 {
+			t.Errorf("%s appears %d times, want %d", element, got, want)
+		}
+	}
+	if got, want := heights(t, filepath.Join(output, "cube.gml")), []string{"100.000000", "101.000000"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("heights = %v, want %v", got, want)
+	}
+}
+
+// Only the text of coordinate elements changes; comments, unknown elements,
+// attribute quoting and whitespace are kept byte for byte
+func TestElevateRewritesOnlyCoordinates(t *testing.T) {
+	input := strings.Replace(buildingGML, "<core:cityObjectMember>",
+		"<!-- kept -->\n<core:cityObjectMember>\n  <ext:unknown xmlns:ext='urn:x' a = 'b'>text &amp; more</ext:unknown>", 1)
+	gmlDir := writeInput(t, map[string]string{"b.gml": input})
+	output := t.TempDir()
+
+	if status, log := runMain(t, "-gml", gmlDir, "-output", output, "-only-global", "-global-offset", "1"); status != 0 {
+		t.Fatalf("elevate = %d:\n%s", status, log)
+	}
+	elevated, err := os.ReadFile(filepath.Join(output, "b.gml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blank := func(gml string) string {
+		return heightPattern.ReplaceAllStringFunc(gml, func(element string) string {
+			return element[:strings.Index(element, ">")+1] + "<"
+		})
+	}
+	if got, want := blank(string(elevated)), blank(input); got != want {
+		t.Errorf("content outside the coordinates changed:\n%s\nwant\n%s", got, want)
+	}
+	if got, want := heights(t, filepath.Join(output, "b.gml")), []string{"1.000000", "11.000000"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("heights = %v, want %v", got, want)
+	}
+}
+
+// -elev-prop and -id-prop read the elevation and id from properties named
+// other than ELEV_mean and id
+func TestElevationAndIDProperties(t *testing.T) {
+	gmlDir := writeInput(t, map[string]string{"b.gml": buildingGML})
+	footprints := `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{"gml_id":"b","base_height":12.5},` +
+		`"geometry":{"type":"Polygon","coordinates":[[[99,199],[111,199],[111,211],[99,211],[99,199]]]}}]}`
+	geojson := filepath.Join(writeInput(t, map[string]string{"f.geojson": footprints}), "f.geojson")
+
+	output := t.TempDir()
+	status, log := runMain(t, "-gml", gmlDir, "-geojson", geojson, "-output", output, "-elev-prop", "base_height", "-id-prop", "gml_id")
+	if status != 0 || !strings.Contains(log, "Successfully adjusted 1 GML files") {
+		t.Fatalf("elevate = %d:\n%s", status, log)
+	}
+	if got, want := heights(t, filepath.Join(output, "b.gml")), []string{"12.500000", "22.500000"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("heights = %v, want %v", got, want)
+	}
+
+	// The default property names find nothing in this file
+	status, log = runMain(t, "-gml", gmlDir, "-geojson", geojson, "-output", t.TempDir())
+	if status != 0 || !strings.Contains(log, "No elevation data found for ID b") {
+		t.Errorf("elevate with the default properties = %d:\n%s", status, log)
+	}
+}
+
+// A negative elevation lowers the building, and -clamp-min keeps its base
+// from going below the floor
+func TestNegativeElevationWithClamp(t *testing.T) {
+	gmlDir := writeInput(t, map[string]string{"b.gml": buildingGML})
+	geojson := filepath.Join(writeInput(t, map[string]string{"f.geojson": footprintGeoJSON("b", -8)}), "f.geojson")
+
+	for _, test := range []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"lowered", nil, []string{"-8.000000", "2.000000"}},
+		{"clamped", []string{"-clamp-min", "-5"}, []string{"-5.000000", "2.000000"}},
+		{"clamped at both ends", []string{"-clamp-min", "-5", "-clamp-max", "1"}, []string{"-5.000000", "1.000000"}},
+	} {
+		output := t.TempDir()
+		status, log := runMain(t, append([]string{"-gml", gmlDir, "-geojson", geojson, "-output", output}, test.args...)...)
+		if status != 0 || !strings.Contains(log, "Successfully adjusted 1 GML files") {
+			t.Fatalf("%s: elevate = %d:\n%s", test.name, status, log)
+		}
+		if got := heights(t, filepath.Join(output, "b.gml")); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: heights = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+// Package fileio writes output files atomically: the content goes to a
+// temporary file next to the target, which is synced and renamed over it, so
+// an interrupted run never leaves a partial file behind.
+package fileio
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFile writes data to path atomically
+func WriteFile(path string, data []byte) error {
+	return Replace(path, func(tmp *os.File) error {
+		_, err := tmp.Write(data)
+		return err
+	})
+}
+
+// Replace calls write with a temporary file next to path and, when it
+// succeeds, syncs the file and renames it over path. write may also reopen
+// the file by name, as a database does. The temporary file is removed on
+// failure.
+func Replace(path string, write func(tmp *os.File) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
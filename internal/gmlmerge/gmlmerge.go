@@ -0,0 +1,257 @@
+// Package gmlmerge holds what mergegml and mergegml2 share: the bounds of the
+// merged and chunk envelopes, the chunk file names, and the -snap and
+// -fix-winding ring repairs.
+package gmlmerge
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fakmalpradana/OBJ2GML/citygml"
+)
+
+// ChunkPath names the nth -chunk-size file: output_0001.gml for output.gml
+func ChunkPath(output string, n int) string {
+	ext := filepath.Ext(output)
+	return fmt.Sprintf("%s_%04d%s", strings.TrimSuffix(output, ext), n, ext)
+}
+
+// EmptyBounds returns minX minY minZ maxX maxY maxZ bounds that contain nothing
+func EmptyBounds() [6]float64 {
+	inf := math.Inf(1)
+	return [6]float64{inf, inf, inf, -inf, -inf, -inf}
+}
+
+// UnionBounds returns the bounds covering all the given bounds, and false
+// when every one of them is empty
+func UnionBounds(all [][6]float64) ([6]float64, bool) {
+	union := EmptyBounds()
+	for _, b := range all {
+		for i := 0; i < 3; i++ {
+			union[i] = math.Min(union[i], b[i])
+			union[i+3] = math.Max(union[i+3], b[i+3])
+		}
+	}
+	return union, union[0] <= union[3]
+}
+
+// BuildingBounds returns the bounds of all ring coordinates of a building,
+// and false when it has none
+func BuildingBounds(building *citygml.Building) ([6]float64, bool) {
+	bounds := EmptyBounds()
+	found := false
+	building.EachPolygon(func(surfaceType string, polygon *citygml.Polygon) {
+		points, err := citygml.ParsePosList(polygon.Exterior.LinearRing.Coordinates())
+		if err != nil {
+			return
+		}
+		for _, p := range points {
+			for i := 0; i < 3; i++ {
+				bounds[i] = math.Min(bounds[i], p[i])
+				bounds[i+3] = math.Max(bounds[i+3], p[i])
+			}
+			found = true
+		}
+	})
+	return bounds, found
+}
+
+// EnvelopeBounds returns the bounds of a file's envelope. A file without one
+// has empty bounds; corners that do not parse are an error.
+func EnvelopeBounds(boundedBy *citygml.InputBoundedBy) ([6]float64, error) {
+	if boundedBy == nil || boundedBy.Envelope == nil {
+		return EmptyBounds(), nil
+	}
+	lx, ly, lz, err := citygml.ParseCoordinates(boundedBy.Envelope.LowerCorner)
+	if err != nil {
+		return EmptyBounds(), fmt.Errorf("lowerCorner: %v", err)
+	}
+	ux, uy, uz, err := citygml.ParseCoordinates(boundedBy.Envelope.UpperCorner)
+	if err != nil {
+		return EmptyBounds(), fmt.Errorf("upperCorner: %v", err)
+	}
+	return [6]float64{lx, ly, lz, ux, uy, uz}, nil
+}
+
+// SetEnvelope writes bounds as the corners of an envelope
+func SetEnvelope(envelope *citygml.Envelope, bounds [6]float64) {
+	envelope.LowerCorner = fmt.Sprintf("%f %f %f", bounds[0], bounds[1], bounds[2])
+	envelope.UpperCorner = fmt.Sprintf("%f %f %f", bounds[3], bounds[4], bounds[5])
+}
+
+// FixBuildingWinding reverses the rings of the solid and the semantic surfaces
+// that face into the building, so every ring is counter-clockwise seen from
+// outside. It returns the number of rings reversed.
+func FixBuildingWinding(building *citygml.Building) int {
+	type ring struct {
+		linearRing  *citygml.LinearRing
+		surfaceType string
+	}
+	var rings []ring
+	building.EachPolygon(func(surfaceType string, polygon *citygml.Polygon) {
+		rings = append(rings, ring{&polygon.Exterior.LinearRing, surfaceType})
+	})
+
+	posLists := make([]string, len(rings))
+	for i, r := range rings {
+		posLists[i] = r.linearRing.PosList
+	}
+	center := ringsCenter(posLists)
+
+	fixed := 0
+	for _, r := range rings {
+		if outward, ok := ringOutward(r.linearRing.PosList, r.surfaceType, center); ok && !outward {
+			r.linearRing.PosList = reverseRing(r.linearRing.PosList)
+			fixed++
+		}
+	}
+	return fixed
+}
+
+// SnapBuilding snaps the solid and boundary surface rings of a building to a
+// grid of the given size and removes the rings that collapsed, returning how
+// many it removed
+func SnapBuilding(building *citygml.Building, size float64) int {
+	return building.DropPolygons(func(surfaceType string, polygon *citygml.Polygon) bool {
+		ring := &polygon.Exterior.LinearRing
+		var ok bool
+		ring.PosList, ok = SnapPosList(ring.PosList, size)
+		return !ok
+	})
+}
+
+// SnapPosList rounds every coordinate of a posList to the nearest multiple of
+// size, so vertices that neighboring buildings share become identical. Points
+// that snap onto the point before them are dropped. It returns false when the
+// ring collapsed: fewer than the 4 positions of a closed triangle are left, or
+// no area. Lists it cannot parse are returned unchanged.
+func SnapPosList(posList string, size float64) (string, bool) {
+	fields := strings.Fields(posList)
+	if len(fields)%3 != 0 {
+		return posList, true
+	}
+	decimals := 0
+	if s := strconv.FormatFloat(size, 'f', -1, 64); strings.Contains(s, ".") {
+		decimals = len(s) - strings.IndexByte(s, '.') - 1
+	}
+
+	snapped := make([]string, 0, len(fields))
+	for i := 0; i < len(fields); i += 3 {
+		point := make([]string, 3)
+		for j := 0; j < 3; j++ {
+			v, err := strconv.ParseFloat(fields[i+j], 64)
+			if err != nil {
+				return posList, true
+			}
+			point[j] = strconv.FormatFloat(math.Round(v/size)*size, 'f', decimals, 64)
+		}
+		if n := len(snapped); n >= 3 && snapped[n-3] == point[0] && snapped[n-2] == point[1] && snapped[n-1] == point[2] {
+			continue
+		}
+		snapped = append(snapped, point...)
+	}
+
+	// A ring on the grid that encloses anything has at least half a cell of area
+	snappedList := strings.Join(snapped, " ")
+	points, ok := ringPoints(snappedList)
+	if !ok || len(points) < 4 || ringArea(points) < size*size/4 {
+		return snappedList, false
+	}
+	return snappedList, true
+}
+
+// ringArea returns the area of a ring from the length of its Newell normal
+func ringArea(points [][3]float64) float64 {
+	var normal [3]float64
+	for i, a := range points {
+		b := points[(i+1)%len(points)]
+		normal[0] += (a[1] - b[1]) * (a[2] + b[2])
+		normal[1] += (a[2] - b[2]) * (a[0] + b[0])
+		normal[2] += (a[0] - b[0]) * (a[1] + b[1])
+	}
+	return math.Sqrt(normal[0]*normal[0]+normal[1]*normal[1]+normal[2]*normal[2]) / 2
+}
+
+// reverseRing reverses the order of the positions in a posList while keeping
+// each coordinate's original text. Lists that are not made of xyz triples are
+// returned unchanged.
+func reverseRing(posList string) string {
+	fields := strings.Fields(posList)
+	if len(fields) == 0 || len(fields)%3 != 0 {
+		return posList
+	}
+	reversed := make([]string, 0, len(fields))
+	for i := len(fields) - 3; i >= 0; i -= 3 {
+		reversed = append(reversed, fields[i], fields[i+1], fields[i+2])
+	}
+	return strings.Join(reversed, " ")
+}
+
+// ringPoints parses a posList of xyz triples
+func ringPoints(posList string) ([][3]float64, bool) {
+	points, err := citygml.ParsePosList(posList)
+	if err != nil || len(points) < 3 {
+		return nil, false
+	}
+	return points, true
+}
+
+// ringOutward reports whether a ring's Newell normal points away from center.
+// Roofs are expected to face up and grounds down, unless too steep to tell.
+// ok is false for rings that cannot be parsed or have no area.
+func ringOutward(posList, surfaceType string, center [3]float64) (outward, ok bool) {
+	points, ok := ringPoints(posList)
+	if !ok {
+		return false, false
+	}
+
+	var normal, centroid [3]float64
+	for i, a := range points {
+		b := points[(i+1)%len(points)]
+		normal[0] += (a[1] - b[1]) * (a[2] + b[2])
+		normal[1] += (a[2] - b[2]) * (a[0] + b[0])
+		normal[2] += (a[0] - b[0]) * (a[1] + b[1])
+		for j := 0; j < 3; j++ {
+			centroid[j] += a[j] / float64(len(points))
+		}
+	}
+	length := math.Sqrt(normal[0]*normal[0] + normal[1]*normal[1] + normal[2]*normal[2])
+	if length == 0 {
+		return false, false
+	}
+	nz := normal[2] / length
+
+	switch {
+	case surfaceType == "RoofSurface" && math.Abs(nz) > 0.1:
+		return nz > 0, true
+	case surfaceType == "GroundSurface" && math.Abs(nz) > 0.1:
+		return nz < 0, true
+	}
+	dot := 0.0
+	for j := 0; j < 3; j++ {
+		dot += normal[j] * (centroid[j] - center[j])
+	}
+	return dot >= 0, true
+}
+
+// ringsCenter returns the bounding box center of the positions in posLists
+func ringsCenter(posLists []string) [3]float64 {
+	lo := [3]float64{math.MaxFloat64, math.MaxFloat64, math.MaxFloat64}
+	hi := [3]float64{-math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64}
+	for _, posList := range posLists {
+		points, ok := ringPoints(posList)
+		if !ok {
+			continue
+		}
+		for _, p := range points {
+			for j := 0; j < 3; j++ {
+				lo[j] = math.Min(lo[j], p[j])
+				hi[j] = math.Max(hi[j], p[j])
+			}
+		}
+	}
+	return [3]float64{(lo[0] + hi[0]) / 2, (lo[1] + hi[1]) / 2, (lo[2] + hi[2]) / 2}
+}
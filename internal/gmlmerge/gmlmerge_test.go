@@ -0,0 +1,101 @@
+package gmlmerge
+
+import (
+	"testing"
+
+	"github.com/fakmalpradana/OBJ2GML/citygml"
+)
+
+func TestUnionBoundsSkipsEmpty(t *testing.T) {
+	if _, ok := UnionBounds([][6]float64{EmptyBounds(), EmptyBounds()}); ok {
+		t.Error("union of empty bounds reported as bounds")
+	}
+	bounds, ok := UnionBounds([][6]float64{EmptyBounds(), {1, 2, 3, 4, 5, 6}})
+	if !ok || bounds != [6]float64{1, 2, 3, 4, 5, 6} {
+		t.Errorf("union = %v, %v", bounds, ok)
+	}
+}
+
+// A building's bounds cover every ring, whether written as a posList or as
+// gml:pos elements
+func TestBuildingBounds(t *testing.T) {
+	var building citygml.Building
+	if _, ok := BuildingBounds(&building); ok {
+		t.Error("a building without rings has bounds")
+	}
+	building.Lod1Solid = &citygml.SolidProperty{}
+	building.Lod1Solid.Solid.Exterior.CompositeSurface.SurfaceMember = []citygml.SurfaceMember{
+		{Polygon: &citygml.Polygon{Exterior: citygml.PolygonExterior{LinearRing: citygml.LinearRing{PosList: "0 0 0 4 0 0 4 4 0 0 0 0"}}}},
+		{Polygon: &citygml.Polygon{Exterior: citygml.PolygonExterior{LinearRing: citygml.LinearRing{Pos: []string{"-1 2 3", "1 2 3", "1 5 3", "-1 2 3"}}}}},
+	}
+	bounds, ok := BuildingBounds(&building)
+	if !ok || bounds != [6]float64{-1, 0, 0, 4, 5, 3} {
+		t.Errorf("bounds = %v, %v; want [-1 0 0 4 5 3]", bounds, ok)
+	}
+}
+
+func TestSnapPosListRejectsCollapsedRings(t *testing.T) {
+	for _, test := range []struct {
+		posList string
+		size    float64
+		want    string
+		ok      bool
+	}{
+		{"0.1 0.1 0 4.9 0.1 0 4.9 4.9 0 0.1 4.9 0 0.1 0.1 0", 1, "0 0 0 5 0 0 5 5 0 0 5 0 0 0 0", true},
+		{"0.1 0.1 0 4.9 0.1 0 4.9 4.9 0 0.1 0.1 0", 1, "0 0 0 5 0 0 5 5 0 0 0 0", true},
+		// Every corner snaps onto the origin
+		{"0.1 0.1 0 0.3 0.1 0 0.3 0.3 0 0.1 0.3 0 0.1 0.1 0", 1, "0 0 0", false},
+		// A sliver snaps onto a line
+		{"0 0 0 3 0.2 0 3 0.4 0 0 0 0", 1, "0 0 0 3 0 0 0 0 0", false},
+		{"0 0 0 3 0 0 3 0.9 0 0 0 0", 1, "0 0 0 3 0 0 3 1 0 0 0 0", true},
+		// Neighbors whose shared wall is 0.001 apart meet on a 0.01 grid
+		{"5.001 0 0 5.001 4 0 5.001 4 3 5.001 0 0", 0.01, "5.00 0.00 0.00 5.00 4.00 0.00 5.00 4.00 3.00 5.00 0.00 0.00", true},
+		{"5.002 4 0 5.002 0 0 5.002 0 3 5.002 4 0", 0.01, "5.00 4.00 0.00 5.00 0.00 0.00 5.00 0.00 3.00 5.00 4.00 0.00", true},
+		{"not a posList", 1, "not a posList", true},
+	} {
+		got, ok := SnapPosList(test.posList, test.size)
+		if got != test.want || ok != test.ok {
+			t.Errorf("SnapPosList(%q, %g) = %q, %v; want %q, %v", test.posList, test.size, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+// polygon is a surface member holding one ring
+func polygon(posList string) citygml.SurfaceMember {
+	return citygml.SurfaceMember{Polygon: &citygml.Polygon{Exterior: citygml.PolygonExterior{LinearRing: citygml.LinearRing{PosList: posList}}}}
+}
+
+// A unit cube whose top faces into it and whose roof surface faces down gets
+// both rings reversed; the outward walls and bottom are left alone
+func TestFixBuildingWinding(t *testing.T) {
+	const top = "0 0 1 0 1 1 1 1 1 1 0 1 0 0 1"
+	const roof = "0 0 1 0 1 1 1 1 1 0 0 1"
+	var building citygml.Building
+	building.Lod1Solid = &citygml.SolidProperty{}
+	building.Lod1Solid.Solid.Exterior.CompositeSurface.SurfaceMember = []citygml.SurfaceMember{
+		polygon("0 0 0 0 1 0 1 1 0 1 0 0 0 0 0"),
+		polygon(top),
+		polygon("0 0 0 0 0 1 0 1 1 0 1 0 0 0 0"),
+		polygon("1 0 0 1 1 0 1 1 1 1 0 1 1 0 0"),
+		polygon("0 0 0 1 0 0 1 0 1 0 0 1 0 0 0"),
+		polygon("0 1 0 0 1 1 1 1 1 1 1 0 0 1 0"),
+	}
+	building.BoundedBy = []citygml.BoundarySurface{{RoofSurface: &citygml.SemanticSurface{
+		Lod2MultiSurface: &citygml.MultiSurfaceProperty{MultiSurface: citygml.MultiSurface{SurfaceMember: []citygml.SurfaceMember{polygon(roof)}}},
+	}}}
+
+	if fixed := FixBuildingWinding(&building); fixed != 2 {
+		t.Errorf("fixed %d rings, want 2", fixed)
+	}
+	members := building.Lod1Solid.Solid.Exterior.CompositeSurface.SurfaceMember
+	if got := members[1].Polygon.Exterior.LinearRing.PosList; got != "0 0 1 1 0 1 1 1 1 0 1 1 0 0 1" {
+		t.Errorf("top = %q, want it reversed", got)
+	}
+	if got := members[0].Polygon.Exterior.LinearRing.PosList; got != "0 0 0 0 1 0 1 1 0 1 0 0 0 0 0" {
+		t.Errorf("bottom = %q, want it unchanged", got)
+	}
+	roofRing := building.BoundedBy[0].RoofSurface.Lod2MultiSurface.MultiSurface.SurfaceMember[0].Polygon.Exterior.LinearRing
+	if roofRing.PosList != "0 0 1 1 1 1 0 1 1 0 0 1" {
+		t.Errorf("roof = %q, want it reversed to face up", roofRing.PosList)
+	}
+}
@@ -0,0 +1,2008 @@
+package lod2
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fakmalpradana/OBJ2GML/citygml"
+	"github.com/fakmalpradana/OBJ2GML/internal/fileio"
+	"github.com/fakmalpradana/OBJ2GML/internal/objconv"
+)
+
+// XML namespaces and schema declarations
+const (
+	xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<!-- OBJ to CityGML LOD2 Converter Output -->
+<!-- copyrights 2025 © Fairuz Akmal Pradana | fakmalpradana@gmail.com  -->
+`
+)
+
+// BuildingMetrics is one -metrics JSON line describing a converted building
+type BuildingMetrics struct {
+	ID             string     `json:"id"`
+	Height         float64    `json:"height"`
+	FootprintArea  float64    `json:"footprint_area"`
+	RoofSurfaces   int        `json:"roof_surfaces"`
+	WallSurfaces   int        `json:"wall_surfaces"`
+	GroundSurfaces int        `json:"ground_surfaces"`
+	Volume         float64    `json:"volume"`
+	BBox           [6]float64 `json:"bbox"` // minX, minY, minZ, maxX, maxY, maxZ
+}
+
+// convertedBuilding is a Building along with what createBuilding measured
+// while making it, which is not part of the CityGML
+type convertedBuilding struct {
+	citygml.Building
+
+	metrics BuildingMetrics // written to -metrics
+	slivers int             // polygons dropped by -min-surface-area
+}
+
+type OBJFace struct {
+	VertexIndices  []int
+	Material       string
+	SmoothingGroup int // from the last s line; 0 when off or unset
+}
+
+// Ring returns the corners of the face, which are already 0-based
+func (f OBJFace) Ring() []int { return f.VertexIndices }
+
+// WithRing returns the face, with its material and smoothing group, with ring
+// as its corners
+func (f OBJFace) WithRing(ring []int) OBJFace {
+	f.VertexIndices = ring
+	return f
+}
+
+// MTL material structure
+type MTLMaterial struct {
+	Name string
+	Kd   [3]float64 // Diffuse color
+}
+
+// ConvertOptions holds the per-run settings shared by every converted file
+type ConvertOptions struct {
+	EPSGCode      string
+	SRSForm       string // url or urn srsName
+	EPSGAttribute bool   // add an EPSG string attribute to each building
+	Namespaces    citygml.Namespaces
+
+	SplitComponents bool
+	Simplify        float64                   // coplanar merge tolerance in degrees, 0 disables
+	MergeCoplanar   bool                      // merge adjacent coplanar faces inside each wall/roof group
+	ZNudge          string                    // handling of coincident faces: dedup, nudge or empty for none
+	Parts           string                    // roof moves the roof surfaces into a BuildingPart
+	Lod2Geometry    string                    // semantic boundary surfaces, or one building-level multisurface
+	EmitBounds      bool                      // add bounding sphere center/radius as measure attributes
+	EmitVolume      bool                      // add the enclosed volume as a measure attribute
+	EmitSurfaceArea bool                      // add each boundary surface's area as a measure attribute
+	EmitRoofSolar   bool                      // add each roof surface's slope and aspect as measure attributes
+	MinSurfaceArea  float64                   // drop polygons with a smaller 3D area, 0 disables
+	ColorByMaterial bool                      // emit an X3DMaterial per OBJ material from its Kd
+	DefaultColors   bool                      // color meshes without materials by surface type
+	MTLDir          string                    // extra directory searched for mtllib files
+	Metrics         io.Writer                 // receives one JSON line per building, nil disables
+	CCW             bool                      // wind rings counter-clockwise as seen from outside
+	Strict          bool                      // reject files with NaN/Inf coordinates or bad face indices instead of skipping them
+	MaxFaces        int                       // stop parsing once the face count exceeds this, 0 disables
+	MaxVertices     int                       // stop parsing once the vertex count exceeds this, 0 disables
+	MaxRingVertices int                       // split faces with more corners than this, 0 disables
+	ClipMode        string                    // clip or drop faces crossing ClipBelow; empty keeps all geometry
+	ClipBelow       float64                   // datum Z below which geometry is removed when ClipMode is set
+	Units           string                    // uom written for lengths; m or ft
+	ConvertUnits    bool                      // scale meter heights into Units
+	PreserveCoords  bool                      // write the OBJ coordinate strings unchanged
+	Canonical       bool                      // sort faces by centroid before converting
+	Meta            []citygml.StringAttribute // -meta key=value pairs added to every building
+	Envelope        []float64                 // fixed minx miny minz maxx maxy maxz for gml:Envelope, nil uses the geometry
+	Encoding        string                    // character encoding of the OBJ and MTL files, empty for UTF-8
+
+	FlattenNamespaces bool
+}
+
+// formatCorner writes an envelope corner exactly as given, without rounding
+func formatCorner(values []float64) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strings.Join(parts, " ")
+}
+
+// checkpoint records the inputs converted so far, one per line, so an
+// interrupted batch run can resume where it stopped
+type checkpoint struct {
+	file *os.File
+	done map[string]bool
+}
+
+// openCheckpoint reads the inputs a previous run completed, if any, and opens
+// the file for appending
+func openCheckpoint(path string) (*checkpoint, error) {
+	done := make(map[string]bool)
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			done[line] = true
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &checkpoint{file: file, done: done}, nil
+}
+
+// record marks an input as completed and syncs, so the entry survives a crash
+// right after it
+func (c *checkpoint) record(name string) error {
+	if _, err := fmt.Fprintln(c.file, name); err != nil {
+		return err
+	}
+	c.done[name] = true
+	return c.file.Sync()
+}
+
+// xmlID turns s into a valid gml:id (an XML NCName) by replacing other
+// characters with '_'
+func xmlID(s string) string {
+	id := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, objconv.XMLText(s))
+	if first, _ := utf8.DecodeRuneInString(id); !unicode.IsLetter(first) && first != '_' {
+		id = "_" + id
+	}
+	return id
+}
+
+// Main runs obj2lod2gml with args, the command line after the program name, and
+// returns its exit status: 2 for missing or invalid arguments, 1 when the
+// output cannot be set up or, with -fail-on-error or -fail-fast, a file
+// fails, and 0 otherwise
+func Main(name string, args []string) int {
+	// Parse command-line arguments
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	inputDir := flags.String("input", "", "Directory containing OBJ files")
+	tarArchive := flags.String("tar", "", "Tar archive whose .obj entries are converted instead of -input, without extracting it")
+	outputDir := flags.String("output", "", "Directory for output CityGML files")
+	epsgCode := flags.String("epsg", "32748", "EPSG code for the coordinate reference system")
+	cityGMLVersion := flags.String("citygml-version", "2.0", "CityGML version for namespaces and schemaLocation: 1.0 or 2.0")
+	ccw := flags.Bool("ccw", true, "Orient every ring counter-clockwise as seen from outside the building")
+	minSurfaceArea := flags.Float64("min-surface-area", 0, "Drop polygons whose 3D area is below this value (0 keeps all)")
+	mtlDir := flags.String("mtl-dir", "", "Extra directory to search for MTL files not found next to the OBJ")
+	metricsPath := flags.String("metrics", "", "Write per-building metrics as JSON lines to this file")
+	defaultColors := flags.Bool("default-colors", false, "Color roofs red, walls grey and ground brown with X3DMaterials for meshes without materials")
+	colorByMaterial := flags.Bool("color-by-material", false, "Emit an app:Appearance with an X3DMaterial per OBJ material, colored by its Kd")
+	emitVolume := flags.Bool("emit-volume", false, "Add each building's enclosed volume as a gen:measureAttribute")
+	emitSurfaceArea := flags.Bool("emit-surface-area", false, "Add each roof, wall and ground surface's area as a gen:measureAttribute")
+	emitRoofSolar := flags.Bool("emit-roof-solar", false, "Add each roof surface's Slope (degrees from horizontal) and Aspect (azimuth of the down-slope direction) as gen:measureAttributes")
+	emitBounds := flags.Bool("emit-bounds", false, "Add each building's bounding sphere center and radius as gen:measureAttributes")
+	lod2Geometry := flags.String("lod2-geometry", "semantic", "LOD2 geometry: semantic roof/wall/ground surfaces, or multisurface for one unclassified bldg:lod2MultiSurface")
+	parts := flags.String("parts", "", "Split classified surfaces into bldg:BuildingParts; roof moves the roof surfaces into their own part")
+	zNudge := flags.String("z-nudge", "", "Handle coincident duplicate faces that Z-fight: dedup drops them, nudge lifts them slightly")
+	mergeCoplanar := flags.Bool("merge-coplanar", false, "Merge adjacent coplanar faces within each wall and roof surface into single polygons")
+	simplify := flags.Float64("simplify", 0, "Merge adjacent coplanar faces whose normals differ by at most this many degrees (0 disables)")
+	maxFaces := flags.Int("max-faces", 0, "Reject OBJ files with more than this many faces (0 disables)")
+	units := flags.String("units", "m", "Length unit for uom attributes: m or ft")
+	convertUnits := flags.Bool("convert-units", false, "Scale heights, assumed in meters, into -units; X and Y stay in the units of the CRS")
+	preserveCoords := flags.Bool("preserve-coords", false, "Write coordinates exactly as given in the OBJ instead of reformatting them")
+	canonical := flags.Bool("canonical", false, "Sort faces by centroid so the output does not depend on the face order of the OBJ")
+	maxVertices := flags.Int("max-vertices", 0, "Reject OBJ files with more than this many vertices (0 disables)")
+	clipBelowFlag := flags.String("clip-below", "", "Remove geometry below this Z datum, e.g. basements; see -clip-mode for faces crossing it")
+	clipMode := flags.String("clip-mode", "clip", "How -clip-below treats faces crossing the datum: clip cuts them at it, drop removes them")
+	maxRingVertices := flags.Int("max-ring-vertices", 0, "Split polygons with more corners than this into smaller polygons or triangles (0 disables, at least 3)")
+	strict := flags.Bool("strict", false, "Fail on NaN/Inf vertex coordinates or malformed face vertices instead of skipping the affected faces")
+	encoding := flags.String("encoding", "utf-8", "Character encoding of the OBJ and MTL files: utf-8, windows-1252 or iso-8859-1")
+	envelope := flags.String("envelope", "", "Fixed envelope \"minx miny minz maxx maxy maxz\" written instead of the geometry bounds, e.g. a tile extent")
+	splitComponents := flags.Bool("split-components", false, "Emit a separate building for each disconnected part of a mesh")
+	srsForm := flags.String("srs-form", "url", "srsName style: url (http://www.opengis.net/def/crs/EPSG/0/<code>) or urn (urn:ogc:def:crs:EPSG::<code>)")
+	epsgAttribute := flags.Bool("epsg-attribute", false, "Add the EPSG code as an \"EPSG\" gen:stringAttribute on each building")
+	epsgMap := flags.String("epsg-map", "", "CSV of filename-or-prefix,epsg rows giving per-file EPSG codes; unmapped files use -epsg")
+	onlyIDs := flags.String("only-ids", "", "Convert only these building ids (file names without .obj): id1,id2,... or @file with one id per line")
+	failOnError := flags.Bool("fail-on-error", false, "Exit with status 1 when any file fails")
+	failFast := flags.Bool("fail-fast", false, "Stop at the first failed file and exit with status 1")
+	checkpointPath := flags.String("checkpoint", "", "File recording converted inputs; rerunning with it skips inputs already converted")
+	var meta objconv.MetaFlag
+	flags.Var(&meta, "meta", "Add a key=value gen:stringAttribute to every building (repeatable)")
+	flattenNS := flags.Bool("flatten-namespaces", false, "Emit unprefixed elements with default namespaces for legacy consumers")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if (*inputDir == "" && *tarArchive == "") || *outputDir == "" {
+		fmt.Println("Usage: obj2citygml -input <input_directory> | -tar <archive.tar> -output <output_directory> [-epsg <epsg_code>]")
+		return 2
+	}
+
+	// Create output directory if it doesn't exist
+	if err := os.MkdirAll(*outputDir, 0755); err != nil {
+		fmt.Printf("Error creating output directory: %v\n", err)
+		return 1
+	}
+
+	if *srsForm != "url" && *srsForm != "urn" {
+		fmt.Printf("Error: unsupported -srs-form %q (use url or urn)\n", *srsForm)
+		return 2
+	}
+
+	if *maxRingVertices != 0 && *maxRingVertices < 3 {
+		fmt.Println("Error: -max-ring-vertices must be 0 or at least 3")
+		return 2
+	}
+
+	if *clipMode != "clip" && *clipMode != "drop" {
+		fmt.Printf("Error: unsupported -clip-mode %q (use clip or drop)\n", *clipMode)
+		return 2
+	}
+	var clipDatum float64
+	if *clipBelowFlag != "" {
+		datum, err := strconv.ParseFloat(*clipBelowFlag, 64)
+		if err != nil || math.IsNaN(datum) || math.IsInf(datum, 0) {
+			fmt.Printf("Error: invalid -clip-below %q\n", *clipBelowFlag)
+			return 2
+		}
+		clipDatum = datum
+	} else {
+		*clipMode = ""
+	}
+
+	namespaces, err := citygml.NamespacesFor(*cityGMLVersion)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 2
+	}
+
+	if *lod2Geometry != "semantic" && *lod2Geometry != "multisurface" {
+		fmt.Printf("Error: unsupported -lod2-geometry %q (use semantic or multisurface)\n", *lod2Geometry)
+		return 2
+	}
+	if *lod2Geometry == "multisurface" && *parts != "" {
+		fmt.Println("Error: -parts needs the semantic surfaces of -lod2-geometry semantic")
+		return 2
+	}
+	if *parts != "" && *parts != "roof" {
+		fmt.Printf("Error: unsupported -parts %q (use roof)\n", *parts)
+		return 2
+	}
+
+	if *zNudge != "" && *zNudge != "dedup" && *zNudge != "nudge" {
+		fmt.Printf("Error: unsupported -z-nudge %q (use dedup or nudge)\n", *zNudge)
+		return 2
+	}
+
+	if _, ok := objconv.UnitScales[*units]; !ok {
+		fmt.Printf("Error: unsupported -units %q (use m or ft)\n", *units)
+		return 2
+	}
+
+	if _, err := decodeReader(nil, *encoding); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 2
+	}
+
+	var envelopeBounds []float64
+	if *envelope != "" {
+		if envelopeBounds, err = objconv.ParseEnvelope(*envelope); err != nil {
+			fmt.Printf("Error: invalid -envelope: %v\n", err)
+			return 2
+		}
+	}
+
+	opts := ConvertOptions{
+		EPSGCode:      *epsgCode,
+		SRSForm:       *srsForm,
+		EPSGAttribute: *epsgAttribute,
+		Namespaces:    namespaces,
+
+		SplitComponents: *splitComponents,
+		Simplify:        *simplify,
+		MergeCoplanar:   *mergeCoplanar,
+		ZNudge:          *zNudge,
+		Parts:           *parts,
+		Lod2Geometry:    *lod2Geometry,
+		EmitBounds:      *emitBounds,
+		EmitVolume:      *emitVolume,
+		EmitSurfaceArea: *emitSurfaceArea,
+		EmitRoofSolar:   *emitRoofSolar,
+		ColorByMaterial: *colorByMaterial,
+		DefaultColors:   *defaultColors,
+		MTLDir:          *mtlDir,
+		MinSurfaceArea:  *minSurfaceArea,
+		CCW:             *ccw,
+		Strict:          *strict,
+		MaxFaces:        *maxFaces,
+		MaxVertices:     *maxVertices,
+		MaxRingVertices: *maxRingVertices,
+		ClipMode:        *clipMode,
+		ClipBelow:       clipDatum,
+		Units:           *units,
+		ConvertUnits:    *convertUnits,
+		PreserveCoords:  *preserveCoords,
+		Canonical:       *canonical,
+		Envelope:        envelopeBounds,
+		Encoding:        *encoding,
+
+		Meta:              meta,
+		FlattenNamespaces: *flattenNS,
+	}
+
+	if *metricsPath != "" {
+		// Metrics are collected in memory and written once at the end, like
+		// every other output
+		var metrics bytes.Buffer
+		opts.Metrics = &metrics
+		defer func() {
+			if err := fileio.WriteFile(*metricsPath, metrics.Bytes()); err != nil {
+				fmt.Printf("Error writing metrics file: %v\n", err)
+			}
+		}()
+	}
+
+	var ids map[string]bool
+	if *onlyIDs != "" {
+		ids, err = objconv.ParseIDList(*onlyIDs)
+		if err != nil {
+			fmt.Printf("Error: invalid -only-ids: %v\n", err)
+			return 2
+		}
+	}
+
+	var epsgMappings []objconv.EPSGMapping
+	if *epsgMap != "" {
+		epsgMappings, err = objconv.ReadEPSGMap(*epsgMap)
+		if err != nil {
+			fmt.Printf("Error: invalid -epsg-map: %v\n", err)
+			return 2
+		}
+	}
+
+	var cp *checkpoint
+	if *checkpointPath != "" {
+		cp, err = openCheckpoint(*checkpointPath)
+		if err != nil {
+			fmt.Printf("Error opening checkpoint: %v\n", err)
+			return 1
+		}
+		defer cp.file.Close()
+		fmt.Printf("Checkpoint %s lists %d converted inputs\n", *checkpointPath, len(cp.done))
+	}
+
+	processed, resumed := 0, 0
+	successCount := 0
+	errorFiles := []string{}
+
+	// convertOne converts one OBJ source with run and reports whether to go on
+	convertOne := func(name string, run func(outputFile, buildingID string, fileOpts ConvertOptions) error) bool {
+		baseFileName := filepath.Base(name)
+		if cp != nil && cp.done[baseFileName] {
+			resumed++
+			return true
+		}
+		processed++
+		fileNameWithoutExt := strings.TrimSuffix(baseFileName, filepath.Ext(baseFileName))
+		outputFile := filepath.Join(*outputDir, fileNameWithoutExt+".gml")
+
+		fileOpts := opts
+		fileOpts.EPSGCode = objconv.EPSGForFile(epsgMappings, baseFileName, opts.EPSGCode)
+
+		if err := run(outputFile, fileNameWithoutExt, fileOpts); err != nil {
+			fmt.Printf("Error processing %s: %v\n", baseFileName, err)
+			errorFiles = append(errorFiles, baseFileName)
+			if *failFast {
+				fmt.Println("Stopping at the first failure (-fail-fast)")
+				return false
+			}
+		} else {
+			successCount++
+			if cp != nil {
+				if err := cp.record(baseFileName); err != nil {
+					fmt.Printf("Error updating checkpoint: %v\n", err)
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	if *tarArchive != "" {
+		// Stream the .obj entries of the archive without extracting them;
+		// mtllib paths resolve next to the archive and in -mtl-dir
+		found, skipped := 0, 0
+		entries := make(map[string]string)
+		err = objconv.EachTarOBJ(*tarArchive, func(name string, r io.Reader) bool {
+			found++
+			if ids != nil && !ids[strings.TrimSuffix(path.Base(name), path.Ext(name))] {
+				skipped++
+				return true
+			}
+			// Outputs and checkpoints are keyed by the base name, so an entry
+			// of the same name in another directory would overwrite the first
+			if first, duplicate := entries[path.Base(name)]; duplicate {
+				fmt.Printf("Error processing %s: %s already has the name %s, rename one of them\n", name, first, path.Base(name))
+				errorFiles = append(errorFiles, name)
+				if *failFast {
+					fmt.Println("Stopping at the first failure (-fail-fast)")
+					return false
+				}
+				return true
+			}
+			entries[path.Base(name)] = name
+			objFile := filepath.Join(filepath.Dir(*tarArchive), filepath.FromSlash(name))
+			return convertOne(name, func(outputFile, buildingID string, fileOpts ConvertOptions) error {
+				return convertOBJReader(r, objFile, outputFile, buildingID, fileOpts)
+			})
+		})
+		if err != nil {
+			fmt.Printf("Error reading tar archive %s: %v\n", *tarArchive, err)
+			errorFiles = append(errorFiles, filepath.Base(*tarArchive))
+		}
+		fmt.Printf("Found %d OBJ entries in %s\n", found, filepath.Base(*tarArchive))
+		if ids != nil {
+			fmt.Printf("Skipped %d of %d OBJ entries not listed in -only-ids\n", skipped, found)
+		}
+	} else {
+		// Find all OBJ files in the input directory
+		objFiles, err := filepath.Glob(filepath.Join(*inputDir, "*.obj"))
+		if err != nil {
+			fmt.Printf("Error finding OBJ files: %v\n", err)
+			return 0
+		}
+
+		if ids != nil {
+			found := len(objFiles)
+			objFiles = objconv.FilterByID(objFiles, ids)
+			fmt.Printf("Skipped %d of %d OBJ files not listed in -only-ids\n", found-len(objFiles), found)
+		}
+
+		fmt.Printf("Found %d OBJ files to process\n", len(objFiles))
+
+		// Process each OBJ file
+		for _, objFile := range objFiles {
+			objFile := objFile
+			if !convertOne(objFile, func(outputFile, buildingID string, fileOpts ConvertOptions) error {
+				return convertOBJToCityGML(objFile, outputFile, buildingID, fileOpts)
+			}) {
+				break
+			}
+		}
+	}
+
+	// Print summary
+	if resumed > 0 {
+		fmt.Printf("Skipped %d OBJ files already converted according to the checkpoint\n", resumed)
+	}
+	fmt.Printf("Successfully converted %d from %d OBJ files\n", successCount, processed)
+	if len(errorFiles) > 0 {
+		fmt.Printf("Failed to convert %d files: %v\n", len(errorFiles), errorFiles)
+		if *failOnError || *failFast {
+			return 1
+		}
+	}
+	return 0
+}
+
+// insideDir reports whether path stays within dir once both are cleaned
+func insideDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// resolveMTLPath finds the file named by mtllib. It tries the path as given
+// relative to the OBJ directory, its base name in the OBJ directory, then the
+// same two under mtlDir when set. Absolute paths are tried as they are first.
+func resolveMTLPath(objFile, mtlLib, mtlDir string) (string, bool) {
+	// mtllib paths written on Windows use backslashes
+	mtlLib = strings.ReplaceAll(mtlLib, "\\", "/")
+	objDir := filepath.Dir(objFile)
+	base := filepath.Base(mtlLib)
+
+	candidates := []string{}
+	if filepath.IsAbs(mtlLib) {
+		candidates = append(candidates, mtlLib)
+	}
+	candidates = append(candidates, filepath.Join(objDir, mtlLib), filepath.Join(objDir, base))
+	if mtlDir != "" {
+		candidates = append(candidates, filepath.Join(mtlDir, mtlLib), filepath.Join(mtlDir, base))
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// zNudgeEpsilon is how far -z-nudge nudge lifts each repeated coincident face
+const zNudgeEpsilon = 0.001
+
+// resolveCoincidentFaces finds faces covering exactly the same positions as an
+// earlier face, whatever their vertex order or indices. In dedup mode they are
+// dropped; in nudge mode each repeat is moved up by zNudgeEpsilon onto its own
+// copies of the vertices, so the first face keeps its place.
+func resolveCoincidentFaces(vertices []objconv.Vertex, faces []OBJFace, mode string) ([]objconv.Vertex, []OBJFace, int) {
+	seen := make(map[string]int)
+	result := make([]OBJFace, 0, len(faces))
+	count := 0
+	for _, face := range faces {
+		positions := make([]string, 0, len(face.VertexIndices))
+		valid := true
+		for _, idx := range face.VertexIndices {
+			if idx < 0 || idx >= len(vertices) {
+				valid = false
+				break
+			}
+			v := vertices[idx]
+			positions = append(positions, fmt.Sprintf("%.6f %.6f %.6f", v.X, v.Y, v.Z))
+		}
+		if !valid {
+			result = append(result, face)
+			continue
+		}
+		sort.Strings(positions)
+		key := strings.Join(positions, ",")
+
+		repeats := seen[key]
+		seen[key]++
+		if repeats == 0 {
+			result = append(result, face)
+			continue
+		}
+
+		count++
+		if mode == "dedup" {
+			continue
+		}
+		nudged := face
+		nudged.VertexIndices = make([]int, len(face.VertexIndices))
+		for i, idx := range face.VertexIndices {
+			v := vertices[idx]
+			v.Z += zNudgeEpsilon * float64(repeats)
+			v.Raw = [3]string{}
+			vertices = append(vertices, v)
+			nudged.VertexIndices[i] = len(vertices) - 1
+		}
+		result = append(result, nudged)
+	}
+	return vertices, result, count
+}
+
+// windows1252High maps bytes 0x80-0x9F of Windows-1252 to Unicode; the five
+// undefined bytes keep their ISO-8859-1 control code points
+var windows1252High = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+// singleByteReader transcodes a single-byte encoding to UTF-8
+type singleByteReader struct {
+	r       *bufio.Reader
+	cp1252  bool
+	pending []byte
+}
+
+func (d *singleByteReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(d.pending) > 0 {
+			copied := copy(p[n:], d.pending)
+			d.pending = d.pending[copied:]
+			n += copied
+			continue
+		}
+		b, err := d.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		if b < utf8.RuneSelf {
+			p[n] = b
+			n++
+			continue
+		}
+		r := rune(b)
+		if d.cp1252 && b < 0xA0 {
+			r = windows1252High[b-0x80]
+		}
+		d.pending = utf8.AppendRune(d.pending[:0], r)
+	}
+	return n, nil
+}
+
+// decodeReader wraps r so it yields UTF-8 for the given -encoding
+func decodeReader(r io.Reader, encoding string) (io.Reader, error) {
+	switch strings.ToLower(encoding) {
+	case "", "utf-8", "utf8":
+		return r, nil
+	case "windows-1252", "cp1252":
+		return &singleByteReader{r: bufio.NewReader(r), cp1252: true}, nil
+	case "iso-8859-1", "latin1":
+		return &singleByteReader{r: bufio.NewReader(r)}, nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %q (use utf-8, windows-1252 or iso-8859-1)", encoding)
+}
+
+// Parse MTL file to extract materials
+func parseMTLFile(filePath, encoding string) (map[string]MTLMaterial, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := decodeReader(file, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	materials := make(map[string]MTLMaterial)
+	var currentMaterial string
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "newmtl":
+			if len(fields) > 1 {
+				currentMaterial = fields[1]
+				materials[currentMaterial] = MTLMaterial{Name: currentMaterial}
+			}
+		case "Kd":
+			if len(fields) > 3 && currentMaterial != "" {
+				r, _ := strconv.ParseFloat(fields[1], 64)
+				g, _ := strconv.ParseFloat(fields[2], 64)
+				b, _ := strconv.ParseFloat(fields[3], 64)
+				mat := materials[currentMaterial]
+				mat.Kd = [3]float64{r, g, b}
+				materials[currentMaterial] = mat
+			}
+		}
+	}
+
+	return materials, scanner.Err()
+}
+
+// Enhanced OBJ file parser that captures material assignments
+func parseOBJ(r io.Reader, name string, opts ConvertOptions) ([]objconv.Vertex, []OBJFace, string, error) {
+	reader, err := decodeReader(r, opts.Encoding)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	var vertices []objconv.Vertex
+	var faces []OBJFace
+	var mtlLib string
+	currentMaterial := ""
+	materialLine, materialFaces := 0, 0 // line of the last usemtl and faces since it
+	currentSmoothingGroup := 0
+	skipped := make(map[string]int)
+	badVertices := make(map[int]bool)
+	lineNum := 0
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		fields := strings.Fields(line)
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "v":
+			if len(fields) < 3 {
+				return nil, nil, "", fmt.Errorf("line %d: vertex %q needs at least x and y", lineNum, line)
+			}
+			// "v x y" is a 2D vertex; it gets z=0 instead of being dropped,
+			// which would shift every later face index
+			zField := "0"
+			if len(fields) > 3 {
+				zField = fields[3]
+			}
+			var coords [3]float64
+			for i, field := range []string{fields[1], fields[2], zField} {
+				value, err := strconv.ParseFloat(field, 64)
+				// An overflowing coordinate parses as ±Inf, which the
+				// non-finite check below handles
+				if err != nil && !errors.Is(err, strconv.ErrRange) {
+					return nil, nil, "", fmt.Errorf("line %d: invalid vertex coordinate %q", lineNum, field)
+				}
+				coords[i] = value
+			}
+			vertex := objconv.Vertex{X: coords[0], Y: coords[1], Z: coords[2]}
+			if opts.PreserveCoords {
+				vertex.Raw = [3]string{fields[1], fields[2], zField}
+			}
+			if !objconv.IsFiniteVertex(vertex) {
+				if opts.Strict {
+					return nil, nil, "", fmt.Errorf("line %d: non-finite vertex coordinate %q", lineNum, line)
+				}
+				// Keep the slot so later face indices still line up
+				badVertices[len(vertices)] = true
+			}
+			vertices = append(vertices, vertex)
+			if opts.MaxVertices > 0 && len(vertices) > opts.MaxVertices {
+				return nil, nil, "", fmt.Errorf("line %d: more than %d vertices (-max-vertices)", lineNum, opts.MaxVertices)
+			}
+		case "mtllib":
+			if len(fields) > 1 {
+				mtlLib = fields[1]
+			}
+		case "usemtl":
+			if len(fields) > 1 {
+				// Materials switch per face, even within one object
+				if materialLine > 0 && materialFaces == 0 {
+					fmt.Printf("Warning: %s line %d: usemtl %s is not used by any face\n", filepath.Base(name), materialLine, currentMaterial)
+				}
+				currentMaterial = fields[1]
+				materialLine, materialFaces = lineNum, 0
+			}
+		case "s":
+			// "s off" and "s 0" both turn smoothing off
+			currentSmoothingGroup = 0
+			if len(fields) > 1 && fields[1] != "off" {
+				group, err := strconv.Atoi(fields[1])
+				if err != nil || group < 0 {
+					fmt.Printf("Warning: %s line %d: invalid smoothing group %q, treating as off\n", filepath.Base(name), lineNum, fields[1])
+					group = 0
+				}
+				currentSmoothingGroup = group
+			}
+		case "f":
+			if len(fields) >= 4 {
+				var indices []int
+				malformed, problem := "", ""
+				for _, f := range fields[1:] {
+					parts := strings.Split(f, "/")
+					index, err := strconv.Atoi(parts[0])
+					if err == nil && index < 0 {
+						index += len(vertices) + 1 // negative indices count back from the last vertex
+					}
+					if err != nil || index < 1 {
+						malformed, problem = f, "malformed"
+						break
+					}
+					// A face may only use vertices defined above it
+					if index > len(vertices) {
+						malformed, problem = f, fmt.Sprintf("out-of-range (%d vertices defined)", len(vertices))
+						break
+					}
+					indices = append(indices, index-1) // OBJ indices are 1-based
+				}
+				if malformed != "" {
+					if opts.Strict {
+						return nil, nil, "", fmt.Errorf("line %d: %s face vertex %q", lineNum, problem, malformed)
+					}
+					fmt.Printf("Warning: %s line %d: %s face vertex %q, skipping face\n", filepath.Base(name), lineNum, problem, malformed)
+					continue
+				}
+				faces = append(faces, OBJFace{indices, currentMaterial, currentSmoothingGroup})
+				materialFaces++
+				if opts.MaxFaces > 0 && len(faces) > opts.MaxFaces {
+					return nil, nil, "", fmt.Errorf("line %d: more than %d faces (-max-faces)", lineNum, opts.MaxFaces)
+				}
+			}
+		case "l", "p", "curv", "surf":
+			skipped[fields[0]]++
+		}
+	}
+
+	if materialLine > 0 && materialFaces == 0 {
+		fmt.Printf("Warning: %s line %d: usemtl %s is not used by any face\n", filepath.Base(name), materialLine, currentMaterial)
+	}
+	warnNonPolygonal(filepath.Base(name), skipped)
+
+	if len(badVertices) > 0 {
+		before := len(faces)
+		faces = objconv.DropNonFiniteFaces(faces, badVertices)
+		fmt.Printf("Warning: %s has %d vertices with NaN/Inf coordinates; skipped %d faces using them\n",
+			filepath.Base(name), len(badVertices), before-len(faces))
+	}
+
+	return vertices, faces, mtlLib, scanner.Err()
+}
+
+// nonPolygonalTypes lists the OBJ element types that carry no polygon geometry
+var nonPolygonalTypes = []string{"l", "p", "curv", "surf"}
+
+// warnNonPolygonal reports how many non-polygonal elements were dropped while parsing
+func warnNonPolygonal(source string, counts map[string]int) {
+	parts := []string{}
+	for _, t := range nonPolygonalTypes {
+		if counts[t] > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", t, counts[t]))
+		}
+	}
+	if len(parts) > 0 {
+		fmt.Printf("Warning: %s contains non-polygonal elements that were ignored: %s\n", source, strings.Join(parts, ", "))
+	}
+}
+
+// Determine if a face is a roof, wall, or ground surface based on its normal and material
+func classifySurface(face OBJFace, vertices []objconv.Vertex, material string) string {
+	if strings.Contains(material, "Roof") {
+		return "Roof"
+	}
+	if strings.Contains(material, "Wall") {
+		return "Wall"
+	}
+	if strings.Contains(material, "Ground") {
+		return "Ground"
+	}
+
+	// If material name doesn't give us a clue, use the face normal
+	// Calculate face normal
+	if len(face.VertexIndices) >= 3 {
+		v1 := vertices[face.VertexIndices[0]]
+		v2 := vertices[face.VertexIndices[1]]
+		v3 := vertices[face.VertexIndices[2]]
+
+		// Calculate two edges
+		edge1 := objconv.Vector3D{X: v2.X - v1.X, Y: v2.Y - v1.Y, Z: v2.Z - v1.Z}
+		edge2 := objconv.Vector3D{X: v3.X - v1.X, Y: v3.Y - v1.Y, Z: v3.Z - v1.Z}
+
+		// Calculate cross product to get normal
+		normal := objconv.Vector3D{
+			X: edge1.Y*edge2.Z - edge1.Z*edge2.Y,
+			Y: edge1.Z*edge2.X - edge1.X*edge2.Z,
+			Z: edge1.X*edge2.Y - edge1.Y*edge2.X,
+		}
+
+		// Normalize
+		length := math.Sqrt(normal.X*normal.X + normal.Y*normal.Y + normal.Z*normal.Z)
+		if length > 0 {
+			normal.X /= length
+			normal.Y /= length
+			normal.Z /= length
+		}
+
+		// Check if normal is pointing upward (roof), horizontal (wall), or downward (ground)
+		if normal.Z > 0.7 {
+			return "Roof"
+		} else if normal.Z < -0.7 {
+			return "Ground"
+		} else {
+			return "Wall"
+		}
+	}
+
+	// Default to Wall if we can't determine
+	return "Wall"
+}
+
+// heightScale is the factor -convert-units applies to heights. X and Y stay
+// in the meters of the projected CRS. The mesh itself is kept in meters while
+// converting, so face normals, and with them the roof and wall classification,
+// are not skewed; heights, areas and volumes are scaled as they are written.
+func heightScale(opts ConvertOptions) float64 {
+	if !opts.ConvertUnits {
+		return 1
+	}
+	return 1 / objconv.UnitScales[opts.Units]
+}
+
+// scalePositionHeights multiplies the Z of every position of the building by factor
+func scalePositionHeights(building *citygml.Building, factor float64) {
+	building.EachPolygon(func(surfaceType string, polygon *citygml.Polygon) {
+		ring := &polygon.Exterior.LinearRing
+		for i, position := range ring.Pos {
+			if x, y, z, err := citygml.ParseCoordinates(position); err == nil {
+				ring.Pos[i] = fmt.Sprintf("%f %f %f", x, y, z*factor)
+			}
+		}
+	})
+}
+
+// simplifyFaces merges adjacent coplanar faces sharing a material into single polygons
+func simplifyFaces(vertices []objconv.Vertex, faces []OBJFace, toleranceDeg float64) []OBJFace {
+	var rings [][]int
+	var groups []string
+	var result []OBJFace
+	// parseOBJ has already rejected faces with bad indices
+	for _, face := range faces {
+		rings = append(rings, face.VertexIndices)
+		groups = append(groups, face.Material)
+	}
+
+	merged, sources := objconv.MergeCoplanarRings(vertices, rings, groups, toleranceDeg)
+	for i, ring := range merged {
+		face := faces[sources[i]]
+		face.VertexIndices = ring
+		result = append(result, face)
+	}
+	return result
+}
+
+// Convert OBJ file to CityGML
+func convertOBJToCityGML(objFile, outputFile, buildingID string, opts ConvertOptions) error {
+	file, err := os.Open(objFile)
+	if err != nil {
+		return fmt.Errorf("error parsing OBJ file: %v", err)
+	}
+	defer file.Close()
+	return convertOBJReader(file, objFile, outputFile, buildingID, opts)
+}
+
+// convertOBJReader converts OBJ data read from r, such as a tar entry; objFile
+// names the source in messages and is where mtllib paths are resolved from
+func convertOBJReader(r io.Reader, objFile, outputFile, buildingID string, opts ConvertOptions) error {
+	// The id ends up in gml:id and xlink targets, so it must be valid XML text
+	if safe := objconv.XMLText(buildingID); safe != buildingID {
+		fmt.Printf("Warning: removed characters not allowed in XML from building id %q\n", buildingID)
+		buildingID = safe
+	}
+
+	// Parse OBJ file
+	vertices, faces, mtlLib, err := parseOBJ(r, objFile, opts)
+	if err != nil {
+		return fmt.Errorf("error parsing OBJ file: %v", err)
+	}
+
+	if opts.Canonical {
+		faces = objconv.CanonicalFaces(vertices, faces)
+	}
+
+	if opts.ClipMode != "" {
+		var dropped, clipped int
+		// The datum is in -units, like the heights written
+		vertices, faces, dropped, clipped = objconv.ClipBelow(vertices, faces, opts.ClipBelow/heightScale(opts), opts.ClipMode)
+		if dropped+clipped > 0 {
+			fmt.Printf("Clipped %s below z=%g: dropped %d faces, cut %d faces at the datum\n", buildingID, opts.ClipBelow, dropped, clipped)
+		}
+		if len(faces) == 0 {
+			return fmt.Errorf("no faces left above -clip-below %g", opts.ClipBelow)
+		}
+	}
+
+	if opts.Simplify > 0 {
+		before := len(faces)
+		faces = simplifyFaces(vertices, faces, opts.Simplify)
+		fmt.Printf("Simplified %s: %d faces -> %d faces\n", buildingID, before, len(faces))
+	}
+
+	if opts.ZNudge != "" {
+		var count int
+		vertices, faces, count = resolveCoincidentFaces(vertices, faces, opts.ZNudge)
+		if count > 0 {
+			fmt.Printf("Resolved %d coincident faces in %s (%s)\n", count, buildingID, opts.ZNudge)
+		}
+	}
+
+	if opts.MaxRingVertices > 0 {
+		var split int
+		faces, split = objconv.SplitLargeFaces(vertices, faces, opts.MaxRingVertices)
+		if split > 0 {
+			fmt.Printf("Split %d faces of %s with more than %d vertices\n", split, buildingID, opts.MaxRingVertices)
+		}
+	}
+
+	// Parse MTL file if available
+	var materials map[string]MTLMaterial
+	if mtlLib != "" {
+		mtlFile, ok := resolveMTLPath(objFile, mtlLib, opts.MTLDir)
+		if !ok {
+			fmt.Printf("Warning: Could not find MTL file %s for %s\n", mtlLib, filepath.Base(objFile))
+		} else {
+			// Log the absolute path, since mtllib ../shared.mtl or -mtl-dir can
+			// resolve to a file outside the OBJ's directory
+			if abs, err := filepath.Abs(mtlFile); err == nil {
+				mtlFile = abs
+			}
+			fmt.Printf("Using MTL file %s for %s\n", mtlFile, filepath.Base(objFile))
+			if objDir, err := filepath.Abs(filepath.Dir(objFile)); err == nil && !insideDir(objDir, mtlFile) {
+				fmt.Printf("Note: MTL file of %s is outside its directory %s\n", filepath.Base(objFile), objDir)
+			}
+			materials, err = parseMTLFile(mtlFile, opts.Encoding)
+			if err != nil {
+				fmt.Printf("Warning: Could not parse MTL file: %v\n", err)
+			}
+		}
+	}
+
+	// Create CityGML model
+	model, buildings := CreateCityGMLModel(vertices, faces, materials, buildingID, opts)
+
+	if opts.MinSurfaceArea > 0 {
+		slivers := 0
+		for _, building := range buildings {
+			slivers += building.slivers
+		}
+		if slivers > 0 {
+			fmt.Printf("Dropped %d sliver polygons below %g from %s\n", slivers, opts.MinSurfaceArea, buildingID)
+		}
+	}
+
+	// Marshal the model
+	var buf bytes.Buffer
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(model); err != nil {
+		return fmt.Errorf("error encoding CityGML for building %s: %v", buildingID, err)
+	}
+
+	output := buf.Bytes()
+	if opts.FlattenNamespaces {
+		if output, err = citygml.FlattenNamespaces(output); err != nil {
+			return fmt.Errorf("error flattening namespaces: %v", err)
+		}
+	}
+
+	// Write to file with the XML header
+	if err := fileio.WriteFile(outputFile, append([]byte(xmlHeader), output...)); err != nil {
+		return fmt.Errorf("error writing output file: %v", err)
+	}
+
+	if opts.Metrics != nil {
+		encoder := json.NewEncoder(opts.Metrics)
+		for _, building := range buildings {
+			if err := encoder.Encode(building.metrics); err != nil {
+				return fmt.Errorf("error writing metrics: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Create CityGML model from OBJ data, returning its buildings along with their metrics
+func CreateCityGMLModel(vertices []objconv.Vertex, faces []OBJFace, materials map[string]MTLMaterial, buildingID string, opts ConvertOptions) (citygml.CityModel, []*convertedBuilding) {
+	// Calculate bounding box
+	minX, minY, minZ, maxX, maxY, maxZ := objconv.VertexBounds(vertices)
+	scale := heightScale(opts)
+	lowerCorner, upperCorner := objconv.EnvelopeCorners(minX, minY, minZ*scale, maxX, maxY, maxZ*scale)
+
+	// Generate current date for CreationDate
+	currentDate := time.Now().Format("2006-01-02")
+
+	// Create CityGML model
+	ns := opts.Namespaces
+	model := citygml.CityModel{
+		GML:   citygml.GMLNamespace,
+		Core:  ns.Core,
+		Bldg:  ns.Bldg,
+		App:   ns.App,
+		Gen:   ns.Gen,
+		Grp:   ns.Grp,
+		XAL:   citygml.XALNamespace,
+		XLink: citygml.XLinkNamespace,
+		XSI:   citygml.XSINamespace,
+		SchemaLocation: strings.Join([]string{
+			citygml.SchemaLocation(ns.Core, "", ns.Version, "cityGMLBase.xsd"),
+			citygml.SchemaLocation(ns.App, "appearance/", ns.Version, "appearance.xsd"),
+			citygml.SchemaLocation(ns.Bldg, "building/", ns.Version, "building.xsd"),
+			citygml.SchemaLocation(ns.Gen, "generics/", ns.Version, "generics.xsd"),
+		}, " "),
+		Name: fmt.Sprintf("AC14-%s", buildingID),
+
+		BoundedBy: citygml.BoundedBy{
+			Envelope: citygml.Envelope{
+				SrsName:      citygml.SRSName(opts.SRSForm, opts.EPSGCode),
+				SrsDimension: "3",
+				LowerCorner:  lowerCorner,
+				UpperCorner:  upperCorner,
+			},
+		},
+	}
+	if opts.Envelope != nil {
+		model.BoundedBy.Envelope.LowerCorner = formatCorner(opts.Envelope[:3])
+		model.BoundedBy.Envelope.UpperCorner = formatCorner(opts.Envelope[3:])
+	}
+
+	// Add building to city model, one per connected component when splitting
+	var buildings []*convertedBuilding
+	if opts.SplitComponents {
+		components := connectedComponents(faces)
+		if len(components) > 1 {
+			fmt.Printf("Split %s into %d disconnected buildings\n", buildingID, len(components))
+			for i, component := range components {
+				_, _, componentMinZ, _, _, componentMaxZ := faceBounds(vertices, component)
+				building := createBuilding(vertices, component, fmt.Sprintf("%s_%d", buildingID, i+1), componentMaxZ-componentMinZ, currentDate, opts)
+				buildings = append(buildings, building)
+				model.CityObjectMember = append(model.CityObjectMember, citygml.CityObjectMember{Building: &building.Building})
+			}
+		}
+	}
+
+	if len(model.CityObjectMember) == 0 {
+		building := createBuilding(vertices, faces, buildingID, maxZ-minZ, currentDate, opts)
+		buildings = []*convertedBuilding{building}
+		model.CityObjectMember = []citygml.CityObjectMember{{Building: &building.Building}}
+	}
+
+	// Only appearances target polygons by id; without one the shared ids stay
+	// as they were
+	if opts.ColorByMaterial || opts.DefaultColors {
+		uniquePolygonIDs(model.CityObjectMember)
+	}
+	if opts.ColorByMaterial {
+		model.AppearanceMember = createAppearance(model.CityObjectMember, materials, buildingID)
+	}
+	if opts.DefaultColors {
+		if hasMaterials(faces) {
+			fmt.Printf("Note: %s has materials, -default-colors only colors meshes without any\n", buildingID)
+		} else {
+			model.AppearanceMember = createDefaultAppearance(model.CityObjectMember, buildingID)
+		}
+	}
+
+	return model, buildings
+}
+
+// surfacePolygons returns the polygons of every boundary surface of the buildings
+func surfacePolygons(members []citygml.CityObjectMember) []*citygml.Polygon {
+	var polygons []*citygml.Polygon
+	eachSurfacePolygon(members, func(surfaceType string, polygon *citygml.Polygon) {
+		polygons = append(polygons, polygon)
+	})
+	return polygons
+}
+
+// eachSurfacePolygon calls visit with every boundary surface polygon of the
+// buildings and their parts, along with its surface type: Roof, Wall or Ground.
+// Polygons of a building-level lod2MultiSurface have no surface type.
+func eachSurfacePolygon(members []citygml.CityObjectMember, visit func(surfaceType string, polygon *citygml.Polygon)) {
+	for _, member := range members {
+		member.Building.EachPolygon(func(surfaceType string, polygon *citygml.Polygon) {
+			visit(strings.TrimSuffix(surfaceType, "Surface"), polygon)
+		})
+	}
+}
+
+// uniquePolygonIDs suffixes repeated polygon ids, which surfaces of the same
+// type would otherwise share, so every gml:id can be targeted on its own
+func uniquePolygonIDs(members []citygml.CityObjectMember) {
+	seen := make(map[string]int)
+	for _, polygon := range surfacePolygons(members) {
+		seen[polygon.ID]++
+		if n := seen[polygon.ID]; n > 1 {
+			polygon.ID = fmt.Sprintf("%s_%d", polygon.ID, n)
+			polygon.Exterior.LinearRing.ID = polygon.ID + "_0"
+		}
+	}
+}
+
+// appearanceTheme names the theme of the X3DMaterial colors. rgbTexture is
+// what viewers expect for texture images, which this tool never writes.
+const appearanceTheme = "visual"
+
+// createAppearance builds one X3DMaterial per OBJ material with a Kd, targeting
+// every polygon made from a face of that material. Faces without a material,
+// or with one missing from the MTL file, are left uncolored.
+func createAppearance(members []citygml.CityObjectMember, materials map[string]MTLMaterial, buildingID string) []citygml.AppearanceMember {
+	targets := make(map[string][]string)
+	for _, polygon := range surfacePolygons(members) {
+		if _, ok := materials[polygon.Material]; !ok || polygon.Material == "" {
+			continue
+		}
+		targets[polygon.Material] = append(targets[polygon.Material], "#"+polygon.ID)
+	}
+
+	if len(targets) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	appearance := citygml.Appearance{Theme: appearanceTheme}
+	for _, name := range names {
+		kd := materials[name].Kd
+		appearance.SurfaceDataMember = append(appearance.SurfaceDataMember, citygml.SurfaceDataMember{
+			X3DMaterial: citygml.X3DMaterial{
+				ID:           xmlID(fmt.Sprintf("%s_mat_%s", buildingID, name)),
+				Name:         objconv.XMLText(name),
+				DiffuseColor: fmt.Sprintf("%g %g %g", kd[0], kd[1], kd[2]),
+				Target:       targets[name],
+			},
+		})
+	}
+	return []citygml.AppearanceMember{{Appearance: appearance}}
+}
+
+// surfaceDefaultColors are the -default-colors diffuse colors per surface type:
+// red roofs, grey walls and brown ground
+var surfaceDefaultColors = []struct {
+	SurfaceType string
+	Color       string
+}{
+	{"Roof", "0.8 0.1 0.1"},
+	{"Wall", "0.7 0.7 0.7"},
+	{"Ground", "0.5 0.35 0.2"},
+}
+
+// hasMaterials reports whether any face was given a material with usemtl
+func hasMaterials(faces []OBJFace) bool {
+	for _, face := range faces {
+		if face.Material != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// createDefaultAppearance colors every polygon by its surface type, for
+// meshes without materials to color by
+func createDefaultAppearance(members []citygml.CityObjectMember, buildingID string) []citygml.AppearanceMember {
+	targets := make(map[string][]string)
+	eachSurfacePolygon(members, func(surfaceType string, polygon *citygml.Polygon) {
+		targets[surfaceType] = append(targets[surfaceType], "#"+polygon.ID)
+	})
+
+	appearance := citygml.Appearance{Theme: appearanceTheme}
+	for _, color := range surfaceDefaultColors {
+		if len(targets[color.SurfaceType]) == 0 {
+			continue
+		}
+		appearance.SurfaceDataMember = append(appearance.SurfaceDataMember, citygml.SurfaceDataMember{
+			X3DMaterial: citygml.X3DMaterial{
+				ID:           xmlID(fmt.Sprintf("%s_default_%s", buildingID, color.SurfaceType)),
+				Name:         color.SurfaceType,
+				DiffuseColor: color.Color,
+				Target:       targets[color.SurfaceType],
+			},
+		})
+	}
+	if len(appearance.SurfaceDataMember) == 0 {
+		return nil
+	}
+	return []citygml.AppearanceMember{{Appearance: appearance}}
+}
+
+// coplanarTolerance is the normal angle in degrees under which -merge-coplanar
+// treats adjacent faces of a surface group as one plane
+const coplanarTolerance = 1.0
+
+// mergeSurfaceFaces merges the coplanar faces of one surface for
+// -merge-coplanar. Merging would rejoin the pieces -max-ring-vertices split
+// large faces into, so merged polygons over the limit are split again.
+func mergeSurfaceFaces(vertices []objconv.Vertex, faces []OBJFace, maxRingVertices int) []OBJFace {
+	faces = simplifyFaces(vertices, faces, coplanarTolerance)
+	if maxRingVertices > 0 {
+		faces, _ = objconv.SplitLargeFaces(vertices, faces, maxRingVertices)
+	}
+	return faces
+}
+
+// createBuilding classifies the faces into boundary surfaces and wraps them in a Building
+func createBuilding(vertices []objconv.Vertex, faces []OBJFace, buildingID string, height float64, currentDate string, opts ConvertOptions) *convertedBuilding {
+	// Point faces away from the center first, since classification reads the normal
+	var center objconv.Vector3D
+	if opts.CCW {
+		center, _ = boundingSphere(faceVertices(vertices, faces))
+		faces = orientOutward(vertices, faces, "", center)
+	}
+
+	// Group faces by their surface type
+	roofFaces := []OBJFace{}
+	wallFaces := []OBJFace{}
+	groundFaces := []OBJFace{}
+
+	for _, face := range faces {
+		surfaceType := classifySurface(face, vertices, face.Material)
+		switch surfaceType {
+		case "Roof":
+			roofFaces = append(roofFaces, face)
+		case "Wall":
+			wallFaces = append(wallFaces, face)
+		case "Ground":
+			groundFaces = append(groundFaces, face)
+		}
+	}
+
+	// Make every ring counter-clockwise when seen from outside
+	if opts.CCW {
+		roofFaces = orientOutward(vertices, roofFaces, "Roof", center)
+		wallFaces = orientOutward(vertices, wallFaces, "Wall", center)
+		groundFaces = orientOutward(vertices, groundFaces, "Ground", center)
+	}
+
+	// Measures are written in -units; the placeholder area is in square meters
+	scale := heightScale(opts)
+	height *= scale
+	grossPlannedArea := 120.0 * scale * scale
+	minSurfaceArea := opts.MinSurfaceArea / (scale * scale)
+
+	// Create building with filename as ID and current date as CreationDate
+	building := &convertedBuilding{Building: citygml.Building{
+		ID:                 buildingID, // Use the filename without extension directly
+		Name:               fmt.Sprintf("AC14-%s", buildingID),
+		Description:        fmt.Sprintf("%s, created by converter", buildingID),
+		CreationDate:       currentDate, // Use current date
+		RelativeToTerrain:  "entirelyAboveTerrain",
+		YearOfConstruction: fmt.Sprintf("%d", time.Now().Year()), // Use current year
+		MeasuredHeight:     &citygml.Measure{Value: fmt.Sprintf("%.2f", height), UOM: opts.Units},
+		StoreysAboveGround: "2",
+		StoreysBelowGround: "0",
+		Class:              &citygml.Code{Value: "1000", CodeSpace: "http://www.sig3d.org/codelists/citygml/2.0/building/2.0/_AbstractBuilding_class.xml"},
+		Function:           &citygml.Code{Value: "1000", CodeSpace: "http://www.sig3d.org/codelists/citygml/2.0/building/2.0/_AbstractBuilding_function.xml"},
+		Usage:              &citygml.Code{Value: "1000", CodeSpace: "http://www.sig3d.org/codelists/citygml/2.0/building/2.0/_AbstractBuilding_usage.xml"},
+		RoofType:           &citygml.Code{Value: "1030", CodeSpace: "http://www.sig3d.org/codelists/citygml/2.0/building/2.0/_AbstractBuilding_roofType.xml"},
+		MeasureAttributes: []citygml.MeasureAttribute{
+			{
+				Name: "GrossPlannedArea",
+				Value: citygml.Measure{
+					Value: fmt.Sprintf("%.2f", grossPlannedArea),
+					UOM:   opts.Units + "2",
+				},
+			},
+		},
+		StringAttributes: []citygml.StringAttribute{
+			{
+				Name:  "ConstructionMethod",
+				Value: "New Building",
+			},
+			{
+				Name:  "IsLandmarked",
+				Value: "NO",
+			},
+		},
+	}}
+
+	if opts.EmitBounds {
+		center, radius := boundingSphere(faceVertices(vertices, faces))
+		building.MeasureAttributes = append(building.MeasureAttributes,
+			citygml.MeasureAttribute{Name: "BoundingSphereCenterX", Value: citygml.Measure{Value: fmt.Sprintf("%f", center.X), UOM: opts.Units}},
+			citygml.MeasureAttribute{Name: "BoundingSphereCenterY", Value: citygml.Measure{Value: fmt.Sprintf("%f", center.Y), UOM: opts.Units}},
+			citygml.MeasureAttribute{Name: "BoundingSphereCenterZ", Value: citygml.Measure{Value: fmt.Sprintf("%f", center.Z*scale), UOM: opts.Units}},
+			citygml.MeasureAttribute{Name: "BoundingSphereRadius", Value: citygml.Measure{Value: fmt.Sprintf("%f", radius*scale), UOM: opts.Units}},
+		)
+	}
+
+	if opts.EPSGAttribute {
+		building.StringAttributes = append(building.StringAttributes, citygml.StringAttribute{Name: "EPSG", Value: opts.EPSGCode})
+	}
+
+	for _, attribute := range opts.Meta {
+		attribute = citygml.StringAttribute{Name: objconv.XMLText(attribute.Name), Value: objconv.XMLText(attribute.Value)}
+		building.StringAttributes = objconv.SetStringAttribute(building.StringAttributes, attribute)
+	}
+
+	if opts.EmitVolume {
+		if !isClosedMesh(faces) {
+			fmt.Printf("Warning: %s is not a closed mesh; its volume is unreliable\n", buildingID)
+		}
+		building.MeasureAttributes = append(building.MeasureAttributes,
+			citygml.MeasureAttribute{Name: "Volume", Value: citygml.Measure{Value: fmt.Sprintf("%.2f", meshVolume(vertices, faces)*scale*scale*scale), UOM: opts.Units + "3"}},
+		)
+	}
+
+	if opts.Lod2Geometry == "multisurface" {
+		// One unclassified MultiSurface holding every face instead of boundedBy
+		multiSurface, dropped := createBuildingMultiSurface(buildingID, vertices, faces, minSurfaceArea)
+		building.slivers += dropped
+		building.Lod2MultiSurface = &multiSurface
+	} else {
+		// Create boundary surfaces
+		boundedBy := []citygml.BoundarySurface{}
+
+		// Create wall surfaces
+		if len(wallFaces) > 0 {
+			// Split wall faces into separate surfaces by orientation
+			wallGroups := groupFacesByOrientation(wallFaces, vertices)
+			for i, group := range wallGroups {
+				if opts.MergeCoplanar {
+					group = mergeSurfaceFaces(vertices, group, opts.MaxRingVertices)
+				}
+				wallSurface, dropped := createWallSurface(buildingID, fmt.Sprintf("Outer Wall %d", i+1), vertices, group, minSurfaceArea)
+				building.slivers += dropped
+				if len(wallSurface.Lod2MultiSurface.MultiSurface.SurfaceMember) == 0 {
+					continue
+				}
+				if opts.EmitSurfaceArea {
+					wallSurface.MeasureAttributes = surfaceAreaAttributes(vertices, group, opts.Units, scale)
+				}
+				boundedBy = append(boundedBy, citygml.BoundarySurface{WallSurface: &wallSurface})
+			}
+		}
+
+		// Create roof surfaces
+		if len(roofFaces) > 0 {
+			// Split roof faces into separate surfaces if needed
+			roofGroups := groupFacesByOrientation(roofFaces, vertices)
+			for i, group := range roofGroups {
+				if opts.MergeCoplanar {
+					group = mergeSurfaceFaces(vertices, group, opts.MaxRingVertices)
+				}
+				roofSurface, dropped := createRoofSurface(buildingID, fmt.Sprintf("Roof %d", i+1), vertices, group, minSurfaceArea)
+				building.slivers += dropped
+				if len(roofSurface.Lod2MultiSurface.MultiSurface.SurfaceMember) == 0 {
+					continue
+				}
+				if opts.EmitSurfaceArea {
+					roofSurface.MeasureAttributes = surfaceAreaAttributes(vertices, group, opts.Units, scale)
+				}
+				if opts.EmitRoofSolar {
+					roofSurface.MeasureAttributes = append(roofSurface.MeasureAttributes, roofSolarAttributes(vertices, group)...)
+				}
+				boundedBy = append(boundedBy, citygml.BoundarySurface{RoofSurface: &roofSurface})
+			}
+		}
+
+		// Create ground surface
+		if len(groundFaces) > 0 {
+			groundSurface, dropped := createGroundSurface(buildingID, "Base Surface", vertices, groundFaces, minSurfaceArea)
+			building.slivers += dropped
+			if opts.EmitSurfaceArea {
+				groundSurface.MeasureAttributes = surfaceAreaAttributes(vertices, groundFaces, opts.Units, scale)
+			}
+			if len(groundSurface.Lod2MultiSurface.MultiSurface.SurfaceMember) > 0 {
+				boundedBy = append(boundedBy, citygml.BoundarySurface{GroundSurface: &groundSurface})
+			}
+		}
+
+		// Add boundary surfaces to building
+		building.BoundedBy = boundedBy
+	}
+
+	minX, minY, minZ, maxX, maxY, maxZ := faceBounds(vertices, faces)
+	building.metrics = BuildingMetrics{
+		ID:            buildingID,
+		Height:        height,
+		FootprintArea: projectedArea(vertices, groundFaces) * scale * scale,
+		Volume:        meshVolume(vertices, faces) * scale * scale * scale,
+		BBox:          [6]float64{minX, minY, minZ * scale, maxX, maxY, maxZ * scale},
+	}
+	for _, surface := range building.BoundedBy {
+		switch {
+		case surface.RoofSurface != nil:
+			building.metrics.RoofSurfaces++
+		case surface.WallSurface != nil:
+			building.metrics.WallSurfaces++
+		case surface.GroundSurface != nil:
+			building.metrics.GroundSurfaces++
+		}
+	}
+
+	if scale != 1 {
+		scalePositionHeights(&building.Building, scale)
+	}
+	if opts.Parts == "roof" {
+		building.BoundedBy, building.Parts = splitRoofPart(buildingID, building.BoundedBy)
+	}
+
+	return building
+}
+
+// splitRoofPart moves the roof surfaces into a BuildingPart of their own and
+// leaves the walls and ground on the main building
+func splitRoofPart(buildingID string, boundedBy []citygml.BoundarySurface) ([]citygml.BoundarySurface, []citygml.BuildingPartProperty) {
+	var body, roof []citygml.BoundarySurface
+	for _, surface := range boundedBy {
+		if surface.RoofSurface != nil {
+			roof = append(roof, surface)
+		} else {
+			body = append(body, surface)
+		}
+	}
+	if len(roof) == 0 {
+		return boundedBy, nil
+	}
+
+	part := citygml.BuildingPart{
+		ID:        buildingID + "_roof",
+		Name:      "Roof",
+		BoundedBy: roof,
+	}
+	return body, []citygml.BuildingPartProperty{{BuildingPart: part}}
+}
+
+// projectedArea sums the XY-plane area of faces, ignoring their height
+func projectedArea(vertices []objconv.Vertex, faces []OBJFace) float64 {
+	total := 0.0
+	for _, face := range faces {
+		area := 0.0
+		for i, idx := range face.VertexIndices {
+			a := vertices[idx]
+			b := vertices[face.VertexIndices[(i+1)%len(face.VertexIndices)]]
+			area += a.X*b.Y - b.X*a.Y
+		}
+		total += math.Abs(area) / 2
+	}
+	return total
+}
+
+// polygonArea3D returns the area of a face in its own plane: half the length
+// of the summed cross products of consecutive vertices. Vertices are taken
+// relative to the first one to keep precision with projected coordinates.
+func polygonArea3D(vertices []objconv.Vertex, face OBJFace) float64 {
+	if len(face.VertexIndices) < 3 {
+		return 0
+	}
+	origin := vertices[face.VertexIndices[0]]
+	var sum objconv.Vector3D
+	for i, idx := range face.VertexIndices {
+		a := vertices[idx]
+		b := vertices[face.VertexIndices[(i+1)%len(face.VertexIndices)]]
+		ax, ay, az := a.X-origin.X, a.Y-origin.Y, a.Z-origin.Z
+		bx, by, bz := b.X-origin.X, b.Y-origin.Y, b.Z-origin.Z
+		sum.X += ay*bz - az*by
+		sum.Y += az*bx - ax*bz
+		sum.Z += ax*by - ay*bx
+	}
+	return math.Sqrt(sum.X*sum.X+sum.Y*sum.Y+sum.Z*sum.Z) / 2
+}
+
+// surfaceArea sums the area of faces in their own planes, so a sloped roof
+// counts its full area rather than its footprint
+func surfaceArea(vertices []objconv.Vertex, faces []OBJFace) float64 {
+	total := 0.0
+	for _, face := range faces {
+		total += polygonArea3D(vertices, face)
+	}
+	return total
+}
+
+// roofSolarAttributes returns the Slope and Aspect measure attributes of one
+// roof surface from its area-weighted normal: slope in degrees from horizontal
+// and aspect as the azimuth of the down-slope direction, clockwise from north
+// (+Y). Flat roofs have no down-slope direction and get no Aspect.
+func roofSolarAttributes(vertices []objconv.Vertex, faces []OBJFace) []citygml.MeasureAttribute {
+	var n objconv.Vector3D
+	for _, face := range faces {
+		normal := objconv.NewellNormal(vertices, face.VertexIndices)
+		area := surfaceArea(vertices, []OBJFace{face})
+		n.X += normal.X * area
+		n.Y += normal.Y * area
+		n.Z += normal.Z * area
+	}
+	length := math.Sqrt(n.X*n.X + n.Y*n.Y + n.Z*n.Z)
+	if length == 0 {
+		return nil
+	}
+
+	slope := math.Acos(math.Max(-1, math.Min(1, n.Z/length))) * 180 / math.Pi
+	attributes := []citygml.MeasureAttribute{
+		{Name: "Slope", Value: citygml.Measure{Value: fmt.Sprintf("%.2f", slope), UOM: "deg"}},
+	}
+	if math.Hypot(n.X, n.Y)/length > 1e-6 {
+		aspect := math.Atan2(n.X, n.Y) * 180 / math.Pi
+		if aspect < 0 {
+			aspect += 360
+		}
+		attributes = append(attributes, citygml.MeasureAttribute{Name: "Aspect", Value: citygml.Measure{Value: fmt.Sprintf("%.2f", aspect), UOM: "deg"}})
+	}
+	return attributes
+}
+
+// surfaceAreaAttributes returns the Area measure attribute of one boundary surface
+func surfaceAreaAttributes(vertices []objconv.Vertex, faces []OBJFace, units string, scale float64) []citygml.MeasureAttribute {
+	return []citygml.MeasureAttribute{
+		{Name: "Area", Value: citygml.Measure{Value: fmt.Sprintf("%.2f", surfaceArea(vertices, faces)*scale*scale), UOM: units + "2"}},
+	}
+}
+
+// isClosedMesh reports whether every edge is shared by exactly two faces,
+// which meshVolume needs for the volume to be meaningful
+func isClosedMesh(faces []OBJFace) bool {
+	edgeCount := make(map[[2]int]int)
+	for _, face := range faces {
+		ring := face.VertexIndices
+		for i := range ring {
+			edgeCount[objconv.UndirectedEdge(ring[i], ring[(i+1)%len(ring)])]++
+		}
+	}
+	if len(edgeCount) == 0 {
+		return false
+	}
+	for _, count := range edgeCount {
+		if count != 2 {
+			return false
+		}
+	}
+	return true
+}
+
+// meshVolume sums the signed volumes of the tetrahedra formed by each fan
+// triangle and the mesh center (the divergence theorem). It is exact for
+// closed, consistently wound meshes; the sign is dropped so inward winding
+// still gives a positive volume.
+func meshVolume(vertices []objconv.Vertex, faces []OBJFace) float64 {
+	// Measure from the mesh center so large projected coordinates keep precision
+	center, _ := boundingSphere(faceVertices(vertices, faces))
+
+	volume := 0.0
+	for _, face := range faces {
+		for i := 1; i+1 < len(face.VertexIndices); i++ {
+			a := vertices[face.VertexIndices[0]]
+			b := vertices[face.VertexIndices[i]]
+			c := vertices[face.VertexIndices[i+1]]
+			ax, ay, az := a.X-center.X, a.Y-center.Y, a.Z-center.Z
+			bx, by, bz := b.X-center.X, b.Y-center.Y, b.Z-center.Z
+			cx, cy, cz := c.X-center.X, c.Y-center.Y, c.Z-center.Z
+			volume += ax*(by*cz-bz*cy) - ay*(bx*cz-bz*cx) + az*(bx*cy-by*cx)
+		}
+	}
+	return math.Abs(volume) / 6
+}
+
+// orientOutward reverses the faces whose normal points into the building. Roofs
+// face up and grounds face down; walls, and roofs or grounds too steep to tell,
+// face away from the building center.
+func orientOutward(vertices []objconv.Vertex, faces []OBJFace, surfaceType string, center objconv.Vector3D) []OBJFace {
+	result := make([]OBJFace, len(faces))
+	for i, face := range faces {
+		result[i] = face
+		if len(face.VertexIndices) < 3 {
+			continue
+		}
+
+		valid := true
+		var centroid objconv.Vector3D
+		for _, idx := range face.VertexIndices {
+			if idx < 0 || idx >= len(vertices) {
+				valid = false
+				break
+			}
+			centroid.X += vertices[idx].X
+			centroid.Y += vertices[idx].Y
+			centroid.Z += vertices[idx].Z
+		}
+		if !valid {
+			continue
+		}
+		n := float64(len(face.VertexIndices))
+		centroid = objconv.Vector3D{X: centroid.X / n, Y: centroid.Y / n, Z: centroid.Z / n}
+
+		normal := objconv.NewellNormal(vertices, face.VertexIndices)
+		var outward float64
+		switch {
+		case surfaceType == "Roof" && math.Abs(normal.Z) > 0.1:
+			outward = normal.Z
+		case surfaceType == "Ground" && math.Abs(normal.Z) > 0.1:
+			outward = -normal.Z
+		default:
+			outward = normal.X*(centroid.X-center.X) + normal.Y*(centroid.Y-center.Y) + normal.Z*(centroid.Z-center.Z)
+		}
+
+		if outward < 0 {
+			reversed := make([]int, len(face.VertexIndices))
+			for j, idx := range face.VertexIndices {
+				reversed[len(reversed)-1-j] = idx
+			}
+			result[i].VertexIndices = reversed
+		}
+	}
+	return result
+}
+
+// boundingSphere returns a sphere centered on the bounding box center with the
+// radius reaching the farthest vertex
+func boundingSphere(vertices []objconv.Vertex) (objconv.Vector3D, float64) {
+	if len(vertices) == 0 {
+		return objconv.Vector3D{}, 0
+	}
+
+	minX, minY, minZ := math.MaxFloat64, math.MaxFloat64, math.MaxFloat64
+	maxX, maxY, maxZ := -math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64
+	for _, v := range vertices {
+		minX = math.Min(minX, v.X)
+		minY = math.Min(minY, v.Y)
+		minZ = math.Min(minZ, v.Z)
+		maxX = math.Max(maxX, v.X)
+		maxY = math.Max(maxY, v.Y)
+		maxZ = math.Max(maxZ, v.Z)
+	}
+	center := objconv.Vector3D{X: (minX + maxX) / 2, Y: (minY + maxY) / 2, Z: (minZ + maxZ) / 2}
+
+	radius := 0.0
+	for _, v := range vertices {
+		dx, dy, dz := v.X-center.X, v.Y-center.Y, v.Z-center.Z
+		radius = math.Max(radius, math.Sqrt(dx*dx+dy*dy+dz*dz))
+	}
+	return center, radius
+}
+
+// faceVertices returns each vertex referenced by faces once
+func faceVertices(vertices []objconv.Vertex, faces []OBJFace) []objconv.Vertex {
+	seen := make(map[int]bool)
+	var result []objconv.Vertex
+	for _, face := range faces {
+		for _, idx := range face.VertexIndices {
+			if idx < 0 || idx >= len(vertices) || seen[idx] {
+				continue
+			}
+			seen[idx] = true
+			result = append(result, vertices[idx])
+		}
+	}
+	return result
+}
+
+// connectedComponents splits faces into groups that share vertices, using
+// union-find over vertex indices. Components keep the order of their first face.
+// Faces without vertices belong to no component and are left out.
+func connectedComponents(faces []OBJFace) [][]OBJFace {
+	parent := make(map[int]int)
+	var find func(int) int
+	find = func(i int) int {
+		if _, ok := parent[i]; !ok {
+			parent[i] = i
+		}
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for _, face := range faces {
+		if len(face.VertexIndices) == 0 {
+			continue
+		}
+		for _, idx := range face.VertexIndices[1:] {
+			parent[find(idx)] = find(face.VertexIndices[0])
+		}
+	}
+
+	componentIndex := make(map[int]int)
+	components := [][]OBJFace{}
+	for _, face := range faces {
+		if len(face.VertexIndices) == 0 {
+			continue
+		}
+		root := find(face.VertexIndices[0])
+		i, ok := componentIndex[root]
+		if !ok {
+			i = len(components)
+			componentIndex[root] = i
+			components = append(components, nil)
+		}
+		components[i] = append(components[i], face)
+	}
+	return components
+}
+
+// faceBounds returns the bounding box of the vertices referenced by faces
+func faceBounds(vertices []objconv.Vertex, faces []OBJFace) (minX, minY, minZ, maxX, maxY, maxZ float64) {
+	minX, minY, minZ = math.MaxFloat64, math.MaxFloat64, math.MaxFloat64
+	maxX, maxY, maxZ = -math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64
+
+	for _, face := range faces {
+		for _, idx := range face.VertexIndices {
+			if idx < 0 || idx >= len(vertices) {
+				continue
+			}
+			v := vertices[idx]
+			minX = math.Min(minX, v.X)
+			minY = math.Min(minY, v.Y)
+			minZ = math.Min(minZ, v.Z)
+			maxX = math.Max(maxX, v.X)
+			maxY = math.Max(maxY, v.Y)
+			maxZ = math.Max(maxZ, v.Z)
+		}
+	}
+	return
+}
+
+// Group faces by their orientation for better surface organization
+func groupFacesByOrientation(faces []OBJFace, vertices []objconv.Vertex) [][]OBJFace {
+	groups := make(map[string][]OBJFace)
+	var keys []string
+
+	for _, face := range faces {
+		if len(face.VertexIndices) < 3 {
+			continue
+		}
+
+		// Calculate face normal
+		v1 := vertices[face.VertexIndices[0]]
+		v2 := vertices[face.VertexIndices[1]]
+		v3 := vertices[face.VertexIndices[2]]
+
+		// Calculate two edges
+		edge1 := objconv.Vector3D{X: v2.X - v1.X, Y: v2.Y - v1.Y, Z: v2.Z - v1.Z}
+		edge2 := objconv.Vector3D{X: v3.X - v1.X, Y: v3.Y - v1.Y, Z: v3.Z - v1.Z}
+
+		// Calculate cross product to get normal
+		normal := objconv.Vector3D{
+			X: edge1.Y*edge2.Z - edge1.Z*edge2.Y,
+			Y: edge1.Z*edge2.X - edge1.X*edge2.Z,
+			Z: edge1.X*edge2.Y - edge1.Y*edge2.X,
+		}
+
+		// Normalize
+		length := math.Sqrt(normal.X*normal.X + normal.Y*normal.Y + normal.Z*normal.Z)
+		if length > 0 {
+			normal.X /= length
+			normal.Y /= length
+			normal.Z /= length
+		}
+
+		// Round to 1 decimal place for grouping
+		key := fmt.Sprintf("%.1f,%.1f,%.1f", normal.X, normal.Y, normal.Z)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], face)
+	}
+
+	// Groups come out in the order their first face appears, so the output is
+	// the same on every run
+	result := [][]OBJFace{}
+	for _, key := range keys {
+		result = append(result, groups[key])
+	}
+
+	return result
+}
+
+// Simple UUID generator based on string hash
+func generateUUID(input string) string {
+	hash := 0
+	for _, char := range input {
+		hash = 31*hash + int(char)
+	}
+	return fmt.Sprintf("d281adfc-4901-0f52-540b-%d", hash)
+}
+
+// Create a roof surface
+func createRoofSurface(buildingID, name string, vertices []objconv.Vertex, faces []OBJFace, minArea float64) (citygml.SemanticSurface, int) {
+	id := fmt.Sprintf("GML_%s", generateUUID(buildingID+name))
+
+	// Create polygons for each face
+	surfaceMembers := []citygml.SurfaceMember{}
+	dropped := 0
+	for i, face := range faces {
+		if minArea > 0 && polygonArea3D(vertices, face) < minArea {
+			dropped++
+			continue
+		}
+		polyID := fmt.Sprintf("PolyID%d_%d_%d_%d", 7353+i, 166, 774155, 320806+i)
+		polygon := createPolygon(polyID, vertices, face)
+		surfaceMembers = append(surfaceMembers, citygml.SurfaceMember{Polygon: polygon})
+	}
+
+	return citygml.SemanticSurface{
+		ID:   id,
+		Name: name,
+		Lod2MultiSurface: &citygml.MultiSurfaceProperty{
+			MultiSurface: citygml.MultiSurface{
+				SurfaceMember: surfaceMembers,
+			},
+		},
+	}, dropped
+}
+
+// Create a wall surface
+func createWallSurface(buildingID, name string, vertices []objconv.Vertex, faces []OBJFace, minArea float64) (citygml.SemanticSurface, int) {
+	id := fmt.Sprintf("GML_%s", generateUUID(buildingID+name))
+
+	// Create polygons for each face
+	surfaceMembers := []citygml.SurfaceMember{}
+	dropped := 0
+	for i, face := range faces {
+		if minArea > 0 && polygonArea3D(vertices, face) < minArea {
+			dropped++
+			continue
+		}
+		polyID := fmt.Sprintf("PolyID%d_%d_%d_%d", 7350+i, 878, 759628, 120742+i)
+		polygon := createPolygon(polyID, vertices, face)
+		surfaceMembers = append(surfaceMembers, citygml.SurfaceMember{Polygon: polygon})
+	}
+
+	return citygml.SemanticSurface{
+		ID:   id,
+		Name: name,
+		Lod2MultiSurface: &citygml.MultiSurfaceProperty{
+			MultiSurface: citygml.MultiSurface{
+				SurfaceMember: surfaceMembers,
+			},
+		},
+	}, dropped
+}
+
+// createBuildingMultiSurface puts a polygon for every face, in OBJ order, into
+// one MultiSurface for -lod2-geometry multisurface
+func createBuildingMultiSurface(buildingID string, vertices []objconv.Vertex, faces []OBJFace, minArea float64) (citygml.MultiSurfaceProperty, int) {
+	surfaceMembers := []citygml.SurfaceMember{}
+	dropped := 0
+	for i, face := range faces {
+		if minArea > 0 && polygonArea3D(vertices, face) < minArea {
+			dropped++
+			continue
+		}
+		polyID := xmlID(fmt.Sprintf("%s_poly_%d", buildingID, i))
+		surfaceMembers = append(surfaceMembers, citygml.SurfaceMember{Polygon: createPolygon(polyID, vertices, face)})
+	}
+	return citygml.MultiSurfaceProperty{MultiSurface: citygml.MultiSurface{SurfaceMember: surfaceMembers}}, dropped
+}
+
+// Create a ground surface
+func createGroundSurface(buildingID, name string, vertices []objconv.Vertex, faces []OBJFace, minArea float64) (citygml.SemanticSurface, int) {
+	id := fmt.Sprintf("GML_%s", generateUUID(buildingID+name))
+
+	// Create polygons for each face
+	surfaceMembers := []citygml.SurfaceMember{}
+	dropped := 0
+	for i, face := range faces {
+		if minArea > 0 && polygonArea3D(vertices, face) < minArea {
+			dropped++
+			continue
+		}
+		polyID := fmt.Sprintf("PolyID7356_%d_%d_%d", 612, 880782, 415367+i)
+		polygon := createPolygon(polyID, vertices, face)
+		surfaceMembers = append(surfaceMembers, citygml.SurfaceMember{Polygon: polygon})
+	}
+
+	return citygml.SemanticSurface{
+		ID:          id,
+		Description: "Bodenplatte",
+		Name:        name,
+		Lod2MultiSurface: &citygml.MultiSurfaceProperty{
+			MultiSurface: citygml.MultiSurface{
+				SurfaceMember: surfaceMembers,
+			},
+		},
+	}, dropped
+}
+
+// Create a polygon from a face
+func createPolygon(id string, vertices []objconv.Vertex, face OBJFace) *citygml.Polygon {
+	// Create positions for the linear ring
+	positions := []string{}
+	for _, idx := range face.VertexIndices {
+		if idx < len(vertices) {
+			v := vertices[idx]
+			positions = append(positions, v.Position())
+		}
+	}
+
+	// Close the polygon by repeating the first vertex
+	if len(face.VertexIndices) > 0 && face.VertexIndices[0] < len(vertices) {
+		v := vertices[face.VertexIndices[0]]
+		positions = append(positions, v.Position())
+	}
+
+	return &citygml.Polygon{
+		ID: id,
+		Exterior: citygml.PolygonExterior{
+			LinearRing: citygml.LinearRing{
+				ID:  id + "_0",
+				Pos: positions,
+			},
+		},
+		Material: face.Material,
+	}
+}
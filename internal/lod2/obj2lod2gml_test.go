@@ -0,0 +1,1148 @@
+package lod2
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/fakmalpradana/OBJ2GML/citygml"
+	"github.com/fakmalpradana/OBJ2GML/internal/convert"
+	"github.com/fakmalpradana/OBJ2GML/internal/objconv"
+)
+
+// testOptions are the obj2lod2gml defaults
+func testOptions(t testing.TB) ConvertOptions {
+	t.Helper()
+	namespaces, err := citygml.NamespacesFor("2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ConvertOptions{
+		EPSGCode:   "32748",
+		SRSForm:    "url",
+		Namespaces: namespaces,
+		CCW:        true,
+		Units:      "m",
+	}
+}
+
+// convertString converts OBJ text to a CityGML file in a temporary directory
+// and returns the file content
+func convertString(t *testing.T, obj string, opts ConvertOptions) []byte {
+	t.Helper()
+	dir := t.TempDir()
+	objFile := filepath.Join(dir, "b.obj")
+	outputFile := filepath.Join(dir, "b.gml")
+	if err := convertOBJReader(strings.NewReader(obj), objFile, outputFile, "b", opts); err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+	output, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return output
+}
+
+// parseBuilding reads the single building of converted output
+func parseBuilding(t *testing.T, output []byte) *citygml.InputBuilding {
+	t.Helper()
+	var model citygml.InputCityModel
+	if err := xml.Unmarshal(output, &model); err != nil {
+		t.Fatalf("parsing output: %v", err)
+	}
+	if len(model.CityObjectMember) != 1 || model.CityObjectMember[0].Building == nil {
+		t.Fatalf("want one building, got %d members", len(model.CityObjectMember))
+	}
+	return model.CityObjectMember[0].Building
+}
+
+// ringSizes returns the number of positions of each ring of a surface type
+func ringSizes(t *testing.T, building *citygml.InputBuilding, surfaceType string) []int {
+	t.Helper()
+	var sizes []int
+	building.EachRing(func(ringSurfaceType string, ring *citygml.InputLinearRing) {
+		if ringSurfaceType != surfaceType {
+			return
+		}
+		points, err := citygml.ParsePosList(ring.Coordinates())
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, len(points))
+	})
+	return sizes
+}
+
+// captureStdout runs f and returns what it printed
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+	f()
+	os.Stdout = stdout
+	w.Close()
+	return <-output
+}
+
+// polygonRoofOBJ is a flat roof with n corners, counter-clockwise from above
+func polygonRoofOBJ(n int) string {
+	var obj strings.Builder
+	for i := 0; i < n; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		fmt.Fprintf(&obj, "v %f %f 5\n", 10*math.Cos(angle), 10*math.Sin(angle))
+	}
+	obj.WriteString("f")
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&obj, " %d", i)
+	}
+	obj.WriteString("\n")
+	return obj.String()
+}
+
+// -merge-coplanar merges each surface's faces after -max-ring-vertices split
+// them, and must not rejoin the pieces into a ring over the limit
+func TestMaxRingVerticesWithMergeCoplanar(t *testing.T) {
+	for _, mergeCoplanar := range []bool{false, true} {
+		t.Run(fmt.Sprintf("merge-coplanar=%v", mergeCoplanar), func(t *testing.T) {
+			opts := testOptions(t)
+			opts.MaxRingVertices = 100
+			opts.MergeCoplanar = mergeCoplanar
+
+			building := parseBuilding(t, convertString(t, polygonRoofOBJ(200), opts))
+			if got, want := ringSizes(t, building, "RoofSurface"), []int{101, 101, 5}; !reflect.DeepEqual(got, want) {
+				t.Errorf("roof ring positions = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// A face using a vertex that was never defined is skipped instead of
+// reaching classifySurface, and fails the file with -strict
+func TestFaceIndexOutOfRange(t *testing.T) {
+	obj := "v 0 0 5\nv 10 0 5\nv 10 10 5\nv 0 10 5\nf 1 2 3 4\nf 1 2 99\nf 1 2 -9\n"
+	opts := testOptions(t)
+
+	vertices, faces, _, err := parseOBJ(strings.NewReader(obj), "b.obj", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vertices) != 4 || len(faces) != 1 {
+		t.Errorf("parsed %d vertices and %d faces, want 4 and the one valid face", len(vertices), len(faces))
+	}
+	building := parseBuilding(t, convertString(t, obj, opts))
+	if got := ringSizes(t, building, "RoofSurface"); !reflect.DeepEqual(got, []int{5}) {
+		t.Errorf("roof ring positions = %v, want [5]", got)
+	}
+
+	opts.Strict = true
+	if _, _, _, err := parseOBJ(strings.NewReader(obj), "b.obj", opts); err == nil || !strings.Contains(err.Error(), `line 6: out-of-range (4 vertices defined) face vertex "99"`) {
+		t.Errorf("strict parse error = %v", err)
+	}
+}
+
+// An unparsable or missing vertex coordinate is an error naming its line; an
+// overflowing one is non-finite and skipped like NaN
+func TestVertexParseErrors(t *testing.T) {
+	for obj, want := range map[string]string{
+		"v 0 0 5\nv 1 zero 5\n":  `line 2: invalid vertex coordinate "zero"`,
+		"v 0 0 5\nv 1\n":         `line 2: vertex "v 1" needs at least x and y`,
+		"v 0 0 5\nv 1 0 5e999\n": "",
+	} {
+		got := ""
+		if _, _, _, err := parseOBJ(strings.NewReader(obj), "b.obj", testOptions(t)); err != nil {
+			got = err.Error()
+		}
+		if got != want {
+			t.Errorf("parseOBJ(%q) error = %q, want %q", obj, got, want)
+		}
+	}
+}
+
+// polygonIDs lists the gml:id of every surface polygon in converted output
+func polygonIDs(output []byte) []string {
+	var ids []string
+	for _, match := range regexp.MustCompile(`<gml:Polygon gml:id="([^"]*)"`).FindAllSubmatch(output, -1) {
+		ids = append(ids, string(match[1]))
+	}
+	return ids
+}
+
+// Polygon ids are only made unique for the appearance that targets them, and
+// the appearance carries a material theme rather than a texture one
+func TestAppearancePolygonIDs(t *testing.T) {
+	obj := "v 0 0 0\nv 10 0 0\nv 10 10 0\nv 0 10 0\nv 0 0 5\nv 10 0 5\nv 10 10 5\nv 0 10 5\n" +
+		"f 1 2 6 5\nf 2 3 7 6\nf 3 4 8 7\nf 4 1 5 8\nf 5 6 7 8\nf 1 4 3 2\n"
+
+	opts := testOptions(t)
+	output := convertString(t, obj, opts)
+	ids := polygonIDs(output)
+	if len(ids) != 6 {
+		t.Fatalf("got %d polygons, want 6", len(ids))
+	}
+	if strings.Contains(string(output), "app:Appearance") {
+		t.Error("appearance written without -default-colors or -color-by-material")
+	}
+	plain := make(map[string]bool)
+	for _, id := range ids {
+		plain[id] = true
+	}
+
+	opts.DefaultColors = true
+	output = convertString(t, obj, opts)
+	colored := polygonIDs(output)
+	seen := make(map[string]bool)
+	for _, id := range colored {
+		if seen[id] {
+			t.Errorf("polygon id %s repeated with -default-colors", id)
+		}
+		seen[id] = true
+		if !strings.Contains(string(output), "<app:target>#"+id+"</app:target>") {
+			t.Errorf("polygon %s is not an appearance target", id)
+		}
+	}
+	// Each surface numbers its polygons from the same base, so without an
+	// appearance the ids repeat across surfaces as they always have
+	if len(plain) == len(ids) {
+		t.Errorf("polygon ids %v were renumbered without an appearance", ids)
+	}
+	if !strings.Contains(string(output), "<app:theme>visual</app:theme>") || strings.Contains(string(output), "rgbTexture") {
+		t.Errorf("appearance theme is not visual:\n%s", output)
+	}
+}
+
+// gableOBJ is a 10 x 10 m house with 5 m walls and a gable roof 1 m high,
+// a slope that -convert-units must not turn into walls
+const gableOBJ = `v 0 0 0
+v 10 0 0
+v 10 10 0
+v 0 10 0
+v 0 0 5
+v 10 0 5
+v 10 10 5
+v 0 10 5
+v 0 5 6
+v 10 5 6
+f 1 4 3 2
+f 1 2 6 5
+f 3 4 8 7
+f 2 3 7 10 6
+f 4 1 5 9 8
+f 5 6 10 9
+f 7 8 9 10
+`
+
+// -convert-units converts heights only; X and Y stay in the meters of the CRS
+func TestConvertUnitsScalesHeightsOnly(t *testing.T) {
+	opts := testOptions(t)
+	meters := parseBuilding(t, convertString(t, gableOBJ, opts))
+
+	opts.Units = "ft"
+	opts.ConvertUnits = true
+	opts.EmitVolume = true
+	output := convertString(t, gableOBJ, opts)
+	feet := parseBuilding(t, output)
+
+	if feet.MeasuredHeight == nil || feet.MeasuredHeight.Value != "19.69" || feet.MeasuredHeight.UOM != "ft" {
+		t.Errorf("measuredHeight = %+v, want 19.69 ft", feet.MeasuredHeight)
+	}
+	for _, surfaceType := range []string{"RoofSurface", "WallSurface", "GroundSurface"} {
+		if got, want := ringSizes(t, feet, surfaceType), ringSizes(t, meters, surfaceType); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s rings = %v, want %v as in meters", surfaceType, got, want)
+		}
+	}
+	// The ridge at (0, 5, 6 m)
+	if !strings.Contains(string(output), "0.000000 5.000000 19.685039") {
+		t.Errorf("ridge not at x=0 y=5 with its height in feet:\n%s", output)
+	}
+	if !strings.Contains(string(output), `<gml:upperCorner>10.000000 10.000000 19.685039</gml:upperCorner>`) {
+		t.Errorf("envelope not converted the same way:\n%s", output)
+	}
+	// 10 x 10 x 5 m plus the 50 m3 roof prism, in cubic feet
+	if !strings.Contains(string(output), `uom="ft3">19423.07<`) {
+		t.Errorf("volume not converted to cubic feet:\n%s", output)
+	}
+}
+
+// materialColors maps each X3DMaterial name to its diffuse color
+func materialColors(t *testing.T, output []byte) map[string]string {
+	t.Helper()
+	var model struct {
+		Materials []struct {
+			Name         string `xml:"name"`
+			DiffuseColor string `xml:"diffuseColor"`
+		} `xml:"appearanceMember>Appearance>surfaceDataMember>X3DMaterial"`
+	}
+	if err := xml.Unmarshal(output, &model); err != nil {
+		t.Fatal(err)
+	}
+	colors := make(map[string]string)
+	for _, material := range model.Materials {
+		colors[material.Name] = material.DiffuseColor
+	}
+	return colors
+}
+
+// -default-colors gives each surface type its color on a mesh without
+// materials, and leaves a mesh with materials alone even when
+// -color-by-material is not given
+func TestDefaultColors(t *testing.T) {
+	opts := testOptions(t)
+	opts.DefaultColors = true
+
+	colors := materialColors(t, convertString(t, gableOBJ, opts))
+	want := map[string]string{"Roof": "0.8 0.1 0.1", "Wall": "0.7 0.7 0.7", "Ground": "0.5 0.35 0.2"}
+	if !reflect.DeepEqual(colors, want) {
+		t.Errorf("default colors = %v, want %v", colors, want)
+	}
+
+	withMaterial := strings.Replace(gableOBJ, "f 5 6 10 9", "usemtl tiles\nf 5 6 10 9", 1)
+	if colors := materialColors(t, convertString(t, withMaterial, opts)); len(colors) != 0 {
+		t.Errorf("mesh with materials got default colors %v", colors)
+	}
+}
+
+func TestConnectedComponentsSkipsEmptyFaces(t *testing.T) {
+	faces := []OBJFace{
+		{VertexIndices: []int{0, 1, 2}},
+		{},
+		{VertexIndices: []int{3, 4, 5}},
+		{VertexIndices: []int{2, 6, 0}},
+	}
+	var sizes []int
+	for _, component := range connectedComponents(faces) {
+		sizes = append(sizes, len(component))
+	}
+	if want := []int{2, 1}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("component sizes = %v, want %v", sizes, want)
+	}
+}
+
+// benchBoxes is the OBJ text of n×n 6 m boxes 10 m apart, the benchmark
+// fixture; n = 25 gives 5000 vertices and 3750 faces
+func benchBoxes(n int) string {
+	var obj strings.Builder
+	v := 0
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			x, y := float64(i*10), float64(j*10)
+			for _, z := range []float64{0, 6} {
+				fmt.Fprintf(&obj, "v %g %g %g\nv %g %g %g\nv %g %g %g\nv %g %g %g\n", x, y, z, x+6, y, z, x+6, y+6, z, x, y+6, z)
+			}
+			for _, face := range [][4]int{{1, 4, 3, 2}, {5, 6, 7, 8}, {1, 2, 6, 5}, {2, 3, 7, 6}, {3, 4, 8, 7}, {4, 1, 5, 8}} {
+				fmt.Fprintf(&obj, "f %d %d %d %d\n", v+face[0], v+face[1], v+face[2], v+face[3])
+			}
+			v += 8
+		}
+	}
+	return obj.String()
+}
+
+func BenchmarkParseOBJ(b *testing.B) {
+	obj := benchBoxes(25)
+	opts := testOptions(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, err := parseOBJ(strings.NewReader(obj), "b.obj", opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCreateCityGMLModel(b *testing.B) {
+	opts := testOptions(b)
+	vertices, faces, _, err := parseOBJ(strings.NewReader(benchBoxes(25)), "b.obj", opts)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CreateCityGMLModel(vertices, faces, nil, "b", opts)
+	}
+}
+
+// obj2gml and obj2lod2gml write the same envelope and measured height for one
+// OBJ, so its LOD1 and LOD2 buildings line up
+func TestLOD1AndLOD2Agree(t *testing.T) {
+	obj := `v 0.25 0.5 1.3
+v 3.75 0.5 1.3
+v 3.75 2.125 1.3
+v 0.25 2.125 1.3
+v 0.25 0.5 7.85
+v 3.75 0.5 7.85
+v 3.75 2.125 7.85
+v 0.25 2.125 7.85
+f 1 2 6 5
+f 2 3 7 6
+f 3 4 8 7
+f 4 1 5 8
+f 5 6 7 8
+f 1 4 3 2
+`
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "b.obj"), []byte(obj), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lod1Dir := filepath.Join(dir, "lod1")
+	if status := convert.Main("obj2gml", []string{"-input", dir, "-output", lod1Dir}); status != 0 {
+		t.Fatalf("obj2gml exited with %d", status)
+	}
+	lod1, err := os.ReadFile(filepath.Join(lod1Dir, "b.gml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lod2 := convertString(t, obj, testOptions(t))
+
+	var models [2]citygml.InputCityModel
+	for i, output := range [][]byte{lod1, lod2} {
+		if err := xml.Unmarshal(output, &models[i]); err != nil {
+			t.Fatal(err)
+		}
+		if models[i].BoundedBy == nil || models[i].BoundedBy.Envelope == nil {
+			t.Fatalf("output %d has no envelope", i+1)
+		}
+	}
+	lod1Envelope, lod2Envelope := models[0].BoundedBy.Envelope, models[1].BoundedBy.Envelope
+	if lod1Envelope.LowerCorner != lod2Envelope.LowerCorner || lod1Envelope.UpperCorner != lod2Envelope.UpperCorner {
+		t.Errorf("LOD1 envelope %s / %s, LOD2 %s / %s", lod1Envelope.LowerCorner, lod1Envelope.UpperCorner, lod2Envelope.LowerCorner, lod2Envelope.UpperCorner)
+	}
+	if lod1Envelope.LowerCorner != "0.250000 0.500000 1.300000" || lod1Envelope.UpperCorner != "3.750000 2.125000 7.850000" {
+		t.Errorf("envelope %s / %s, want the box's corners", lod1Envelope.LowerCorner, lod1Envelope.UpperCorner)
+	}
+	lod1Height := parseBuilding(t, lod1).MeasuredHeight
+	lod2Height := parseBuilding(t, lod2).MeasuredHeight
+	if lod1Height == nil || lod2Height == nil || *lod1Height != *lod2Height || lod1Height.Value != "6.55" {
+		t.Errorf("measuredHeight LOD1 %+v, LOD2 %+v; want 6.55 in both", lod1Height, lod2Height)
+	}
+}
+
+// Bad arguments exit with 2 and setup failures with 1, before anything is
+// converted
+func TestInvalidArgumentsExitStatus(t *testing.T) {
+	input := t.TempDir()
+	if err := os.WriteFile(filepath.Join(input, "a.obj"), []byte(polygonRoofOBJ(4)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	notADir := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(notADir, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "out")
+	run := func(extra ...string) []string {
+		return append([]string{"-input", input, "-output", output}, extra...)
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		status int
+	}{
+		{"no input", []string{"-output", output}, 2},
+		{"no output", []string{"-input", input}, 2},
+		{"srs-form", run("-srs-form", "x"), 2},
+		{"max-ring-vertices", run("-max-ring-vertices", "2"), 2},
+		{"clip-mode", run("-clip-below", "0", "-clip-mode", "cut"), 2},
+		{"clip-below", run("-clip-below", "low"), 2},
+		{"citygml-version", run("-citygml-version", "3.0"), 2},
+		{"lod2-geometry", run("-lod2-geometry", "solid"), 2},
+		{"parts with multisurface", run("-lod2-geometry", "multisurface", "-parts", "roof"), 2},
+		{"parts", run("-parts", "wall"), 2},
+		{"z-nudge", run("-z-nudge", "lift"), 2},
+		{"units", run("-units", "yd"), 2},
+		{"encoding", run("-encoding", "ebcdic"), 2},
+		{"envelope", run("-envelope", "1 2"), 2},
+		{"only-ids", run("-only-ids", ","), 2},
+		{"epsg-map", run("-epsg-map", filepath.Join(input, "missing.csv")), 2},
+		{"unopenable checkpoint", run("-checkpoint", filepath.Join(notADir, "cp")), 1},
+		{"output under a file", []string{"-input", input, "-output", filepath.Join(notADir, "out")}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := Main("obj2lod2gml", tt.args); status != tt.status {
+				t.Errorf("exit status = %d, want %d", status, tt.status)
+			}
+			if _, err := os.Stat(filepath.Join(output, "a.gml")); err == nil {
+				t.Error("a.obj was converted")
+			}
+		})
+	}
+}
+
+// CityGML 1.0 output declares the 1.0 namespaces and schemas only
+func TestCityGMLVersion(t *testing.T) {
+	opts := testOptions(t)
+	namespaces, err := citygml.NamespacesFor("1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Namespaces = namespaces
+	output := string(convertString(t, polygonRoofOBJ(4), opts))
+	for _, want := range []string{
+		`xmlns:core="http://www.opengis.net/citygml/1.0"`,
+		`xmlns:app="http://www.opengis.net/citygml/appearance/1.0"`,
+		"http://www.opengis.net/citygml/building/1.0 http://schemas.opengis.net/citygml/building/1.0/building.xsd",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output lacks %s", want)
+		}
+	}
+	// The SIG3D code lists keep their own version in codeSpace
+	if regexp.MustCompile(`opengis\.net/citygml/(\w+/)?2\.0`).MatchString(output) {
+		t.Errorf("output still uses a 2.0 namespace:\n%s", output)
+	}
+	parseBuilding(t, []byte(output))
+}
+
+// -merge-coplanar joins a flat roof's two triangles into one square polygon
+func TestMergeCoplanarRoofTriangles(t *testing.T) {
+	obj := "v 0 0 5\nv 10 0 5\nv 10 10 5\nv 0 10 5\nf 1 2 3\nf 1 3 4\n"
+	for mergeCoplanar, want := range map[bool][]int{false: {4, 4}, true: {5}} {
+		opts := testOptions(t)
+		opts.MergeCoplanar = mergeCoplanar
+		building := parseBuilding(t, convertString(t, obj, opts))
+		if got := ringSizes(t, building, "RoofSurface"); !reflect.DeepEqual(got, want) {
+			t.Errorf("merge-coplanar=%v: roof ring positions = %v, want %v", mergeCoplanar, got, want)
+		}
+	}
+}
+
+// -emit-bounds adds the bounding sphere of the building's vertices: the center
+// of their box and the distance to the farthest one
+func TestEmitBoundsBoundingSphere(t *testing.T) {
+	obj := "v 0 0 0\nv 2 0 0\nv 2 4 0\nv 0 4 0\nv 0 0 4\nv 2 0 4\nv 2 4 4\nv 0 4 4\n" +
+		"f 1 4 3 2\nf 5 6 7 8\nf 1 2 6 5\nf 2 3 7 6\nf 3 4 8 7\nf 4 1 5 8\n"
+	opts := testOptions(t)
+	opts.EmitBounds = true
+	output := string(convertString(t, obj, opts))
+
+	for name, want := range map[string]string{
+		"BoundingSphereCenterX": "1.000000",
+		"BoundingSphereCenterY": "2.000000",
+		"BoundingSphereCenterZ": "2.000000",
+		"BoundingSphereRadius":  "3.000000",
+	} {
+		pattern := regexp.MustCompile(`<gen:measureAttribute name="` + name + `">\s*<gen:value uom="m">([^<]*)</gen:value>`)
+		if match := pattern.FindStringSubmatch(output); match == nil || match[1] != want {
+			t.Errorf("%s = %v, want %s", name, match, want)
+		}
+	}
+}
+
+// With -ccw every ring winds counter-clockwise seen from outside, so its
+// normal points away from the building, even when the OBJ winds them inward
+func TestCCWOrientsRingsOutward(t *testing.T) {
+	// A 0..1 cube whose faces all wind inward
+	obj := "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\nv 0 0 1\nv 1 0 1\nv 1 1 1\nv 0 1 1\n" +
+		"f 1 2 3 4\nf 8 7 6 5\nf 5 6 2 1\nf 6 7 3 2\nf 7 8 4 3\nf 8 5 1 4\n"
+	for _, ccw := range []bool{false, true} {
+		opts := testOptions(t)
+		opts.CCW = ccw
+		building := parseBuilding(t, convertString(t, obj, opts))
+		outward := 0
+		building.EachRing(func(surfaceType string, ring *citygml.InputLinearRing) {
+			points, err := citygml.ParsePosList(ring.Coordinates())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var vertices []objconv.Vertex
+			var indices []int
+			var centroid objconv.Vector3D
+			for i, p := range points[:len(points)-1] {
+				vertices = append(vertices, objconv.Vertex{X: p[0], Y: p[1], Z: p[2]})
+				indices = append(indices, i)
+				centroid.X, centroid.Y, centroid.Z = centroid.X+p[0], centroid.Y+p[1], centroid.Z+p[2]
+			}
+			n := float64(len(indices))
+			normal := objconv.NewellNormal(vertices, indices)
+			if normal.X*(centroid.X/n-0.5)+normal.Y*(centroid.Y/n-0.5)+normal.Z*(centroid.Z/n-0.5) > 0 {
+				outward++
+			}
+		})
+		if want := map[bool]int{false: 0, true: 6}[ccw]; outward != want {
+			t.Errorf("ccw=%v: %d rings face outward, want %d", ccw, outward, want)
+		}
+	}
+}
+
+// -max-faces and -max-vertices stop the parse with an error as soon as the
+// count is exceeded
+func TestParseLimits(t *testing.T) {
+	obj := "v 0 0 0\nv 1 0 0\nv 1 1 0\n" + strings.Repeat("f 1 2 3\n", 200)
+
+	_, _, _, err := parseOBJ(strings.NewReader(obj), "b.obj", ConvertOptions{MaxFaces: 100})
+	if err == nil || err.Error() != "line 104: more than 100 faces (-max-faces)" {
+		t.Errorf("parse with -max-faces 100 = %v", err)
+	}
+	_, _, _, err = parseOBJ(strings.NewReader(obj), "b.obj", ConvertOptions{MaxVertices: 2})
+	if err == nil || err.Error() != "line 3: more than 2 vertices (-max-vertices)" {
+		t.Errorf("parse with -max-vertices 2 = %v", err)
+	}
+	if _, faces, _, err := parseOBJ(strings.NewReader(obj), "b.obj", ConvertOptions{MaxFaces: 200, MaxVertices: 3}); err != nil || len(faces) != 200 {
+		t.Errorf("parse at the limits = %d faces, %v", len(faces), err)
+	}
+}
+
+// mtllib resolves as given relative to the OBJ, by base name next to it, and
+// in -mtl-dir, in that order
+func TestResolveMTLPath(t *testing.T) {
+	dir := t.TempDir()
+	shared := t.TempDir()
+	for _, file := range []string{filepath.Join(dir, "mats", "foo.mtl"), filepath.Join(dir, "bar.mtl"), filepath.Join(shared, "baz.mtl")} {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte("newmtl m\nKd 1 1 1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	objFile := filepath.Join(dir, "b.obj")
+
+	for mtlLib, want := range map[string]string{
+		"mats/foo.mtl":     filepath.Join(dir, "mats", "foo.mtl"),
+		`mats\foo.mtl`:     filepath.Join(dir, "mats", "foo.mtl"),
+		"textures/bar.mtl": filepath.Join(dir, "bar.mtl"),
+		"baz.mtl":          filepath.Join(shared, "baz.mtl"),
+		"missing.mtl":      "",
+	} {
+		got, ok := resolveMTLPath(objFile, mtlLib, shared)
+		if got != want || ok != (want != "") {
+			t.Errorf("resolveMTLPath(%q) = %q, %v, want %q", mtlLib, got, ok, want)
+		}
+	}
+
+	// The conversion finds the material through the subdirectory
+	obj := "mtllib mats/foo.mtl\nv 0 0 0\nv 1 0 0\nv 1 1 0\nusemtl m\nf 1 2 3\n"
+	if err := os.WriteFile(objFile, []byte(obj), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputFile := filepath.Join(dir, "b.gml")
+	opts := testOptions(t)
+	opts.ColorByMaterial = true
+	if err := convertOBJToCityGML(objFile, outputFile, "b", opts); err != nil {
+		t.Fatal(err)
+	}
+	output, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(output), "<app:diffuseColor>1 1 1</app:diffuseColor>") {
+		t.Errorf("material not applied:\n%s", output)
+	}
+}
+
+// mtllib ../mats.mtl finds the material one directory up and logs where the
+// file was found, noting it is outside the OBJ's directory
+func TestParentRelativeMTL(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "objs")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "mats.mtl"), []byte("newmtl m\nKd 1 0 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	objFile := filepath.Join(dir, "b.obj")
+	obj := "mtllib ../mats.mtl\nv 0 0 0\nv 1 0 0\nv 1 1 0\nusemtl m\nf 1 2 3\n"
+	if err := os.WriteFile(objFile, []byte(obj), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputFile := filepath.Join(root, "b.gml")
+	opts := testOptions(t)
+	opts.ColorByMaterial = true
+
+	log := captureStdout(t, func() {
+		if err := convertOBJToCityGML(objFile, outputFile, "b", opts); err != nil {
+			t.Fatal(err)
+		}
+	})
+	output, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(output), "<app:diffuseColor>1 0 0</app:diffuseColor>") {
+		t.Errorf("material not applied:\n%s", output)
+	}
+	for _, want := range []string{
+		"Using MTL file " + filepath.Join(root, "mats.mtl") + " for b.obj",
+		"Note: MTL file of b.obj is outside its directory " + dir,
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log lacks %q:\n%s", want, log)
+		}
+	}
+}
+
+// -metrics writes one JSON line per building; a unit cube has a footprint of
+// 1, a volume of 1 and six surfaces
+func TestMetricsUnitCube(t *testing.T) {
+	obj := "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\nv 0 0 1\nv 1 0 1\nv 1 1 1\nv 0 1 1\n" +
+		"f 1 4 3 2\nf 5 6 7 8\nf 1 2 6 5\nf 2 3 7 6\nf 3 4 8 7\nf 4 1 5 8\n"
+	var metrics bytes.Buffer
+	opts := testOptions(t)
+	opts.Metrics = &metrics
+	convertString(t, obj, opts)
+
+	lines := strings.Split(strings.TrimSpace(metrics.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("metrics = %q, want one line", metrics.String())
+	}
+	var got BuildingMetrics
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := BuildingMetrics{
+		ID:             "b",
+		Height:         1,
+		FootprintArea:  1,
+		RoofSurfaces:   1,
+		WallSurfaces:   4,
+		GroundSurfaces: 1,
+		Volume:         1,
+		BBox:           [6]float64{0, 0, 0, 1, 1, 1},
+	}
+	if got != want {
+		t.Errorf("metrics = %+v, want %+v", got, want)
+	}
+}
+
+// boxOBJ is an outward-wound box of the given size with its corner at the
+// origin
+func boxOBJ(size float64) string {
+	var obj strings.Builder
+	for _, z := range []float64{0, size} {
+		fmt.Fprintf(&obj, "v 0 0 %[2]g\nv %[1]g 0 %[2]g\nv %[1]g %[1]g %[2]g\nv 0 %[1]g %[2]g\n", size, z)
+	}
+	obj.WriteString("f 1 4 3 2\nf 5 6 7 8\nf 1 2 6 5\nf 2 3 7 6\nf 3 4 8 7\nf 4 1 5 8\n")
+	return obj.String()
+}
+
+// -emit-volume adds the volume of a closed box, and warns about an open one
+func TestEmitVolume(t *testing.T) {
+	volume := regexp.MustCompile(`<gen:measureAttribute name="Volume">\s*<gen:value uom="m3">([^<]*)</gen:value>`)
+	opts := testOptions(t)
+	opts.EmitVolume = true
+	for size, want := range map[float64]string{1: "1.00", 2: "8.00"} {
+		var output []byte
+		log := captureStdout(t, func() { output = convertString(t, boxOBJ(size), opts) })
+		if match := volume.FindSubmatch(output); match == nil || string(match[1]) != want {
+			t.Errorf("%gm box: volume %q, want %s", size, match, want)
+		}
+		if strings.Contains(log, "not a closed mesh") {
+			t.Errorf("%gm box reported open:\n%s", size, log)
+		}
+	}
+
+	// Without its bottom face the box is open
+	open := strings.Replace(boxOBJ(1), "f 1 4 3 2\n", "", 1)
+	log := captureStdout(t, func() { convertString(t, open, opts) })
+	if !strings.Contains(log, "Warning: b is not a closed mesh; its volume is unreliable") {
+		t.Errorf("no warning for an open mesh:\n%s", log)
+	}
+}
+
+// Faces carry the group of the last s line; "s off", "s 0" and an invalid
+// group turn smoothing off
+func TestSmoothingGroups(t *testing.T) {
+	obj := "v 0 0 0\nv 1 0 0\nv 1 1 0\nf 1 2 3\ns 1\nf 1 2 3\ns 4\nf 1 2 3\nf 1 2 3\ns off\nf 1 2 3\ns 2\ns 0\nf 1 2 3\ns x\nf 1 2 3\n"
+	var faces []OBJFace
+	var err error
+	log := captureStdout(t, func() {
+		_, faces, _, err = parseOBJ(strings.NewReader(obj), "b.obj", testOptions(t))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var groups []int
+	for _, face := range faces {
+		groups = append(groups, face.SmoothingGroup)
+	}
+	if want := []int{0, 1, 4, 4, 0, 0, 0}; !reflect.DeepEqual(groups, want) {
+		t.Errorf("smoothing groups = %v, want %v", groups, want)
+	}
+	if !strings.Contains(log, `Warning: b.obj line 15: invalid smoothing group "x", treating as off`) {
+		t.Errorf("no warning for the invalid group:\n%s", log)
+	}
+}
+
+// -z-nudge dedup drops a repeated roof face, even one listing its vertices in
+// another order, while nudge lifts it just above the first
+func TestZNudgeCoincidentRoofs(t *testing.T) {
+	obj := "v 0 0 5\nv 10 0 5\nv 10 10 5\nv 0 10 5\nf 1 2 3 4\nf 3 4 1 2\n"
+	for mode, want := range map[string][]float64{
+		"":      {5, 5},
+		"dedup": {5},
+		"nudge": {5, 5 + zNudgeEpsilon},
+	} {
+		opts := testOptions(t)
+		opts.ZNudge = mode
+		var heights []float64
+		parseBuilding(t, convertString(t, obj, opts)).EachRing(func(surfaceType string, ring *citygml.InputLinearRing) {
+			points, err := citygml.ParsePosList(ring.Coordinates())
+			if err != nil {
+				t.Fatal(err)
+			}
+			heights = append(heights, points[0][2])
+		})
+		sort.Float64s(heights)
+		if !reflect.DeepEqual(heights, want) {
+			t.Errorf("-z-nudge %q: roof heights %v, want %v", mode, heights, want)
+		}
+	}
+}
+
+// surfaceKinds counts the boundary surfaces by type
+func surfaceKinds(surfaces []citygml.InputBoundarySurface) map[string]int {
+	kinds := make(map[string]int)
+	for _, surface := range surfaces {
+		switch {
+		case surface.RoofSurface != nil:
+			kinds["RoofSurface"]++
+		case surface.WallSurface != nil:
+			kinds["WallSurface"]++
+		case surface.GroundSurface != nil:
+			kinds["GroundSurface"]++
+		}
+	}
+	return kinds
+}
+
+// -parts roof moves the roof into a BuildingPart and leaves the walls and
+// ground on the building
+func TestPartsRoof(t *testing.T) {
+	opts := testOptions(t)
+	opts.Parts = "roof"
+	building := parseBuilding(t, convertString(t, boxOBJ(1), opts))
+
+	if got, want := surfaceKinds(building.BoundedBy), map[string]int{"WallSurface": 4, "GroundSurface": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("building surfaces = %v, want %v", got, want)
+	}
+	if len(building.Parts) != 1 || building.Parts[0].BuildingPart == nil {
+		t.Fatalf("parts = %+v, want the roof part", building.Parts)
+	}
+	part := building.Parts[0].BuildingPart
+	if got := surfaceKinds(part.BoundedBy); part.ID != "b_roof" || !reflect.DeepEqual(got, map[string]int{"RoofSurface": 1}) {
+		t.Errorf("part %s surfaces = %v, want b_roof with the roof", part.ID, got)
+	}
+}
+
+// -meta pairs become string attributes of the building
+func TestMetaStringAttributes(t *testing.T) {
+	opts := testOptions(t)
+	opts.Meta = []citygml.StringAttribute{{Name: "SourceDataset", Value: "Jakarta <2025>"}, {Name: "Operator", Value: "a=b"}}
+	output := convertString(t, boxOBJ(1), opts)
+	for _, want := range []string{
+		`<gen:stringAttribute name="SourceDataset">\s*<gen:value>Jakarta &lt;2025&gt;</gen:value>`,
+		`<gen:stringAttribute name="Operator">\s*<gen:value>a=b</gen:value>`,
+	} {
+		if !regexp.MustCompile(want).Match(output) {
+			t.Errorf("missing %s in:\n%s", want, output)
+		}
+	}
+}
+
+// -emit-surface-area gives a sloped roof its true area: 4 m along the eaves
+// by 5 m up the slope is 20 m2, not the 16 m2 it covers horizontally
+func TestEmitSurfaceAreaSlopedRoof(t *testing.T) {
+	obj := "v 0 0 5\nv 4 0 5\nv 4 4 8\nv 0 4 8\nf 1 2 3 4\n"
+	opts := testOptions(t)
+	opts.EmitSurfaceArea = true
+	output := convertString(t, obj, opts)
+
+	area := regexp.MustCompile(`(?s)<bldg:RoofSurface[^>]*>.*?<gen:measureAttribute name="Area">\s*<gen:value uom="m2">([^<]*)</gen:value>`)
+	if match := area.FindSubmatch(output); match == nil || string(match[1]) != "20.00" {
+		t.Errorf("roof area %q, want 20.00 in:\n%s", match, output)
+	}
+}
+
+// -emit-roof-solar gives a roof rising 30° towards the north a slope of 30
+// and an aspect of 180, since it drains to the south
+func TestEmitRoofSolarSouthFacing(t *testing.T) {
+	rise := 4 * math.Tan(math.Pi/6)
+	obj := fmt.Sprintf("v 0 0 5\nv 4 0 5\nv 4 4 %[1]g\nv 0 4 %[1]g\nf 1 2 3 4\n", 5+rise)
+	opts := testOptions(t)
+	opts.EmitRoofSolar = true
+	output := convertString(t, obj, opts)
+
+	for name, want := range map[string]string{"Slope": "30.00", "Aspect": "180.00"} {
+		attribute := regexp.MustCompile(`(?s)<bldg:RoofSurface[^>]*>.*?<gen:measureAttribute name="` + name + `">\s*<gen:value uom="deg">([^<]*)</gen:value>`)
+		if match := attribute.FindSubmatch(output); match == nil || string(match[1]) != want {
+			t.Errorf("roof %s %q, want %s in:\n%s", name, match, want, output)
+		}
+	}
+}
+
+// polygonArea3D measures a face in its own plane, whatever its tilt or
+// position
+func TestPolygonArea3D(t *testing.T) {
+	c, s := math.Cos(math.Pi/3), math.Sin(math.Pi/3)
+	for name, test := range map[string]struct {
+		vertices []objconv.Vertex
+		want     float64
+	}{
+		"horizontal square": {[]objconv.Vertex{{X: 0, Y: 0, Z: 0}, {X: 1, Y: 0, Z: 0}, {X: 1, Y: 1, Z: 0}, {X: 0, Y: 1, Z: 0}}, 1},
+		"square tilted 60°": {[]objconv.Vertex{{X: 0, Y: 0, Z: 0}, {X: 1, Y: 0, Z: 0}, {X: 1, Y: c, Z: s}, {X: 0, Y: c, Z: s}}, 1},
+		"scaled triangle":   {[]objconv.Vertex{{X: 0, Y: 0, Z: 2}, {X: 3, Y: 0, Z: 2}, {X: 0, Y: 4, Z: 2}}, 6},
+		"far from origin":   {[]objconv.Vertex{{X: 692653, Y: 9326565, Z: 10}, {X: 692655, Y: 9326565, Z: 10}, {X: 692655, Y: 9326565, Z: 13}}, 3},
+	} {
+		face := OBJFace{}
+		for i := range test.vertices {
+			face.VertexIndices = append(face.VertexIndices, i)
+		}
+		if got := polygonArea3D(test.vertices, face); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: area = %v, want %v", name, got, test.want)
+		}
+	}
+}
+
+// -min-surface-area drops a 1e-8 m2 sliver and reports it, while the roof
+// face next to it is kept
+func TestMinSurfaceAreaDropsSlivers(t *testing.T) {
+	obj := "v 0 0 5\nv 10 0 5\nv 10 10 5\nv 0 10 5\nv 10.0001 0 5\nv 10 0.0002 5\nf 1 2 3 4\nf 2 5 6\n"
+	for minArea, want := range map[float64][]int{0: {5, 4}, 1e-6: {5}} {
+		opts := testOptions(t)
+		opts.MinSurfaceArea = minArea
+		var output []byte
+		log := captureStdout(t, func() { output = convertString(t, obj, opts) })
+		if got := ringSizes(t, parseBuilding(t, output), "RoofSurface"); !reflect.DeepEqual(got, want) {
+			t.Errorf("-min-surface-area %g: roof ring positions = %v, want %v", minArea, got, want)
+		}
+		if reported := strings.Contains(log, "Dropped 1 sliver polygons below 1e-06 from b"); reported != (minArea > 0) {
+			t.Errorf("-min-surface-area %g: log:\n%s", minArea, log)
+		}
+	}
+}
+
+// A fixed envelope replaces the geometry bounds, which stay as they were
+func TestEnvelopeOverride(t *testing.T) {
+	opts := testOptions(t)
+	opts.Envelope = []float64{-500, -500, 0, 500, 500, 100.5}
+	var model citygml.InputCityModel
+	if err := xml.Unmarshal(convertString(t, boxOBJ(1), opts), &model); err != nil {
+		t.Fatal(err)
+	}
+	if model.BoundedBy == nil || model.BoundedBy.Envelope == nil {
+		t.Fatal("no envelope")
+	}
+	if envelope := model.BoundedBy.Envelope; envelope.LowerCorner != "-500 -500 0" || envelope.UpperCorner != "500 500 100.5" {
+		t.Errorf("envelope %s / %s, want the override", envelope.LowerCorner, envelope.UpperCorner)
+	}
+	if got := ringSizes(t, model.CityObjectMember[0].Building, "RoofSurface"); !reflect.DeepEqual(got, []int{5}) {
+		t.Errorf("roof ring positions = %v, want the box's roof", got)
+	}
+}
+
+// With -encoding windows-1252 an accented material name in the OBJ and MTL
+// reads as UTF-8, matches its MTL entry and still names the surface type
+func TestWindows1252Materials(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"b.obj": "mtllib b.mtl\nv 0 0 5\nv 10 0 5\nv 10 10 5\nv 0 10 5\nusemtl Fa\xe7adeWall\nf 1 2 3 4\n",
+		"b.mtl": "newmtl Fa\xe7adeWall\nKd 0.5 0.25 1\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := testOptions(t)
+	opts.Encoding = "windows-1252"
+	opts.ColorByMaterial = true
+	outputFile := filepath.Join(dir, "b.gml")
+	captureStdout(t, func() {
+		if err := convertOBJToCityGML(filepath.Join(dir, "b.obj"), outputFile, "b", opts); err != nil {
+			t.Fatal(err)
+		}
+	})
+	output, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`<gml:name>FaçadeWall</gml:name>\s*<app:diffuseColor>0.5 0.25 1</app:diffuseColor>`).Match(output) {
+		t.Errorf("material not read as UTF-8:\n%s", output)
+	}
+	if !strings.Contains(string(output), "<bldg:WallSurface") || strings.Contains(string(output), "<bldg:RoofSurface") {
+		t.Errorf("the flat face is not a wall by its material:\n%s", output)
+	}
+}
+
+// -only-ids converts only the listed building and reports the others skipped
+func TestOnlyIDs(t *testing.T) {
+	input := t.TempDir()
+	for _, name := range []string{"a.obj", "b.obj", "c.obj"} {
+		if err := os.WriteFile(filepath.Join(input, name), []byte(boxOBJ(1)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	output := t.TempDir()
+	var status int
+	log := captureStdout(t, func() {
+		status = Main("obj2lod2gml", []string{"-input", input, "-output", output, "-only-ids", "b"})
+	})
+	if status != 0 || !strings.Contains(log, "Skipped 2 of 3 OBJ files not listed in -only-ids") {
+		t.Fatalf("obj2lod2gml exited with %d:\n%s", status, log)
+	}
+	entries, err := os.ReadDir(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "b.gml" {
+		t.Errorf("wrote %v, want b.gml", entries)
+	}
+}
+
+// A control character in a material name is dropped from the X3DMaterial's id
+// and name, so the output encodes and parses
+func TestControlCharacterInMaterialName(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"b.obj": "mtllib b.mtl\nv 0 0 5\nv 10 0 5\nv 10 10 5\nv 0 10 5\nusemtl Brick\x01Red\nf 1 2 3 4\n",
+		"b.mtl": "newmtl Brick\x01Red\nKd 0.5 0.25 1\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := testOptions(t)
+	opts.ColorByMaterial = true
+	outputFile := filepath.Join(dir, "b.gml")
+	captureStdout(t, func() {
+		if err := convertOBJToCityGML(filepath.Join(dir, "b.obj"), outputFile, "b", opts); err != nil {
+			t.Fatal(err)
+		}
+	})
+	output, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.ContainsRune(string(output), '\x01') {
+		t.Fatalf("control character written:\n%q", output)
+	}
+	if !regexp.MustCompile(`<app:X3DMaterial gml:id="b_mat_BrickRed">\s*<gml:name>BrickRed</gml:name>`).Match(output) {
+		t.Errorf("material not sanitized:\n%s", output)
+	}
+	parseBuilding(t, output)
+}
+
+// -preserve-coords writes the OBJ's coordinate strings into the positions
+// byte for byte instead of reformatting them
+func TestPreserveCoords(t *testing.T) {
+	obj := "v 692827.46065 9326565.1 12.5\nv 692837.46065 9326565.1 12.5\nv 692837.46065 9326575.1 12.5\nv 692827.46065 9326575.1 12.5\nf 1 2 3 4\n"
+	for preserve, want := range map[bool]string{
+		false: "692827.460650 9326565.100000 12.500000",
+		true:  "692827.46065 9326565.1 12.5",
+	} {
+		opts := testOptions(t)
+		opts.PreserveCoords = preserve
+		if output := convertString(t, obj, opts); !strings.Contains(string(output), "<gml:pos>"+want+"</gml:pos>") {
+			t.Errorf("-preserve-coords=%v: no %q in:\n%s", preserve, want, output)
+		}
+	}
+}
+
+// A 2D "v x y" line is the vertex (x, y, 0), so later vertices keep their
+// indices and the faces using them stay intact
+func TestTwoDimensionalVertex(t *testing.T) {
+	obj := "v 0 0 5\nv 10 0\nv 10 10 5\nv 0 10 5\nf 1 3 4\n"
+	vertices, faces, _, err := parseOBJ(strings.NewReader(obj), "b.obj", testOptions(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vertices) != 4 || vertices[1].X != 10 || vertices[1].Y != 0 || vertices[1].Z != 0 {
+		t.Fatalf("vertices = %+v, want (10, 0, 0) second", vertices)
+	}
+	if len(faces) != 1 || !reflect.DeepEqual(faces[0].VertexIndices, []int{0, 2, 3}) {
+		t.Errorf("faces = %+v", faces)
+	}
+	if got := vertices[faces[0].VertexIndices[1]]; got.X != 10 || got.Y != 10 || got.Z != 5 {
+		t.Errorf("face's second corner = %+v, want (10, 10, 5)", got)
+	}
+}
+
+// -lod2-geometry multisurface puts every face into one building-level
+// lod2MultiSurface instead of boundary surfaces
+func TestLod2GeometryMultiSurface(t *testing.T) {
+	opts := testOptions(t)
+	opts.Lod2Geometry = "multisurface"
+	building := parseBuilding(t, convertString(t, boxOBJ(1), opts))
+
+	if len(building.BoundedBy) != 0 {
+		t.Errorf("%d boundedBy surfaces, want none", len(building.BoundedBy))
+	}
+	if building.Lod2MultiSurface == nil || building.Lod2MultiSurface.MultiSurface == nil {
+		t.Fatal("no lod2MultiSurface")
+	}
+	if n := len(building.Lod2MultiSurface.MultiSurface.SurfaceMember); n != 6 {
+		t.Errorf("lod2MultiSurface has %d polygons, want the box's 6", n)
+	}
+}
+
+// With -canonical two OBJs listing the same faces in another order convert
+// to identical files
+func TestCanonicalIgnoresFaceOrder(t *testing.T) {
+	box := boxOBJ(1)
+	reordered := strings.Replace(box, "f 1 4 3 2\nf 5 6 7 8\nf 1 2 6 5\nf 2 3 7 6\nf 3 4 8 7\nf 4 1 5 8\n",
+		"f 3 4 8 7\nf 1 2 6 5\nf 5 6 7 8\nf 4 1 5 8\nf 1 4 3 2\nf 2 3 7 6\n", 1)
+	if reordered == box {
+		t.Fatal("faces not reordered")
+	}
+
+	opts := testOptions(t)
+	if bytes.Equal(convertString(t, box, opts), convertString(t, reordered, opts)) {
+		t.Fatal("face order does not change the output without -canonical")
+	}
+	opts.Canonical = true
+	if a, b := convertString(t, box, opts), convertString(t, reordered, opts); !bytes.Equal(a, b) {
+		t.Errorf("canonical outputs differ:\n%s\n---\n%s", a, b)
+	}
+}
+
+// Switching usemtl within one object gives each face the material before it,
+// and a usemtl no face follows is reported with its line
+func TestMaterialSwitchWithinObject(t *testing.T) {
+	obj := "o house\nv 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\n" +
+		"usemtl brick\nf 1 2 3\nf 1 3 4\nusemtl glass\nf 1 2 4\nusemtl unused\nusemtl tile\nf 2 3 4\nusemtl trailing\n"
+	var faces []OBJFace
+	var err error
+	log := captureStdout(t, func() {
+		_, faces, _, err = parseOBJ(strings.NewReader(obj), "b.obj", testOptions(t))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var materials []string
+	for _, face := range faces {
+		materials = append(materials, face.Material)
+	}
+	if want := []string{"brick", "brick", "glass", "tile"}; !reflect.DeepEqual(materials, want) {
+		t.Errorf("face materials = %v, want %v", materials, want)
+	}
+	for _, want := range []string{
+		"Warning: b.obj line 11: usemtl unused is not used by any face",
+		"Warning: b.obj line 14: usemtl trailing is not used by any face",
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("missing %q in:\n%s", want, log)
+		}
+	}
+}
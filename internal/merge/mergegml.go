@@ -0,0 +1,542 @@
+package merge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fakmalpradana/OBJ2GML/citygml"
+	"github.com/fakmalpradana/OBJ2GML/internal/fileio"
+	"github.com/fakmalpradana/OBJ2GML/internal/gmlmerge"
+)
+
+// XML namespaces and schema declarations
+const (
+	xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<!-- Merged CityGML File -->
+`
+)
+
+// Main runs mergegml with args, the command line after the program name, and
+// returns its exit status
+func Main(name string, args []string) int {
+	// Parse command-line arguments
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	inputDir := flags.String("input", "", "Directory containing CityGML files")
+	outputFile := flags.String("output", "", "Output merged CityGML file")
+	epsgCode := flags.String("epsg", "32748", "EPSG code for the coordinate reference system")
+	chunkSize := flags.Int("chunk-size", 0, "Split the output into files of at most this many city objects, named like output_0001.gml (0 writes one file)")
+	snap := flags.Float64("snap", 0, "Round posList coordinates to a grid of this size so walls shared by neighboring buildings line up (0 disables)")
+	fixWinding := flags.Bool("fix-winding", false, "Reverse rings that face into their building so all rings wind counter-clockwise from outside")
+	flattenNS := flags.Bool("flatten-namespaces", false, "Emit unprefixed elements with default namespaces for legacy consumers")
+	baselinePath := flags.String("baseline", "", "Previous merged CityGML; buildings are compared with it by id and geometry hash")
+	diffOnly := flags.Bool("diff-only", false, "With -baseline, write only buildings that are new or whose geometry changed")
+	indexPath := flags.String("index", "", "Also write a JSON index of each building's id, output file and posList bounding box")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if *inputDir == "" || *outputFile == "" {
+		fmt.Println("Usage: citygml-merger -input <input_directory> -output <output_file> [-epsg <epsg_code>]")
+		return 0
+	}
+
+	if *snap < 0 {
+		fmt.Println("Error: -snap must not be negative")
+		return 0
+	}
+
+	if *diffOnly && *baselinePath == "" {
+		fmt.Println("Error: -diff-only needs a -baseline")
+		return 0
+	}
+
+	var baseline map[string]string
+	if *baselinePath != "" {
+		var err error
+		baseline, err = readBaseline(*baselinePath)
+		if err != nil {
+			fmt.Printf("Error reading baseline: %v\n", err)
+			return 0
+		}
+		fmt.Printf("Baseline %s has %d buildings\n", filepath.Base(*baselinePath), len(baseline))
+	}
+	changedCount, newCount, unchangedCount, skippedOthers := 0, 0, 0, 0
+
+	// Every parsed building is either emitted or skipped for a reason, checked at the end
+	parsedBuildings := 0
+	skipReasons := make(map[string]int)
+	seenInBaseline := make(map[string]bool)
+
+	// Find all GML files in the input directory
+	gmlFiles, err := filepath.Glob(filepath.Join(*inputDir, "*.gml"))
+	if err != nil {
+		fmt.Printf("Error finding GML files: %v\n", err)
+		return 0
+	}
+
+	// Add XML files as well (some CityGML files might have .xml extension)
+	xmlFiles, err := filepath.Glob(filepath.Join(*inputDir, "*.xml"))
+	if err == nil {
+		gmlFiles = append(gmlFiles, xmlFiles...)
+	}
+
+	fmt.Printf("Found %d CityGML files to merge\n", len(gmlFiles))
+	if len(gmlFiles) == 0 {
+		fmt.Println("No files to merge. Exiting.")
+		return 0
+	}
+
+	// Create output CityGML model with proper namespaces
+	outputModel := citygml.CityModel{
+		GML:            citygml.GMLNamespace,
+		Core:           "http://www.opengis.net/citygml/2.0",
+		Bldg:           "http://www.opengis.net/citygml/building/2.0",
+		App:            "http://www.opengis.net/citygml/appearance/2.0",
+		Gen:            "http://www.opengis.net/citygml/generics/2.0",
+		Grp:            "http://www.opengis.net/citygml/cityobjectgroup/2.0",
+		XLink:          citygml.XLinkNamespace,
+		XSI:            citygml.XSINamespace,
+		SchemaLocation: "http://www.opengis.net/citygml/2.0 http://schemas.opengis.net/citygml/2.0/cityGMLBase.xsd http://www.opengis.net/citygml/building/2.0 http://schemas.opengis.net/citygml/building/2.0/building.xsd",
+		BoundedBy: citygml.BoundedBy{
+			Envelope: citygml.Envelope{
+				SrsName:      fmt.Sprintf("http://www.opengis.net/def/crs/EPSG/0/%s", *epsgCode),
+				SrsDimension: "3",
+				// We'll calculate these values as we process files
+				LowerCorner: "0 0 0",
+				UpperCorner: "0 0 0",
+			},
+		},
+	}
+
+	// Process each CityGML file
+	successCount := 0
+	errorFiles := []string{}
+	buildingCount, otherCount := 0, 0
+
+	// Bounds of each output member, for the merged and -chunk-size envelopes:
+	// a building's own coordinates, or its source file envelope for other
+	// city objects
+	memberBounds := [][6]float64{}
+
+	// gml:ids emitted so far; a.gml and a.xml share the prefix "a"
+	usedIDs := make(map[string]bool)
+	renamedIDs := 0
+	uniqueID := func(id string) string {
+		unique := id
+		for n := 2; usedIDs[unique]; n++ {
+			unique = fmt.Sprintf("%s_%d", id, n)
+		}
+		usedIDs[unique] = true
+		if unique != id {
+			renamedIDs++
+		}
+		return unique
+	}
+
+	for _, gmlFile := range gmlFiles {
+		fmt.Printf("Processing %s...\n", filepath.Base(gmlFile))
+
+		// Read file content
+		fileContent, err := ioutil.ReadFile(gmlFile)
+		if err != nil {
+			fmt.Printf("Error reading file %s: %v\n", filepath.Base(gmlFile), err)
+			errorFiles = append(errorFiles, filepath.Base(gmlFile))
+			continue
+		}
+
+		// Preprocess the XML to handle namespace issues
+		fileContentStr := string(fileContent)
+
+		// Remove namespace prefixes from elements for flexible parsing
+		// This is a simplistic approach - a more robust solution would use a proper XML parser
+		fileContentStr = regexp.MustCompile(`<(/?)(gml|core|bldg):([^>\s]+)`).ReplaceAllString(fileContentStr, "<$1$3")
+
+		// Parse CityGML file with relaxed namespace requirements
+		var cityModel citygml.InputCityModel
+		err = xml.Unmarshal([]byte(fileContentStr), &cityModel)
+		if err != nil {
+			fmt.Printf("Error parsing CityGML file %s: %v\n", filepath.Base(gmlFile), err)
+			errorFiles = append(errorFiles, filepath.Base(gmlFile))
+			continue
+		}
+
+		// Other city objects are bounded by their file's envelope, if it has one
+		fileBounds, err := gmlmerge.EnvelopeBounds(cityModel.BoundedBy)
+		if err != nil {
+			fmt.Printf("Warning: Could not read the envelope of %s: %v\n", filepath.Base(gmlFile), err)
+		}
+
+		// Convert to output model format with proper namespaces
+		fileBaseName := strings.TrimSuffix(filepath.Base(gmlFile), filepath.Ext(gmlFile))
+
+		// Other city objects are copied verbatim from the original content
+		rawMembers, namespaces, err := citygml.RawCityObjectMembers(fileContent)
+		if err == nil && len(rawMembers) != len(cityModel.CityObjectMember) {
+			err = fmt.Errorf("found %d members, expected %d", len(rawMembers), len(cityModel.CityObjectMember))
+		}
+		if err != nil {
+			fmt.Printf("Warning: Could not read non-building members of %s: %v\n", filepath.Base(gmlFile), err)
+			rawMembers = nil
+		}
+
+		// Add city objects to merged model
+		for i, cityObjectMember := range cityModel.CityObjectMember {
+			if cityObjectMember.Building == nil {
+				if *diffOnly {
+					skippedOthers++
+					continue
+				}
+				if rawMembers != nil {
+					outputModel.CityObjectMember = append(outputModel.CityObjectMember, citygml.CityObjectMember{Raw: rawMembers[i]})
+					memberBounds = append(memberBounds, fileBounds)
+					outputModel.Namespaces = citygml.AddNamespaces(outputModel.Namespaces, namespaces)
+					otherCount++
+				}
+				continue
+			}
+			parsedBuildings++
+			if reason := incompleteBuilding(cityObjectMember.Building); reason != "" {
+				fmt.Printf("Warning: Building %s in %s has incomplete structure (%s), skipping\n",
+					cityObjectMember.Building.ID, filepath.Base(gmlFile), reason)
+				skipReasons["incomplete structure: "+reason]++
+				continue
+			}
+
+			// Create output building with proper namespaces
+			renamedBefore := renamedIDs
+			outputBuilding := citygml.Building{
+				ID:                 uniqueID(fmt.Sprintf("%s_%s", fileBaseName, cityObjectMember.Building.ID)),
+				GenericAttributes:  citygml.GenericAttributes(cityObjectMember.Building.GenericAttributes),
+				YearOfConstruction: cityObjectMember.Building.YearOfConstruction,
+				RoofType:           cityObjectMember.Building.RoofType,
+				MeasuredHeight:     cityObjectMember.Building.MeasuredHeight,
+				Lod1Solid: &citygml.SolidProperty{
+					Solid: citygml.Solid{
+						ID: uniqueID(fmt.Sprintf("%s_%s", fileBaseName, cityObjectMember.Building.Lod1Solid.Solid.ID)),
+					},
+				},
+			}
+
+			// Copy surface members with proper namespaces
+			for _, surfaceMember := range cityObjectMember.Building.Lod1Solid.Members() {
+				ring := surfaceMember.Ring()
+				if ring == nil {
+					continue
+				}
+
+				outputSurfaceMember := citygml.SurfaceMember{
+					Polygon: &citygml.Polygon{
+						ID: uniqueID(fmt.Sprintf("%s_%s", fileBaseName, surfaceMember.Polygon.ID)),
+						Exterior: citygml.PolygonExterior{
+							LinearRing: citygml.LinearRing{
+								PosList: ring.Coordinates(),
+							},
+						},
+					},
+				}
+
+				outputBuilding.Lod1Solid.Solid.Exterior.CompositeSurface.SurfaceMember = append(
+					outputBuilding.Lod1Solid.Solid.Exterior.CompositeSurface.SurfaceMember, outputSurfaceMember)
+			}
+
+			if renamedIDs > renamedBefore {
+				fmt.Printf("Warning: %d gml:ids of building %s in %s were already used, added numeric suffixes\n",
+					renamedIDs-renamedBefore, outputBuilding.ID, filepath.Base(gmlFile))
+			}
+
+			if *snap > 0 {
+				if collapsed := gmlmerge.SnapBuilding(&outputBuilding, *snap); collapsed > 0 {
+					fmt.Printf("Warning: -snap collapsed %d rings of building %s in %s, removed them\n",
+						collapsed, outputBuilding.ID, filepath.Base(gmlFile))
+				}
+				if len(outputBuilding.Lod1Solid.Solid.Exterior.CompositeSurface.SurfaceMember) == 0 {
+					fmt.Printf("Warning: Building %s in %s has no rings left after -snap, skipping\n",
+						outputBuilding.ID, filepath.Base(gmlFile))
+					skipReasons["every ring collapsed by -snap"]++
+					continue
+				}
+			}
+			if *fixWinding {
+				if fixed := gmlmerge.FixBuildingWinding(&outputBuilding); fixed > 0 {
+					fmt.Printf("Fixed winding of %d rings in building %s\n", fixed, outputBuilding.ID)
+				}
+			}
+
+			if baseline != nil {
+				hash, found := baseline[outputBuilding.ID]
+				switch {
+				case !found:
+					newCount++
+				case hash != geometryHash(outputPosLists(&outputBuilding)):
+					changedCount++
+					seenInBaseline[outputBuilding.ID] = true
+				default:
+					unchangedCount++
+					seenInBaseline[outputBuilding.ID] = true
+					if *diffOnly {
+						skipReasons["unchanged from the baseline (-diff-only)"]++
+						continue
+					}
+				}
+			}
+
+			// Add to output model
+			outputModel.CityObjectMember = append(outputModel.CityObjectMember, citygml.CityObjectMember{
+				Building: &outputBuilding,
+			})
+			bounds, ok := gmlmerge.BuildingBounds(&outputBuilding)
+			if !ok {
+				bounds = fileBounds
+			}
+			memberBounds = append(memberBounds, bounds)
+			buildingCount++
+		}
+
+		successCount++
+	}
+
+	// The merged envelope covers what was written, so a delta only covers its buildings
+	if bounds, ok := gmlmerge.UnionBounds(memberBounds); ok {
+		gmlmerge.SetEnvelope(&outputModel.BoundedBy.Envelope, bounds)
+	} else {
+		fmt.Println("Warning: no coordinates or envelopes to bound the merged model, writing a 0 0 0 envelope")
+	}
+
+	// Write one file, or standalone chunks each with the envelope of its own members
+	index := spatialIndex{SrsName: outputModel.BoundedBy.Envelope.SrsName, Buildings: []indexEntry{}}
+	chunkCount := 0
+	if *chunkSize > 0 {
+		members := outputModel.CityObjectMember
+		for start := 0; start < len(members); start += *chunkSize {
+			end := min(start+*chunkSize, len(members))
+			chunk := outputModel
+			chunk.CityObjectMember = members[start:end]
+			// A chunk with nothing to bound keeps the merged envelope
+			if bounds, ok := gmlmerge.UnionBounds(memberBounds[start:end]); ok {
+				gmlmerge.SetEnvelope(&chunk.BoundedBy.Envelope, bounds)
+			}
+
+			chunkCount++
+			path := gmlmerge.ChunkPath(*outputFile, chunkCount)
+			if err := writeMergedModel(chunk, path, *flattenNS); err != nil {
+				fmt.Printf("Error writing chunk %s: %v\n", path, err)
+				return 0
+			}
+			fmt.Printf("Wrote %d city objects to %s\n", end-start, path)
+			index = appendIndex(index, chunk.CityObjectMember, path)
+		}
+	} else if err := writeMergedModel(outputModel, *outputFile, *flattenNS); err != nil {
+		fmt.Printf("Error writing output file: %v\n", err)
+		return 0
+	} else {
+		index = appendIndex(index, outputModel.CityObjectMember, *outputFile)
+	}
+
+	if *indexPath != "" {
+		if err := writeIndex(*indexPath, index); err != nil {
+			fmt.Printf("Error writing index: %v\n", err)
+			return 0
+		}
+		fmt.Printf("Spatial index of %d buildings written to: %s\n", len(index.Buildings), *indexPath)
+	}
+
+	// Print summary
+	fmt.Printf("Successfully merged %d from %d CityGML files\n", successCount, len(gmlFiles))
+	if len(errorFiles) > 0 {
+		fmt.Printf("Failed to process %d files: %v\n", len(errorFiles), errorFiles)
+	}
+	if *chunkSize > 0 {
+		fmt.Printf("Merged CityGML written to %d files of at most %d city objects\n", chunkCount, *chunkSize)
+	} else {
+		fmt.Printf("Merged CityGML file written to: %s\n", *outputFile)
+	}
+	fmt.Printf("Bounding box: [%s] to [%s]\n", outputModel.BoundedBy.Envelope.LowerCorner, outputModel.BoundedBy.Envelope.UpperCorner)
+	fmt.Printf("Total buildings: %d\n", buildingCount)
+	if renamedIDs > 0 {
+		fmt.Printf("Duplicate gml:ids made unique: %d\n", renamedIDs)
+	}
+	if baseline != nil {
+		fmt.Printf("Compared with baseline: %d changed, %d new, %d unchanged, %d no longer in the input\n",
+			changedCount, newCount, unchangedCount, len(baseline)-len(seenInBaseline))
+		if skippedOthers > 0 {
+			fmt.Printf("Left out %d non-building city objects, -diff-only compares buildings only\n", skippedOthers)
+		}
+	}
+	if otherCount > 0 {
+		fmt.Printf("Other city objects passed through: %d\n", otherCount)
+	}
+
+	// Reconcile the building counts: parsed = emitted + skipped
+	emitted := 0
+	for _, member := range outputModel.CityObjectMember {
+		if member.Building != nil {
+			emitted++
+		}
+	}
+	skippedTotal := 0
+	reasons := make([]string, 0, len(skipReasons))
+	for reason, count := range skipReasons {
+		skippedTotal += count
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	fmt.Println("Building reconciliation:")
+	fmt.Printf("  parsed:  %d\n", parsedBuildings)
+	for _, reason := range reasons {
+		fmt.Printf("  skipped: %d %s\n", skipReasons[reason], reason)
+	}
+	fmt.Printf("  emitted: %d\n", emitted)
+	if parsedBuildings != emitted+skippedTotal || emitted != buildingCount {
+		fmt.Printf("Error: building counts do not reconcile: %d parsed != %d emitted + %d skipped\n",
+			parsedBuildings, emitted, skippedTotal)
+		return 1
+	}
+	fmt.Printf("  check:   %d parsed = %d emitted + %d skipped\n", parsedBuildings, emitted, skippedTotal)
+	return 0
+}
+
+// incompleteBuilding names the first missing part of a building's LOD1 solid,
+// or returns "" when the building can be merged
+func incompleteBuilding(building *citygml.InputBuilding) string {
+	switch {
+	case building.Lod1Solid == nil:
+		return "no lod1Solid"
+	case building.Lod1Solid.Solid == nil:
+		return "no Solid"
+	case building.Lod1Solid.Solid.Exterior == nil:
+		return "no Solid exterior"
+	case building.Lod1Solid.Solid.Exterior.CompositeSurface == nil:
+		return "no CompositeSurface"
+	}
+	return ""
+}
+
+// writeMergedModel marshals a merged model and writes it with the XML header
+func writeMergedModel(model citygml.CityModel, path string, flatten bool) error {
+	output, err := xml.MarshalIndent(model, "", "  ")
+	if err != nil {
+		return fmt.Errorf("generating merged XML: %v", err)
+	}
+
+	if flatten {
+		if output, err = citygml.FlattenNamespaces(output); err != nil {
+			return fmt.Errorf("flattening namespaces: %v", err)
+		}
+	}
+
+	return fileio.WriteFile(path, []byte(xmlHeader+string(output)))
+}
+
+// readBaseline reads a previous merged CityGML and returns the geometry hash of
+// each building by gml:id
+func readBaseline(path string) (map[string]string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	content = regexp.MustCompile(`<(/?)(gml|core|bldg):([^>\s]+)`).ReplaceAll(content, []byte("<$1$3"))
+
+	var model citygml.InputCityModel
+	if err := xml.Unmarshal(content, &model); err != nil {
+		return nil, err
+	}
+
+	hashes := make(map[string]string)
+	for _, member := range model.CityObjectMember {
+		building := member.Building
+		if building == nil {
+			continue
+		}
+		var posLists []string
+		for _, surfaceMember := range building.Lod1Solid.Members() {
+			if ring := surfaceMember.Ring(); ring != nil {
+				posLists = append(posLists, ring.Coordinates())
+			}
+		}
+		hashes[building.ID] = geometryHash(posLists)
+	}
+	return hashes, nil
+}
+
+// outputPosLists returns the posList of every polygon of a merged building
+func outputPosLists(building *citygml.Building) []string {
+	var posLists []string
+	building.EachPolygon(func(surfaceType string, polygon *citygml.Polygon) {
+		posLists = append(posLists, polygon.Exterior.LinearRing.Coordinates())
+	})
+	return posLists
+}
+
+// geometryHash hashes a building's rings regardless of their order and of the
+// whitespace in the posLists, so only a geometry change alters it
+func geometryHash(posLists []string) string {
+	normalized := make([]string, len(posLists))
+	for i, posList := range posLists {
+		normalized[i] = strings.Join(strings.Fields(posList), " ")
+	}
+	sort.Strings(normalized)
+	sum := sha256.Sum256([]byte(strings.Join(normalized, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// spatialIndex is the -index sidecar, letting consumers find the buildings in
+// an extent without parsing the merged GML
+type spatialIndex struct {
+	SrsName   string       `json:"srsName"`
+	Buildings []indexEntry `json:"buildings"`
+}
+
+// indexEntry locates one building: bbox is minX minY minZ maxX maxY maxZ
+type indexEntry struct {
+	ID   string     `json:"id"`
+	File string     `json:"file"`
+	BBox [6]float64 `json:"bbox"`
+}
+
+// appendIndex adds the buildings of members written to file to the index
+func appendIndex(index spatialIndex, members []citygml.CityObjectMember, file string) spatialIndex {
+	for _, member := range members {
+		if member.Building == nil {
+			continue
+		}
+		bounds, ok := gmlmerge.BuildingBounds(member.Building)
+		if !ok {
+			fmt.Printf("Warning: building %s has no coordinates, left out of the index\n", member.Building.ID)
+			continue
+		}
+		index.Buildings = append(index.Buildings, indexEntry{
+			ID:   member.Building.ID,
+			File: filepath.Base(file),
+			BBox: bounds,
+		})
+	}
+	return index
+}
+
+// writeIndex writes the spatial index as JSON
+func writeIndex(path string, index spatialIndex) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileio.WriteFile(path, data)
+}
+
+// // Helper function for string to float conversion
+// func strconv.ParseFloat(s string, bitSize int) (float64, error) {
+// 	// Implementation not shown - use the standard library
+// 	return 0, nil
+// }
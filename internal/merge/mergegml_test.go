@@ -0,0 +1,331 @@
+package merge
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/fakmalpradana/OBJ2GML/internal/gmlmerge"
+)
+
+// runMain runs mergegml with args and returns its exit status and everything
+// it wrote to stdout and stderr
+func runMain(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+
+	status := Main("mergegml", args)
+	os.Stdout, os.Stderr = stdout, stderr
+	w.Close()
+	return status, <-output
+}
+
+// lod1GML is a LOD1 file with one building per ring, each a solid with that
+// single polygon, and the given boundedBy element
+func lod1GML(boundedBy string, rings ...string) string {
+	var members strings.Builder
+	for i, ring := range rings {
+		fmt.Fprintf(&members, `<core:cityObjectMember><bldg:Building gml:id="b%d"><bldg:lod1Solid><gml:Solid gml:id="s%d"><gml:exterior><gml:CompositeSurface>`+
+			`<gml:surfaceMember><gml:Polygon gml:id="p%d"><gml:exterior><gml:LinearRing><gml:posList>%s</gml:posList></gml:LinearRing></gml:exterior></gml:Polygon></gml:surfaceMember>`+
+			`</gml:CompositeSurface></gml:exterior></gml:Solid></bldg:lod1Solid></bldg:Building></core:cityObjectMember>`+"\n", i, i, i, ring)
+	}
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<core:CityModel xmlns:core="http://www.opengis.net/citygml/2.0" xmlns:bldg="http://www.opengis.net/citygml/building/2.0" xmlns:gml="http://www.opengis.net/gml">
+` + boundedBy + "\n" + members.String() + "</core:CityModel>\n"
+}
+
+// square is a closed posList of a size x size square at x, y and height z
+func square(x, y, z, size float64) string {
+	return fmt.Sprintf("%g %g %g %g %g %g %g %g %g %g %g %g %g %g %g",
+		x, y, z, x+size, y, z, x+size, y+size, z, x, y+size, z, x, y, z)
+}
+
+var cornersPattern = regexp.MustCompile(`<gml:lowerCorner>([^<]*)</gml:lowerCorner>\s*<gml:upperCorner>([^<]*)</gml:upperCorner>`)
+
+// envelopeCorners returns the lower and upper corner of a file's envelope
+func envelopeCorners(t *testing.T, path string) string {
+	t.Helper()
+	gml, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := cornersPattern.FindSubmatch(gml)
+	if match == nil {
+		t.Fatalf("%s has no envelope", path)
+	}
+	return string(match[1]) + " / " + string(match[2])
+}
+
+// The merged and chunk envelopes come from the buildings' coordinates, so a
+// file without an envelope, or with one that is off, still bounds correctly
+func TestEnvelopesFromBuildingCoordinates(t *testing.T) {
+	input := t.TempDir()
+	files := map[string]string{
+		// An envelope far larger than the building
+		"a.gml": lod1GML(`<gml:boundedBy><gml:Envelope><gml:lowerCorner>-1000 -1000 -1000</gml:lowerCorner><gml:upperCorner>1000 1000 1000</gml:upperCorner></gml:Envelope></gml:boundedBy>`,
+			square(0, 0, 0, 1)),
+		"b.gml": lod1GML("", square(10, 20, 5, 2)),
+		"c.gml": lod1GML(`<gml:boundedBy><gml:Envelope><gml:lowerCorner>x</gml:lowerCorner><gml:upperCorner>1 1 1</gml:upperCorner></gml:Envelope></gml:boundedBy>`,
+			square(-5, -5, 2, 1)),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(input, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	output := filepath.Join(t.TempDir(), "merged.gml")
+
+	status, log := runMain(t, "-input", input, "-output", output, "-chunk-size", "1")
+	if status != 0 {
+		t.Fatalf("mergegml exited with %d:\n%s", status, log)
+	}
+	if !strings.Contains(log, "Warning: Could not read the envelope of c.gml: lowerCorner") {
+		t.Errorf("bad envelope of c.gml not reported:\n%s", log)
+	}
+	for chunk, want := range []string{
+		"0.000000 0.000000 0.000000 / 1.000000 1.000000 0.000000",
+		"10.000000 20.000000 5.000000 / 12.000000 22.000000 5.000000",
+		"-5.000000 -5.000000 2.000000 / -4.000000 -4.000000 2.000000",
+	} {
+		if got := envelopeCorners(t, gmlmerge.ChunkPath(output, chunk+1)); got != want {
+			t.Errorf("chunk %d envelope = %s, want its building's bounds %s", chunk+1, got, want)
+		}
+	}
+	if !strings.Contains(log, "Bounding box: [-5.000000 -5.000000 0.000000] to [12.000000 22.000000 5.000000]") {
+		t.Errorf("merged envelope is not the buildings' bounds:\n%s", log)
+	}
+	if strings.Contains(log, "Inf") {
+		t.Errorf("infinite bounds written:\n%s", log)
+	}
+}
+
+// -snap removes rings it collapses and skips buildings left without any, and
+// the envelope follows the snapped coordinates
+func TestSnapDropsCollapsedRings(t *testing.T) {
+	input := t.TempDir()
+	content := lod1GML("", square(0.4, 0.4, 0.4, 4.8), square(10.1, 10.1, 0, 0.2))
+	if err := os.WriteFile(filepath.Join(input, "a.gml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "merged.gml")
+
+	status, log := runMain(t, "-input", input, "-output", output, "-snap", "1")
+	if status != 0 {
+		t.Fatalf("mergegml exited with %d:\n%s", status, log)
+	}
+	for _, want := range []string{
+		"Warning: -snap collapsed 1 rings of building a_b1 in a.gml, removed them",
+		"Warning: Building a_b1 in a.gml has no rings left after -snap, skipping",
+		"skipped: 1 every ring collapsed by -snap",
+		"check:   2 parsed = 1 emitted + 1 skipped",
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log lacks %q:\n%s", want, log)
+		}
+	}
+	if got, want := envelopeCorners(t, output), "0.000000 0.000000 0.000000 / 5.000000 5.000000 0.000000"; got != want {
+		t.Errorf("envelope = %s, want the snapped %s", got, want)
+	}
+}
+
+// With -baseline and -diff-only only the building whose geometry changed
+// since the baseline is written
+func TestDiffOnlyWritesChangedBuildings(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("before/tile.gml", lod1GML("", square(0, 0, 0, 1), square(10, 0, 0, 1)))
+	write("after/tile.gml", lod1GML("", square(0, 0, 0, 1), square(10, 0, 0, 2)))
+	baseline := filepath.Join(dir, "baseline.gml")
+	if status, log := runMain(t, "-input", filepath.Join(dir, "before"), "-output", baseline); status != 0 {
+		t.Fatalf("merging the baseline exited with %d:\n%s", status, log)
+	}
+
+	output := filepath.Join(dir, "delta.gml")
+	status, log := runMain(t, "-input", filepath.Join(dir, "after"), "-output", output, "-baseline", baseline, "-diff-only")
+	if status != 0 {
+		t.Fatalf("mergegml exited with %d:\n%s", status, log)
+	}
+	if !strings.Contains(log, "Compared with baseline: 1 changed, 0 new, 1 unchanged, 0 no longer in the input") {
+		t.Errorf("unexpected comparison:\n%s", log)
+	}
+	gml, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(gml), "<bldg:Building ") != 1 || !strings.Contains(string(gml), `gml:id="tile_b1"`) {
+		t.Errorf("delta should hold only tile_b1:\n%s", gml)
+	}
+}
+
+// A building without a solid is counted as skipped, with its reason, and the
+// report checks parsed = emitted + skipped
+func TestReconciliationReport(t *testing.T) {
+	input := t.TempDir()
+	gml := strings.Replace(lod1GML("", square(0, 0, 0, 1), square(5, 0, 0, 1)), "</core:CityModel>",
+		`<core:cityObjectMember><bldg:Building gml:id="empty"></bldg:Building></core:cityObjectMember>`+"\n</core:CityModel>", 1)
+	if err := os.WriteFile(filepath.Join(input, "tile.gml"), []byte(gml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	status, log := runMain(t, "-input", input, "-output", filepath.Join(t.TempDir(), "merged.gml"))
+	if status != 0 {
+		t.Fatalf("mergegml exited with %d:\n%s", status, log)
+	}
+	want := "Building reconciliation:\n" +
+		"  parsed:  3\n" +
+		"  skipped: 1 incomplete structure: no lod1Solid\n" +
+		"  emitted: 2\n" +
+		"  check:   3 parsed = 2 emitted + 1 skipped\n"
+	if !strings.Contains(log, want) {
+		t.Errorf("report lacks\n%s\nin:\n%s", want, log)
+	}
+}
+
+// A LOD1 building's string and measure attributes survive the merge in their
+// original order
+func TestMergeKeepsGenericAttributes(t *testing.T) {
+	input := t.TempDir()
+	attributes := `<gen:stringAttribute name="ConstructionMethod"><gen:value>Concrete</gen:value></gen:stringAttribute>` +
+		`<gen:measureAttribute name="GrossPlannedArea"><gen:value uom="m2">120.00</gen:value></gen:measureAttribute>` +
+		`<gen:stringAttribute name="Usage"><gen:value>office</gen:value></gen:stringAttribute>`
+	gml := strings.Replace(lod1GML("", square(0, 0, 0, 1)), `<bldg:Building gml:id="b0">`, `<bldg:Building gml:id="b0">`+attributes, 1)
+	gml = strings.Replace(gml, `xmlns:gml=`, `xmlns:gen="http://www.opengis.net/citygml/generics/2.0" xmlns:gml=`, 1)
+	if err := os.WriteFile(filepath.Join(input, "a.gml"), []byte(gml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "merged.gml")
+
+	if status, log := runMain(t, "-input", input, "-output", output); status != 0 {
+		t.Fatalf("mergegml = %d:\n%s", status, log)
+	}
+	merged, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pattern := regexp.MustCompile(`<gen:(?:string|measure)Attribute name="([^"]*)">\s*<gen:value[^>]*>([^<]*)</gen:value>`)
+	var got []string
+	for _, match := range pattern.FindAllStringSubmatch(string(merged), -1) {
+		got = append(got, match[1]+"="+match[2])
+	}
+	if want := "ConstructionMethod=Concrete GrossPlannedArea=120.00 Usage=office"; strings.Join(got, " ") != want {
+		t.Errorf("merged attributes = %q, want %s\n%s", got, want, merged)
+	}
+}
+
+// vegetationMember is a non-building cityObjectMember the mergers know nothing about
+const vegetationMember = `<veg:SolitaryVegetationObject gml:id="tree1"><veg:species>Ficus</veg:species><veg:height uom="m">7.5</veg:height></veg:SolitaryVegetationObject>`
+
+// A vegetation object is passed through verbatim, next to the merged and
+// prefixed building
+func TestMergeKeepsNonBuildingMembers(t *testing.T) {
+	input := t.TempDir()
+	gml := strings.Replace(lod1GML("", square(0, 0, 0, 1)), "</core:CityModel>", "<core:cityObjectMember>"+vegetationMember+"</core:cityObjectMember>\n</core:CityModel>", 1)
+	gml = strings.Replace(gml, `xmlns:gml=`, `xmlns:veg="http://www.opengis.net/citygml/vegetation/2.0" xmlns:gml=`, 1)
+	if err := os.WriteFile(filepath.Join(input, "a.gml"), []byte(gml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "merged.gml")
+
+	if status, log := runMain(t, "-input", input, "-output", output); status != 0 {
+		t.Fatalf("mergegml = %d:\n%s", status, log)
+	}
+	merged, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(merged), "<core:cityObjectMember>"+vegetationMember+"</core:cityObjectMember>") {
+		t.Errorf("vegetation object not passed through:\n%s", merged)
+	}
+	if !strings.Contains(string(merged), `xmlns:veg="http://www.opengis.net/citygml/vegetation/2.0"`) || !strings.Contains(string(merged), `<bldg:Building gml:id="a_b0">`) {
+		t.Errorf("namespace or prefixed building missing:\n%s", merged)
+	}
+}
+
+// Files sharing a base name prefix their ids the same way, so the second
+// file's ids get numeric suffixes and every gml:id stays unique
+func TestMergeSuffixesDuplicateIDs(t *testing.T) {
+	input := t.TempDir()
+	for _, name := range []string{"a.gml", "a.xml"} {
+		if err := os.WriteFile(filepath.Join(input, name), []byte(lod1GML("", square(0, 0, 0, 1), square(5, 5, 0, 1))), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	output := filepath.Join(t.TempDir(), "merged.gml")
+
+	status, log := runMain(t, "-input", input, "-output", output)
+	if status != 0 {
+		t.Fatalf("mergegml exited with %d:\n%s", status, log)
+	}
+	gml, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, match := range regexp.MustCompile(`gml:id="([^"]*)"`).FindAllSubmatch(gml, -1) {
+		if seen[string(match[1])] {
+			t.Errorf("gml:id %s written twice", match[1])
+		}
+		seen[string(match[1])] = true
+	}
+	if len(seen) != 12 {
+		t.Errorf("merged file has %d gml:ids, want 4 buildings, 4 solids and 4 polygons", len(seen))
+	}
+	if !seen["a_b0_2"] || strings.Count(log, "were already used, added numeric suffixes") != 2 {
+		t.Errorf("collisions not suffixed and reported:\n%s", log)
+	}
+}
+
+// -index lists every building with the output file holding it and the bounds
+// of its coordinates
+func TestSpatialIndex(t *testing.T) {
+	input := t.TempDir()
+	if err := os.WriteFile(filepath.Join(input, "a.gml"), []byte(lod1GML("", square(0, 0, 0, 1), square(10, 20, 5, 2))), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "merged.gml")
+	indexPath := filepath.Join(t.TempDir(), "index.json")
+
+	status, log := runMain(t, "-input", input, "-output", output, "-index", indexPath)
+	if status != 0 {
+		t.Fatalf("mergegml exited with %d:\n%s", status, log)
+	}
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index spatialIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatalf("index is not JSON: %v\n%s", err, data)
+	}
+	want := []indexEntry{
+		{ID: "a_b0", File: "merged.gml", BBox: [6]float64{0, 0, 0, 1, 1, 0}},
+		{ID: "a_b1", File: "merged.gml", BBox: [6]float64{10, 20, 5, 12, 22, 5}},
+	}
+	if fmt.Sprint(index.Buildings) != fmt.Sprint(want) {
+		t.Errorf("index buildings = %v, want %v", index.Buildings, want)
+	}
+}
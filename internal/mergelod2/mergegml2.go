@@ -0,0 +1,224 @@
+package mergelod2
+
+import (
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+
+	"github.com/fakmalpradana/OBJ2GML/citygml"
+	"github.com/fakmalpradana/OBJ2GML/internal/fileio"
+	"github.com/fakmalpradana/OBJ2GML/internal/gmlmerge"
+)
+
+// setSourceFile records the input filename as a SourceFile attribute, replacing
+// any value carried over from an earlier merge
+func setSourceFile(attributes []citygml.GenericAttribute, sourceFile string) []citygml.GenericAttribute {
+	attribute := citygml.GenericAttribute{
+		XMLName: xml.Name{Local: "gen:stringAttribute"},
+		Name:    "SourceFile",
+		Value:   citygml.Measure{Value: sourceFile},
+	}
+	for i := range attributes {
+		if attributes[i].Name == "SourceFile" {
+			attributes[i] = attribute
+			return attributes
+		}
+	}
+	return append(attributes, attribute)
+}
+
+// Main runs mergegml2 with args, the command line after the program name, and
+// returns its exit status
+func Main(name string, args []string) int {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	inputDir := flags.String("input", "", "Directory containing CityGML files")
+	outputFile := flags.String("output", "", "Output merged CityGML file")
+	epsgCode := flags.String("epsg", "32748", "EPSG code for the coordinate reference system")
+	chunkSize := flags.Int("chunk-size", 0, "Split the output into files of at most this many city objects, named like output_0001.gml (0 writes one file)")
+	tagSource := flags.Bool("tag-source", false, "Add a SourceFile string attribute with the input filename to each building")
+	snap := flags.Float64("snap", 0, "Round posList coordinates to a grid of this size so walls shared by neighboring buildings line up (0 disables)")
+	fixWinding := flags.Bool("fix-winding", false, "Reverse rings that face into their building so all rings wind counter-clockwise from outside")
+	flattenNS := flags.Bool("flatten-namespaces", false, "Emit unprefixed elements with default namespaces for legacy consumers")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if *inputDir == "" || *outputFile == "" {
+		fmt.Println("Usage: citygml-merger -input <input_directory> -output <output_file> [-epsg <epsg_code>]")
+		return 0
+	}
+
+	if *snap < 0 {
+		fmt.Println("Error: -snap must not be negative")
+		return 0
+	}
+
+	gmlFiles, _ := filepath.Glob(filepath.Join(*inputDir, "*.gml"))
+	xmlFiles, _ := filepath.Glob(filepath.Join(*inputDir, "*.xml"))
+	gmlFiles = append(gmlFiles, xmlFiles...)
+	if len(gmlFiles) == 0 {
+		fmt.Println("No files to merge. Exiting.")
+		return 0
+	}
+
+	outputModel := citygml.CityModel{
+		GML:            citygml.GMLNamespace,
+		Core:           "http://www.opengis.net/citygml/2.0",
+		Bldg:           "http://www.opengis.net/citygml/building/2.0",
+		App:            "http://www.opengis.net/citygml/appearance/2.0",
+		Gen:            "http://www.opengis.net/citygml/generics/2.0",
+		Grp:            "http://www.opengis.net/citygml/cityobjectgroup/2.0",
+		XLink:          citygml.XLinkNamespace,
+		XSI:            citygml.XSINamespace,
+		SchemaLocation: "http://www.opengis.net/citygml/2.0 http://schemas.opengis.net/citygml/2.0/cityGMLBase.xsd http://www.opengis.net/citygml/building/2.0 http://schemas.opengis.net/citygml/building/2.0/building.xsd",
+		BoundedBy: citygml.BoundedBy{
+			Envelope: citygml.Envelope{
+				SrsName:      fmt.Sprintf("urn:ogc:def:crs:EPSG::%s", *epsgCode),
+				SrsDimension: "3",
+				LowerCorner:  "0 0 0",
+				UpperCorner:  "0 0 0",
+			},
+		},
+	}
+
+	// Bounds of each output member, for the merged and -chunk-size envelopes:
+	// a building's own coordinates, or its source file envelope for other
+	// city objects
+	memberBounds := [][6]float64{}
+
+	for _, gmlFile := range gmlFiles {
+		fileContent, err := ioutil.ReadFile(gmlFile)
+		if err != nil {
+			fmt.Printf("Error reading file %s: %v\n", gmlFile, err)
+			continue
+		}
+		fileContentStr := string(fileContent)
+		// Remove namespace prefixes for easier parsing
+		fileContentStr = regexp.MustCompile(`<(/?)(gml|core|bldg|app):`).ReplaceAllString(fileContentStr, "<$1")
+		var cityModel citygml.InputCityModel
+		if err := xml.Unmarshal([]byte(fileContentStr), &cityModel); err != nil {
+			fmt.Printf("Error parsing file %s: %v\n", gmlFile, err)
+			continue
+		}
+		// Other city objects are bounded by their file's envelope, if it has one
+		fileBounds, err := gmlmerge.EnvelopeBounds(cityModel.BoundedBy)
+		if err != nil {
+			fmt.Printf("Warning: Could not read the envelope of %s: %v\n", gmlFile, err)
+		}
+
+		// Other city objects are copied verbatim from the original content
+		rawMembers, namespaces, err := citygml.RawCityObjectMembers(fileContent)
+		if err == nil && len(rawMembers) != len(cityModel.CityObjectMember) {
+			err = fmt.Errorf("found %d members, expected %d", len(rawMembers), len(cityModel.CityObjectMember))
+		}
+		if err != nil {
+			fmt.Printf("Warning: Could not read non-building members of %s: %v\n", gmlFile, err)
+			rawMembers = nil
+		}
+
+		for i, com := range cityModel.CityObjectMember {
+			if com.Building == nil {
+				if rawMembers != nil {
+					outputModel.CityObjectMember = append(outputModel.CityObjectMember, citygml.CityObjectMember{Raw: rawMembers[i]})
+					memberBounds = append(memberBounds, fileBounds)
+					outputModel.Namespaces = citygml.AddNamespaces(outputModel.Namespaces, namespaces)
+				}
+				continue
+			}
+			outB := com.Building.Output()
+			if *tagSource {
+				outB.GenericAttributes = setSourceFile(outB.GenericAttributes, filepath.Base(gmlFile))
+			}
+			// Rings are written as posLists, which snapping and winding fixes edit
+			outB.EachPolygon(func(surfaceType string, polygon *citygml.Polygon) {
+				ring := &polygon.Exterior.LinearRing
+				ring.PosList, ring.Pos = ring.Coordinates(), nil
+			})
+			if *snap > 0 {
+				if collapsed := gmlmerge.SnapBuilding(&outB, *snap); collapsed > 0 {
+					fmt.Printf("Warning: -snap collapsed %d rings of building %s in %s, removed them\n", collapsed, outB.ID, gmlFile)
+				}
+				rings := 0
+				outB.EachPolygon(func(surfaceType string, polygon *citygml.Polygon) { rings++ })
+				if rings == 0 {
+					fmt.Printf("Warning: Building %s in %s has no rings left after -snap, skipping\n", outB.ID, gmlFile)
+					continue
+				}
+			}
+			if *fixWinding {
+				if fixed := gmlmerge.FixBuildingWinding(&outB); fixed > 0 {
+					fmt.Printf("Fixed winding of %d rings in building %s\n", fixed, outB.ID)
+				}
+			}
+			outputModel.CityObjectMember = append(outputModel.CityObjectMember, citygml.CityObjectMember{Building: &outB})
+			bounds, ok := gmlmerge.BuildingBounds(&outB)
+			if !ok {
+				bounds = fileBounds
+			}
+			memberBounds = append(memberBounds, bounds)
+		}
+	}
+
+	if bounds, ok := gmlmerge.UnionBounds(memberBounds); ok {
+		gmlmerge.SetEnvelope(&outputModel.BoundedBy.Envelope, bounds)
+	} else {
+		fmt.Println("Warning: no coordinates or envelopes to bound the merged model, writing a 0 0 0 envelope")
+	}
+
+	// Write one file, or standalone chunks each with the envelope of its own members
+	if *chunkSize > 0 {
+		members := outputModel.CityObjectMember
+		chunkCount := 0
+		for start := 0; start < len(members); start += *chunkSize {
+			end := min(start+*chunkSize, len(members))
+			chunk := outputModel
+			chunk.CityObjectMember = members[start:end]
+			// A chunk with nothing to bound keeps the merged envelope
+			if bounds, ok := gmlmerge.UnionBounds(memberBounds[start:end]); ok {
+				gmlmerge.SetEnvelope(&chunk.BoundedBy.Envelope, bounds)
+			}
+
+			chunkCount++
+			path := gmlmerge.ChunkPath(*outputFile, chunkCount)
+			if err := writeMergedModel(chunk, path, *flattenNS); err != nil {
+				fmt.Printf("Error writing chunk %s: %v\n", path, err)
+				return 0
+			}
+			fmt.Printf("Wrote %d city objects to %s\n", end-start, path)
+		}
+		fmt.Printf("Merged CityGML LoD2 written to %d files of at most %d city objects\n", chunkCount, *chunkSize)
+		return 0
+	}
+
+	if err := writeMergedModel(outputModel, *outputFile, *flattenNS); err != nil {
+		fmt.Printf("Error writing output file: %v\n", err)
+		return 0
+	}
+	fmt.Println("Merged CityGML LoD2 file written to:", *outputFile)
+	return 0
+}
+
+// writeMergedModel marshals a merged model and writes it with the XML header
+func writeMergedModel(model citygml.CityModel, path string, flatten bool) error {
+	output, err := xml.MarshalIndent(model, "", "  ")
+	if err != nil {
+		return fmt.Errorf("generating merged XML: %v", err)
+	}
+
+	if flatten {
+		if output, err = citygml.FlattenNamespaces(output); err != nil {
+			return fmt.Errorf("flattening namespaces: %v", err)
+		}
+	}
+	xmlHeader := `<?xml version="1.0" encoding="UTF-8"?>
+<!-- Merged CityGML LoD2 File -->
+`
+	return fileio.WriteFile(path, []byte(xmlHeader+string(output)))
+}
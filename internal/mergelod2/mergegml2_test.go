@@ -0,0 +1,161 @@
+package mergelod2
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// runMain runs mergegml2 with args and returns its exit status and everything
+// it wrote to stdout and stderr
+func runMain(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+
+	status := Main("mergegml2", args)
+	os.Stdout, os.Stderr = stdout, stderr
+	w.Close()
+	return status, <-output
+}
+
+// readCube returns the obj2lod2gml output for a unit cube
+func readCube(t *testing.T) string {
+	t.Helper()
+	content, err := os.ReadFile("../../citygml/testdata/lod2_cube.gml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+// A building whose every ring -snap collapses is left out rather than
+// written with degenerate rings
+func TestSnapSkipsCollapsedBuilding(t *testing.T) {
+	input := t.TempDir()
+	if err := os.WriteFile(filepath.Join(input, "cube.gml"), []byte(readCube(t)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "merged.gml")
+
+	// On a 5 m grid the unit cube snaps onto the origin
+	status, log := runMain(t, "-input", input, "-output", output, "-snap", "5")
+	if status != 0 || !strings.Contains(log, "-snap collapsed 6 rings of building cube") || !strings.Contains(log, "Building cube in "+filepath.Join(input, "cube.gml")+" has no rings left after -snap, skipping") {
+		t.Fatalf("mergegml2 = %d:\n%s", status, log)
+	}
+	gml, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(gml), "<bldg:Building") {
+		t.Errorf("collapsed building written:\n%s", gml)
+	}
+
+	// On a 1 m grid it stays whole
+	if status, log := runMain(t, "-input", input, "-output", output, "-snap", "1"); status != 0 || strings.Contains(log, "collapsed") {
+		t.Errorf("mergegml2 -snap 1 = %d:\n%s", status, log)
+	}
+}
+
+var genericAttributePattern = regexp.MustCompile(`<gen:(string|measure)Attribute name="([^"]*)">\s*<gen:value[^>]*>([^<]*)</gen:value>`)
+
+// genericAttributes lists every generic attribute of a file as name=value, in
+// document order
+func genericAttributes(gml string) []string {
+	var attributes []string
+	for _, match := range genericAttributePattern.FindAllStringSubmatch(gml, -1) {
+		attributes = append(attributes, match[2]+"="+match[3])
+	}
+	return attributes
+}
+
+// The building's string and measure attributes, such as ConstructionMethod
+// and GrossPlannedArea, survive the merge in their original order
+func TestMergeKeepsGenericAttributes(t *testing.T) {
+	input := t.TempDir()
+	cube := readCube(t)
+	if err := os.WriteFile(filepath.Join(input, "cube.gml"), []byte(cube), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "merged.gml")
+
+	if status, log := runMain(t, "-input", input, "-output", output); status != 0 {
+		t.Fatalf("mergegml2 = %d:\n%s", status, log)
+	}
+	merged, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := genericAttributes(cube)
+	if got := genericAttributes(string(merged)); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged attributes = %q\nwant %q", got, want)
+	}
+}
+
+// -tag-source adds each building's input file as a SourceFile attribute
+func TestTagSource(t *testing.T) {
+	input := t.TempDir()
+	cube := readCube(t)
+	for _, name := range []string{"north.gml", "south.gml"} {
+		if err := os.WriteFile(filepath.Join(input, name), []byte(cube), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	output := filepath.Join(t.TempDir(), "merged.gml")
+
+	if status, log := runMain(t, "-input", input, "-output", output, "-tag-source"); status != 0 {
+		t.Fatalf("mergegml2 -tag-source = %d:\n%s", status, log)
+	}
+	merged, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sources []string
+	for _, attribute := range genericAttributes(string(merged)) {
+		if strings.HasPrefix(attribute, "SourceFile=") {
+			sources = append(sources, attribute)
+		}
+	}
+	if want := []string{"SourceFile=north.gml", "SourceFile=south.gml"}; !reflect.DeepEqual(sources, want) {
+		t.Errorf("source attributes = %q, want %q", sources, want)
+	}
+}
+
+// A vegetation object is passed through verbatim, next to the merged building
+func TestMergeKeepsNonBuildingMembers(t *testing.T) {
+	input := t.TempDir()
+	vegetation := `<veg:SolitaryVegetationObject gml:id="tree1"><veg:species>Ficus</veg:species><veg:height uom="m">7.5</veg:height></veg:SolitaryVegetationObject>`
+	gml := strings.Replace(readCube(t), "</core:CityModel>", "<core:cityObjectMember>"+vegetation+"</core:cityObjectMember>\n</core:CityModel>", 1)
+	gml = strings.Replace(gml, `xmlns:gml=`, `xmlns:veg="http://www.opengis.net/citygml/vegetation/2.0" xmlns:gml=`, 1)
+	if err := os.WriteFile(filepath.Join(input, "cube.gml"), []byte(gml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "merged.gml")
+
+	if status, log := runMain(t, "-input", input, "-output", output); status != 0 {
+		t.Fatalf("mergegml2 = %d:\n%s", status, log)
+	}
+	merged, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(merged), "<core:cityObjectMember>"+vegetation+"</core:cityObjectMember>") {
+		t.Errorf("vegetation object not passed through:\n%s", merged)
+	}
+	if !strings.Contains(string(merged), `xmlns:veg="http://www.opengis.net/citygml/vegetation/2.0"`) || strings.Count(string(merged), "<bldg:Building ") != 1 {
+		t.Errorf("namespace or building missing:\n%s", merged)
+	}
+}
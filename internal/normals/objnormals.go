@@ -0,0 +1,256 @@
+package normals
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fakmalpradana/OBJ2GML/internal/fileio"
+)
+
+// OBJVertex represents a vertex in OBJ file
+type OBJVertex struct {
+	X, Y, Z float64
+}
+
+// Vector3D represents a 3D vector
+type Vector3D struct {
+	X, Y, Z float64
+}
+
+// Main runs objnormals with args, the command line after the program name, and
+// returns its exit status
+func Main(name string, args []string) int {
+	// Parse command-line arguments
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	inputPath := flags.String("input", "", "OBJ file or directory containing OBJ files")
+	outputDir := flags.String("output", "", "Output directory for OBJ files with recomputed normals")
+	smooth := flags.Bool("smooth", false, "Write area-weighted vertex normals instead of one flat normal per face")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if *inputPath == "" || *outputDir == "" {
+		fmt.Println("Usage: go run objnormals.go -input <obj_file_or_directory> -output <output_directory> [-smooth]")
+		return 0
+	}
+
+	fileInfo, err := os.Stat(*inputPath)
+	if err != nil {
+		fmt.Printf("Error accessing input path: %v\n", err)
+		return 0
+	}
+
+	var objFiles []string
+	if fileInfo.IsDir() {
+		objFiles, err = filepath.Glob(filepath.Join(*inputPath, "*.obj"))
+		if err != nil {
+			fmt.Printf("Error finding OBJ files: %v\n", err)
+			return 0
+		}
+	} else {
+		objFiles = []string{*inputPath}
+	}
+
+	if err := os.MkdirAll(*outputDir, 0755); err != nil {
+		fmt.Printf("Error creating output directory: %v\n", err)
+		return 0
+	}
+
+	fmt.Printf("Found %d OBJ files to process\n", len(objFiles))
+
+	successCount := 0
+	errorFiles := []string{}
+	for _, objFile := range objFiles {
+		outputFile := filepath.Join(*outputDir, filepath.Base(objFile))
+		if err := recomputeNormals(objFile, outputFile, *smooth); err != nil {
+			fmt.Printf("Error processing %s: %v\n", filepath.Base(objFile), err)
+			errorFiles = append(errorFiles, filepath.Base(objFile))
+			continue
+		}
+		successCount++
+	}
+
+	fmt.Printf("Successfully processed %d from %d OBJ files\n", successCount, len(objFiles))
+	if len(errorFiles) > 0 {
+		fmt.Printf("Failed to process %d files: %v\n", len(errorFiles), errorFiles)
+	}
+	return 0
+}
+
+// recomputeNormals drops the existing vn lines, writes freshly computed normals
+// just before the first face and points every face corner at them
+func recomputeNormals(inputPath, outputPath string, smooth bool) error {
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var lines []string
+	var vertices []OBJVertex
+	var faces [][]int // 0-based vertex indices per face, in file order
+
+	scanner := bufio.NewScanner(file)
+	const maxCapacity = 1024 * 1024 // 1MB
+	scanner.Buffer(make([]byte, maxCapacity), maxCapacity)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			switch fields[0] {
+			case "vn":
+				continue // replaced by the recomputed normals
+			case "v":
+				var coords [3]float64
+				for i := 0; i < 3 && i+1 < len(fields); i++ {
+					coords[i], _ = strconv.ParseFloat(fields[i+1], 64)
+				}
+				vertices = append(vertices, OBJVertex{coords[0], coords[1], coords[2]})
+			case "f":
+				var face []int
+				for _, token := range fields[1:] {
+					idx, err := strconv.Atoi(strings.Split(token, "/")[0])
+					if err != nil {
+						return fmt.Errorf("invalid face index %q", token)
+					}
+					// Resolve relative indices against the vertices seen so far
+					if idx < 0 {
+						idx += len(vertices) + 1
+					}
+					if idx < 1 || idx > len(vertices) {
+						return fmt.Errorf("face index %d out of range", idx)
+					}
+					face = append(face, idx-1)
+				}
+				faces = append(faces, face)
+			}
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	faceNormals := make([]Vector3D, len(faces))
+	faceAreas := make([]float64, len(faces))
+	degenerate := 0
+	for i, face := range faces {
+		faceNormals[i], faceAreas[i] = faceNormal(vertices, face)
+		if faceAreas[i] == 0 {
+			degenerate++
+		}
+	}
+	if degenerate > 0 {
+		fmt.Printf("Warning: %s has %d degenerate faces; their normals default to +Z\n", filepath.Base(inputPath), degenerate)
+	}
+
+	var normals []Vector3D
+	if smooth {
+		normals = vertexNormals(len(vertices), faces, faceNormals, faceAreas)
+	} else {
+		normals = faceNormals
+	}
+
+	var writer bytes.Buffer
+	faceIndex := 0
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "f" {
+			fmt.Fprintln(&writer, line)
+			continue
+		}
+
+		if faceIndex == 0 {
+			for _, n := range normals {
+				fmt.Fprintf(&writer, "vn %f %f %f\n", n.X, n.Y, n.Z)
+			}
+		}
+
+		// Keep the v and vt parts of each corner and replace the vn part
+		face := faces[faceIndex]
+		corners := make([]string, len(fields)-1)
+		for i, token := range fields[1:] {
+			parts := strings.Split(token, "/")
+			normalIndex := faceIndex + 1
+			if smooth {
+				normalIndex = face[i] + 1
+			}
+			texture := ""
+			if len(parts) > 1 {
+				texture = parts[1]
+			}
+			corners[i] = fmt.Sprintf("%s/%s/%d", parts[0], texture, normalIndex)
+		}
+		fmt.Fprintf(&writer, "f %s\n", strings.Join(corners, " "))
+		faceIndex++
+	}
+
+	if err := fileio.WriteFile(outputPath, writer.Bytes()); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d normals for %d faces to %s\n", len(normals), len(faces), outputPath)
+	return nil
+}
+
+// faceNormal returns the unit normal and area of a polygon, summing the cross
+// products of a triangle fan so non-triangular faces are handled too
+func faceNormal(vertices []OBJVertex, face []int) (Vector3D, float64) {
+	var sum Vector3D
+	for i := 1; i+1 < len(face); i++ {
+		c := crossProduct(vertices[face[0]], vertices[face[i]], vertices[face[i+1]])
+		sum.X += c.X
+		sum.Y += c.Y
+		sum.Z += c.Z
+	}
+
+	length := math.Sqrt(sum.X*sum.X + sum.Y*sum.Y + sum.Z*sum.Z)
+	if length == 0 {
+		return Vector3D{0, 0, 1}, 0
+	}
+	return Vector3D{sum.X / length, sum.Y / length, sum.Z / length}, length / 2
+}
+
+// crossProduct returns the unnormalized normal of triangle v1 v2 v3
+func crossProduct(v1, v2, v3 OBJVertex) Vector3D {
+	ux, uy, uz := v2.X-v1.X, v2.Y-v1.Y, v2.Z-v1.Z
+	vx, vy, vz := v3.X-v1.X, v3.Y-v1.Y, v3.Z-v1.Z
+	return Vector3D{
+		X: uy*vz - uz*vy,
+		Y: uz*vx - ux*vz,
+		Z: ux*vy - uy*vx,
+	}
+}
+
+// vertexNormals averages the face normals around each vertex, weighted by face area
+func vertexNormals(vertexCount int, faces [][]int, faceNormals []Vector3D, faceAreas []float64) []Vector3D {
+	normals := make([]Vector3D, vertexCount)
+	for i, face := range faces {
+		for _, idx := range face {
+			normals[idx].X += faceNormals[i].X * faceAreas[i]
+			normals[idx].Y += faceNormals[i].Y * faceAreas[i]
+			normals[idx].Z += faceNormals[i].Z * faceAreas[i]
+		}
+	}
+
+	for i, n := range normals {
+		length := math.Sqrt(n.X*n.X + n.Y*n.Y + n.Z*n.Z)
+		if length == 0 {
+			normals[i] = Vector3D{0, 0, 1}
+			continue
+		}
+		normals[i] = Vector3D{n.X / length, n.Y / length, n.Z / length}
+	}
+	return normals
+}
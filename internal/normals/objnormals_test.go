@@ -0,0 +1,60 @@
+package normals
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const triangleOBJ = "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 3\n"
+
+func TestRecomputeNormals(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.obj")
+	output := filepath.Join(dir, "out.obj")
+	if err := os.WriteFile(input, []byte(triangleOBJ), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := recomputeNormals(input, output, false); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "v 0 0 0\nv 1 0 0\nv 0 1 0\nvn 0.000000 0.000000 1.000000\nf 1//1 2//1 3//1\n"; string(got) != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+// When the output cannot be put in place, nothing is left behind: not a
+// partial file and not the temporary file it was written to
+func TestRecomputeNormalsFailureLeavesNoPartialFile(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.obj")
+	if err := os.WriteFile(input, []byte(triangleOBJ), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A non-empty directory in the way of the output file makes the rename fail
+	output := filepath.Join(dir, "out.obj")
+	if err := os.MkdirAll(filepath.Join(output, "keep"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := recomputeNormals(input, output, true); err == nil {
+		t.Fatal("writing over a directory did not fail")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp") {
+			t.Errorf("temporary file %s left behind", entry.Name())
+		}
+	}
+	if len(entries) != 2 {
+		t.Errorf("%d entries in the output directory, want the input and the directory", len(entries))
+	}
+}
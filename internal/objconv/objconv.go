@@ -0,0 +1,725 @@
+// Package objconv holds what obj2gml and obj2lod2gml share: the OBJ vertex,
+// the mesh repairs that do not depend on how a converter stores its faces,
+// the bounds and envelope of a building, and the command-line helpers for
+// -envelope, -only-ids, -epsg-map, -meta, -units and tar input.
+package objconv
+
+import (
+	"archive/tar"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fakmalpradana/OBJ2GML/citygml"
+)
+
+// Vertex is an OBJ vertex
+type Vertex struct {
+	X, Y, Z float64
+
+	Raw [3]string // original OBJ tokens kept by -preserve-coords, empty once changed
+}
+
+// Position formats a vertex for a pos/posList, reusing the OBJ tokens when
+// -preserve-coords kept them so the coordinates round-trip exactly
+func (v Vertex) Position() string {
+	if v.Raw[0] != "" {
+		return v.Raw[0] + " " + v.Raw[1] + " " + v.Raw[2]
+	}
+	return fmt.Sprintf("%f %f %f", v.X, v.Y, v.Z)
+}
+
+// Vector3D represents a 3D vector
+type Vector3D struct {
+	X, Y, Z float64
+}
+
+// Face is implemented by the face types of the converters, which keep
+// different data per face and number their corners differently. F is the
+// face type itself.
+type Face[F any] interface {
+	// Ring returns the corners of the face as 0-based vertex indices
+	Ring() []int
+	// WithRing returns a copy of the face with ring, 0-based, as its corners
+	WithRing(ring []int) F
+}
+
+// EPSGMapping assigns an EPSG code to the OBJ files whose name matches pattern
+type EPSGMapping struct {
+	pattern string
+	code    string
+}
+
+// ReadEPSGMap reads a CSV of "filename or prefix,epsg" rows for -epsg-map.
+// Blank lines, lines starting with # and a header row are skipped.
+func ReadEPSGMap(path string) ([]EPSGMapping, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var mappings []EPSGMapping
+	for i, record := range records {
+		if len(record) != 2 {
+			return nil, fmt.Errorf("line %d: expected filename,epsg", i+1)
+		}
+		pattern, code := strings.TrimSpace(record[0]), strings.TrimSpace(record[1])
+		if _, err := strconv.Atoi(code); err != nil {
+			if i == 0 {
+				continue // header
+			}
+			return nil, fmt.Errorf("line %d: invalid EPSG code %q", i+1, code)
+		}
+		if pattern == "" {
+			return nil, fmt.Errorf("line %d: empty filename", i+1)
+		}
+		mappings = append(mappings, EPSGMapping{pattern: pattern, code: code})
+	}
+	return mappings, nil
+}
+
+// EPSGForFile returns the code mapped to an OBJ file name. An exact match on
+// the name, with or without .obj, wins over the longest matching prefix;
+// unmapped files get the fallback.
+func EPSGForFile(mappings []EPSGMapping, baseFileName, fallback string) string {
+	stem := strings.TrimSuffix(baseFileName, filepath.Ext(baseFileName))
+	code, longest := fallback, -1
+	for _, m := range mappings {
+		if m.pattern == baseFileName || m.pattern == stem {
+			return m.code
+		}
+		if strings.HasPrefix(baseFileName, m.pattern) && len(m.pattern) > longest {
+			code, longest = m.code, len(m.pattern)
+		}
+	}
+	return code
+}
+
+// ParseEnvelope reads a -envelope value of six numbers, minx miny minz maxx
+// maxy maxz, separated by spaces or commas
+func ParseEnvelope(value string) ([]float64, error) {
+	fields := strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == ',' })
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("expected 6 numbers \"minx miny minz maxx maxy maxz\", got %d", len(fields))
+	}
+	bounds := make([]float64, 6)
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("invalid number %q", field)
+		}
+		bounds[i] = v
+	}
+	for i := 0; i < 3; i++ {
+		if bounds[i] > bounds[i+3] {
+			return nil, fmt.Errorf("minimum %g is greater than maximum %g", bounds[i], bounds[i+3])
+		}
+	}
+	return bounds, nil
+}
+
+// VertexBounds returns the bounding box of the finite vertices. obj2gml and
+// obj2lod2gml both take the envelope and the measured height from it, so the
+// LOD1 and LOD2 output of one OBJ agree on them.
+func VertexBounds(vertices []Vertex) (minX, minY, minZ, maxX, maxY, maxZ float64) {
+	minX, minY, minZ = math.MaxFloat64, math.MaxFloat64, math.MaxFloat64
+	maxX, maxY, maxZ = -math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64
+	for _, v := range vertices {
+		if !IsFiniteVertex(v) {
+			continue
+		}
+		minX = math.Min(minX, v.X)
+		minY = math.Min(minY, v.Y)
+		minZ = math.Min(minZ, v.Z)
+		maxX = math.Max(maxX, v.X)
+		maxY = math.Max(maxY, v.Y)
+		maxZ = math.Max(maxZ, v.Z)
+	}
+	return minX, minY, minZ, maxX, maxY, maxZ
+}
+
+// EnvelopeCorners formats the lower and upper corners of an envelope
+func EnvelopeCorners(minX, minY, minZ, maxX, maxY, maxZ float64) (string, string) {
+	return fmt.Sprintf("%f %f %f", minX, minY, minZ), fmt.Sprintf("%f %f %f", maxX, maxY, maxZ)
+}
+
+// ParseIDList reads -only-ids: comma-separated building ids, or @path to a
+// file with one id per line (commas also allowed)
+func ParseIDList(value string) (map[string]bool, error) {
+	if path, ok := strings.CutPrefix(value, "@"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		value = string(data)
+	}
+	ids := make(map[string]bool)
+	for _, id := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+		if id = strings.TrimSpace(id); id != "" {
+			ids[id] = true
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no ids given")
+	}
+	return ids, nil
+}
+
+// EachTarOBJ calls fn with every regular .obj entry of a tar archive, reading
+// it straight from the archive; fn returns false to stop early
+func EachTarOBJ(archive string, fn func(name string, r io.Reader) bool) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !header.FileInfo().Mode().IsRegular() || path.Ext(header.Name) != ".obj" {
+			continue
+		}
+		if !fn(header.Name, tr) {
+			return nil
+		}
+	}
+}
+
+// FilterByID keeps the OBJ files whose building id, the file name without
+// extension, is in ids and warns about ids that matched no file
+func FilterByID(objFiles []string, ids map[string]bool) []string {
+	kept := []string{}
+	found := make(map[string]bool)
+	for _, objFile := range objFiles {
+		baseFileName := filepath.Base(objFile)
+		id := strings.TrimSuffix(baseFileName, filepath.Ext(baseFileName))
+		if ids[id] {
+			kept = append(kept, objFile)
+			found[id] = true
+		}
+	}
+	missing := []string{}
+	for id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		fmt.Printf("Warning: -only-ids matched no OBJ file for %v\n", missing)
+	}
+	return kept
+}
+
+// isXMLChar reports whether r may appear in an XML 1.0 document
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		(r >= 0x20 && r <= 0xD7FF) || (r >= 0xE000 && r < utf8.RuneError) || (r >= 0x10000 && r <= unicode.MaxRune)
+}
+
+// XMLText drops characters XML 1.0 does not allow, such as control
+// characters and invalid UTF-8, from a string written to the CityGML
+func XMLText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if !isXMLChar(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// MetaFlag collects repeated -meta key=value flags as string attributes
+type MetaFlag []citygml.StringAttribute
+
+func (m *MetaFlag) String() string {
+	pairs := make([]string, len(*m))
+	for i, attribute := range *m {
+		pairs[i] = attribute.Name + "=" + attribute.Value
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m *MetaFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	*m = append(*m, citygml.StringAttribute{Name: key, Value: val})
+	return nil
+}
+
+// SetStringAttribute replaces the value of a string attribute with the same
+// name, or appends the attribute when there is none
+func SetStringAttribute(attributes []citygml.StringAttribute, attribute citygml.StringAttribute) []citygml.StringAttribute {
+	for i := range attributes {
+		if attributes[i].Name == attribute.Name {
+			attributes[i].Value = attribute.Value
+			return attributes
+		}
+	}
+	return append(attributes, attribute)
+}
+
+// UnitScales maps the supported -units values to their size relative to meters
+var UnitScales = map[string]float64{
+	"m":  1,
+	"ft": 0.3048,
+}
+
+// IsFiniteVertex reports whether all coordinates are usable numbers
+func IsFiniteVertex(v Vertex) bool {
+	for _, c := range []float64{v.X, v.Y, v.Z} {
+		if math.IsNaN(c) || math.IsInf(c, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// DropNonFiniteFaces removes faces that reference one of the bad (0-based)
+// vertices
+func DropNonFiniteFaces[F Face[F]](faces []F, badVertices map[int]bool) []F {
+	var kept []F
+	for _, face := range faces {
+		keep := true
+		for _, idx := range face.Ring() {
+			if badVertices[idx] {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			kept = append(kept, face)
+		}
+	}
+	return kept
+}
+
+// ClipBelow removes the geometry below z = datum. Faces entirely below are
+// dropped; faces crossing the datum are cut at it in clip mode and dropped in
+// drop mode. It returns the vertices, the faces and how many faces were
+// dropped and clipped.
+func ClipBelow[F Face[F]](vertices []Vertex, faces []F, datum float64, mode string) ([]Vertex, []F, int, int) {
+	var result []F
+	crossings := make(map[[2]int]int)
+	dropped, clipped := 0, 0
+	for _, face := range faces {
+		ring := face.Ring()
+		below := 0
+		for _, idx := range ring {
+			if vertices[idx].Z < datum {
+				below++
+			}
+		}
+		if below == 0 {
+			result = append(result, face)
+			continue
+		}
+		if below == len(ring) || mode == "drop" {
+			dropped++
+			continue
+		}
+
+		ring = clipRing(&vertices, ring, datum, crossings)
+		if len(ring) < 3 {
+			dropped++
+			continue
+		}
+		result = append(result, face.WithRing(ring))
+		clipped++
+	}
+	// Keep only the vertices the remaining faces use, so the envelope no
+	// longer reaches below the datum
+	remap := make(map[int]int)
+	var kept []Vertex
+	for f, face := range result {
+		ring := face.Ring()
+		renumbered := make([]int, len(ring))
+		for i, idx := range ring {
+			if _, ok := remap[idx]; !ok {
+				kept = append(kept, vertices[idx])
+				remap[idx] = len(kept) - 1
+			}
+			renumbered[i] = remap[idx]
+		}
+		result[f] = face.WithRing(renumbered)
+	}
+	return kept, result, dropped, clipped
+}
+
+// clipRing cuts a ring of 0-based indices at the plane z = datum and keeps the
+// part above it. Points where an edge crosses the plane are appended to
+// vertices once per edge, so neighboring faces share them.
+func clipRing(vertices *[]Vertex, ring []int, datum float64, crossings map[[2]int]int) []int {
+	var clipped []int
+	for i, a := range ring {
+		b := ring[(i+1)%len(ring)]
+		za, zb := (*vertices)[a].Z, (*vertices)[b].Z
+		if za >= datum {
+			clipped = append(clipped, a)
+		}
+		if (za < datum) != (zb < datum) && za != datum && zb != datum {
+			edge := UndirectedEdge(a, b)
+			idx, ok := crossings[edge]
+			if !ok {
+				p, q := (*vertices)[edge[0]], (*vertices)[edge[1]]
+				t := (datum - p.Z) / (q.Z - p.Z)
+				*vertices = append(*vertices, Vertex{X: p.X + t*(q.X-p.X), Y: p.Y + t*(q.Y-p.Y), Z: datum})
+				idx = len(*vertices) - 1
+				crossings[edge] = idx
+			}
+			clipped = append(clipped, idx)
+		}
+	}
+	return clipped
+}
+
+// SplitLargeFaces replaces faces with more than maxVertices corners by
+// polygons within the limit, keeping the rest of the face, and returns how
+// many faces were split
+func SplitLargeFaces[F Face[F]](vertices []Vertex, faces []F, maxVertices int) ([]F, int) {
+	var result []F
+	split := 0
+	for _, face := range faces {
+		ring := face.Ring()
+		if len(ring) <= maxVertices {
+			result = append(result, face)
+			continue
+		}
+		for _, piece := range splitRing(vertices, ring, maxVertices) {
+			result = append(result, face.WithRing(piece))
+		}
+		split++
+	}
+	return result, split
+}
+
+// splitRing breaks a ring of 0-based indices with more than maxVertices
+// corners into rings within the limit, keeping its orientation. Convex rings
+// are cut into fans sharing the first corner; others are ear-clipped into
+// triangles, since a fan could cross their outline.
+func splitRing(vertices []Vertex, ring []int, maxVertices int) [][]int {
+	if len(ring) <= maxVertices {
+		return [][]int{ring}
+	}
+
+	// Project onto the plane the normal points most along, counter-clockwise
+	// when seen against the normal
+	n := NewellNormal(vertices, ring)
+	ax, ay, az := math.Abs(n.X), math.Abs(n.Y), math.Abs(n.Z)
+	points := make([][2]float64, len(ring))
+	for i, idx := range ring {
+		v := vertices[idx]
+		switch {
+		case az >= ax && az >= ay:
+			points[i] = [2]float64{v.X, v.Y}
+			if n.Z < 0 {
+				points[i][0] = -v.X
+			}
+		case ax >= ay:
+			points[i] = [2]float64{v.Y, v.Z}
+			if n.X < 0 {
+				points[i][0] = -v.Y
+			}
+		default:
+			points[i] = [2]float64{v.Z, v.X}
+			if n.Y < 0 {
+				points[i][0] = -v.Z
+			}
+		}
+	}
+	cross := func(o, a, b [2]float64) float64 {
+		return (a[0]-o[0])*(b[1]-o[1]) - (a[1]-o[1])*(b[0]-o[0])
+	}
+
+	convex := true
+	for i := range points {
+		if cross(points[i], points[(i+1)%len(points)], points[(i+2)%len(points)]) < -1e-9 {
+			convex = false
+			break
+		}
+	}
+
+	var pieces [][]int
+	if convex {
+		for start := 1; start < len(ring)-1; {
+			end := min(start+maxVertices-2, len(ring)-1)
+			piece := append([]int{ring[0]}, ring[start:end+1]...)
+			pieces = append(pieces, piece)
+			start = end
+		}
+		return pieces
+	}
+
+	// Ear clipping: cut off a convex corner whose triangle holds no other corner
+	remaining := make([]int, len(ring))
+	for i := range remaining {
+		remaining[i] = i
+	}
+	for len(remaining) > 3 {
+		clipped := false
+		for i := range remaining {
+			prev := remaining[(i+len(remaining)-1)%len(remaining)]
+			cur := remaining[i]
+			next := remaining[(i+1)%len(remaining)]
+			a, b, c := points[prev], points[cur], points[next]
+			if cross(a, b, c) <= 1e-12 {
+				continue
+			}
+			ear := true
+			for _, other := range remaining {
+				if other == prev || other == cur || other == next {
+					continue
+				}
+				p := points[other]
+				if cross(a, b, p) >= 0 && cross(b, c, p) >= 0 && cross(c, a, p) >= 0 {
+					ear = false
+					break
+				}
+			}
+			if ear {
+				pieces = append(pieces, []int{ring[prev], ring[cur], ring[next]})
+				remaining = append(remaining[:i:i], remaining[i+1:]...)
+				clipped = true
+				break
+			}
+		}
+		if !clipped {
+			// Degenerate or self-intersecting: cut the first corner off anyway
+			pieces = append(pieces, []int{ring[remaining[len(remaining)-1]], ring[remaining[0]], ring[remaining[1]]})
+			remaining = remaining[1:]
+		}
+	}
+	return append(pieces, []int{ring[remaining[0]], ring[remaining[1]], ring[remaining[2]]})
+}
+
+// NewellNormal computes a unit polygon normal with Newell's method, which stays
+// stable for n-gons whose first three vertices are nearly collinear
+func NewellNormal(vertices []Vertex, indices []int) Vector3D {
+	var n Vector3D
+	for i := range indices {
+		a := vertices[indices[i]]
+		b := vertices[indices[(i+1)%len(indices)]]
+		n.X += (a.Y - b.Y) * (a.Z + b.Z)
+		n.Y += (a.Z - b.Z) * (a.X + b.X)
+		n.Z += (a.X - b.X) * (a.Y + b.Y)
+	}
+
+	length := math.Sqrt(n.X*n.X + n.Y*n.Y + n.Z*n.Z)
+	if length > 0 {
+		n.X /= length
+		n.Y /= length
+		n.Z /= length
+	}
+	return n
+}
+
+// UndirectedEdge returns a map key that is the same for a->b and b->a
+func UndirectedEdge(a, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+// CanonicalFaces sorts faces by centroid, then by their coordinates, so two
+// OBJs holding the same faces in a different order convert to the same file
+func CanonicalFaces[F Face[F]](vertices []Vertex, faces []F) []F {
+	type keyedFace struct {
+		centroid [3]float64
+		ring     string
+		face     F
+	}
+	keyed := make([]keyedFace, len(faces))
+	for i, face := range faces {
+		var centroid [3]float64
+		var ring strings.Builder
+		n := 0
+		for _, idx := range face.Ring() {
+			if idx < 0 || idx >= len(vertices) {
+				continue
+			}
+			v := vertices[idx]
+			centroid[0] += v.X
+			centroid[1] += v.Y
+			centroid[2] += v.Z
+			fmt.Fprintf(&ring, "%f %f %f ", v.X, v.Y, v.Z)
+			n++
+		}
+		if n > 0 {
+			for j := range centroid {
+				centroid[j] /= float64(n)
+			}
+		}
+		keyed[i] = keyedFace{centroid, ring.String(), face}
+	}
+
+	sort.SliceStable(keyed, func(i, j int) bool {
+		a, b := keyed[i], keyed[j]
+		for k := range a.centroid {
+			if a.centroid[k] != b.centroid[k] {
+				return a.centroid[k] < b.centroid[k]
+			}
+		}
+		return a.ring < b.ring
+	})
+
+	sorted := make([]F, len(faces))
+	for i, k := range keyed {
+		sorted[i] = k.face
+	}
+	return sorted
+}
+
+// MergeCoplanarRings clusters edge-adjacent rings whose normals are within
+// toleranceDeg of the cluster's first ring (and whose group keys match) and
+// replaces each cluster by its outer boundary. Clusters whose boundary is not a
+// single consistently wound loop (holes, flipped faces) are left untouched, so
+// the merged geometry always covers exactly the same surface.
+func MergeCoplanarRings(vertices []Vertex, rings [][]int, groups []string, toleranceDeg float64) ([][]int, []int) {
+	cosTolerance := math.Cos(toleranceDeg * math.Pi / 180)
+
+	normals := make([]Vector3D, len(rings))
+	edgeRings := make(map[[2]int][]int)
+	for i, ring := range rings {
+		normals[i] = NewellNormal(vertices, ring)
+		for j := range ring {
+			key := UndirectedEdge(ring[j], ring[(j+1)%len(ring)])
+			edgeRings[key] = append(edgeRings[key], i)
+		}
+	}
+
+	visited := make([]bool, len(rings))
+	var merged [][]int
+	var sources []int // index of the first source ring for each output ring
+	for seed := range rings {
+		if visited[seed] {
+			continue
+		}
+		visited[seed] = true
+
+		// Flood fill across manifold edges to coplanar neighbours
+		cluster := []int{seed}
+		for k := 0; k < len(cluster); k++ {
+			ring := rings[cluster[k]]
+			for j := range ring {
+				neighbours := edgeRings[UndirectedEdge(ring[j], ring[(j+1)%len(ring)])]
+				if len(neighbours) != 2 {
+					continue
+				}
+				for _, other := range neighbours {
+					if visited[other] || groups[other] != groups[seed] {
+						continue
+					}
+					n1, n2 := normals[seed], normals[other]
+					if n1.X*n2.X+n1.Y*n2.Y+n1.Z*n2.Z >= cosTolerance {
+						visited[other] = true
+						cluster = append(cluster, other)
+					}
+				}
+			}
+		}
+
+		if len(cluster) > 1 {
+			if boundary, ok := clusterBoundary(vertices, rings, cluster); ok {
+				merged = append(merged, boundary)
+				sources = append(sources, seed)
+				continue
+			}
+		}
+		for _, i := range cluster {
+			merged = append(merged, rings[i])
+			sources = append(sources, i)
+		}
+	}
+
+	return merged, sources
+}
+
+// clusterBoundary traces the boundary edges of a cluster of rings into one loop,
+// rotated so the first corner is convex and non-degenerate
+func clusterBoundary(vertices []Vertex, rings [][]int, cluster []int) ([]int, bool) {
+	edgeCount := make(map[[2]int]int)
+	for _, i := range cluster {
+		ring := rings[i]
+		for j := range ring {
+			edgeCount[UndirectedEdge(ring[j], ring[(j+1)%len(ring)])]++
+		}
+	}
+
+	next := make(map[int]int)
+	start := -1
+	for _, i := range cluster {
+		ring := rings[i]
+		for j := range ring {
+			a, b := ring[j], ring[(j+1)%len(ring)]
+			if edgeCount[UndirectedEdge(a, b)] != 1 {
+				continue
+			}
+			if _, exists := next[a]; exists {
+				return nil, false // Boundary touches itself
+			}
+			next[a] = b
+			if start == -1 {
+				start = a
+			}
+		}
+	}
+	if len(next) < 3 {
+		return nil, false
+	}
+
+	loop := []int{start}
+	for current := next[start]; current != start; {
+		loop = append(loop, current)
+		following, ok := next[current]
+		if !ok || len(loop) > len(next) {
+			return nil, false
+		}
+		current = following
+	}
+	if len(loop) != len(next) {
+		return nil, false // More than one loop, e.g. a hole
+	}
+
+	// The lexicographically smallest vertex is always a strictly convex corner;
+	// put it second so normals computed from the first three vertices are valid
+	extreme := 0
+	for i := range loop {
+		a, b := vertices[loop[i]], vertices[loop[extreme]]
+		if a.X < b.X || (a.X == b.X && (a.Y < b.Y || (a.Y == b.Y && a.Z < b.Z))) {
+			extreme = i
+		}
+	}
+	offset := (extreme - 1 + len(loop)) % len(loop)
+	rotated := append(append([]int{}, loop[offset:]...), loop[:offset]...)
+
+	return rotated, true
+}
@@ -0,0 +1,57 @@
+package objconv
+
+import "testing"
+
+// ringFace is the simplest Face: its 0-based corners and nothing else
+type ringFace []int
+
+func (f ringFace) Ring() []int                  { return f }
+func (f ringFace) WithRing(ring []int) ringFace { return ring }
+
+// A box from z=-2 to 2 loses its bottom below z=0; its walls are cut at the
+// datum in clip mode and dropped in drop mode
+func TestClipBelow(t *testing.T) {
+	var vertices []Vertex
+	for _, z := range []float64{-2, 2} {
+		vertices = append(vertices, Vertex{X: 0, Y: 0, Z: z}, Vertex{X: 4, Y: 0, Z: z}, Vertex{X: 4, Y: 4, Z: z}, Vertex{X: 0, Y: 4, Z: z})
+	}
+	faces := []ringFace{{0, 3, 2, 1}, {4, 5, 6, 7}, {0, 1, 5, 4}, {1, 2, 6, 5}, {2, 3, 7, 6}, {3, 0, 4, 7}}
+
+	tests := []struct {
+		mode                          string
+		faces, vertices, dropped, cut int
+	}{
+		{"clip", 5, 8, 1, 4},
+		{"drop", 1, 4, 5, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			keptVertices, keptFaces, dropped, cut := ClipBelow(append([]Vertex(nil), vertices...), faces, 0, tt.mode)
+			if len(keptFaces) != tt.faces || len(keptVertices) != tt.vertices || dropped != tt.dropped || cut != tt.cut {
+				t.Errorf("got %d faces, %d vertices, %d dropped, %d cut; want %d, %d, %d, %d",
+					len(keptFaces), len(keptVertices), dropped, cut, tt.faces, tt.vertices, tt.dropped, tt.cut)
+			}
+			for _, v := range keptVertices {
+				if v.Z < 0 {
+					t.Errorf("vertex %+v is below the datum", v)
+				}
+			}
+		})
+	}
+}
+
+// Control characters and invalid UTF-8 are dropped; tabs, newlines and
+// accented letters are kept
+func TestXMLText(t *testing.T) {
+	for in, want := range map[string]string{
+		"Façade":          "Façade",
+		"Brick\x01Red":    "BrickRed",
+		"tab\tand\nline":  "tab\tand\nline",
+		"bad\xffutf8":     "badutf8",
+		"nul\x00\x1fchar": "nulchar",
+	} {
+		if got := XMLText(in); got != want {
+			t.Errorf("XMLText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,492 @@
+package overlap
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fakmalpradana/OBJ2GML/internal/fileio"
+)
+
+// Tolerance for point-in-polygon boundary tests, as in objseparator.go
+var pointEpsilon = 1e-9
+
+type Point struct {
+	X float64
+	Y float64
+	Z float64
+}
+
+// Footprint is the 2D ground outline of one building, as triangles
+type Footprint struct {
+	ID                     string
+	Triangles              [][]Point
+	MinX, MinY, MaxX, MaxY float64
+}
+
+// Overlap is a pair of buildings whose footprints share area
+type Overlap struct {
+	A, B string
+	Area float64
+}
+
+// Main runs gmloverlap with args, the command line after the program name, and
+// returns its exit status
+func Main(name string, args []string) int {
+	// Parse command-line arguments
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	inputPath := flags.String("input", "", "Merged CityGML file, or a directory of CityGML files")
+	minArea := flags.Float64("min-area", 0.01, "Ignore overlaps smaller than this area, such as rounding along shared walls")
+	outputFile := flags.String("output", "", "Optional CSV output of the overlapping pairs")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if *inputPath == "" {
+		fmt.Println("Usage: go run gmloverlap.go -input <merged_gml_or_directory> [-min-area <area>] [-output <pairs.csv>]")
+		return 0
+	}
+
+	fileInfo, err := os.Stat(*inputPath)
+	if err != nil {
+		fmt.Printf("Error accessing input path: %v\n", err)
+		return 0
+	}
+
+	gmlFiles := []string{*inputPath}
+	if fileInfo.IsDir() {
+		gmlFiles, err = filepath.Glob(filepath.Join(*inputPath, "*.gml"))
+		if err != nil {
+			fmt.Printf("Error finding GML files: %v\n", err)
+			return 0
+		}
+	}
+
+	footprints := []Footprint{}
+	for _, gmlFile := range gmlFiles {
+		file, err := os.Open(gmlFile)
+		if err != nil {
+			fmt.Printf("Error reading file %s: %v\n", filepath.Base(gmlFile), err)
+			continue
+		}
+		fileFootprints, err := readFootprints(file)
+		file.Close()
+		if err != nil {
+			fmt.Printf("Error parsing GML file %s: %v\n", filepath.Base(gmlFile), err)
+			continue
+		}
+		footprints = append(footprints, fileFootprints...)
+	}
+
+	overlaps := findOverlaps(footprints, *minArea)
+	for _, overlap := range overlaps {
+		fmt.Printf("Overlap: %s and %s share %.3f\n", overlap.A, overlap.B, overlap.Area)
+	}
+	fmt.Printf("Found %d overlapping pairs among %d buildings\n", len(overlaps), len(footprints))
+
+	if *outputFile != "" {
+		if err := writeOverlapsCSV(overlaps, *outputFile); err != nil {
+			fmt.Printf("Error writing CSV: %v\n", err)
+			return 0
+		}
+		fmt.Println("CSV file saved:", *outputFile)
+	}
+	return 0
+}
+
+// readFootprints collects the ground rings of every building in a CityGML
+// document. GroundSurfaces are used when a building has them; otherwise the
+// lowest horizontal rings of its geometry, which is the LOD1 bottom face.
+func readFootprints(r io.Reader) ([]Footprint, error) {
+	decoder := xml.NewDecoder(r)
+
+	var footprints []Footprint
+	var buildingID string
+	buildingDepth, groundDepth, depth := 0, 0, 0
+	var groundRings, allRings [][]Point
+	var ring []Point
+	var text strings.Builder
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			text.Reset()
+			switch t.Name.Local {
+			case "Building":
+				// Parts nested in a building belong to its footprint
+				if buildingDepth == 0 {
+					buildingDepth = depth
+					buildingID = ""
+					for _, attr := range t.Attr {
+						if attr.Name.Local == "id" {
+							buildingID = attr.Value
+						}
+					}
+					groundRings, allRings = nil, nil
+				}
+			case "GroundSurface":
+				if groundDepth == 0 {
+					groundDepth = depth
+				}
+			case "LinearRing":
+				ring = nil
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "posList":
+				ring = append(ring, parsePoints(text.String())...)
+			case "pos":
+				ring = append(ring, parsePoints(text.String())...)
+			case "LinearRing":
+				if buildingDepth > 0 && len(ring) >= 3 {
+					allRings = append(allRings, ring)
+					if groundDepth > 0 {
+						groundRings = append(groundRings, ring)
+					}
+				}
+			case "GroundSurface":
+				if depth == groundDepth {
+					groundDepth = 0
+				}
+			case "Building":
+				if depth == buildingDepth {
+					buildingDepth = 0
+					if len(groundRings) == 0 {
+						groundRings = lowestHorizontalRings(allRings)
+					}
+					if footprint, ok := newFootprint(buildingID, groundRings); ok {
+						footprints = append(footprints, footprint)
+					} else {
+						fmt.Printf("Warning: building %s has no ground rings, skipping\n", buildingID)
+					}
+				}
+			}
+			depth--
+			text.Reset()
+		}
+	}
+
+	return footprints, nil
+}
+
+// parsePoints reads 3D coordinates from a gml:posList or gml:pos
+func parsePoints(value string) []Point {
+	fields := strings.Fields(value)
+	points := make([]Point, 0, len(fields)/3)
+	for i := 0; i+2 < len(fields); i += 3 {
+		x, errX := strconv.ParseFloat(fields[i], 64)
+		y, errY := strconv.ParseFloat(fields[i+1], 64)
+		z, errZ := strconv.ParseFloat(fields[i+2], 64)
+		if errX != nil || errY != nil || errZ != nil {
+			continue
+		}
+		points = append(points, Point{x, y, z})
+	}
+	return points
+}
+
+// lowestHorizontalRings returns the horizontal rings within 1% of the
+// building height of its lowest point
+func lowestHorizontalRings(rings [][]Point) [][]Point {
+	minZ, maxZ := math.Inf(1), math.Inf(-1)
+	for _, ring := range rings {
+		for _, p := range ring {
+			minZ = math.Min(minZ, p.Z)
+			maxZ = math.Max(maxZ, p.Z)
+		}
+	}
+	tolerance := math.Max((maxZ-minZ)*0.01, 1e-6)
+
+	lowest := [][]Point{}
+	for _, ring := range rings {
+		nx, ny, nz := newellNormal(ring)
+		length := math.Sqrt(nx*nx + ny*ny + nz*nz)
+		if length == 0 || math.Abs(nz) < 0.99*length {
+			continue
+		}
+		bottom := true
+		for _, p := range ring {
+			if p.Z > minZ+tolerance {
+				bottom = false
+				break
+			}
+		}
+		if bottom {
+			lowest = append(lowest, ring)
+		}
+	}
+	return lowest
+}
+
+// newellNormal returns the unnormalized normal of a ring
+func newellNormal(ring []Point) (float64, float64, float64) {
+	var nx, ny, nz float64
+	for i := range ring {
+		a := ring[i]
+		b := ring[(i+1)%len(ring)]
+		nx += (a.Y - b.Y) * (a.Z + b.Z)
+		ny += (a.Z - b.Z) * (a.X + b.X)
+		nz += (a.X - b.X) * (a.Y + b.Y)
+	}
+	return nx, ny, nz
+}
+
+// newFootprint triangulates the ground rings of a building in the XY plane
+func newFootprint(id string, rings [][]Point) (Footprint, bool) {
+	footprint := Footprint{
+		ID:   id,
+		MinX: math.Inf(1), MinY: math.Inf(1),
+		MaxX: math.Inf(-1), MaxY: math.Inf(-1),
+	}
+	for _, ring := range rings {
+		for _, triangle := range triangulate(ring) {
+			footprint.Triangles = append(footprint.Triangles, triangle)
+			for _, p := range triangle {
+				footprint.MinX = math.Min(footprint.MinX, p.X)
+				footprint.MinY = math.Min(footprint.MinY, p.Y)
+				footprint.MaxX = math.Max(footprint.MaxX, p.X)
+				footprint.MaxY = math.Max(footprint.MaxY, p.Y)
+			}
+		}
+	}
+	return footprint, len(footprint.Triangles) > 0
+}
+
+// ringArea returns the signed XY area of a ring, positive when counter-clockwise.
+// Coordinates are taken relative to the first point to keep precision in UTM.
+func ringArea(ring []Point) float64 {
+	if len(ring) < 3 {
+		return 0
+	}
+	area := 0.0
+	origin := ring[0]
+	for i := range ring {
+		a := ring[i]
+		b := ring[(i+1)%len(ring)]
+		area += (a.X-origin.X)*(b.Y-origin.Y) - (b.X-origin.X)*(a.Y-origin.Y)
+	}
+	return area / 2
+}
+
+// triangulate splits a simple ring into counter-clockwise triangles by ear clipping
+func triangulate(ring []Point) [][]Point {
+	// Drop the closing point and repeated points, and flatten to XY
+	points := []Point{}
+	for _, p := range ring {
+		p.Z = 0
+		if len(points) == 0 || points[len(points)-1] != p {
+			points = append(points, p)
+		}
+	}
+	for len(points) > 1 && points[0] == points[len(points)-1] {
+		points = points[:len(points)-1]
+	}
+	if len(points) < 3 || math.Abs(ringArea(points)) == 0 {
+		return nil
+	}
+	if ringArea(points) < 0 {
+		for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+			points[i], points[j] = points[j], points[i]
+		}
+	}
+
+	triangles := [][]Point{}
+	for len(points) > 3 {
+		ear := -1
+		for i := range points {
+			prev := points[(i+len(points)-1)%len(points)]
+			next := points[(i+1)%len(points)]
+			if ringArea([]Point{prev, points[i], next}) <= 0 {
+				continue // reflex or degenerate corner
+			}
+			triangle := []Point{prev, points[i], next}
+			blocked := false
+			for j, p := range points {
+				if j == i || p == prev || p == next {
+					continue
+				}
+				// A vertex on the diagonal blocks the ear as well
+				if inside, _ := pointInRing(p, triangle); inside {
+					blocked = true
+					break
+				}
+			}
+			if !blocked {
+				ear = i
+				break
+			}
+		}
+		if ear < 0 {
+			// Not a simple ring; clip the first corner rather than loop forever
+			ear = 0
+		}
+		prev := points[(ear+len(points)-1)%len(points)]
+		next := points[(ear+1)%len(points)]
+		if ringArea([]Point{prev, points[ear], next}) > 0 {
+			triangles = append(triangles, []Point{prev, points[ear], next})
+		}
+		points = append(points[:ear], points[ear+1:]...)
+	}
+	if ringArea(points) > 0 {
+		triangles = append(triangles, points)
+	}
+	return triangles
+}
+
+// pointInRing runs the even-odd ray casting test against a single ring and
+// separately reports whether the point lies on the ring within pointEpsilon
+func pointInRing(point Point, ring []Point) (bool, bool) {
+	eps := pointEpsilon
+	n := len(ring)
+	if n < 3 {
+		return false, false // Skip invalid polygon parts
+	}
+
+	inside := false
+	j := n - 1 // Previous vertex index
+	for i := 0; i < n; i++ {
+		if pointOnSegment(point, ring[j], ring[i], eps) {
+			return true, true
+		}
+
+		yi, yj := ring[i].Y, ring[j].Y
+		if (yi > point.Y) != (yj > point.Y) { // Check y-bounds
+			xi, xj := ring[i].X, ring[j].X
+			xIntersect := (xj-xi)*(point.Y-yi)/(yj-yi) + xi
+			if point.X < xIntersect {
+				inside = !inside
+			}
+		}
+		j = i
+	}
+	return inside, false
+}
+
+// pointOnSegment reports whether point is within eps of the segment a-b
+func pointOnSegment(point, a, b Point, eps float64) bool {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	lengthSq := dx*dx + dy*dy
+	t := 0.0
+	if lengthSq > 0 {
+		t = ((point.X-a.X)*dx + (point.Y-a.Y)*dy) / lengthSq
+		t = math.Max(0, math.Min(1, t))
+	}
+	px, py := a.X+t*dx-point.X, a.Y+t*dy-point.Y
+	return px*px+py*py <= eps*eps
+}
+
+// clipConvex clips subject against the counter-clockwise convex polygon clip
+// (Sutherland-Hodgman) and returns the intersection
+func clipConvex(subject, clip []Point) []Point {
+	output := subject
+	for i := range clip {
+		if len(output) == 0 {
+			break
+		}
+		a := clip[i]
+		b := clip[(i+1)%len(clip)]
+		side := func(p Point) float64 { return (b.X-a.X)*(p.Y-a.Y) - (b.Y-a.Y)*(p.X-a.X) }
+
+		input := output
+		output = nil
+		for j := range input {
+			current := input[j]
+			previous := input[(j+len(input)-1)%len(input)]
+			sc, sp := side(current), side(previous)
+			if sc >= 0 {
+				if sp < 0 {
+					output = append(output, intersect(previous, current, sp, sc))
+				}
+				output = append(output, current)
+			} else if sp >= 0 {
+				output = append(output, intersect(previous, current, sp, sc))
+			}
+		}
+	}
+	return output
+}
+
+// intersect returns the point on p-q where the side distance crosses zero
+func intersect(p, q Point, sp, sq float64) Point {
+	t := sp / (sp - sq)
+	return Point{X: p.X + t*(q.X-p.X), Y: p.Y + t*(q.Y-p.Y)}
+}
+
+// overlapArea returns the XY area shared by two footprints
+func overlapArea(a, b Footprint) float64 {
+	area := 0.0
+	for _, ta := range a.Triangles {
+		for _, tb := range b.Triangles {
+			if clipped := clipConvex(ta, tb); len(clipped) >= 3 {
+				area += math.Abs(ringArea(clipped))
+			}
+		}
+	}
+	return area
+}
+
+// findOverlaps compares every pair of footprints whose bounding boxes meet,
+// sweeping along X so distant buildings are never compared
+func findOverlaps(footprints []Footprint, minArea float64) []Overlap {
+	sorted := append([]Footprint(nil), footprints...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].MinX < sorted[j].MinX })
+
+	overlaps := []Overlap{}
+	for i, a := range sorted {
+		for _, b := range sorted[i+1:] {
+			if b.MinX > a.MaxX {
+				break
+			}
+			if b.MinY > a.MaxY || b.MaxY < a.MinY {
+				continue
+			}
+			if area := overlapArea(a, b); area >= minArea {
+				overlaps = append(overlaps, Overlap{A: a.ID, B: b.ID, Area: area})
+			}
+		}
+	}
+	return overlaps
+}
+
+// writeOverlapsCSV writes the overlapping pairs with their shared area
+func writeOverlapsCSV(overlaps []Overlap, filename string) error {
+	var out bytes.Buffer
+	writer := csv.NewWriter(&out)
+	if err := writer.Write([]string{"building_a", "building_b", "overlap_area"}); err != nil {
+		return err
+	}
+	for _, overlap := range overlaps {
+		if err := writer.Write([]string{overlap.A, overlap.B, fmt.Sprintf("%.3f", overlap.Area)}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return fileio.WriteFile(filename, out.Bytes())
+}
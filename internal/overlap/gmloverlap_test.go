@@ -0,0 +1,83 @@
+package overlap
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runMain runs gmloverlap with args and returns its exit status and output
+func runMain(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+
+	status := Main("gmloverlap", args)
+	os.Stdout = stdout
+	w.Close()
+	return status, <-output
+}
+
+// boxGML is a CityModel with one LOD1 box per footprint, each given as the
+// x, y and size of a square, with the bottom and the top of the box as rings
+func boxGML(boxes map[string][3]float64) string {
+	var members strings.Builder
+	for id, box := range boxes {
+		x, y, size := box[0], box[1], box[2]
+		members.WriteString(`<core:cityObjectMember><bldg:Building gml:id="` + id + `"><bldg:lod1Solid><gml:Solid><gml:exterior><gml:CompositeSurface>`)
+		for _, z := range []float64{0, 10} {
+			fmt.Fprintf(&members, `<gml:surfaceMember><gml:Polygon><gml:exterior><gml:LinearRing><gml:posList>%g %g %g %g %g %g %g %g %g %g %g %g %g %g %g</gml:posList></gml:LinearRing></gml:exterior></gml:Polygon></gml:surfaceMember>`,
+				x, y, z, x, y+size, z, x+size, y+size, z, x+size, y, z, x, y, z)
+		}
+		members.WriteString("</gml:CompositeSurface></gml:exterior></gml:Solid></bldg:lod1Solid></bldg:Building></core:cityObjectMember>\n")
+	}
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<core:CityModel xmlns:core="http://www.opengis.net/citygml/2.0" xmlns:bldg="http://www.opengis.net/citygml/building/2.0" xmlns:gml="http://www.opengis.net/gml">
+` + members.String() + "</core:CityModel>\n"
+}
+
+// Two boxes sharing a 5 x 5 corner are reported with that area; a disjoint
+// pair and one touching along an edge are not
+func TestOverlappingFootprints(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "merged.gml")
+	gml := boxGML(map[string][3]float64{
+		"a": {0, 0, 10},
+		"b": {5, 5, 10},
+		"c": {100, 0, 10},
+		"d": {120, 0, 10},
+		"e": {130, 0, 10},
+	})
+	if err := os.WriteFile(input, []byte(gml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	csvFile := filepath.Join(dir, "pairs.csv")
+
+	status, log := runMain(t, "-input", input, "-output", csvFile)
+	if status != 0 {
+		t.Fatalf("gmloverlap exited with %d:\n%s", status, log)
+	}
+	if !strings.Contains(log, "Overlap: a and b share 25.000\n") || !strings.Contains(log, "Found 1 overlapping pairs among 5 buildings") {
+		t.Errorf("unexpected report:\n%s", log)
+	}
+	pairs, err := os.ReadFile(csvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(pairs)), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "a,b,25") {
+		t.Errorf("CSV:\n%s", pairs)
+	}
+}
@@ -0,0 +1,150 @@
+package separate
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/fakmalpradana/OBJ2GML/internal/fileio"
+
+	_ "modernc.org/sqlite" // pure-Go SQLite driver, registered as "sqlite"
+)
+
+// gpkgTable is the feature table -gpkg writes
+const gpkgTable = "matched_objects"
+
+// gpkgSchema creates the GeoPackage tables, with the DDL the specification
+// gives, and the feature table
+var gpkgSchema = []string{
+	`PRAGMA application_id = 0x47504B47`, // "GPKG"
+	`PRAGMA user_version = 10300`,        // GeoPackage 1.3
+	`CREATE TABLE gpkg_spatial_ref_sys (srs_name TEXT NOT NULL, srs_id INTEGER NOT NULL PRIMARY KEY, organization TEXT NOT NULL, organization_coordsys_id INTEGER NOT NULL, definition TEXT NOT NULL, description TEXT)`,
+	`CREATE TABLE gpkg_contents (table_name TEXT NOT NULL PRIMARY KEY, data_type TEXT NOT NULL, identifier TEXT UNIQUE, description TEXT DEFAULT '', last_change DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')), min_x DOUBLE, min_y DOUBLE, max_x DOUBLE, max_y DOUBLE, srs_id INTEGER, CONSTRAINT fk_gc_r_srs_id FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys(srs_id))`,
+	`CREATE TABLE gpkg_geometry_columns (table_name TEXT NOT NULL, column_name TEXT NOT NULL, geometry_type_name TEXT NOT NULL, srs_id INTEGER NOT NULL, z TINYINT NOT NULL, m TINYINT NOT NULL, CONSTRAINT pk_geom_cols PRIMARY KEY (table_name, column_name), CONSTRAINT uk_gc_table_name UNIQUE (table_name), CONSTRAINT fk_gc_tn FOREIGN KEY (table_name) REFERENCES gpkg_contents(table_name), CONSTRAINT fk_gc_srs FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys (srs_id))`,
+	`CREATE TABLE ` + gpkgTable + ` (fid INTEGER PRIMARY KEY NOT NULL, geom POINT, feature_id INTEGER, source_file TEXT)`,
+}
+
+// wgs84WKT is the definition of EPSG:4326, which every GeoPackage lists
+const wgs84WKT = `GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563,AUTHORITY["EPSG","7030"]],AUTHORITY["EPSG","6326"]],PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],AUTHORITY["EPSG","4326"]]`
+
+// srsDefinition returns the WKT of an EPSG code for WGS 84 and its UTM zones,
+// and "undefined", which the specification allows, for any other
+func srsDefinition(code int) string {
+	if code == 4326 {
+		return wgs84WKT
+	}
+	zone, south := code-32600, false
+	if code > 32700 {
+		zone, south = code-32700, true
+	}
+	if zone < 1 || zone > 60 {
+		return "undefined"
+	}
+	hemisphere, falseNorthing := "N", 0
+	if south {
+		hemisphere, falseNorthing = "S", 10000000
+	}
+	return fmt.Sprintf(`PROJCS["WGS 84 / UTM zone %d%s",%s,PROJECTION["Transverse_Mercator"],`+
+		`PARAMETER["latitude_of_origin",0],PARAMETER["central_meridian",%d],PARAMETER["scale_factor",0.9996],`+
+		`PARAMETER["false_easting",500000],PARAMETER["false_northing",%d],UNIT["metre",1,AUTHORITY["EPSG","9001"]],`+
+		`AXIS["Easting",EAST],AXIS["Northing",NORTH],AUTHORITY["EPSG","%d"]]`,
+		zone, hemisphere, wgs84WKT, zone*6-183, falseNorthing, code)
+}
+
+// gpkgPoint encodes a point as a GeoPackage geometry blob: the GP header in
+// little-endian order without an envelope, then ISO WKB Point Z
+func gpkgPoint(p Point, srsID int) []byte {
+	blob := make([]byte, 0, 37)
+	blob = append(blob, 'G', 'P', 0, 1)
+	blob = binary.LittleEndian.AppendUint32(blob, uint32(int32(srsID)))
+	blob = append(blob, 1)
+	blob = binary.LittleEndian.AppendUint32(blob, 1001)
+	for _, c := range []float64{p.X, p.Y, p.Z} {
+		blob = binary.LittleEndian.AppendUint64(blob, math.Float64bits(c))
+	}
+	return blob
+}
+
+// WritePointsToGeoPackage writes the matched centroids, in the original CRS,
+// to a GeoPackage with one row per object: its number, the index of its
+// footprint as in the CSV, the point and the OBJ file it came from
+func WritePointsToGeoPackage(points []Point, index []int, filename, source string, cx, cy float64, epsg int) error {
+	err := fileio.Replace(filename, func(tmp *os.File) error {
+		db, err := sql.Open("sqlite", tmp.Name())
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		if err := writeGeoPackage(db, points, index, source, cx, cy, epsg); err != nil {
+			return err
+		}
+		return db.Close()
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("GeoPackage saved: %s (%d objects in %s)\n", filename, len(points), gpkgTable)
+	return nil
+}
+
+// writeGeoPackage fills an empty database in one transaction
+func writeGeoPackage(db *sql.DB, points []Point, index []int, source string, cx, cy float64, epsg int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, statement := range gpkgSchema {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("creating tables: %v", err)
+		}
+	}
+
+	srs := [][]interface{}{
+		{"Undefined Cartesian SRS", -1, "NONE", -1, "undefined", "undefined Cartesian coordinate reference system"},
+		{"Undefined geographic SRS", 0, "NONE", 0, "undefined", "undefined geographic coordinate reference system"},
+		{"WGS 84 geodetic", 4326, "EPSG", 4326, wgs84WKT, "longitude/latitude coordinates in decimal degrees on the WGS 84 spheroid"},
+	}
+	if epsg != -1 && epsg != 0 && epsg != 4326 {
+		srs = append(srs, []interface{}{"EPSG:" + strconv.Itoa(epsg), epsg, "EPSG", epsg, srsDefinition(epsg), nil})
+	}
+	for _, row := range srs {
+		if _, err := tx.Exec(`INSERT INTO gpkg_spatial_ref_sys VALUES (?, ?, ?, ?, ?, ?)`, row...); err != nil {
+			return fmt.Errorf("writing the spatial reference systems: %v", err)
+		}
+	}
+
+	insert, err := tx.Prepare(`INSERT INTO ` + gpkgTable + ` (fid, geom, feature_id, source_file) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+	var extent Extent
+	for i, p := range points {
+		p.X += cx
+		p.Y += cy
+		GetExtent(p.X, p.Y, &extent)
+		if _, err := insert.Exec(i+1, gpkgPoint(p, epsg), index[i], source); err != nil {
+			return fmt.Errorf("writing object %d: %v", i+1, err)
+		}
+	}
+
+	// An empty table has no bounds
+	bounds := []interface{}{nil, nil, nil, nil}
+	if extent.set {
+		bounds = []interface{}{extent.minX, extent.minY, extent.maxX, extent.maxY}
+	}
+	lastChange := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	contents := append([]interface{}{gpkgTable, "features", gpkgTable, "Objects matched to footprints by objseparator", lastChange}, bounds...)
+	if _, err := tx.Exec(`INSERT INTO gpkg_contents VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, append(contents, epsg)...); err != nil {
+		return fmt.Errorf("writing gpkg_contents: %v", err)
+	}
+	if _, err := tx.Exec(`INSERT INTO gpkg_geometry_columns VALUES (?, 'geom', 'POINT', ?, 1, 0)`, gpkgTable, epsg); err != nil {
+		return fmt.Errorf("writing gpkg_geometry_columns: %v", err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,85 @@
+package separate
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// -gpkg writes a valid GeoPackage with one row per matched object
+func TestGeoPackageHasOneRowPerMatchedObject(t *testing.T) {
+	dir := t.TempDir()
+	objFile := filepath.Join(dir, "tile.obj")
+	geojson := filepath.Join(dir, "footprints.geojson")
+	gpkg := filepath.Join(dir, "objects.gpkg")
+	// Squares a and c lie in the second footprint, b in neither
+	obj := "o a\nv 12 12 2\nv 14 12 2\nv 14 14 2\nv 12 14 2\nf 1 2 3 4\n" +
+		"o b\nv 30 30 2\nv 32 30 2\nv 32 32 2\nv 30 32 2\nf 5 6 7 8\n" +
+		"o c\nv 15 15 2\nv 17 15 2\nv 17 17 2\nv 15 17 2\nf 9 10 11 12\n"
+	footprints := `{"type":"FeatureCollection","features":[` +
+		`{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[5,0],[5,5],[0,5],[0,0]]]}},` +
+		`{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[10,10],[20,10],[20,20],[10,20],[10,10]]]}}]}`
+	for file, content := range map[string]string{objFile: obj, geojson: footprints} {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var status int
+	log := captureStdout(t, func() {
+		status = Main("objseparator", []string{"-cx=0", "-cy=0", "-gpkg", gpkg, objFile, geojson, filepath.Join(dir, "out")})
+	})
+	if status != 0 {
+		t.Fatalf("objseparator exited with %d:\n%s", status, log)
+	}
+
+	db, err := sql.Open("sqlite", gpkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var integrity string
+	var applicationID int
+	if err := db.QueryRow("pragma integrity_check").Scan(&integrity); err != nil || integrity != "ok" {
+		t.Errorf("integrity_check = %q, %v; want ok", integrity, err)
+	}
+	if err := db.QueryRow("pragma application_id").Scan(&applicationID); err != nil || applicationID != 0x47504B47 {
+		t.Errorf("application_id = %#x, %v; want GPKG", applicationID, err)
+	}
+
+	rows, err := db.Query("select fid, feature_id, source_file, geom from matched_objects order by fid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var fid, feature int
+		var source string
+		var geom []byte
+		if err := rows.Scan(&fid, &feature, &source, &geom); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%d %d %s %x", fid, feature, source, geom))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	// An object's centroid averages the first corner of each face, here the
+	// single square's first vertex
+	want := []string{
+		fmt.Sprintf("1 1 tile.obj %x", gpkgPoint(Point{12, 12, 2}, 32748)),
+		fmt.Sprintf("2 1 tile.obj %x", gpkgPoint(Point{15, 15, 2}, 32748)),
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("matched_objects holds\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	var srs, z int
+	if err := db.QueryRow("select srs_id, z from gpkg_geometry_columns").Scan(&srs, &z); err != nil || srs != 32748 || z != 1 {
+		t.Errorf("gpkg_geometry_columns srs_id, z = %d, %d, %v; want 32748, 1", srs, z, err)
+	}
+}
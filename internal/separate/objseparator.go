@@ -0,0 +1,1838 @@
+package separate
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fakmalpradana/OBJ2GML/internal/fileio"
+)
+
+type Point struct {
+	X float64
+	Y float64
+	Z float64
+}
+type Extent struct {
+	maxX float64
+	maxY float64
+	minX float64
+	minY float64
+	set  bool // false until the first point is added
+}
+type MultiPolygon struct {
+	outer  []Point
+	hole   []Point
+	island []*MultiPolygon
+}
+type Faces struct {
+	v  int
+	vt int
+	vn int
+}
+
+type Tiles struct {
+	extent     Extent
+	childTiles []*Tiles
+	index      []int
+}
+
+// defaultEpsilon is the default tolerance of the point-in-polygon boundary
+// tests
+const defaultEpsilon = 1e-9
+
+// Main runs objseparator with args, the command line after the program name, and
+// returns its exit status: 2 when the flags cannot be parsed, 3 when no object
+// matched a footprint, 1 for any other failure and 0 otherwise
+func Main(name string, args []string) int {
+	// Define command-line flags
+	var cx, cy float64
+	var centroidsGeoJSON string
+	var keepOutliers string
+	var groupBy string
+	var failOnError bool
+	var timing bool
+	var featureFilter string
+	var georefOutput bool
+	var dumpTiles string
+	var deriveFootprints string
+	var footprintMethod string
+	var alpha float64
+	var validateOnly bool
+	var matchStrategy string
+	var gpkg string
+	var epsg int
+	var epsilon float64
+
+	// Create a new FlagSet to handle arguments
+	flagSet := flag.NewFlagSet(name, flag.ContinueOnError)
+	flagSet.Usage = func() {
+		fmt.Fprintf(flagSet.Output(), "Usage: %s [options] <obj_file> <geojson_file> <output_dir>\nOptions:\n", name)
+		flagSet.PrintDefaults()
+		fmt.Fprintln(flagSet.Output(), "Exit status: 0 on success, 1 on failure, 2 for unparsable flags, 3 when no object matched a footprint")
+	}
+
+	// Define flags
+	flagSet.Float64Var(&cx, "cx", 692827.46065, "X coordinate offset")
+	flagSet.Float64Var(&cy, "cy", 9326588.60235, "Y coordinate offset")
+	flagSet.Float64Var(&epsilon, "epsilon", defaultEpsilon, "Tolerance for point-in-polygon boundary tests")
+	flagSet.StringVar(&centroidsGeoJSON, "centroids-geojson", "", "Optional GeoJSON output path for matched centroids")
+	flagSet.StringVar(&gpkg, "gpkg", "", "Optional GeoPackage output path for matched centroids, with their footprint index and source file")
+	flagSet.IntVar(&epsg, "epsg", 32748, "EPSG code of the GeoJSON coordinates, recorded in the -gpkg output")
+	flagSet.StringVar(&groupBy, "group-by", "", "Optional GeoJSON property; write one OBJ per distinct value instead of one per footprint")
+	flagSet.StringVar(&keepOutliers, "keep-outliers", "", "Optional directory for unmatched objects, written as OBJ files plus a CSV")
+	flagSet.BoolVar(&failOnError, "fail-on-error", false, "Exit with status 1 when any output file cannot be written")
+	flagSet.StringVar(&matchStrategy, "match-strategy", "centroid", "How objects are matched to footprints: centroid (any vertex as fallback), any-vertex or majority (footprint with most vertices)")
+	flagSet.StringVar(&featureFilter, "feature-filter", "", "Only match against GeoJSON features whose property equals a value, as key=value")
+	flagSet.BoolVar(&georefOutput, "georef-output", false, "Write the separated OBJ vertices in the original CRS (cx/cy added back), like the CSV")
+	flagSet.StringVar(&dumpTiles, "dump-tiles", "", "Optional GeoJSON output path for the tile grid, one rectangle per non-empty tile with its footprint count")
+	flagSet.StringVar(&deriveFootprints, "derive-footprints", "", "Optional GeoJSON output path for footprints derived from the meshes of unmatched objects")
+	flagSet.StringVar(&footprintMethod, "footprint-method", "convex", "How -derive-footprints outlines a mesh: convex (hull) or alpha (concave alpha shape)")
+	flagSet.Float64Var(&alpha, "alpha", 0, "Alpha shape radius for -footprint-method alpha; smaller values follow concave outlines more closely")
+	flagSet.BoolVar(&timing, "timing", false, "Print the wall-clock time spent in each stage")
+	flagSet.BoolVar(&validateOnly, "validate-geojson", false, "Only check that the GeoJSON has usable polygon footprints, then exit")
+
+	// Parse flags from args before the file paths
+	if err := flagSet.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	// Get file paths from remaining arguments
+	remainingArgs := flagSet.Args()
+	if len(remainingArgs) < 3 {
+		fmt.Println("Missing required arguments")
+		flagSet.SetOutput(os.Stdout)
+		flagSet.Usage()
+		return 1
+	}
+
+	objFilePath := remainingArgs[0]
+	geojsonFilePath := remainingArgs[1]
+	outputDir := remainingArgs[2]
+
+	if footprintMethod != "convex" && footprintMethod != "alpha" {
+		fmt.Printf("Unsupported -footprint-method %q (use convex or alpha)\n", footprintMethod)
+		return 1
+	}
+	if footprintMethod == "alpha" && alpha <= 0 {
+		fmt.Println("-footprint-method alpha needs a positive -alpha radius")
+		return 1
+	}
+
+	filter, err := parseFeatureFilter(featureFilter)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	fmt.Printf("Processing with parameters:\n")
+	fmt.Printf("  OBJ file: %s\n", objFilePath)
+	fmt.Printf("  GeoJSON file: %s\n", geojsonFilePath)
+	fmt.Printf("  Output directory: %s\n", outputDir)
+	fmt.Printf("  CX: %.5f\n", cx)
+	fmt.Printf("  CY: %.5f\n", cy)
+
+	var timer *stageTimer
+	if timing {
+		timer = newStageTimer()
+	}
+
+	// Read the GeoJSON first and check it has footprints before any OBJ work.
+	// Separate times the reading and parsing of both inputs.
+	geoJSONString := ReadFile(geojsonFilePath)
+	usable, err := validateGeoJSON(geoJSONString, filter)
+	if err != nil {
+		fmt.Printf("Invalid GeoJSON %s: %v\n", geojsonFilePath, err)
+		return 1
+	}
+	if filter != nil {
+		fmt.Printf("GeoJSON has %d polygon footprints with %s=%s\n", usable, filter.key, filter.value)
+	} else {
+		fmt.Printf("GeoJSON has %d polygon footprints\n", usable)
+	}
+	if validateOnly {
+		return 0
+	}
+
+	objFile, err := os.Open(objFilePath)
+	if err != nil {
+		fmt.Println("Error opening OBJ:", err)
+		return 1
+	}
+	defer objFile.Close()
+
+	var tiles Tiles
+	objects, err := Separate(objFile, geoJSONString, SeparateOptions{CX: cx, CY: cy, TileSize: 500, FeatureFilter: featureFilter, MatchStrategy: matchStrategy, Epsilon: epsilon, Timer: timer, TilesOut: &tiles})
+	if err != nil {
+		fmt.Println("Error separating objects:", err)
+		return 1
+	}
+	if dumpTiles != "" {
+		if err := WriteTilesToGeoJSON(tiles, dumpTiles, cx, cy); err != nil {
+			fmt.Println("Error writing tile GeoJSON:", err)
+		}
+	}
+	fmt.Println("Number of Object to extract: ", len(objects))
+
+	// Unpack the results into the parallel slices the writers work with
+	var v, vn []Point
+	if len(objects) > 0 {
+		v, vn = objects[0].Vertices, objects[0].Normals
+	}
+	cent := []Point{}
+	index := []int{}
+	Mesh := [][][]Faces{}
+	properties := make(map[int]map[string]interface{})
+	for _, object := range objects {
+		cent = append(cent, object.Centroid)
+		Mesh = append(Mesh, object.Mesh)
+		if object.Matched {
+			index = append(index, object.FeatureIndex)
+			properties[object.FeatureIndex] = object.Properties
+		} else {
+			index = append(index, 12030)
+		}
+	}
+
+	// Filter out outliers (index 12030) before writing
+	start := time.Now()
+	filteredCent, filteredIndex, filteredMesh := FilterOutliers(cent, index, Mesh)
+	timer.add("filter", start)
+
+	fmt.Printf("Objects before filtering: %d\n", len(index))
+	fmt.Printf("Objects after filtering: %d\n", len(filteredIndex))
+	fmt.Printf("Outliers removed: %d\n", len(index)-len(filteredIndex))
+
+	// The CSV is always written, even when nothing matched, so downstream
+	// scripts can tell an empty run apart from one that never happened
+	start = time.Now()
+	if err := WritePointsToCSV(filteredCent, filteredIndex, objFilePath+".csv", cx, cy); err != nil {
+		fmt.Println("Error writing CSV:", err)
+		return 1
+	}
+	var objCount, writeErrors int
+	if groupBy != "" {
+		// One OBJ per distinct property value instead of one per footprint
+		groupIndex, groupNames := GroupByProperty(filteredIndex, properties, groupBy)
+		objCount, writeErrors = WriteToObj(objFilePath, outputDir, groupIndex, filteredMesh, v, vn, cx, cy, georefOutput, groupNames)
+	} else {
+		objCount, writeErrors = WriteToObj(objFilePath, outputDir, filteredIndex, filteredMesh, v, vn, cx, cy, georefOutput, nil)
+	}
+
+	// Keep the unmatched objects for QA instead of dropping them silently
+	if keepOutliers != "" {
+		outlierCent, outlierIndex, outlierMesh := CollectOutliers(cent, index, Mesh)
+		outlierCSV := filepath.Join(keepOutliers, filepath.Base(objFilePath)+".unmatched.csv")
+		if err := os.MkdirAll(keepOutliers, os.ModePerm); err != nil {
+			fmt.Println("Error creating outlier directory:", err)
+			writeErrors++
+		} else if err := WritePointsToCSV(outlierCent, outlierIndex, outlierCSV, cx, cy); err != nil {
+			fmt.Println("Error writing outlier CSV:", err)
+			writeErrors++
+		}
+		_, outlierErrors := WriteToObj(objFilePath, keepOutliers, outlierIndex, outlierMesh, v, vn, cx, cy, georefOutput, nil)
+		writeErrors += outlierErrors
+	}
+
+	if deriveFootprints != "" {
+		footprints := make(map[int]DerivedFootprint)
+		for i, object := range objects {
+			if object.Matched {
+				continue
+			}
+			if footprint := DeriveFootprint(object.Mesh, object.Vertices, footprintMethod, alpha); len(footprint.Polygons) > 0 {
+				footprints[i] = footprint
+			}
+		}
+		if err := WriteFootprintsToGeoJSON(footprints, deriveFootprints, cx, cy); err != nil {
+			fmt.Println("Error writing derived footprint GeoJSON:", err)
+			writeErrors++
+		}
+	}
+
+	if centroidsGeoJSON != "" {
+		if err := WritePointsToGeoJSON(filteredCent, filteredIndex, centroidsGeoJSON, cx, cy); err != nil {
+			fmt.Println("Error writing centroid GeoJSON:", err)
+			writeErrors++
+		}
+	}
+
+	if gpkg != "" {
+		if err := WritePointsToGeoPackage(filteredCent, filteredIndex, gpkg, filepath.Base(objFilePath), cx, cy, epsg); err != nil {
+			fmt.Println("Error writing GeoPackage:", err)
+			writeErrors++
+		}
+	}
+
+	timer.add("write", start)
+
+	fmt.Printf("%d objects matched; wrote %d OBJ files\n", len(filteredIndex), objCount)
+	if timer != nil {
+		timer.report(os.Stdout)
+	}
+	if writeErrors > 0 {
+		fmt.Printf("%d outputs could not be written\n", writeErrors)
+		if failOnError {
+			return 1
+		}
+	}
+
+	// Exit code 3 marks an empty result, distinct from success (0), failure
+	// (1) and a usage error (2)
+	if len(filteredIndex) == 0 {
+		return 3
+	}
+	return 0
+}
+
+// SeparateOptions configures Separate
+type SeparateOptions struct {
+	CX, CY        float64     // offsets subtracted from the GeoJSON coordinates
+	TileSize      float64     // tile size used to narrow the footprint search
+	FeatureFilter string      // optional key=value; other features are not matched
+	Timer         *stageTimer // optional; collects per-stage timings when set
+	TilesOut      *Tiles      // optional; receives the tile grid used for matching
+	MatchStrategy string      // centroid (default, any vertex as fallback), any-vertex or majority
+	Epsilon       float64     // tolerance of the point-in-polygon boundary tests; 0 tests exactly
+}
+
+// separatorStages are the stages -timing reports, in pipeline order
+var separatorStages = []string{"read OBJ", "parse mesh", "read GeoJSON", "build tiles", "search loop", "filter", "write"}
+
+// stageTimer accumulates wall-clock time per stage. A nil timer records nothing.
+type stageTimer struct {
+	durations map[string]time.Duration
+}
+
+func newStageTimer() *stageTimer {
+	return &stageTimer{durations: make(map[string]time.Duration)}
+}
+
+// add charges the time since start to the named stage
+func (t *stageTimer) add(stage string, start time.Time) {
+	if t == nil {
+		return
+	}
+	t.durations[stage] += time.Since(start)
+}
+
+// report prints every stage in pipeline order, followed by the total
+func (t *stageTimer) report(w io.Writer) {
+	var total time.Duration
+	fmt.Fprintln(w, "Timing:")
+	for _, stage := range separatorStages {
+		d := t.durations[stage]
+		total += d
+		fmt.Fprintf(w, "  %-13s %v\n", stage+":", d.Round(time.Microsecond))
+	}
+	fmt.Fprintf(w, "  %-13s %v\n", "total:", total.Round(time.Microsecond))
+}
+
+// SeparatedObject is one OBJ object ("o" group) and the footprint it fell in.
+// Mesh holds 1-based indices into Vertices and Normals, which are the whole
+// file's vertex and normal lists, shared by every object of one call.
+type SeparatedObject struct {
+	Mesh         [][]Faces
+	Vertices     []Point
+	Normals      []Point
+	Centroid     Point
+	FeatureIndex int                    // index of the matched GeoJSON feature
+	Matched      bool                   // false when no footprint contains the object
+	Properties   map[string]interface{} // properties of the matched feature
+}
+
+// Separate matches every object of an OBJ against the GeoJSON footprints
+// without touching the filesystem, so it can run inside a server
+func Separate(objReader io.Reader, geojsonData []byte, opts SeparateOptions) ([]SeparatedObject, error) {
+	start := time.Now()
+	data, err := io.ReadAll(objReader)
+	if err != nil {
+		return nil, fmt.Errorf("reading OBJ: %v", err)
+	}
+	opts.Timer.add("read OBJ", start)
+
+	start = time.Now()
+	var geojson map[string]interface{}
+	if err := json.Unmarshal(geojsonData, &geojson); err != nil {
+		return nil, fmt.Errorf("parsing GeoJSON: %v", err)
+	}
+
+	filter, err := parseFeatureFilter(opts.FeatureFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	tileSize := opts.TileSize
+	if tileSize <= 0 {
+		tileSize = 500
+	}
+
+	strategy := opts.MatchStrategy
+	if strategy == "" {
+		strategy = "centroid"
+	}
+	if strategy != "centroid" && strategy != "any-vertex" && strategy != "majority" {
+		return nil, fmt.Errorf("unsupported match strategy %q (use centroid, any-vertex or majority)", strategy)
+	}
+
+	geoPolygon, properties, extent, err := ReadGeomGeojson(geojson, opts.CX, opts.CY, filter)
+	if err != nil {
+		return nil, fmt.Errorf("parsing GeoJSON: %v", err)
+	}
+	opts.Timer.add("read GeoJSON", start)
+
+	start = time.Now()
+	v, vn, Mesh, err := ReadMesh(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing OBJ: %v", err)
+	}
+	opts.Timer.add("parse mesh", start)
+
+	// Proses Tiling agar mengurangi search pada geojson
+	start = time.Now()
+	tiles := CreateTiles(extent, tileSize, geoPolygon)
+	opts.Timer.add("build tiles", start)
+	if opts.TilesOut != nil {
+		*opts.TilesOut = tiles
+	}
+	start = time.Now()
+	cent := []Point{}
+	objects := make([]SeparatedObject, len(Mesh))
+	for i := range Mesh {
+		index := SearchIdInGeom(Mesh, geoPolygon, tiles, v, i, &cent, strategy, opts.Epsilon)
+		objects[i] = SeparatedObject{
+			Mesh:     Mesh[i],
+			Vertices: v,
+			Normals:  vn,
+			Centroid: cent[i],
+		}
+		if index != 12030 {
+			objects[i].FeatureIndex = index
+			objects[i].Matched = true
+			objects[i].Properties = properties[index]
+		}
+	}
+	opts.Timer.add("search loop", start)
+	return objects, nil
+}
+
+// FilterOutliers removes objects with index 12030 (outliers)
+func FilterOutliers(centroids []Point, indices []int, meshes [][][]Faces) ([]Point, []int, [][][]Faces) {
+	const outlierIndex = 12030
+
+	var filteredCentroids []Point
+	var filteredIndices []int
+	var filteredMeshes [][][]Faces
+
+	for i, idx := range indices {
+		if idx != outlierIndex {
+			filteredCentroids = append(filteredCentroids, centroids[i])
+			filteredIndices = append(filteredIndices, idx)
+			filteredMeshes = append(filteredMeshes, meshes[i])
+		}
+	}
+
+	return filteredCentroids, filteredIndices, filteredMeshes
+}
+
+// SearchIdInGeom returns the footprint object i falls in, or 12030 when none.
+// centroid tests the centroid and then any face's first vertex, any-vertex
+// takes the first footprint holding any vertex and majority the one holding most.
+// Points within eps of a footprint's boundary count as inside.
+func SearchIdInGeom(Mesh [][][]Faces, geom []MultiPolygon, tile Tiles, v []Point, i int, cent *[]Point, strategy string, eps float64) int {
+	const defaultRes = 12030
+	res := defaultRes
+
+	// Compute centroid in a single loop; Z is the object's base, the lowest
+	// Z of any of its vertices, so the point can be used for placement
+	var p []Point
+	var cx, cy float64
+	baseZ := math.Inf(1)
+	faceCount := len(Mesh[i])
+
+	for _, face := range Mesh[i] {
+		vx := v[face[0].v-1]
+		cx += vx.X
+		cy += vx.Y
+		p = append(p, Point{vx.X, vx.Y, 0})
+		for _, corner := range face {
+			baseZ = math.Min(baseZ, v[corner.v-1].Z)
+		}
+	}
+
+	cx /= float64(faceCount)
+	cy /= float64(faceCount)
+	if math.IsInf(baseZ, 1) {
+		baseZ = 0
+	}
+	point := Point{cx, cy, baseZ}
+	*cent = append(*cent, point)
+
+	switch strategy {
+	case "any-vertex", "majority":
+		// Every distinct vertex of the object, looked up in the tiles it falls in
+		var vertices []Point
+		seen := make(map[int]bool)
+		for _, face := range Mesh[i] {
+			for _, corner := range face {
+				if !seen[corner.v] {
+					seen[corner.v] = true
+					vertices = append(vertices, Point{v[corner.v-1].X, v[corner.v-1].Y, 0})
+				}
+			}
+		}
+		candidates := tileCandidates(tile, vertices)
+
+		if strategy == "any-vertex" {
+			for _, index := range candidates {
+				for _, pt := range vertices {
+					if IsPointInPolygon(pt, geom[index], eps) {
+						return index
+					}
+				}
+			}
+			return res
+		}
+
+		// The footprint holding the most vertices wins, the lowest index on a tie
+		best := 0
+		for _, index := range candidates {
+			count := 0
+			for _, pt := range vertices {
+				if IsPointInPolygon(pt, geom[index], eps) {
+					count++
+				}
+			}
+			if count > best {
+				best, res = count, index
+			}
+		}
+		return res
+	}
+
+	// Collect candidates from every tile containing the point, so a point on a
+	// tile edge sees the same footprints regardless of which tile comes first
+	candidates := tileCandidates(tile, []Point{point})
+
+	for _, index := range candidates {
+		if IsPointInPolygon(point, geom[index], eps) {
+			return index
+		}
+	}
+	for _, index := range candidates {
+		for _, pt := range p {
+			if IsPointInPolygon(pt, geom[index], eps) {
+				return index
+			}
+		}
+	}
+
+	return res
+}
+
+// tileCandidates returns the footprints of every tile containing one of the
+// points, sorted so the lowest index wins when a point lies on a boundary
+// shared by several footprints
+func tileCandidates(tile Tiles, points []Point) []int {
+	candidates := []int{}
+	seen := make(map[int]bool)
+	for _, child := range tile.childTiles {
+		for _, point := range points {
+			if child.extent.minX <= point.X && point.X <= child.extent.maxX &&
+				child.extent.minY <= point.Y && point.Y <= child.extent.maxY {
+				for _, index := range child.index {
+					if !seen[index] {
+						seen[index] = true
+						candidates = append(candidates, index)
+					}
+				}
+				break
+			}
+		}
+	}
+	sort.Ints(candidates)
+	return candidates
+}
+
+func CreateTiles(extens Extent, size float64, geom []MultiPolygon) Tiles {
+	var tile Tiles
+	tile.extent = extens
+
+	// Count tiles up front so the grid always reaches the max edge, whatever the
+	// sign of the extent, and a zero-width extent still gets one tile
+	cols := int(math.Ceil((extens.maxX - extens.minX) / size))
+	rows := int(math.Ceil((extens.maxY - extens.minY) / size))
+	if cols < 1 {
+		cols = 1
+	}
+	if rows < 1 {
+		rows = 1
+	}
+	for w := 0; w < cols; w++ {
+		for h := 0; h < rows; h++ {
+			minx := extens.minX + float64(w)*size
+			maxx := minx + size
+			miny := extens.minY + float64(h)*size
+			maxy := miny + size
+
+			if w == cols-1 {
+				maxx = extens.maxX
+			}
+			if h == rows-1 {
+				maxy = extens.maxY
+			}
+
+			tileExtent := Extent{maxX: maxx, maxY: maxy, minX: minx, minY: miny, set: true}
+			tile.childTiles = append(tile.childTiles, &Tiles{tileExtent, nil, []int{}})
+		}
+	}
+
+	var processPolygon = func(index int, points []Point) {
+		if len(points) == 0 {
+			return
+		}
+
+		var extent Extent
+		for _, p := range points {
+			GetExtent(p.X, p.Y, &extent)
+		}
+
+		// Register the footprint in every tile its bounding box overlaps, not
+		// only the tiles holding its corners, so large footprints are found too
+		for _, child := range tile.childTiles {
+			if child.extent.maxX < extent.minX || child.extent.minX > extent.maxX ||
+				child.extent.maxY < extent.minY || child.extent.minY > extent.maxY {
+				continue
+			}
+
+			if len(child.index) == 0 || child.index[len(child.index)-1] != index {
+				child.index = append(child.index, index)
+			}
+		}
+	}
+
+	for i, g := range geom {
+		if len(g.outer) == 0 {
+			continue
+		}
+
+		processPolygon(i, g.outer)
+
+		for _, island := range g.island {
+			processPolygon(i, island.outer)
+		}
+	}
+	return tile
+}
+
+// GroupByProperty maps each matched footprint index to a group id shared by all
+// footprints with the same value of key, and returns the file-safe name of each group
+func GroupByProperty(indices []int, properties map[int]map[string]interface{}, key string) ([]int, map[int]string) {
+	groupIDs := make(map[string]int)
+	groupNames := make(map[int]string)
+	groupIndex := make([]int, len(indices))
+
+	for i, idx := range indices {
+		value := "none"
+		if v, ok := properties[idx][key]; ok && v != nil {
+			value = fmt.Sprint(v)
+		}
+
+		id, ok := groupIDs[value]
+		if !ok {
+			id = len(groupIDs)
+			groupIDs[value] = id
+			groupNames[id] = fileSafeName(value)
+		}
+		groupIndex[i] = id
+	}
+	return groupIndex, groupNames
+}
+
+// fileSafeName replaces characters that cannot appear in a file name, and
+// the ".." sequence, so a value can never point outside its directory
+func fileSafeName(value string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', ' ':
+			return '_'
+		}
+		if r < 32 || r == 127 {
+			return '_'
+		}
+		return r
+	}, value)
+	return strings.ReplaceAll(safe, "..", "_")
+}
+
+// insideDir reports whether path stays within dir once both are cleaned
+func insideDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// CollectOutliers returns the objects FilterOutliers drops. Each one is indexed
+// by its position in the OBJ so WriteToObj writes it to a file of its own.
+func CollectOutliers(centroids []Point, indices []int, meshes [][][]Faces) ([]Point, []int, [][][]Faces) {
+	const outlierIndex = 12030
+
+	var outlierCentroids []Point
+	var objectIndices []int
+	var outlierMeshes [][][]Faces
+
+	for i, idx := range indices {
+		if idx == outlierIndex {
+			outlierCentroids = append(outlierCentroids, centroids[i])
+			objectIndices = append(objectIndices, i)
+			outlierMeshes = append(outlierMeshes, meshes[i])
+		}
+	}
+
+	return outlierCentroids, objectIndices, outlierMeshes
+}
+
+// WriteToObj exports one OBJ file per matched footprint and returns the number
+// of files written and the number that could not be written
+func WriteToObj(baseFilename string, outputDir string, index []int, Mesh [][][]Faces, vertices []Point, normals []Point, cx, cy float64, georef bool, groupNames map[int]string) (int, int) {
+	// Map untuk menyimpan grup berdasarkan indeks unik
+	groupedMeshes := make(map[int][][][]Faces)
+
+	// Kumpulkan semua grup berdasarkan indeks unik
+	for i, idx := range index {
+		// Skip outliers (index 12030) - this is a safety check
+		if idx == 12030 {
+			continue
+		}
+
+		if _, exists := groupedMeshes[idx]; !exists {
+			groupedMeshes[idx] = [][][]Faces{} // Inisialisasi jika belum ada
+		}
+		groupedMeshes[idx] = append(groupedMeshes[idx], Mesh[i])
+	}
+
+	// Create output directory if it doesn't exist
+	err := os.MkdirAll(outputDir, os.ModePerm)
+	if err != nil {
+		fmt.Printf("Error creating output directory: %v\n", err)
+		return 0, len(groupedMeshes)
+	}
+
+	// Extract base filename without extension and path
+	baseName := strings.TrimSuffix(baseFilename, ".obj")
+	if strings.Contains(baseName, "/") {
+		parts := strings.Split(baseName, "/")
+		baseName = parts[len(parts)-1]
+	}
+	if strings.Contains(baseName, "\\") {
+		parts := strings.Split(baseName, "\\")
+		baseName = parts[len(parts)-1]
+	}
+	baseName = fileSafeName(baseName)
+
+	// Go through the footprints in order, so the names given on a collision
+	// are the same from run to run
+	indices := make([]int, 0, len(groupedMeshes))
+	for idx := range groupedMeshes {
+		indices = append(indices, idx)
+	}
+	sort.Ints(indices)
+
+	// Proses setiap indeks unik dan ekspor sebagai file .obj terpisah
+	written := 0
+	failed := 0
+	mergedCount := 0
+	renamed := 0
+	usedNames := make(map[string]bool)
+	for _, idx := range indices {
+		groups := groupedMeshes[idx]
+		// Objects matched to the same footprint are parts of one building (roof
+		// and body exported separately, for example), so the centroid is taken
+		// over all their faces as if they were a single object
+		avgCentroid := Point{0, 0, 0}
+		faceCount := 0
+		for _, facesGroup := range groups {
+			for _, sides := range facesGroup {
+				avgCentroid.X += vertices[sides[0].v-1].X
+				avgCentroid.Y += vertices[sides[0].v-1].Y
+				faceCount++
+			}
+		}
+		if faceCount > 0 {
+			avgCentroid.X /= float64(faceCount)
+			avgCentroid.Y /= float64(faceCount)
+		}
+		if len(groups) > 1 {
+			mergedCount++
+		}
+
+		// Convert back to original coordinate system and format as integers
+		originalX := int(avgCentroid.X + cx)
+		originalY := int(avgCentroid.Y + cy)
+
+		// Generate filename with the new format, or from the group name when grouping by property
+		objectName := fmt.Sprintf("%s_%d_%d", baseName, originalX, originalY)
+		if name, ok := groupNames[idx]; ok {
+			objectName = fmt.Sprintf("%s_%s", baseName, fileSafeName(name))
+		}
+		// Footprints can share a centroid name, and group values a file-safe
+		// name; later ones get a numeric suffix instead of overwriting the
+		// first. Case is ignored for case-insensitive filesystems.
+		if usedNames[strings.ToLower(objectName)] {
+			taken := objectName
+			for n := 2; usedNames[strings.ToLower(objectName)]; n++ {
+				objectName = fmt.Sprintf("%s_%d", taken, n)
+			}
+			fmt.Printf("Warning: output name %s is already used, writing %s instead\n", taken, objectName)
+			renamed++
+		}
+		usedNames[strings.ToLower(objectName)] = true
+		filename := filepath.Join(outputDir, objectName+".obj")
+		if !insideDir(outputDir, filename) {
+			fmt.Printf("Error: output name %q leaves %s, skipping\n", objectName, outputDir)
+			failed++
+			continue
+		}
+
+		// Built in memory and written atomically, so a failure leaves no partial OBJ
+		var out bytes.Buffer
+
+		// Map untuk menyimpan vertex & normal lokal agar indeksnya tetap berurutan.
+		// Vertices at the same written position are welded, so parts that were
+		// separate objects share their seams in the merged object.
+		vertexMap := make(map[int]int)
+		positionMap := make(map[string]int)
+		normalMap := make(map[int]int)
+		localVertices := []Point{}
+		localNormals := []Point{}
+		vertexCounter := 1
+		normalCounter := 1
+
+		// 1. Kumpulkan semua vertex & normal yang digunakan dalam grup ini
+		for _, facesGroup := range groups {
+			for _, sides := range facesGroup { // Sisi-sisi dalam grup
+				for _, faces := range sides {
+					// Konversi indeks vertex ke lokal
+					if _, exists := vertexMap[faces.v]; !exists {
+						v := vertices[faces.v-1] // -1 karena index mulai dari 1
+						key := fmt.Sprintf("%.6f %.6f %.6f", v.X, v.Y, v.Z)
+						if local, welded := positionMap[key]; welded {
+							vertexMap[faces.v] = local
+						} else {
+							vertexMap[faces.v] = vertexCounter
+							positionMap[key] = vertexCounter
+							localVertices = append(localVertices, v)
+							vertexCounter++
+						}
+					}
+					// Konversi indeks normal ke lokal; 0 means the corner has none
+					if _, exists := normalMap[faces.vn]; !exists && faces.vn > 0 {
+						normalMap[faces.vn] = normalCounter
+						localNormals = append(localNormals, normals[faces.vn-1])
+						normalCounter++
+					}
+				}
+			}
+		}
+
+		// 2. Tulis semua vertex (v x y z), in the original CRS like the CSV when georef is set
+		for _, v := range localVertices {
+			if georef {
+				v.X += cx
+				v.Y += cy
+			}
+			out.WriteString(fmt.Sprintf("v %.6f %.6f %.6f\n", v.X, v.Y, v.Z))
+		}
+
+		// 3. Tulis semua normal (vn nx ny nz)
+		for _, vn := range localNormals {
+			out.WriteString(fmt.Sprintf("vn %.6f %.6f %.6f\n", vn.X, vn.Y, vn.Z))
+		}
+
+		// 4. Menulis objek dengan nama unik berdasarkan centroid
+		out.WriteString(fmt.Sprintf("o %s\n", objectName))
+
+		// 5. Menulis face dengan indeks yang sesuai
+		for _, facesGroup := range groups {
+			for _, sides := range facesGroup { // Sisi dalam grup
+				facesTxt := "f "
+				// A face keeps its normals only if every corner has one
+				withNormals := true
+				for _, face := range sides {
+					if face.vn == 0 {
+						withNormals = false
+					}
+				}
+				for _, face := range sides {
+					facesTxt += strconv.Itoa(vertexMap[face.v])
+					if withNormals {
+						facesTxt += "//" + strconv.Itoa(normalMap[face.vn])
+					}
+					facesTxt += " "
+				}
+				out.WriteString(facesTxt + "\n")
+			}
+		}
+
+		if err := fileio.WriteFile(filename, out.Bytes()); err != nil {
+			fmt.Println("Error writing file:", err)
+			failed++
+			continue
+		}
+		written++
+	}
+
+	if mergedCount > 0 {
+		fmt.Printf("Merged the objects of %d footprints matched by more than one object\n", mergedCount)
+	}
+	if renamed > 0 {
+		fmt.Printf("Gave %d files a numeric suffix because their name was already used\n", renamed)
+	}
+	fmt.Printf("Exported %d OBJ files to %s (outliers excluded)\n", written, outputDir)
+	return written, failed
+}
+
+// WritePointsToCSV writes the matched centroids in the original CRS. X and Y
+// are the object centroid and Z its base height. Values are written with a
+// fixed 6 decimals (micrometres), coarser than the float64 error of averaging
+// projected coordinates, so repeated runs give identical files.
+func WritePointsToCSV(points []Point, index []int, filename string, cx, cy float64) error {
+	var out bytes.Buffer
+	writer := csv.NewWriter(&out)
+
+	// Write CSV header
+	if err := writer.Write([]string{"X", "Y", "Z", "Index"}); err != nil {
+		return err
+	}
+
+	// Write each point to CSV (outliers already filtered out)
+	for i, p := range points {
+		row := []string{
+			strconv.FormatFloat(p.X+cx, 'f', 6, 64),
+			strconv.FormatFloat(p.Y+cy, 'f', 6, 64),
+			strconv.FormatFloat(p.Z, 'f', 6, 64),
+			strconv.FormatInt(int64(index[i]), 10),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	if err := fileio.WriteFile(filename, out.Bytes()); err != nil {
+		return err
+	}
+
+	fmt.Println("CSV file saved:", filename, "(outliers excluded)")
+
+	return nil
+}
+
+// WritePointsToGeoJSON writes the matched centroids as a Point FeatureCollection
+// in the original CRS, each feature carrying the index of its matched footprint
+func WritePointsToGeoJSON(points []Point, index []int, filename string, cx, cy float64) error {
+	features := make([]map[string]interface{}, 0, len(points))
+	for i, p := range points {
+		features = append(features, map[string]interface{}{
+			"type": "Feature",
+			"geometry": map[string]interface{}{
+				"type":        "Point",
+				"coordinates": []float64{p.X + cx, p.Y + cy, p.Z},
+			},
+			"properties": map[string]interface{}{
+				"index": index[i],
+			},
+		})
+	}
+
+	collection := map[string]interface{}{
+		"type":     "FeatureCollection",
+		"features": features,
+	}
+
+	data, err := json.MarshalIndent(collection, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := fileio.WriteFile(filename, data); err != nil {
+		return err
+	}
+
+	fmt.Println("GeoJSON file saved:", filename, "(outliers excluded)")
+
+	return nil
+}
+
+// WriteTilesToGeoJSON writes one rectangle per tile holding footprints, in the
+// original CRS, with the number and indices of the footprints registered in it
+func WriteTilesToGeoJSON(tiles Tiles, filename string, cx, cy float64) error {
+	features := []map[string]interface{}{}
+	for i, tile := range tiles.childTiles {
+		if len(tile.index) == 0 {
+			continue
+		}
+		e := tile.extent
+		ring := [][]float64{
+			{e.minX + cx, e.minY + cy},
+			{e.maxX + cx, e.minY + cy},
+			{e.maxX + cx, e.maxY + cy},
+			{e.minX + cx, e.maxY + cy},
+			{e.minX + cx, e.minY + cy},
+		}
+		features = append(features, map[string]interface{}{
+			"type": "Feature",
+			"geometry": map[string]interface{}{
+				"type":        "Polygon",
+				"coordinates": [][][]float64{ring},
+			},
+			"properties": map[string]interface{}{
+				"tile":       i,
+				"count":      len(tile.index),
+				"footprints": tile.index,
+			},
+		})
+	}
+
+	collection := map[string]interface{}{
+		"type":     "FeatureCollection",
+		"features": features,
+	}
+
+	data, err := json.MarshalIndent(collection, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := fileio.WriteFile(filename, data); err != nil {
+		return err
+	}
+
+	fmt.Printf("Tile GeoJSON saved: %s (%d of %d tiles hold footprints)\n", filename, len(features), len(tiles.childTiles))
+	return nil
+}
+
+// DerivedFootprint is the outline DeriveFootprint found for one object and
+// the method that produced it
+type DerivedFootprint struct {
+	Polygons [][][]Point
+	Method   string
+}
+
+// DeriveFootprint projects the vertices of an object's faces onto the XY
+// plane and outlines them, for objects no GeoJSON footprint contains. The
+// convex method returns the convex hull; alpha returns the alpha shape, the
+// union of the Delaunay triangles whose circumradius is at most alpha, which
+// follows concave outlines such as L-shaped buildings. Each returned polygon
+// is an outer ring followed by its holes, all closed. An alpha too small to
+// keep any triangle falls back to the convex hull.
+func DeriveFootprint(mesh [][]Faces, vertices []Point, method string, alpha float64) DerivedFootprint {
+	seen := make(map[[2]float64]bool)
+	var points []Point
+	for _, face := range mesh {
+		for _, corner := range face {
+			if corner.v < 1 || corner.v > len(vertices) {
+				continue
+			}
+			v := vertices[corner.v-1]
+			key := [2]float64{math.Round(v.X*1e6) / 1e6, math.Round(v.Y*1e6) / 1e6}
+			if !seen[key] {
+				seen[key] = true
+				points = append(points, Point{key[0], key[1], 0})
+			}
+		}
+	}
+	if len(points) < 3 {
+		return DerivedFootprint{}
+	}
+
+	if method == "alpha" {
+		if polygons := alphaShape(points, alpha); len(polygons) > 0 {
+			return DerivedFootprint{polygons, "alpha"}
+		}
+	}
+	hull := convexHull(points)
+	if len(hull) < 3 {
+		return DerivedFootprint{}
+	}
+	return DerivedFootprint{[][][]Point{{closeRing(hull)}}, "convex"}
+}
+
+// convexHull returns the counter-clockwise convex hull of the points
+// (Andrew's monotone chain), without repeating the first point
+func convexHull(points []Point) []Point {
+	sorted := append([]Point(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].X != sorted[j].X {
+			return sorted[i].X < sorted[j].X
+		}
+		return sorted[i].Y < sorted[j].Y
+	})
+	cross := func(o, a, b Point) float64 {
+		return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+	}
+
+	var hull []Point
+	for _, p := range sorted {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	lower := len(hull) + 1
+	for i := len(sorted) - 2; i >= 0; i-- {
+		p := sorted[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	return hull[:len(hull)-1]
+}
+
+// triangle holds three indices into the points being triangulated, counter-clockwise
+type triangle [3]int
+
+// delaunay triangulates the points with the Bowyer-Watson algorithm
+func delaunay(points []Point) []triangle {
+	minX, minY := points[0].X, points[0].Y
+	maxX, maxY := minX, minY
+	for _, p := range points {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+	span := math.Max(maxX-minX, maxY-minY)
+	if span == 0 {
+		return nil
+	}
+	midX, midY := (minX+maxX)/2, (minY+maxY)/2
+
+	// A super triangle around everything, removed at the end
+	pts := append(append([]Point(nil), points...),
+		Point{midX - 20*span, midY - span, 0},
+		Point{midX, midY + 20*span, 0},
+		Point{midX + 20*span, midY - span, 0},
+	)
+	n := len(points)
+	triangles := []triangle{orientTriangle(pts, triangle{n, n + 1, n + 2})}
+
+	for i := 0; i < n; i++ {
+		p := pts[i]
+		var bad []triangle
+		var kept []triangle
+		for _, t := range triangles {
+			if inCircumcircle(pts, t, p) {
+				bad = append(bad, t)
+			} else {
+				kept = append(kept, t)
+			}
+		}
+
+		// The edges of the cavity are the bad triangles' edges no two share
+		edgeCount := make(map[[2]int]int)
+		for _, t := range bad {
+			for k := 0; k < 3; k++ {
+				a, b := t[k], t[(k+1)%3]
+				edgeCount[[2]int{min(a, b), max(a, b)}]++
+			}
+		}
+		for _, t := range bad {
+			for k := 0; k < 3; k++ {
+				a, b := t[k], t[(k+1)%3]
+				if edgeCount[[2]int{min(a, b), max(a, b)}] == 1 {
+					kept = append(kept, orientTriangle(pts, triangle{a, b, i}))
+				}
+			}
+		}
+		triangles = kept
+	}
+
+	result := triangles[:0]
+	for _, t := range triangles {
+		if t[0] < n && t[1] < n && t[2] < n {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// orientTriangle returns the triangle with its corners counter-clockwise
+func orientTriangle(points []Point, t triangle) triangle {
+	a, b, c := points[t[0]], points[t[1]], points[t[2]]
+	if (b.X-a.X)*(c.Y-a.Y)-(b.Y-a.Y)*(c.X-a.X) < 0 {
+		t[1], t[2] = t[2], t[1]
+	}
+	return t
+}
+
+// inCircumcircle reports whether p lies inside the circumcircle of the
+// counter-clockwise triangle t
+func inCircumcircle(points []Point, t triangle, p Point) bool {
+	a, b, c := points[t[0]], points[t[1]], points[t[2]]
+	ax, ay := a.X-p.X, a.Y-p.Y
+	bx, by := b.X-p.X, b.Y-p.Y
+	cx, cy := c.X-p.X, c.Y-p.Y
+	det := (ax*ax+ay*ay)*(bx*cy-cx*by) - (bx*bx+by*by)*(ax*cy-cx*ay) + (cx*cx+cy*cy)*(ax*by-bx*ay)
+	return det > 0
+}
+
+// circumradius returns the radius of the circle through the triangle's corners
+func circumradius(a, b, c Point) float64 {
+	ab := math.Hypot(b.X-a.X, b.Y-a.Y)
+	bc := math.Hypot(c.X-b.X, c.Y-b.Y)
+	ca := math.Hypot(a.X-c.X, a.Y-c.Y)
+	area2 := math.Abs((b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X))
+	if area2 == 0 {
+		return math.Inf(1)
+	}
+	return ab * bc * ca / (2 * area2)
+}
+
+// alphaShape keeps the Delaunay triangles with a circumradius of at most alpha
+// and returns the outline of their union as polygons of an outer ring and holes
+func alphaShape(points []Point, alpha float64) [][][]Point {
+	// Directed boundary edges: those of kept triangles whose twin is not kept
+	edges := make(map[[2]int]bool)
+	for _, t := range delaunay(points) {
+		if circumradius(points[t[0]], points[t[1]], points[t[2]]) > alpha {
+			continue
+		}
+		for k := 0; k < 3; k++ {
+			a, b := t[k], t[(k+1)%3]
+			if edges[[2]int{b, a}] {
+				delete(edges, [2]int{b, a})
+			} else {
+				edges[[2]int{a, b}] = true
+			}
+		}
+	}
+
+	// Chain the edges into loops; the interior lies to the left of each edge,
+	// so outer rings run counter-clockwise and holes clockwise
+	next := make(map[int][]int)
+	var starts []int
+	for edge := range edges {
+		next[edge[0]] = append(next[edge[0]], edge[1])
+		starts = append(starts, edge[0])
+	}
+	sort.Ints(starts)
+	for _, targets := range next {
+		sort.Ints(targets)
+	}
+
+	var outers, holes [][]Point
+	for _, start := range starts {
+		if len(next[start]) == 0 {
+			continue
+		}
+		var ring []Point
+		for at := start; ; {
+			targets := next[at]
+			if len(targets) == 0 {
+				break
+			}
+			to := targets[0]
+			next[at] = targets[1:]
+			ring = append(ring, points[at])
+			at = to
+			if at == start {
+				break
+			}
+		}
+		if len(ring) < 3 {
+			continue
+		}
+		if ringArea(ring) > 0 {
+			outers = append(outers, ring)
+		} else {
+			holes = append(holes, ring)
+		}
+	}
+
+	polygons := make([][][]Point, len(outers))
+	for i, outer := range outers {
+		polygons[i] = [][]Point{closeRing(outer)}
+	}
+	for _, hole := range holes {
+		for i, outer := range outers {
+			if inside, _ := pointInRing(hole[0], closeRing(outer), defaultEpsilon); inside {
+				polygons[i] = append(polygons[i], closeRing(hole))
+				break
+			}
+		}
+	}
+	return polygons
+}
+
+// ringArea returns the signed area of an open ring, positive when counter-clockwise
+func ringArea(ring []Point) float64 {
+	area := 0.0
+	for i := range ring {
+		a, b := ring[i], ring[(i+1)%len(ring)]
+		area += a.X*b.Y - b.X*a.Y
+	}
+	return area / 2
+}
+
+// closeRing returns the ring with its first point repeated at the end
+func closeRing(ring []Point) []Point {
+	return append(append([]Point(nil), ring...), ring[0])
+}
+
+// WriteFootprintsToGeoJSON writes the derived footprints of unmatched objects,
+// in the original CRS, with the object index and the method used
+func WriteFootprintsToGeoJSON(footprints map[int]DerivedFootprint, filename string, cx, cy float64) error {
+	indices := make([]int, 0, len(footprints))
+	for i := range footprints {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+
+	features := []map[string]interface{}{}
+	for _, i := range indices {
+		var coordinates [][][][]float64
+		area := 0.0
+		for _, polygon := range footprints[i].Polygons {
+			var rings [][][]float64
+			for r, ring := range polygon {
+				var coords [][]float64
+				for _, p := range ring {
+					coords = append(coords, []float64{p.X + cx, p.Y + cy})
+				}
+				rings = append(rings, coords)
+				if r == 0 {
+					area += math.Abs(ringArea(ring[:len(ring)-1]))
+				} else {
+					area -= math.Abs(ringArea(ring[:len(ring)-1]))
+				}
+			}
+			coordinates = append(coordinates, rings)
+		}
+		features = append(features, map[string]interface{}{
+			"type": "Feature",
+			"geometry": map[string]interface{}{
+				"type":        "MultiPolygon",
+				"coordinates": coordinates,
+			},
+			"properties": map[string]interface{}{
+				"object": i,
+				"method": footprints[i].Method,
+				"area":   area,
+			},
+		})
+	}
+
+	collection := map[string]interface{}{
+		"type":     "FeatureCollection",
+		"features": features,
+	}
+
+	data, err := json.MarshalIndent(collection, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := fileio.WriteFile(filename, data); err != nil {
+		return err
+	}
+
+	fmt.Printf("Derived footprint GeoJSON saved: %s (%d unmatched objects)\n", filename, len(features))
+	return nil
+}
+
+// Rest of the functions remain the same...
+func IsPointInPolygon(point Point, polygon MultiPolygon, eps float64) bool {
+	// A part contains the point when its outer ring does and its hole does not.
+	// Points within eps of either boundary count as inside.
+	inOuter, onOuter := pointInRing(point, polygon.outer, eps)
+	if onOuter {
+		return true
+	}
+	if inOuter {
+		inHole, onHole := pointInRing(point, polygon.hole, eps)
+		if onHole || !inHole {
+			return true
+		}
+	}
+
+	// Islands are separate parts, each of which may carry its own hole
+	for _, island := range polygon.island {
+		if IsPointInPolygon(point, *island, eps) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pointInRing runs the even-odd ray casting test against a single ring and
+// separately reports whether the point lies on the ring within eps
+func pointInRing(point Point, ring []Point, eps float64) (bool, bool) {
+	n := len(ring)
+	if n < 3 {
+		return false, false // Skip invalid polygon parts
+	}
+
+	inside := false
+	j := n - 1 // Previous vertex index
+	for i := 0; i < n; i++ {
+		if pointOnSegment(point, ring[j], ring[i], eps) {
+			return true, true
+		}
+
+		yi, yj := ring[i].Y, ring[j].Y
+		if (yi > point.Y) != (yj > point.Y) { // Check y-bounds
+			xi, xj := ring[i].X, ring[j].X
+			xIntersect := (xj-xi)*(point.Y-yi)/(yj-yi) + xi
+			if point.X < xIntersect {
+				inside = !inside
+			}
+		}
+		j = i
+	}
+	return inside, false
+}
+
+// pointOnSegment reports whether point is within eps of the segment a-b
+func pointOnSegment(point, a, b Point, eps float64) bool {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	lengthSq := dx*dx + dy*dy
+	t := 0.0
+	if lengthSq > 0 {
+		t = ((point.X-a.X)*dx + (point.Y-a.Y)*dy) / lengthSq
+		t = math.Max(0, math.Min(1, t))
+	}
+	px, py := a.X+t*dx-point.X, a.Y+t*dy-point.Y
+	return px*px+py*py <= eps*eps
+}
+
+func ReadMesh(data []byte) ([]Point, []Point, [][][]Faces, error) {
+	var v = []Point{}
+	var vn = []Point{}
+	var Mesh [][][]Faces
+	var names []string
+	skipped := make(map[string]int)
+
+	// Every "o" or "g" line starts a new object, and faces before the first one
+	// form an object of their own. Vertices and normals are global, so they are
+	// read wherever they appear. A group line that follows another before any
+	// face (o then g, say) continues the same object.
+	var meshGroup [][]Faces
+	name := "(unnamed)"
+	for lineIndex, rawLine := range strings.Split(string(data), "\n") {
+		line := strings.Fields(rawLine)
+		if len(line) == 0 {
+			continue
+		}
+		if line[0] == "o" || line[0] == "g" {
+			if len(meshGroup) > 0 {
+				Mesh = append(Mesh, meshGroup)
+				names = append(names, name)
+				meshGroup = nil
+			}
+			if len(line) > 1 {
+				name = strings.Join(line[1:], " ")
+			}
+			continue
+		}
+		if len(line) > 1 {
+			if line[0] == "v" || line[0] == "vn" {
+				vertex, err := parsePoint(line)
+				if err != nil {
+					return nil, nil, nil, fmt.Errorf("line %d: %v", lineIndex+1, err)
+				}
+				if line[0] == "v" {
+					v = append(v, vertex)
+				} else {
+					vn = append(vn, vertex)
+				}
+			} else if line[0] == "f" {
+				var f = make([]Faces, len(line)-1)
+				for k := 1; k < len(line); k++ {
+					indexes := strings.Split(line[k], "/")
+					f[k-1].v = objIndex(indexes[0], len(v))
+					if len(indexes) > 2 && indexes[2] != "" {
+						f[k-1].vn = objIndex(indexes[2], len(vn))
+						if f[k-1].vn == 0 {
+							// 0 means no normal, so a bad normal field must not become one
+							f[k-1].vn = -1
+						}
+					}
+				}
+				meshGroup = append(meshGroup, f)
+			} else if line[0] == "l" || line[0] == "p" || line[0] == "curv" || line[0] == "surf" {
+				skipped[line[0]]++
+			}
+		}
+	}
+	if len(meshGroup) > 0 {
+		Mesh = append(Mesh, meshGroup)
+		names = append(names, name)
+	}
+	warnNonPolygonal("OBJ", skipped)
+	return v, vn, dropInvalidFaces(Mesh, names, len(v), len(vn)), nil
+}
+
+// parsePoint reads the x, y and z of a "v" or "vn" line; a vertex's optional
+// w is ignored
+func parsePoint(line []string) (Point, error) {
+	if len(line) < 4 {
+		return Point{}, fmt.Errorf("%s needs three coordinates, got %d", line[0], len(line)-1)
+	}
+	var coords [3]float64
+	for i := range coords {
+		value, err := strconv.ParseFloat(line[i+1], 64)
+		if err != nil {
+			return Point{}, fmt.Errorf("invalid %s coordinate %q", line[0], line[i+1])
+		}
+		coords[i] = value
+	}
+	return Point{coords[0], coords[1], coords[2]}, nil
+}
+
+// objIndex parses a 1-based OBJ index. Negative indices count back from the
+// last element read so far, as the format allows; anything unparsable
+// becomes 0, which dropInvalidFaces reports.
+func objIndex(field string, count int) int {
+	value, err := strconv.ParseInt(field, 10, 64)
+	if err != nil || value > math.MaxInt32 || value < math.MinInt32 {
+		return 0
+	}
+	if value < 0 {
+		return count + int(value) + 1
+	}
+	return int(value)
+}
+
+// dropInvalidFaces removes faces whose vertex or normal indices fall outside
+// 1..count, which would otherwise panic later, and reports them by object name.
+// A normal index of 0 means the corner has no normal and is kept. Objects left
+// without faces are removed.
+func dropInvalidFaces(mesh [][][]Faces, names []string, vertexCount, normalCount int) [][][]Faces {
+	const maxReported = 10
+	var kept [][][]Faces
+	dropped := 0
+	for i, group := range mesh {
+		var faces [][]Faces
+		for f, face := range group {
+			problem := ""
+			for _, corner := range face {
+				if corner.v < 1 || corner.v > vertexCount {
+					problem = fmt.Sprintf("vertex index %d outside 1..%d", corner.v, vertexCount)
+					break
+				}
+				if corner.vn < 0 || corner.vn > normalCount {
+					problem = fmt.Sprintf("normal index %d outside 1..%d", corner.vn, normalCount)
+					break
+				}
+			}
+			if problem == "" {
+				faces = append(faces, face)
+				continue
+			}
+			if dropped < maxReported {
+				fmt.Printf("Warning: object %s face %d: %s, skipping face\n", names[i], f+1, problem)
+			}
+			dropped++
+		}
+		if len(faces) > 0 {
+			kept = append(kept, faces)
+		}
+	}
+	if dropped > maxReported {
+		fmt.Printf("Warning: skipped %d faces with invalid indices in total\n", dropped)
+	}
+	return kept
+}
+
+// nonPolygonalTypes lists the OBJ element types that carry no polygon geometry
+var nonPolygonalTypes = []string{"l", "p", "curv", "surf"}
+
+// warnNonPolygonal reports how many non-polygonal elements were dropped while parsing
+func warnNonPolygonal(source string, counts map[string]int) {
+	parts := []string{}
+	for _, t := range nonPolygonalTypes {
+		if counts[t] > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", t, counts[t]))
+		}
+	}
+	if len(parts) > 0 {
+		fmt.Printf("Warning: %s contains non-polygonal elements that were ignored: %s\n", source, strings.Join(parts, ", "))
+	}
+}
+
+func GetExtent(X float64, Y float64, extents *Extent) {
+	// Zero is a valid coordinate once offsets are applied, so track emptiness explicitly
+	if !extents.set {
+		extents.maxX, extents.minX = X, X
+		extents.maxY, extents.minY = Y, Y
+		extents.set = true
+		return
+	}
+	if extents.maxX < X {
+		extents.maxX = X
+	}
+	if X < extents.minX {
+		extents.minX = X
+	}
+	if extents.maxY < Y {
+		extents.maxY = Y
+	}
+	if Y < extents.minY {
+		extents.minY = Y
+	}
+}
+
+// validateGeoJSON checks that the GeoJSON parses, has a features array and at
+// least one Polygon or MultiPolygon feature with coordinates that passes the
+// filter, and returns how many such footprints it has
+func validateGeoJSON(data []byte, filter *featureFilter) (int, error) {
+	var geojson map[string]interface{}
+	if err := json.Unmarshal(data, &geojson); err != nil {
+		return 0, fmt.Errorf("not valid JSON: %v", err)
+	}
+	features, ok := geojson["features"].([]interface{})
+	if !ok {
+		return 0, fmt.Errorf("no features array, expected a FeatureCollection")
+	}
+
+	usable, filtered := 0, 0
+	others := make(map[string]int)
+	for _, feature := range features {
+		object, _ := feature.(map[string]interface{})
+		properties, _ := object["properties"].(map[string]interface{})
+		if !filter.matches(properties) {
+			filtered++
+			continue
+		}
+		geometry, _ := object["geometry"].(map[string]interface{})
+		geomType, _ := geometry["type"].(string)
+		coordinates, _ := geometry["coordinates"].([]interface{})
+		if (geomType == "Polygon" || geomType == "MultiPolygon") && len(coordinates) > 0 {
+			usable++
+			continue
+		}
+		if geomType == "" {
+			geomType = "no geometry"
+		}
+		others[geomType]++
+	}
+
+	if usable == 0 {
+		if len(features) == 0 {
+			return 0, fmt.Errorf("the features array is empty, there are no footprints to match")
+		}
+		if filtered == len(features) {
+			return 0, fmt.Errorf("none of its %d features has %s=%s", len(features), filter.key, filter.value)
+		}
+		types := make([]string, 0, len(others))
+		for geomType, count := range others {
+			types = append(types, fmt.Sprintf("%s %d", geomType, count))
+		}
+		sort.Strings(types)
+		if filtered > 0 {
+			return 0, fmt.Errorf("none of the %d features with %s=%s is a Polygon or MultiPolygon with coordinates (found %s)",
+				len(features)-filtered, filter.key, filter.value, strings.Join(types, ", "))
+		}
+		return 0, fmt.Errorf("none of its %d features is a Polygon or MultiPolygon with coordinates (found %s)",
+			len(features), strings.Join(types, ", "))
+	}
+	return usable, nil
+}
+
+// featureFilter keeps only the GeoJSON features whose property key holds value
+type featureFilter struct {
+	key, value string
+}
+
+// parseFeatureFilter parses a -feature-filter of the form key=value. An empty
+// string gives a nil filter, which keeps every feature.
+func parseFeatureFilter(s string) (*featureFilter, error) {
+	if s == "" {
+		return nil, nil
+	}
+	key, value, ok := strings.Cut(s, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return nil, fmt.Errorf("invalid feature filter %q, expected key=value", s)
+	}
+	return &featureFilter{key: key, value: strings.TrimSpace(value)}, nil
+}
+
+// matches reports whether a feature's properties pass the filter
+func (f *featureFilter) matches(properties map[string]interface{}) bool {
+	if f == nil {
+		return true
+	}
+	v, ok := properties[f.key]
+	return ok && v != nil && fmt.Sprint(v) == f.value
+}
+
+// ReadGeomGeojson reads the footprint of every feature, shifted by cx and cy.
+// A feature that is not an object or a position that is not a pair of numbers
+// is an error; features without an areal geometry keep an empty slot.
+func ReadGeomGeojson(geojson map[string]interface{}, cx, cy float64, filter *featureFilter) ([]MultiPolygon, []map[string]interface{}, Extent, error) {
+	var MultiPolygons []MultiPolygon
+	var properties []map[string]interface{}
+	var extents Extent
+	features, ok := geojson["features"].([]interface{})
+	if !ok {
+		return nil, nil, Extent{}, fmt.Errorf("no features array")
+	}
+	skipped := make(map[string]int)
+	filtered := 0
+
+	fmt.Printf("Using coordinate offsets: CX=%.5f, CY=%.5f\n", cx, cy)
+
+	for idxFeature, feature := range features {
+		object, ok := feature.(map[string]interface{})
+		if !ok {
+			return nil, nil, Extent{}, fmt.Errorf("feature %d is not an object", idxFeature)
+		}
+		// Properties are kept per feature, in the same order as MultiPolygons
+		featureProperties, _ := object["properties"].(map[string]interface{})
+		properties = append(properties, featureProperties)
+
+		// Filtered features keep an empty slot, like non-areal ones, so the
+		// feature indices stay the same
+		if !filter.matches(featureProperties) {
+			filtered++
+			MultiPolygons = append(MultiPolygons, MultiPolygon{})
+			continue
+		}
+
+		geometry, ok := object["geometry"].(map[string]interface{})
+		if !ok {
+			MultiPolygons = append(MultiPolygons, MultiPolygon{})
+			continue
+		}
+
+		coordinates, ok := geometry["coordinates"].([]interface{})
+		if !ok || len(coordinates) == 0 {
+			MultiPolygons = append(MultiPolygons, MultiPolygon{}) // Append empty MultiPolygon
+			continue
+		}
+
+		// Only areal geometries can hold objects. Others keep an empty slot so
+		// the feature indices used for output names stay the same.
+		switch geomType, _ := geometry["type"].(string); geomType {
+		case "MultiPolygon":
+		case "Polygon":
+			coordinates = []interface{}{coordinates}
+		default:
+			skipped[geomType]++
+			MultiPolygons = append(MultiPolygons, MultiPolygon{})
+			continue
+		}
+
+		var polygons MultiPolygon
+
+		for idxPolygon, polygon := range coordinates {
+			polygonParts, ok := polygon.([]interface{})
+			if !ok {
+				continue
+			}
+
+			// Every polygon after the first becomes one island holding its own outer and hole
+			var island *MultiPolygon
+			if idxPolygon > 0 {
+				island = &MultiPolygon{}
+				polygons.island = append(polygons.island, island)
+			}
+
+			for idxPart, part := range polygonParts {
+				coord, ok := part.([]interface{})
+				if !ok || len(coord) < 3 {
+					continue
+				}
+
+				LinerRing := make([]Point, len(coord))
+				for j := range coord {
+					point, _ := coord[j].([]interface{})
+					var x, y float64
+					okX, okY := false, false
+					if len(point) >= 2 {
+						x, okX = point[0].(float64)
+						y, okY = point[1].(float64)
+					}
+					if !okX || !okY {
+						return nil, nil, Extent{}, fmt.Errorf("feature %d: position %v is not a pair of numbers", idxFeature, coord[j])
+					}
+					X, Y := x-cx, y-cy
+					LinerRing[j] = Point{X, Y, 0}
+
+					GetExtent(X, Y, &extents)
+				}
+
+				if idxPolygon == 0 {
+					if idxPart == 0 {
+						polygons.outer = LinerRing
+					} else {
+						polygons.hole = LinerRing
+					}
+				} else {
+					if idxPart == 0 {
+						island.outer = LinerRing
+					} else {
+						island.hole = LinerRing
+					}
+				}
+			}
+		}
+
+		MultiPolygons = append(MultiPolygons, polygons)
+	}
+
+	if len(skipped) > 0 {
+		parts := []string{}
+		for geomType, count := range skipped {
+			if geomType == "" {
+				geomType = "(missing type)"
+			}
+			parts = append(parts, fmt.Sprintf("%s=%d", geomType, count))
+		}
+		sort.Strings(parts)
+		fmt.Printf("Warning: GeoJSON contains non-areal features that were skipped: %s\n", strings.Join(parts, ", "))
+	}
+	if filter != nil {
+		fmt.Printf("Kept %d of %d features matching %s=%s\n", len(features)-filtered, len(features), filter.key, filter.value)
+	}
+	return MultiPolygons, properties, extents, nil
+}
+
+func ReadFile(filePath string) []byte {
+	file, errFile := os.Open(filePath)
+	stat, errStat := os.Stat(filePath)
+	defer file.Close()
+	if errFile != nil {
+		log.Fatal(errFile)
+	}
+	if errStat != nil {
+		log.Fatal(errStat)
+	}
+
+	fileLength := stat.Size()
+	bytesBuffer := make([]byte, fileLength)
+	bin, err := file.Read(bytesBuffer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var data []byte = bytesBuffer[:bin]
+	return data
+}
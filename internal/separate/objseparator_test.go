@@ -0,0 +1,995 @@
+package separate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// captureStdout runs f and returns what it printed
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+	f()
+	os.Stdout = stdout
+	w.Close()
+	return <-output
+}
+
+// outputFiles lists the file names in dir
+func outputFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// triangleMesh is one object of one triangle over vertices first..first+2
+func triangleMesh(first int) [][]Faces {
+	return [][]Faces{{{v: first, vn: 1}, {v: first + 1, vn: 1}, {v: first + 2, vn: 1}}}
+}
+
+// Footprints whose names collide get a numeric suffix instead of one
+// overwriting the other
+func TestWriteToObjSuffixesCollidingNames(t *testing.T) {
+	vertices := []Point{{0.2, 0.2, 0}, {0.3, 0.2, 0}, {0.2, 0.3, 0}, {0.7, 0.7, 0}, {0.8, 0.7, 0}, {0.7, 0.8, 0}}
+	normals := []Point{{0, 0, 1}}
+	mesh := [][][]Faces{triangleMesh(1), triangleMesh(4)}
+
+	t.Run("centroid", func(t *testing.T) {
+		// Both centroids truncate to 0 0
+		dir := t.TempDir()
+		var written, failed int
+		log := captureStdout(t, func() {
+			written, failed = WriteToObj("tile.obj", dir, []int{0, 1}, mesh, vertices, normals, 0, 0, false, nil)
+		})
+		if written != 2 || failed != 0 {
+			t.Errorf("written, failed = %d, %d; want 2, 0", written, failed)
+		}
+		if got, want := outputFiles(t, dir), []string{"tile_0_0.obj", "tile_0_0_2.obj"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("files = %v, want %v\n%s", got, want, log)
+		}
+	})
+
+	t.Run("group", func(t *testing.T) {
+		// "a/b" and "A b" both become a_b once file-safe, ignoring case
+		dir := t.TempDir()
+		log := captureStdout(t, func() {
+			WriteToObj("tile.obj", dir, []int{0, 1}, mesh, vertices, normals, 0, 0, false, map[int]string{0: "a/b", 1: "A b"})
+		})
+		if got, want := outputFiles(t, dir), []string{"tile_A_b_2.obj", "tile_a_b.obj"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("files = %v, want %v\n%s", got, want, log)
+		}
+		if want := "Warning: output name tile_A_b is already used, writing tile_A_b_2 instead\n"; !strings.Contains(log, want) {
+			t.Errorf("collision not reported:\n%s", log)
+		}
+	})
+}
+
+// Vertex indices of 0 or past the last vertex drop the face and are reported
+// by object name; faces without normals are kept
+func TestReadMeshValidatesFaceIndices(t *testing.T) {
+	obj := "v 0 0 0\nv 1 0 0\nv 0 1 0\nvn 0 0 1\n" +
+		"o plain\nf 1 2 3\n" +
+		"o normals\nf 1//1 2//1 3//1\n" +
+		"o zero\nf 0 2 3\n" +
+		"o past\nf 1 2 4\n" +
+		"o badnormal\nf 1//2 2//1 3//1\n"
+	var mesh [][][]Faces
+	var err error
+	log := captureStdout(t, func() {
+		_, _, mesh, err = ReadMesh([]byte(obj))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mesh) != 2 {
+		t.Fatalf("kept %d objects, want plain and normals\n%s", len(mesh), log)
+	}
+	if got := mesh[0][0][0].vn; got != 0 {
+		t.Errorf("plain face normal = %d, want 0", got)
+	}
+	for _, want := range []string{
+		"object zero face 1: vertex index 0 outside 1..3",
+		"object past face 1: vertex index 4 outside 1..3",
+		"object badnormal face 1: normal index 2 outside 1..1",
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("missing %q in:\n%s", want, log)
+		}
+	}
+}
+
+// A vertex or normal line with missing or unparsable coordinates is an error
+// naming its line
+func TestReadMeshRejectsBadCoordinates(t *testing.T) {
+	for obj, want := range map[string]string{
+		"v 0 0 0\nv 1 2\n":          "line 2: v needs three coordinates, got 2",
+		"v 0 0 0\nv 1 x 2\n":        `line 2: invalid v coordinate "x"`,
+		"v 0 0 0\nvn 0 0 one\n":     `line 2: invalid vn coordinate "one"`,
+		"o a\nv 0 0 0 1\nf 1 1 1\n": "",
+	} {
+		got := ""
+		if _, _, _, err := ReadMesh([]byte(obj)); err != nil {
+			got = err.Error()
+		}
+		if got != want {
+			t.Errorf("ReadMesh(%q) error = %q, want %q", obj, got, want)
+		}
+	}
+}
+
+// Faces read without normals are written as plain vertex indices
+func TestWriteToObjWithoutNormals(t *testing.T) {
+	vertices := []Point{{0.2, 0.2, 0}, {0.3, 0.2, 0}, {0.2, 0.3, 0}}
+	mesh := [][][]Faces{{{{v: 1}, {v: 2}, {v: 3}}}}
+	dir := t.TempDir()
+	captureStdout(t, func() {
+		WriteToObj("tile.obj", dir, []int{0}, mesh, vertices, nil, 0, 0, false, nil)
+	})
+	data, err := os.ReadFile(filepath.Join(dir, "tile_0_0.obj"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "vn ") || !strings.Contains(string(data), "f 1 2 3 \n") {
+		t.Errorf("output:\n%s", data)
+	}
+}
+
+// The preflight counts only footprints that pass -feature-filter
+func TestValidateGeoJSON(t *testing.T) {
+	square := `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`
+	collection := `{"type":"FeatureCollection","features":[` +
+		`{"type":"Feature","properties":{"kind":"house"},"geometry":` + square + `},` +
+		`{"type":"Feature","properties":{"kind":"shed"},"geometry":` + square + `},` +
+		`{"type":"Feature","properties":{"kind":"road"},"geometry":{"type":"Point","coordinates":[0,0]}}]}`
+	filter := func(s string) *featureFilter {
+		f, err := parseFeatureFilter(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	tests := []struct {
+		name, data string
+		filter     *featureFilter
+		usable     int
+		err        string
+	}{
+		{"no features", `{"type":"FeatureCollection"}`, nil, 0, "no features array"},
+		{"empty", `{"type":"FeatureCollection","features":[]}`, nil, 0, "features array is empty"},
+		{"unfiltered", collection, nil, 2, ""},
+		{"filtered", collection, filter("kind=house"), 1, ""},
+		{"nothing matches", collection, filter("kind=church"), 0, "none of its 3 features has kind=church"},
+		{"only points match", collection, filter("kind=road"), 0, "none of the 1 features with kind=road is a Polygon"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usable, err := validateGeoJSON([]byte(tt.data), tt.filter)
+			if tt.err == "" {
+				if err != nil || usable != tt.usable {
+					t.Errorf("got %d, %v; want %d", usable, err, tt.usable)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+// benchGrid is the side of the benchmark fixtures, a grid of benchGrid² 8 m
+// footprints 10 m apart with one box object standing in each
+const benchGrid = 60
+
+// benchOBJ is the OBJ text of the benchmark boxes
+func benchOBJ() []byte {
+	var obj strings.Builder
+	obj.WriteString("vn 0 0 1\n")
+	n := 0
+	for i := 0; i < benchGrid; i++ {
+		for j := 0; j < benchGrid; j++ {
+			x, y := float64(i*10+2), float64(j*10+2)
+			fmt.Fprintf(&obj, "o box_%d_%d\n", i, j)
+			for _, z := range []float64{0, 6} {
+				fmt.Fprintf(&obj, "v %g %g %g\nv %g %g %g\nv %g %g %g\nv %g %g %g\n", x, y, z, x+6, y, z, x+6, y+6, z, x, y+6, z)
+			}
+			for _, face := range [][4]int{{1, 4, 3, 2}, {5, 6, 7, 8}, {1, 2, 6, 5}, {2, 3, 7, 6}, {3, 4, 8, 7}, {4, 1, 5, 8}} {
+				fmt.Fprintf(&obj, "f %d//1 %d//1 %d//1 %d//1\n", n+face[0], n+face[1], n+face[2], n+face[3])
+			}
+			n += 8
+		}
+	}
+	return []byte(obj.String())
+}
+
+// benchFootprints returns the benchmark footprints and their extent
+func benchFootprints() ([]MultiPolygon, Extent) {
+	var footprints []MultiPolygon
+	var extent Extent
+	for i := 0; i < benchGrid; i++ {
+		for j := 0; j < benchGrid; j++ {
+			x, y := float64(i*10+1), float64(j*10+1)
+			ring := []Point{{x, y, 0}, {x + 8, y, 0}, {x + 8, y + 8, 0}, {x, y + 8, 0}, {x, y, 0}}
+			for _, p := range ring {
+				GetExtent(p.X, p.Y, &extent)
+			}
+			footprints = append(footprints, MultiPolygon{outer: ring})
+		}
+	}
+	return footprints, extent
+}
+
+func BenchmarkReadMesh(b *testing.B) {
+	data := benchOBJ()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ReadMesh(data)
+	}
+}
+
+func BenchmarkCreateTiles(b *testing.B) {
+	footprints, extent := benchFootprints()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CreateTiles(extent, 50, footprints)
+	}
+}
+
+func BenchmarkSearchIdInGeom(b *testing.B) {
+	v, _, mesh, err := ReadMesh(benchOBJ())
+	if err != nil {
+		b.Fatal(err)
+	}
+	footprints, extent := benchFootprints()
+	tiles := CreateTiles(extent, 50, footprints)
+	for _, strategy := range []string{"centroid", "majority"} {
+		b.Run(strategy, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var cent []Point
+				for object := range mesh {
+					SearchIdInGeom(mesh, footprints, tiles, v, object, &cent, strategy, defaultEpsilon)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkIsPointInPolygon(b *testing.B) {
+	footprints, _ := benchFootprints()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, footprint := range footprints {
+			IsPointInPolygon(Point{X: 305, Y: 305}, footprint, defaultEpsilon)
+		}
+	}
+}
+
+// -centroids-geojson writes one point per matched object, in the original CRS,
+// carrying the index of its footprint
+func TestWritePointsToGeoJSON(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "centroids.geojson")
+	points := []Point{{1.5, 2.5, 3}, {-4, 5, 0}}
+	captureStdout(t, func() {
+		if err := WritePointsToGeoJSON(points, []int{7, 2}, file, 1000, 2000); err != nil {
+			t.Fatal(err)
+		}
+	})
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var collection struct {
+		Type     string
+		Features []struct {
+			Type     string
+			Geometry struct {
+				Type        string
+				Coordinates []float64
+			}
+			Properties map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal(data, &collection); err != nil {
+		t.Fatal(err)
+	}
+	if collection.Type != "FeatureCollection" || len(collection.Features) != 2 {
+		t.Fatalf("got %s with %d features, want a FeatureCollection of 2", collection.Type, len(collection.Features))
+	}
+	want := []struct {
+		coordinates []float64
+		index       float64
+	}{{[]float64{1001.5, 2002.5, 3}, 7}, {[]float64{996, 2005, 0}, 2}}
+	for i, feature := range collection.Features {
+		if feature.Geometry.Type != "Point" || !reflect.DeepEqual(feature.Geometry.Coordinates, want[i].coordinates) {
+			t.Errorf("feature %d geometry = %s %v, want Point %v", i, feature.Geometry.Type, feature.Geometry.Coordinates, want[i].coordinates)
+		}
+		if feature.Properties["index"] != want[i].index {
+			t.Errorf("feature %d index = %v, want %v", i, feature.Properties["index"], want[i].index)
+		}
+	}
+}
+
+// lMesh is an L-shaped floor, 20 x 10 with a 10 x 10 wing on its left half,
+// as quads over a 5 m grid
+func lMesh() ([][]Faces, []Point) {
+	inside := func(x, y float64) bool { return y < 10 || x < 10 }
+	index := make(map[[2]float64]int)
+	var vertices []Point
+	corner := func(x, y float64) Faces {
+		key := [2]float64{x, y}
+		if _, ok := index[key]; !ok {
+			vertices = append(vertices, Point{x, y, 0})
+			index[key] = len(vertices)
+		}
+		return Faces{v: index[key]}
+	}
+	var mesh [][]Faces
+	for x := 0.0; x < 20; x += 5 {
+		for y := 0.0; y < 20; y += 5 {
+			if inside(x, y) {
+				mesh = append(mesh, []Faces{corner(x, y), corner(x+5, y), corner(x+5, y+5), corner(x, y+5)})
+			}
+		}
+	}
+	return mesh, vertices
+}
+
+// footprintArea sums the areas of the outer rings minus their holes
+func footprintArea(footprint DerivedFootprint) float64 {
+	area := 0.0
+	for _, polygon := range footprint.Polygons {
+		for _, ring := range polygon {
+			area += ringArea(ring)
+		}
+	}
+	return area
+}
+
+// The convex hull covers the notch of an L-shaped mesh; the alpha shape
+// leaves it out
+func TestDeriveFootprintAlphaFollowsConcavity(t *testing.T) {
+	mesh, vertices := lMesh()
+	notch, wing := Point{X: 13, Y: 13}, Point{X: 5, Y: 17}
+
+	convex := DeriveFootprint(mesh, vertices, "convex", 0)
+	if convex.Method != "convex" || len(convex.Polygons) != 1 {
+		t.Fatalf("convex footprint = %+v", convex)
+	}
+	if area := footprintArea(convex); math.Abs(area-350) > 1e-9 {
+		t.Errorf("convex hull area = %g, want 350", area)
+	}
+	if in, _ := pointInRing(notch, convex.Polygons[0][0], defaultEpsilon); !in {
+		t.Error("convex hull does not cover the notch")
+	}
+
+	alpha := DeriveFootprint(mesh, vertices, "alpha", 5)
+	if alpha.Method != "alpha" || len(alpha.Polygons) != 1 {
+		t.Fatalf("alpha footprint = %+v", alpha)
+	}
+	// Grid triangles at the inner corner may close a sliver of the notch
+	if area := footprintArea(alpha); area < 300 || area > 315 {
+		t.Errorf("alpha shape area = %g, want about the L's 300", area)
+	}
+	if in, _ := pointInRing(notch, alpha.Polygons[0][0], defaultEpsilon); in {
+		t.Error("alpha shape covers the notch")
+	}
+	if in, _ := pointInRing(wing, alpha.Polygons[0][0], defaultEpsilon); !in {
+		t.Error("alpha shape misses the wing")
+	}
+
+	// An alpha too small for any triangle falls back to the hull
+	if fallback := DeriveFootprint(mesh, vertices, "alpha", 1); fallback.Method != "convex" {
+		t.Errorf("alpha 1 used %s, want the convex fallback", fallback.Method)
+	}
+}
+
+// An object whose face corners start in a narrow footprint but mostly lie in
+// its neighbor: centroid matching takes the narrow one, majority the neighbor
+func TestMatchStrategies(t *testing.T) {
+	obj := "o wide\n" +
+		"v 1 1 0\nv 10 1 0\nv 10 5 0\n" +
+		"v 2 2 0\nv 12 2 0\nv 12 6 0\n" +
+		"v 3 3 0\nv 14 3 0\nv 14 7 0\n" +
+		"f 1 2 3\nf 4 5 6\nf 7 8 9\n"
+	footprints := []byte(`{"type":"FeatureCollection","features":[` +
+		`{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[4,0],[4,10],[0,10],[0,0]]]}},` +
+		`{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[4,0],[20,0],[20,10],[4,10],[4,0]]]}}]}`)
+
+	for strategy, want := range map[string]int{"centroid": 0, "majority": 1} {
+		var objects []SeparatedObject
+		var err error
+		captureStdout(t, func() {
+			objects, err = Separate(strings.NewReader(obj), footprints, SeparateOptions{TileSize: 500, MatchStrategy: strategy, Epsilon: defaultEpsilon})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(objects) != 1 || !objects[0].Matched || objects[0].FeatureIndex != want {
+			t.Errorf("%s matched %+v, want footprint %d", strategy, objects, want)
+		}
+	}
+}
+
+// An empty result exits with 3, apart from a usage error's 2
+func TestMainExitStatus(t *testing.T) {
+	dir := t.TempDir()
+	objFile := filepath.Join(dir, "tile.obj")
+	geojson := filepath.Join(dir, "footprints.geojson")
+	footprints := `{"type":"FeatureCollection","features":[` +
+		`{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[5,0],[5,5],[0,5],[0,0]]]}}]}`
+	for file, content := range map[string]string{objFile: "o a\nv 1 1 0\nv 2 1 0\nv 2 2 0\nf 1 2 3\n", geojson: footprints} {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		status int
+	}{
+		{"matched", []string{"-cx=0", "-cy=0"}, 0},
+		{"nothing matched", []string{"-cx=100", "-cy=100"}, 3},
+		{"unknown flag", []string{"-frobnicate"}, 2},
+	}
+	for _, tt := range tests {
+		var status int
+		log := captureStdout(t, func() {
+			status = Main("objseparator", append(tt.args, objFile, geojson, filepath.Join(dir, tt.name)))
+		})
+		if status != tt.status {
+			t.Errorf("%s: exit status = %d, want %d:\n%s", tt.name, status, tt.status, log)
+		}
+	}
+}
+
+// SeparateOptions.Epsilon widens the footprints' boundaries: the object lies
+// just outside the inner edge of an L-shaped footprint
+func TestSeparateEpsilon(t *testing.T) {
+	obj := "o edge\nv 3 2.000001 0\nv 4 3 0\nv 3 3 0\nf 1 2 3\n"
+	footprints := []byte(`{"type":"FeatureCollection","features":[` +
+		`{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[5,0],[5,2],[2,2],[2,5],[0,5],[0,0]]]}}]}`)
+
+	for eps, want := range map[float64]bool{0: false, 1e-5: true} {
+		var objects []SeparatedObject
+		var err error
+		captureStdout(t, func() {
+			objects, err = Separate(strings.NewReader(obj), footprints, SeparateOptions{TileSize: 500, Epsilon: eps})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(objects) != 1 || objects[0].Matched != want {
+			t.Errorf("epsilon %g matched %+v, want %v", eps, objects, want)
+		}
+	}
+}
+
+// The tolerance only widens the boundary: a point farther than eps from a
+// ring is classified by the exact crossing test
+func TestPointInRingTolerance(t *testing.T) {
+	// Inside lies above the diagonal from (0,0) to (10,10)
+	ring := []Point{{0, 0, 0}, {10, 10, 0}, {0, 10, 0}, {0, 0, 0}}
+	tests := []struct {
+		point          Point
+		inside, onRing bool
+	}{
+		{Point{6.5, 5, 0}, false, false}, // 1.06 below the diagonal
+		{Point{5, 6.5, 0}, true, false},  // 1.06 above it
+		{Point{5.5, 5, 0}, true, true},   // 0.35 below it
+		{Point{-0.5, 5, 0}, true, true},  // 0.5 left of the left edge
+		{Point{-1.5, 5, 0}, false, false},
+		{Point{0.5, -1, 0}, false, false}, // 1.12 below the corner at the origin
+	}
+	for _, tt := range tests {
+		if inside, onRing := pointInRing(tt.point, ring, 1); inside != tt.inside || onRing != tt.onRing {
+			t.Errorf("pointInRing(%v) = %v, %v; want %v, %v", tt.point, inside, onRing, tt.inside, tt.onRing)
+		}
+	}
+}
+
+// -timing reports every stage once, in pipeline order
+func TestTimingReportsEachStage(t *testing.T) {
+	dir := t.TempDir()
+	objFile := filepath.Join(dir, "tile.obj")
+	geojson := filepath.Join(dir, "footprints.geojson")
+	footprints := `{"type":"FeatureCollection","features":[` +
+		`{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[5,0],[5,5],[0,5],[0,0]]]}}]}`
+	for file, content := range map[string]string{objFile: "o a\nv 1 1 0\nv 2 1 0\nv 2 2 0\nf 1 2 3\n", geojson: footprints} {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var status int
+	log := captureStdout(t, func() {
+		status = Main("objseparator", []string{"-cx=0", "-cy=0", "-timing", objFile, geojson, filepath.Join(dir, "out")})
+	})
+	if status != 0 {
+		t.Fatalf("objseparator exited with %d:\n%s", status, log)
+	}
+	at := strings.Index(log, "Timing:\n")
+	if at < 0 {
+		t.Fatalf("no timing report:\n%s", log)
+	}
+	report := log[at:]
+	var stages []string
+	for _, line := range strings.Split(strings.TrimSpace(report), "\n")[1:] {
+		stages = append(stages, strings.TrimSpace(line[:strings.Index(line, ":")]))
+	}
+	want := append(append([]string{}, separatorStages...), "total")
+	if !reflect.DeepEqual(stages, want) {
+		t.Errorf("timing report lists %q, want %q:\n%s", stages, want, report)
+	}
+}
+
+// Malformed features and positions are errors rather than panics
+func TestReadGeomGeojsonRejectsMalformedFeatures(t *testing.T) {
+	for name, features := range map[string]string{
+		"feature":         `[1]`,
+		"position":        `[{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],"x",[0,0]]]}}]`,
+		"short position":  `[{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1],[0,0]]]}}]`,
+		"string ordinate": `[{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[0,0],[1,"0"],[1,1],[0,0]]]}}]`,
+	} {
+		var geojson map[string]interface{}
+		if err := json.Unmarshal([]byte(`{"type":"FeatureCollection","features":`+features+`}`), &geojson); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		captureStdout(t, func() {
+			_, _, _, err = ReadGeomGeojson(geojson, 0, 0, nil)
+		})
+		if err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+// readFootprints parses a GeoJSON FeatureCollection into footprints without
+// any coordinate offset
+func readFootprints(t *testing.T, collection string) []MultiPolygon {
+	t.Helper()
+	var geojson map[string]interface{}
+	if err := json.Unmarshal([]byte(collection), &geojson); err != nil {
+		t.Fatal(err)
+	}
+	var footprints []MultiPolygon
+	var err error
+	captureStdout(t, func() {
+		footprints, _, _, err = ReadGeomGeojson(geojson, 0, 0, nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return footprints
+}
+
+// The second part of a MultiPolygon keeps its own hole: a point in that hole
+// is outside, one in the part's ring is inside
+func TestIsPointInPolygonIslandHoles(t *testing.T) {
+	footprints := readFootprints(t, `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"MultiPolygon","coordinates":[`+
+		`[[[0,0],[10,0],[10,10],[0,10],[0,0]],[[4,4],[6,4],[6,6],[4,6],[4,4]]],`+
+		`[[[20,0],[30,0],[30,10],[20,10],[20,0]],[[24,4],[26,4],[26,6],[24,6],[24,4]]]]}}]}`)
+	for point, want := range map[Point]bool{
+		{X: 2, Y: 2}:  true,
+		{X: 5, Y: 5}:  false, // the first part's hole
+		{X: 22, Y: 2}: true,
+		{X: 25, Y: 5}: false, // the island's hole
+		{X: 15, Y: 5}: false, // between the parts
+	} {
+		if got := IsPointInPolygon(point, footprints[0], defaultEpsilon); got != want {
+			t.Errorf("IsPointInPolygon(%v) = %v, want %v", point, got, want)
+		}
+	}
+}
+
+// Polylines and points are counted and reported rather than dropped silently
+func TestReadMeshWarnsAboutNonPolygonalElements(t *testing.T) {
+	obj := "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 3\nl 1 2\nl 2 3\np 1\n"
+	var mesh [][][]Faces
+	var err error
+	log := captureStdout(t, func() {
+		_, _, mesh, err = ReadMesh([]byte(obj))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mesh) != 1 || len(mesh[0]) != 1 {
+		t.Errorf("mesh = %v, want the single face", mesh)
+	}
+	if !strings.Contains(log, "Warning: OBJ contains non-polygonal elements that were ignored: l=2, p=1") {
+		t.Errorf("no warning:\n%s", log)
+	}
+}
+
+// A Point feature is skipped with a warning and keeps an empty slot, so the
+// Polygon after it still gets its own feature index
+func TestReadGeomGeojsonSkipsNonArealFeatures(t *testing.T) {
+	var geojson map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"type":"FeatureCollection","features":[`+
+		`{"type":"Feature","properties":{"id":"p"},"geometry":{"type":"Point","coordinates":[5,5]}},`+
+		`{"type":"Feature","properties":{"id":"b"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]]]}}]}`), &geojson); err != nil {
+		t.Fatal(err)
+	}
+	var footprints []MultiPolygon
+	var extent Extent
+	var err error
+	log := captureStdout(t, func() {
+		footprints, _, extent, err = ReadGeomGeojson(geojson, 0, 0, nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(log, "Warning: GeoJSON contains non-areal features that were skipped: Point=1") {
+		t.Errorf("no warning:\n%s", log)
+	}
+	if len(footprints) != 2 || len(footprints[0].outer) != 0 || len(footprints[1].outer) != 5 {
+		t.Fatalf("footprints = %v, want an empty slot and the polygon", footprints)
+	}
+	if extent.minX != 0 || extent.maxX != 10 || extent.minY != 0 || extent.maxY != 10 {
+		t.Errorf("extent = %+v, want the polygon's only", extent)
+	}
+	if IsPointInPolygon(Point{X: 5, Y: 5}, footprints[0], defaultEpsilon) || !IsPointInPolygon(Point{X: 5, Y: 5}, footprints[1], defaultEpsilon) {
+		t.Error("the point feature matched instead of the polygon")
+	}
+}
+
+// Over an extent that is entirely negative, with a partial last column and
+// row, the grid reaches the max edge and every footprint lands in a tile
+func TestCreateTilesNegativeExtent(t *testing.T) {
+	var footprints []MultiPolygon
+	var extent Extent
+	for _, corner := range []Point{{X: -1000, Y: -2000}, {X: -955, Y: -1975}, {X: -931, Y: -1951}} {
+		x, y := corner.X, corner.Y
+		ring := []Point{{x, y, 0}, {x + 4, y, 0}, {x + 4, y + 4, 0}, {x, y + 4, 0}, {x, y, 0}}
+		for _, p := range ring {
+			GetExtent(p.X, p.Y, &extent)
+		}
+		footprints = append(footprints, MultiPolygon{outer: ring})
+	}
+
+	tiles := CreateTiles(extent, 10, footprints)
+	if len(tiles.childTiles) != 8*6 {
+		t.Errorf("%d tiles, want 8 x 6 over %+v", len(tiles.childTiles), extent)
+	}
+	reachesMax := false
+	found := make(map[int]bool)
+	for _, child := range tiles.childTiles {
+		if child.extent.maxX == extent.maxX && child.extent.maxY == extent.maxY {
+			reachesMax = true
+		}
+		for _, index := range child.index {
+			found[index] = true
+		}
+	}
+	if !reachesMax {
+		t.Errorf("no tile reaches the max corner (%v, %v)", extent.maxX, extent.maxY)
+	}
+	for i := range footprints {
+		if !found[i] {
+			t.Errorf("footprint %d is in no tile", i)
+		}
+	}
+}
+
+// -keep-outliers writes the unmatched object and its CSV row to their own
+// directory, while the matched object stays in the output directory
+func TestKeepOutliers(t *testing.T) {
+	dir := t.TempDir()
+	objFile := filepath.Join(dir, "tile.obj")
+	geojson := filepath.Join(dir, "footprints.geojson")
+	footprints := `{"type":"FeatureCollection","features":[` +
+		`{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[5,0],[5,5],[0,5],[0,0]]]}}]}`
+	obj := "o inside\nv 1 1 0\nv 2 1 0\nv 2 2 0\nf 1 2 3\no outside\nv 21 21 0\nv 22 21 0\nv 22 22 0\nf 4 5 6\n"
+	for file, content := range map[string]string{objFile: obj, geojson: footprints} {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	output, outliers := filepath.Join(dir, "out"), filepath.Join(dir, "unmatched")
+	var status int
+	log := captureStdout(t, func() {
+		status = Main("objseparator", []string{"-cx=0", "-cy=0", "-keep-outliers", outliers, objFile, geojson, output})
+	})
+	if status != 0 {
+		t.Fatalf("exit status %d:\n%s", status, log)
+	}
+	if got := outputFiles(t, output); fmt.Sprint(got) != "[tile_1_1.obj]" {
+		t.Errorf("output = %v, want only the matched object", got)
+	}
+	if got := outputFiles(t, outliers); fmt.Sprint(got) != "[tile.obj.unmatched.csv tile_21_21.obj]" {
+		t.Errorf("outliers = %v, want the unmatched object and its CSV", got)
+	}
+	csv, err := os.ReadFile(filepath.Join(outliers, "tile.obj.unmatched.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(csv) != "X,Y,Z,Index\n21.000000,21.000000,0.000000,1\n" {
+		t.Errorf("outlier CSV:\n%s", csv)
+	}
+	matched, err := os.ReadFile(objFile + ".csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(matched), "21.000000") {
+		t.Errorf("the outlier is in the matched CSV:\n%s", matched)
+	}
+}
+
+// The centroid CSV gives each object's base height as Z rather than 0, even
+// when no face starts at the base
+func TestCSVWritesBaseZ(t *testing.T) {
+	dir := t.TempDir()
+	objFile := filepath.Join(dir, "tile.obj")
+	geojson := filepath.Join(dir, "footprints.geojson")
+	footprints := `{"type":"FeatureCollection","features":[` +
+		`{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[5,0],[5,5],[0,5],[0,0]]]}}]}`
+	obj := "o wall\nv 1 1 5.25\nv 2 1 5.25\nv 2 1 9\nv 1 1 9\nf 3 4 1 2\n"
+	for file, content := range map[string]string{objFile: obj, geojson: footprints} {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var status int
+	log := captureStdout(t, func() {
+		status = Main("objseparator", []string{"-cx=0", "-cy=0", objFile, geojson, filepath.Join(dir, "out")})
+	})
+	if status != 0 {
+		t.Fatalf("exit status %d:\n%s", status, log)
+	}
+	csv, err := os.ReadFile(objFile + ".csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "X,Y,Z,Index\n2.000000,1.000000,5.250000,0\n"; string(csv) != want {
+		t.Errorf("CSV:\n%s\nwant:\n%s", csv, want)
+	}
+}
+
+// Two objects matched to one footprint, a body and a roof sharing an edge,
+// are written as a single object whose seam vertices are welded
+func TestWriteToObjMergesObjectsOfOneFootprint(t *testing.T) {
+	vertices := []Point{{0.2, 0.2, 0}, {0.3, 0.2, 0}, {0.2, 0.3, 0}, {0.3, 0.2, 0}, {0.3, 0.3, 0}, {0.2, 0.3, 0}}
+	normals := []Point{{0, 0, 1}}
+	mesh := [][][]Faces{triangleMesh(1), triangleMesh(4)}
+	dir := t.TempDir()
+	var written int
+	captureStdout(t, func() {
+		written, _ = WriteToObj("tile.obj", dir, []int{0, 0}, mesh, vertices, normals, 0, 0, false, nil)
+	})
+	if got := outputFiles(t, dir); written != 1 || !reflect.DeepEqual(got, []string{"tile_0_0.obj"}) {
+		t.Fatalf("wrote %d files %v, want one", written, got)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "tile_0_0.obj"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "v 0.200000 0.200000 0.000000\nv 0.300000 0.200000 0.000000\nv 0.200000 0.300000 0.000000\nv 0.300000 0.300000 0.000000\n" +
+		"vn 0.000000 0.000000 1.000000\no tile_0_0\nf 1//1 2//1 3//1 \nf 2//1 4//1 3//1 \n"
+	if string(data) != want {
+		t.Errorf("output:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+// A g-only OBJ with LF line endings splits into one object per group, as does
+// the same file with CRLF endings and a mix of o and g
+func TestReadMeshSplitsOnGroups(t *testing.T) {
+	gOnly := "v 0 0 0\nv 1 0 0\nv 0 1 0\nv 1 1 0\n" +
+		"g first\nf 1 2 3\ng second\nf 2 4 3\nf 1 2 4\ng third\nf 1 4 3\n"
+	for name, obj := range map[string]string{
+		"lf":    gOnly,
+		"crlf":  strings.ReplaceAll(gOnly, "\n", "\r\n"),
+		"mixed": strings.Replace(gOnly, "g second", "o second", 1),
+	} {
+		var mesh [][][]Faces
+		var err error
+		captureStdout(t, func() {
+			_, _, mesh, err = ReadMesh([]byte(obj))
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var sizes []int
+		for _, object := range mesh {
+			sizes = append(sizes, len(object))
+		}
+		if want := []int{1, 2, 1}; !reflect.DeepEqual(sizes, want) {
+			t.Errorf("%s: faces per object = %v, want %v", name, sizes, want)
+		}
+	}
+}
+
+// Group names and input paths with ../ or backslashes stay inside the output
+// directory
+func TestWriteToObjStaysInOutputDir(t *testing.T) {
+	vertices := []Point{{0.2, 0.2, 0}, {0.3, 0.2, 0}, {0.2, 0.3, 0}, {0.7, 0.7, 0}, {0.8, 0.7, 0}, {0.7, 0.8, 0}}
+	mesh := [][][]Faces{triangleMesh(1), triangleMesh(4)}
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "out")
+
+	var written, failed int
+	captureStdout(t, func() {
+		written, failed = WriteToObj("../tiles/tile.obj", dir, []int{0, 1}, mesh, vertices, []Point{{0, 0, 1}}, 0, 0, false,
+			map[int]string{0: "../../escape", 1: `..\..\evil`})
+	})
+	if written != 2 || failed != 0 {
+		t.Errorf("written, failed = %d, %d; want 2, 0", written, failed)
+	}
+	if got := outputFiles(t, parent); !reflect.DeepEqual(got, []string{"out"}) {
+		t.Errorf("files next to the output directory: %v", got)
+	}
+	for _, name := range outputFiles(t, dir) {
+		if !strings.HasPrefix(name, "tile_") || strings.Contains(name, "..") {
+			t.Errorf("unsafe output name %q", name)
+		}
+	}
+
+	for path, want := range map[string]bool{
+		filepath.Join(dir, "a.obj"):              true,
+		filepath.Join(dir, "..", "a.obj"):        false,
+		filepath.Join(dir, "sub", "..", "a.obj"): true,
+		filepath.Join(parent, "outside.obj"):     false,
+	} {
+		if got := insideDir(dir, path); got != want {
+			t.Errorf("insideDir(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+// -feature-filter leaves only matching features as candidate polygons: the
+// water body keeps an empty slot and lies outside the extent and the tiles
+func TestReadGeomGeojsonFeatureFilter(t *testing.T) {
+	var geojson map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"type":"FeatureCollection","features":[`+
+		`{"type":"Feature","properties":{"type":"water"},"geometry":{"type":"Polygon","coordinates":[[[20,0],[30,0],[30,10],[20,10],[20,0]]]}},`+
+		`{"type":"Feature","properties":{"type":"building"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]]]}}]}`), &geojson); err != nil {
+		t.Fatal(err)
+	}
+	filter, err := parseFeatureFilter("type=building")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var footprints []MultiPolygon
+	var properties []map[string]interface{}
+	var extent Extent
+	log := captureStdout(t, func() {
+		footprints, properties, extent, err = ReadGeomGeojson(geojson, 0, 0, filter)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(log, "Kept 1 of 2 features matching type=building") {
+		t.Errorf("filter not reported:\n%s", log)
+	}
+	if len(footprints) != 2 || len(properties) != 2 || len(footprints[0].outer) != 0 || len(footprints[1].outer) != 5 {
+		t.Fatalf("footprints = %v, want an empty slot for the water and the building", footprints)
+	}
+	if extent.maxX != 10 {
+		t.Errorf("extent = %+v, want the building's only", extent)
+	}
+	for _, tile := range CreateTiles(extent, 5, footprints).childTiles {
+		for _, index := range tile.index {
+			if index != 1 {
+				t.Errorf("tile %+v holds footprint %d", tile.extent, index)
+			}
+		}
+	}
+}
+
+// With georef the separated OBJ's vertices are back in the original CRS, like
+// the CSV; without it they stay in the offset local space
+func TestWriteToObjGeorefOutput(t *testing.T) {
+	vertices := []Point{{1.5, 2.25, 10}, {2.5, 2.25, 10}, {1.5, 3.25, 10}}
+	mesh := [][][]Faces{triangleMesh(1)}
+	const cx, cy = 692653.5, 9326565.25
+	for georef, want := range map[bool]string{
+		false: "v 1.500000 2.250000 10.000000\n",
+		true:  "v 692655.000000 9326567.500000 10.000000\n",
+	} {
+		dir := t.TempDir()
+		captureStdout(t, func() {
+			WriteToObj("tile.obj", dir, []int{0}, mesh, vertices, []Point{{0, 0, 1}}, cx, cy, georef, nil)
+		})
+		data, err := os.ReadFile(filepath.Join(dir, "tile_692655_9326567.obj"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(data), want) {
+			t.Errorf("georef=%v: output\n%s\nwant it to start with %q", georef, data, want)
+		}
+	}
+}
+
+// The dumped tile grid has one rectangle per non-empty tile, in the original
+// CRS, with the number and indices of its footprints
+func TestWriteTilesToGeoJSON(t *testing.T) {
+	var footprints []MultiPolygon
+	var extent Extent
+	for _, x := range []float64{1, 4, 25} {
+		ring := []Point{{x, 1, 0}, {x + 2, 1, 0}, {x + 2, 3, 0}, {x, 3, 0}, {x, 1, 0}}
+		for _, p := range ring {
+			GetExtent(p.X, p.Y, &extent)
+		}
+		footprints = append(footprints, MultiPolygon{outer: ring})
+	}
+	// Three 10 m columns over x 1..27; the middle one is empty
+	tiles := CreateTiles(extent, 10, footprints)
+
+	path := filepath.Join(t.TempDir(), "tiles.geojson")
+	captureStdout(t, func() {
+		if err := WriteTilesToGeoJSON(tiles, path, 1000, 2000); err != nil {
+			t.Fatal(err)
+		}
+	})
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var collection struct {
+		Features []struct {
+			Geometry struct {
+				Coordinates [][][]float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties struct {
+				Tile       int   `json:"tile"`
+				Count      int   `json:"count"`
+				Footprints []int `json:"footprints"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(data, &collection); err != nil {
+		t.Fatal(err)
+	}
+	if len(collection.Features) != 2 {
+		t.Fatalf("%d features, want the 2 non-empty tiles:\n%s", len(collection.Features), data)
+	}
+	for i, want := range []struct {
+		tile, count int
+		footprints  []int
+		corner      []float64
+	}{
+		{0, 2, []int{0, 1}, []float64{1001, 2001}},
+		{2, 1, []int{2}, []float64{1021, 2001}},
+	} {
+		feature := collection.Features[i]
+		if feature.Properties.Tile != want.tile || feature.Properties.Count != want.count || !reflect.DeepEqual(feature.Properties.Footprints, want.footprints) {
+			t.Errorf("feature %d properties = %+v, want %+v", i, feature.Properties, want)
+		}
+		if ring := feature.Geometry.Coordinates[0]; len(ring) != 5 || !reflect.DeepEqual(ring[0], want.corner) {
+			t.Errorf("feature %d ring = %v, want it to start at %v", i, ring, want.corner)
+		}
+	}
+}
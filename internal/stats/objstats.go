@@ -0,0 +1,261 @@
+package stats
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OBJStats holds the summary counts gathered from a single OBJ file
+type OBJStats struct {
+	Vertices       int
+	Normals        int
+	TexCoords      int
+	Faces          int
+	NonTriangular  int
+	Objects        int
+	Groups         int
+	MaterialFaces  map[string]int
+	MTLMaterials   int
+	MinX, MinY     float64
+	MinZ           float64
+	MaxX, MaxY     float64
+	MaxZ           float64
+	hasBoundingBox bool
+}
+
+func newOBJStats() OBJStats {
+	return OBJStats{
+		MaterialFaces: make(map[string]int),
+		MinX:          math.MaxFloat64,
+		MinY:          math.MaxFloat64,
+		MinZ:          math.MaxFloat64,
+		MaxX:          -math.MaxFloat64,
+		MaxY:          -math.MaxFloat64,
+		MaxZ:          -math.MaxFloat64,
+	}
+}
+
+// Main runs objstats with args, the command line after the program name, and
+// returns its exit status
+func Main(name string, args []string) int {
+	// Parse command-line arguments
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	inputPath := flags.String("input", "", "OBJ file or directory containing OBJ files")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if *inputPath == "" {
+		fmt.Println("Usage: go run objstats.go -input <obj_file_or_directory>")
+		return 0
+	}
+
+	fileInfo, err := os.Stat(*inputPath)
+	if err != nil {
+		fmt.Printf("Error accessing input path: %v\n", err)
+		return 0
+	}
+
+	var objFiles []string
+	if fileInfo.IsDir() {
+		objFiles, err = filepath.Glob(filepath.Join(*inputPath, "*.obj"))
+		if err != nil {
+			fmt.Printf("Error finding OBJ files: %v\n", err)
+			return 0
+		}
+	} else {
+		objFiles = []string{*inputPath}
+	}
+
+	if len(objFiles) == 0 {
+		fmt.Println("No OBJ files found")
+		return 0
+	}
+
+	total := newOBJStats()
+	errorFiles := []string{}
+
+	for _, objFile := range objFiles {
+		stats, err := collectOBJStats(objFile)
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", filepath.Base(objFile), err)
+			errorFiles = append(errorFiles, filepath.Base(objFile))
+			continue
+		}
+
+		printOBJStats(filepath.Base(objFile), stats)
+		total.add(stats)
+	}
+
+	// Print directory total
+	if len(objFiles) > 1 {
+		printOBJStats(fmt.Sprintf("TOTAL (%d files)", len(objFiles)-len(errorFiles)), total)
+	}
+	if len(errorFiles) > 0 {
+		fmt.Printf("Failed to read %d files: %v\n", len(errorFiles), errorFiles)
+	}
+	return 0
+}
+
+// collectOBJStats scans an OBJ file and its MTL library, counting elements as it goes
+func collectOBJStats(filePath string) (OBJStats, error) {
+	stats := newOBJStats()
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return stats, err
+	}
+	defer file.Close()
+
+	var mtlLib string
+	currentMaterial := ""
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "v":
+			stats.Vertices++
+			if len(fields) >= 4 {
+				x, errX := strconv.ParseFloat(fields[1], 64)
+				y, errY := strconv.ParseFloat(fields[2], 64)
+				z, errZ := strconv.ParseFloat(fields[3], 64)
+				if errX == nil && errY == nil && errZ == nil {
+					stats.extend(x, y, z)
+				}
+			}
+		case "vn":
+			stats.Normals++
+		case "vt":
+			stats.TexCoords++
+		case "o":
+			stats.Objects++
+		case "g":
+			stats.Groups++
+		case "mtllib":
+			if len(fields) > 1 {
+				mtlLib = fields[1]
+			}
+		case "usemtl":
+			if len(fields) > 1 {
+				currentMaterial = fields[1]
+			}
+		case "f":
+			stats.Faces++
+			if len(fields)-1 != 3 {
+				stats.NonTriangular++
+			}
+			stats.MaterialFaces[currentMaterial]++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return stats, err
+	}
+
+	// Count the materials defined in the MTL library if it can be found
+	if mtlLib != "" {
+		materials, err := parseMTLFile(filepath.Join(filepath.Dir(filePath), mtlLib))
+		if err != nil {
+			fmt.Printf("Warning: Could not parse MTL file: %v\n", err)
+		} else {
+			stats.MTLMaterials = len(materials)
+		}
+	}
+
+	return stats, nil
+}
+
+// Parse MTL file to extract material names
+func parseMTLFile(filePath string) (map[string]bool, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	materials := make(map[string]bool)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 1 && fields[0] == "newmtl" {
+			materials[fields[1]] = true
+		}
+	}
+
+	return materials, scanner.Err()
+}
+
+// extend grows the bounding box to include the given vertex
+func (s *OBJStats) extend(x, y, z float64) {
+	s.MinX = math.Min(s.MinX, x)
+	s.MinY = math.Min(s.MinY, y)
+	s.MinZ = math.Min(s.MinZ, z)
+	s.MaxX = math.Max(s.MaxX, x)
+	s.MaxY = math.Max(s.MaxY, y)
+	s.MaxZ = math.Max(s.MaxZ, z)
+	s.hasBoundingBox = true
+}
+
+// add accumulates another file's stats into a directory total
+func (s *OBJStats) add(other OBJStats) {
+	s.Vertices += other.Vertices
+	s.Normals += other.Normals
+	s.TexCoords += other.TexCoords
+	s.Faces += other.Faces
+	s.NonTriangular += other.NonTriangular
+	s.Objects += other.Objects
+	s.Groups += other.Groups
+	s.MTLMaterials += other.MTLMaterials
+	for material, count := range other.MaterialFaces {
+		s.MaterialFaces[material] += count
+	}
+	if other.hasBoundingBox {
+		s.extend(other.MinX, other.MinY, other.MinZ)
+		s.extend(other.MaxX, other.MaxY, other.MaxZ)
+	}
+}
+
+func printOBJStats(name string, stats OBJStats) {
+	fmt.Printf("== %s\n", name)
+	fmt.Printf("  Vertices:        %d\n", stats.Vertices)
+	fmt.Printf("  Normals:         %d\n", stats.Normals)
+	fmt.Printf("  Texture coords:  %d\n", stats.TexCoords)
+	fmt.Printf("  Faces:           %d (%d non-triangular)\n", stats.Faces, stats.NonTriangular)
+	fmt.Printf("  Objects (o):     %d\n", stats.Objects)
+	fmt.Printf("  Groups (g):      %d\n", stats.Groups)
+	fmt.Printf("  MTL materials:   %d\n", stats.MTLMaterials)
+	if stats.hasBoundingBox {
+		fmt.Printf("  Bounding box:    [%f %f %f] to [%f %f %f]\n",
+			stats.MinX, stats.MinY, stats.MinZ, stats.MaxX, stats.MaxY, stats.MaxZ)
+	}
+
+	// Print per-material face counts in a stable order
+	materials := make([]string, 0, len(stats.MaterialFaces))
+	for material := range stats.MaterialFaces {
+		materials = append(materials, material)
+	}
+	sort.Strings(materials)
+	for _, material := range materials {
+		label := material
+		if label == "" {
+			label = "(none)"
+		}
+		fmt.Printf("  Faces using %s: %d\n", label, stats.MaterialFaces[material])
+	}
+}
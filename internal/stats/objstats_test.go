@@ -0,0 +1,87 @@
+package stats
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// cubeOBJ is a 1 x 1 x 2 box with two materials: four quad walls in one group and
+// the top and bottom split into triangles in another
+const cubeOBJ = `mtllib cube.mtl
+o cube
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+v 0 0 2
+v 1 0 2
+v 1 1 2
+v 0 1 2
+vn 0 0 1
+vt 0 0
+vt 1 0
+g walls
+usemtl wall
+f 1 2 6 5
+f 2 3 7 6
+f 3 4 8 7
+f 4 1 5 8
+g caps
+usemtl roof
+f 5 6 7
+f 5 7 8
+f 1 3 2
+f 1 4 3
+`
+
+const cubeMTL = `newmtl wall
+Kd 0.8 0.8 0.8
+newmtl roof
+Kd 0.6 0.1 0.1
+newmtl unused
+`
+
+// Every count, the bounding box and the per-material faces of one OBJ
+func TestCollectOBJStats(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"cube.obj": cubeOBJ, "cube.mtl": cubeMTL} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := collectOBJStats(filepath.Join(dir, "cube.obj"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := OBJStats{
+		Vertices:       8,
+		Normals:        1,
+		TexCoords:      2,
+		Faces:          8,
+		NonTriangular:  4,
+		Objects:        1,
+		Groups:         2,
+		MaterialFaces:  map[string]int{"wall": 4, "roof": 4},
+		MTLMaterials:   3,
+		MaxX:           1,
+		MaxY:           1,
+		MaxZ:           2,
+		hasBoundingBox: true,
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("stats = %+v\nwant %+v", stats, want)
+	}
+
+	// A directory total adds up the files and spans their bounding boxes
+	total := newOBJStats()
+	total.add(stats)
+	shifted := stats
+	shifted.MinX, shifted.MaxX = 5, 6
+	total.add(shifted)
+	if total.Faces != 16 || total.MaterialFaces["wall"] != 8 || total.MinX != 0 || total.MaxX != 6 || total.MaxZ != 2 {
+		t.Errorf("total = %+v", total)
+	}
+}
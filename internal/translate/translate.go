@@ -0,0 +1,357 @@
+package translate
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fakmalpradana/OBJ2GML/citygml"
+	"github.com/fakmalpradana/OBJ2GML/internal/fileio"
+)
+
+// Main runs translate with args, the command line after the program name, and
+// returns its exit status
+func Main(name string, args []string) int {
+	// Define command-line flags
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	inputDirPtr := flags.String("input", "", "Input directory or file path (required)")
+	translationXPtr := flags.Float64("tx", 0.0, "X translation value")
+	translationYPtr := flags.Float64("ty", 0.0, "Y translation value")
+	translationZPtr := flags.Float64("tz", 0.0, "Z translation value")
+	outputDirPtr := flags.String("output", "", "Output directory (optional: default is inputDir_translated)")
+	workersPtr := flags.Int("workers", 4, "Number of concurrent workers")
+	invertPtr := flags.Bool("invert", false, "Negate the translation to undo an earlier run")
+	fromPtr := flags.String("from", "", "Source origin as x,y,z (use with -to instead of -tx/-ty/-tz)")
+	failOnErrorPtr := flags.Bool("fail-on-error", false, "Exit with status 1 when any file fails")
+	failFastPtr := flags.Bool("fail-fast", false, "Skip the remaining files after the first failure and exit with status 1")
+	toPtr := flags.String("to", "", "Target origin as x,y,z (use with -from instead of -tx/-ty/-tz)")
+
+	// Parse command-line arguments
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	// Validate required parameters
+	if *inputDirPtr == "" {
+		fmt.Println("Error: Input directory/file is required")
+		fmt.Println("Usage:")
+		fmt.Println("  go run translate.go -input=input/obj/dir -output=output/dir -tx=412345.123 -ty=9123456.123 -tz=0")
+		fmt.Println("  go run translate.go -input=input/obj/dir -from=412345.123,9123456.123,0 -to=0,0,0")
+		fmt.Println("  go run translate.go -input=input/gml/dir -tx=412345.123 -ty=9123456.123 -tz=0")
+		fmt.Println("Options:")
+		flags.PrintDefaults()
+		return 0
+	}
+
+	// Configuration parameters
+	inputDir := *inputDirPtr
+	translationX := *translationXPtr
+	translationY := *translationYPtr
+	translationZ := *translationZPtr
+	maxWorkers := *workersPtr
+
+	// An origin pair replaces the explicit offsets with their difference
+	if *fromPtr != "" || *toPtr != "" {
+		if *fromPtr == "" || *toPtr == "" {
+			fmt.Println("Error: -from and -to must be given together")
+			return 0
+		}
+		if translationX != 0 || translationY != 0 || translationZ != 0 {
+			fmt.Println("Error: -from/-to cannot be combined with -tx/-ty/-tz")
+			return 0
+		}
+		from, err := parseOrigin(*fromPtr)
+		if err != nil {
+			fmt.Printf("Error parsing -from: %v\n", err)
+			return 0
+		}
+		to, err := parseOrigin(*toPtr)
+		if err != nil {
+			fmt.Printf("Error parsing -to: %v\n", err)
+			return 0
+		}
+		translationX = to[0] - from[0]
+		translationY = to[1] - from[1]
+		translationZ = to[2] - from[2]
+	}
+
+	if *invertPtr {
+		translationX, translationY, translationZ = -translationX, -translationY, -translationZ
+	}
+
+	// Determine output directory
+	var outputDir string
+	if *outputDirPtr != "" {
+		// Use user-specified output directory
+		outputDir = *outputDirPtr
+		fmt.Printf("Using specified output directory: %s\n", outputDir)
+	} else {
+		// Create default output directory name
+		dirName := filepath.Base(inputDir)
+		parentDir := filepath.Dir(inputDir)
+		outputDir = filepath.Join(parentDir, dirName+"_translated")
+		fmt.Printf("Using default output directory: %s\n", outputDir)
+	}
+
+	// Create output directory if it doesn't exist
+	err := os.MkdirAll(outputDir, 0755)
+	if err != nil {
+		fmt.Printf("Error creating output directory: %v\n", err)
+		return 0
+	}
+
+	// Find all OBJ and CityGML files to process
+	var files []string
+
+	fileInfo, err := os.Stat(inputDir)
+	if err != nil {
+		fmt.Printf("Error accessing input path: %v\n", err)
+		return 0
+	}
+
+	if fileInfo.IsDir() {
+		// Process all OBJ and GML files in directory
+		for _, pattern := range []string{"*.obj", "*.gml", "*.xml"} {
+			matches, err := filepath.Glob(filepath.Join(inputDir, pattern))
+			if err != nil {
+				fmt.Printf("Error finding %s files: %v\n", pattern, err)
+				return 0
+			}
+			files = append(files, matches...)
+		}
+	} else if isGMLFile(inputDir) || strings.ToLower(filepath.Ext(inputDir)) == ".obj" {
+		// Process single file
+		files = []string{inputDir}
+	} else {
+		fmt.Println("Input must be an OBJ or GML file or a directory containing them")
+		return 0
+	}
+
+	totalFiles := len(files)
+	if totalFiles == 0 {
+		fmt.Println("No OBJ or GML files found to process")
+		return 0
+	}
+
+	fmt.Printf("Found %d files to process\n", totalFiles)
+	fmt.Printf("Translating by (%.6f, %.6f, %.6f)\n", translationX, translationY, translationZ)
+	fmt.Printf("Output directory: %s\n", outputDir)
+
+	// Use a wait group to track completion of goroutines
+	var wg sync.WaitGroup
+
+	// Channel to collect results
+	results := make(chan bool, totalFiles)
+	errorFiles := make(chan string, totalFiles)
+
+	// Process files concurrently with worker pool
+	semaphore := make(chan struct{}, maxWorkers)
+
+	// Set after the first failure so -fail-fast workers skip the files left
+	var failed atomic.Bool
+
+	for _, file := range files {
+		wg.Add(1)
+
+		go func(filePath string) {
+			defer wg.Done()
+
+			// Acquire semaphore
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			fileName := filepath.Base(filePath)
+			outputFile := filepath.Join(outputDir, fileName)
+			if *failFastPtr && failed.Load() {
+				return
+			}
+
+			translateFile := translateOBJFile
+			if isGMLFile(filePath) {
+				translateFile = translateGMLFile
+			}
+			err := translateFile(filePath, outputFile, translationX, translationY, translationZ)
+			if err != nil {
+				fmt.Printf("Error processing %s: %v\n", fileName, err)
+				errorFiles <- fileName
+				failed.Store(true)
+			} else {
+				results <- true
+			}
+		}(file)
+	}
+
+	// Close channels when all goroutines complete
+	go func() {
+		wg.Wait()
+		close(results)
+		close(errorFiles)
+	}()
+
+	// Count successful translations
+	successCount := 0
+	for range results {
+		successCount++
+	}
+
+	// Collect error files
+	var failedFiles []string
+	for fileName := range errorFiles {
+		failedFiles = append(failedFiles, fileName)
+	}
+
+	// Print summary
+	fmt.Printf("Successfully translated %d from %d files\n", successCount, totalFiles)
+	fmt.Printf("Output saved to: %s\n", outputDir)
+
+	if len(failedFiles) > 0 {
+		fmt.Printf("Failed to translate %d files: %v\n", len(failedFiles), failedFiles)
+		if *failFastPtr {
+			fmt.Printf("Skipped %d files after the first failure (-fail-fast)\n", totalFiles-successCount-len(failedFiles))
+		}
+		if *failOnErrorPtr || *failFastPtr {
+			return 1
+		}
+	}
+	return 0
+}
+
+// parseOrigin parses an "x,y,z" origin; a missing z defaults to 0
+func parseOrigin(value string) ([3]float64, error) {
+	var origin [3]float64
+	parts := strings.Split(value, ",")
+	if len(parts) < 2 || len(parts) > 3 {
+		return origin, fmt.Errorf("expected x,y or x,y,z but got %q", value)
+	}
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return origin, fmt.Errorf("invalid coordinate %q", part)
+		}
+		origin[i] = v
+	}
+	return origin, nil
+}
+
+// translateOBJFile reads an OBJ file, translates its vertices, and writes to output
+func translateOBJFile(inputPath, outputPath string, tx, ty, tz float64) error {
+	// Open input file
+	inFile, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to open input file: %v", err)
+	}
+	defer inFile.Close()
+
+	// The output is written in one go once the whole input was read
+	var writer bytes.Buffer
+	scanner := bufio.NewScanner(inFile)
+
+	// Increase scanner buffer size for large files
+	const maxCapacity = 1024 * 1024 // 1MB
+	buf := make([]byte, maxCapacity)
+	scanner.Buffer(buf, maxCapacity)
+
+	// Process file line by line
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Check if the line defines a vertex position. Tokenizing keeps vn/vt/vp
+		// lines untouched and also catches "v" followed by a tab.
+		parts := strings.Fields(line)
+		if len(parts) > 0 && parts[0] == "v" {
+			// Parse vertex coordinates
+			if len(parts) >= 4 { // "v x y z" format
+				x, err1 := strconv.ParseFloat(parts[1], 64)
+				y, err2 := strconv.ParseFloat(parts[2], 64)
+				z, err3 := strconv.ParseFloat(parts[3], 64)
+
+				if err1 == nil && err2 == nil && err3 == nil {
+					// Apply translation
+					x += tx
+					y += ty
+					z += tz
+
+					// Write translated vertex efficiently
+					fmt.Fprintf(&writer, "v %g %g %g", x, y, z)
+
+					// Add any additional vertex data (color, etc.)
+					for i := 4; i < len(parts); i++ {
+						fmt.Fprintf(&writer, " %s", parts[i])
+					}
+					fmt.Fprintln(&writer)
+					continue
+				}
+			}
+		}
+
+		// Write unchanged line
+		fmt.Fprintln(&writer, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading input file: %v", err)
+	}
+
+	if err := fileio.WriteFile(outputPath, writer.Bytes()); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	return nil
+}
+
+// isGMLFile reports whether path is a CityGML file rather than an OBJ
+func isGMLFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".gml" || ext == ".xml"
+}
+
+// translateGMLFile shifts every posList, pos and envelope corner of a CityGML
+// file by the translation; everything else is copied unchanged
+func translateGMLFile(inputPath, outputPath string, tx, ty, tz float64) error {
+	content, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to open input file: %v", err)
+	}
+
+	translated, err := citygml.RewriteCoordinates(content, func(name, text string) string {
+		return translateCoordinates(text, tx, ty, tz)
+	})
+	if err != nil {
+		return fmt.Errorf("error parsing GML: %v", err)
+	}
+
+	if err := fileio.WriteFile(outputPath, translated); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	return nil
+}
+
+// translateCoordinates shifts each x y z triple; text that is not a whole
+// number of triples is left unchanged
+func translateCoordinates(text string, tx, ty, tz float64) string {
+	coords := strings.Fields(text)
+	if len(coords) == 0 || len(coords)%3 != 0 {
+		return text
+	}
+
+	offsets := [3]float64{tx, ty, tz}
+	translated := make([]string, len(coords))
+	for i, coord := range coords {
+		value, err := strconv.ParseFloat(coord, 64)
+		if err != nil {
+			return text
+		}
+		translated[i] = fmt.Sprintf("%f", value+offsets[i%3])
+	}
+	return strings.Join(translated, " ")
+}
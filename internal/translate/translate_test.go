@@ -0,0 +1,111 @@
+package translate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// dirEntries lists the names in dir
+func dirEntries(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestTranslateOBJFile(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.obj")
+	output := filepath.Join(dir, "out.obj")
+	if err := os.WriteFile(input, []byte("# cube\nv 1 2 3\nv\t4 5 6 0.5\nvn 0 0 1\nf 1 2 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := translateOBJFile(input, output, 10, 20, -3); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# cube\nv 11 22 0\nv 14 25 3 0.5\nvn 0 0 1\nf 1 2 1\n"; string(got) != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+// A read error part way through the input leaves the previous output as it
+// was, with no partial or temporary file next to it
+func TestTranslateFailureLeavesNoPartialFile(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		input     string
+		translate func(inputPath, outputPath string, tx, ty, tz float64) error
+	}{
+		// The scanner gives up on a line over its 1 MB buffer
+		{"in.obj", "v 1 2 3\nv 4 5 6\n# " + strings.Repeat("x", 2<<20) + "\nv 7 8 9\n", translateOBJFile},
+		{"in.gml", "<CityModel><posList>1 2 3</posList><unclosed>", translateGMLFile},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, test.name)
+			output := filepath.Join(dir, "out"+filepath.Ext(test.name))
+			if err := os.WriteFile(input, []byte(test.input), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(output, []byte("previous run\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := test.translate(input, output, 1, 1, 1); err == nil {
+				t.Fatal("translation did not fail")
+			}
+			if got, _ := os.ReadFile(output); string(got) != "previous run\n" {
+				t.Errorf("output changed to %d bytes starting %.40q", len(got), got)
+			}
+			if names := dirEntries(t, dir); len(names) != 2 {
+				t.Errorf("files left = %v, want only the input and output", names)
+			}
+		})
+	}
+}
+
+// Translating and then translating back with -invert restores the input, and
+// a -from/-to pair shifts by the difference of the origins
+func TestInvertRestoresInput(t *testing.T) {
+	dir := t.TempDir()
+	original := "v 1 2 3\nv 4.25 5.5 6\nf 1 2 1\n"
+	input := filepath.Join(dir, "in")
+	if err := os.Mkdir(input, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(input, "a.obj"), []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	moved, restored, origins := filepath.Join(dir, "moved"), filepath.Join(dir, "restored"), filepath.Join(dir, "origins")
+
+	offsets := []string{"-tx", "412345.5", "-ty", "9123456.25", "-tz", "-7"}
+	if status := Main("translate", append([]string{"-input", input, "-output", moved}, offsets...)); status != 0 {
+		t.Fatalf("translate exited with %d", status)
+	}
+	if status := Main("translate", append([]string{"-input", moved, "-output", restored, "-invert"}, offsets...)); status != 0 {
+		t.Fatalf("translate -invert exited with %d", status)
+	}
+	if got, err := os.ReadFile(filepath.Join(restored, "a.obj")); err != nil || string(got) != original {
+		t.Errorf("restored = %q, %v; want %q", got, err, original)
+	}
+
+	if status := Main("translate", []string{"-input", input, "-output", origins, "-from", "0,0,7", "-to", "412345.5,9123456.25,0"}); status != 0 {
+		t.Fatalf("translate -from -to exited with %d", status)
+	}
+	want, _ := os.ReadFile(filepath.Join(moved, "a.obj"))
+	if got, err := os.ReadFile(filepath.Join(origins, "a.obj")); err != nil || string(got) != string(want) {
+		t.Errorf("-from/-to output = %q, %v; want %q", got, err, want)
+	}
+}
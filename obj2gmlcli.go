@@ -0,0 +1,15 @@
+//go:build ignore
+
+// obj2gmlcli runs the obj2gml subcommands with go run from the repository
+// root. go build ./cmd/obj2gml builds the same command as one binary.
+package main
+
+import (
+	"os"
+
+	"github.com/fakmalpradana/OBJ2GML/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Main("obj2gmlcli", os.Args[1:]))
+}
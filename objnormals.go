@@ -0,0 +1,15 @@
+//go:build ignore
+
+// objnormals recomputes OBJ vertex normals.
+// It is also the normals subcommand of cmd/obj2gml.
+package main
+
+import (
+	"os"
+
+	"github.com/fakmalpradana/OBJ2GML/internal/normals"
+)
+
+func main() {
+	os.Exit(normals.Main("objnormals", os.Args[1:]))
+}
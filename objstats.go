@@ -0,0 +1,15 @@
+//go:build ignore
+
+// objstats prints OBJ mesh statistics.
+// It is also the stats subcommand of cmd/obj2gml.
+package main
+
+import (
+	"os"
+
+	"github.com/fakmalpradana/OBJ2GML/internal/stats"
+)
+
+func main() {
+	os.Exit(stats.Main("objstats", os.Args[1:]))
+}